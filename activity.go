@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultIdleDigestDays is how long a session goes untouched before the digest lists it
+	defaultIdleDigestDays = 7
+	// idleDigestEvery is how often the idle digest is posted
+	idleDigestEvery = 7 * 24 * time.Hour
+)
+
+// activityData is the content of ~/.ccsa/activity.json
+type activityData struct {
+	Channels   map[string]time.Time `json:"channels"` // channel ID -> last prompt or run
+	LastDigest time.Time            `json:"last_digest,omitempty"`
+}
+
+// ActivityStore remembers when each session channel was last used. Unlike the
+// timeline it keeps no history, so it never expires.
+type ActivityStore struct {
+	mu   sync.Mutex
+	path string
+	data *activityData
+}
+
+var activityStore = &ActivityStore{path: getActivityStorePath()}
+
+// getActivityStorePath returns the path to the activity file
+func getActivityStorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "activity.json")
+}
+
+func (s *ActivityStore) loadLocked() {
+	if s.data != nil {
+		return
+	}
+	s.data = &activityData{}
+	data, err := os.ReadFile(s.path)
+	if err == nil {
+		json.Unmarshal(data, s.data)
+	}
+	if s.data.Channels == nil {
+		s.data.Channels = make(map[string]time.Time)
+	}
+	if os.IsNotExist(err) {
+		// First use: start from what the timeline remembers
+		for channelID, at := range timeline.LastActivity() {
+			s.data.Channels[channelID] = at
+		}
+	}
+}
+
+func (s *ActivityStore) saveLocked() {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return
+	}
+	data, err := json.Marshal(s.data)
+	if err != nil {
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, s.path)
+}
+
+// Touch records activity in a channel
+func (s *ActivityStore) Touch(channelID string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	s.data.Channels[channelID] = at
+	s.saveLocked()
+}
+
+// Last returns when a channel was last active (zero if never)
+func (s *ActivityStore) Last(channelID string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	return s.data.Channels[channelID]
+}
+
+// DigestDue reports whether the idle digest should be posted, and records it
+// as posted when it is. The first check only starts the clock.
+func (s *ActivityStore) DigestDue(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	if !s.data.LastDigest.IsZero() && now.Sub(s.data.LastDigest) < idleDigestEvery {
+		return false
+	}
+	due := !s.data.LastDigest.IsZero()
+	s.data.LastDigest = now
+	s.saveLocked()
+	return due
+}
+
+// idleSession is a session untouched for a while
+type idleSession struct {
+	Name      string
+	ChannelID string
+	Last      time.Time // zero: no activity recorded
+}
+
+// idleSessions returns the sessions untouched for at least idle, least recently used first
+func idleSessions(sessions map[string]string, last func(channelID string) time.Time, idle time.Duration, now time.Time) []idleSession {
+	var idleList []idleSession
+	for name, channelID := range sessions {
+		at := last(channelID)
+		if at.IsZero() || now.Sub(at) >= idle {
+			idleList = append(idleList, idleSession{Name: name, ChannelID: channelID, Last: at})
+		}
+	}
+	sort.Slice(idleList, func(i, j int) bool {
+		if !idleList[i].Last.Equal(idleList[j].Last) {
+			return idleList[i].Last.Before(idleList[j].Last)
+		}
+		return idleList[i].Name < idleList[j].Name
+	})
+	return idleList
+}
+
+// parseIdleDuration reads "7d", "36h" or a number of days
+func parseIdleDuration(s string) (time.Duration, bool) {
+	if n, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil && n > 0 {
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, true
+	}
+	return 0, false
+}
+
+// formatIdleFor renders how long a session has been idle
+func formatIdleFor(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
+
+// formatIdleSessions lists idle sessions, with an Archive button for each
+// when buttons is set
+func formatIdleSessions(list []idleSession, idle time.Duration, now time.Time, buttons bool) (string, []Element) {
+	if len(list) == 0 {
+		return fmt.Sprintf(":sparkles: No session has been idle for %s", formatIdleFor(idle)), nil
+	}
+	lines := []string{fmt.Sprintf(":zzz: *Sessions idle for %s or more:*", formatIdleFor(idle))}
+	var archive []Element
+	for _, s := range list {
+		last := "no activity recorded"
+		if !s.Last.IsZero() {
+			last = fmt.Sprintf("idle %s (since %s)", formatIdleFor(now.Sub(s.Last)), s.Last.Local().Format("Jan 2"))
+		}
+		lines = append(lines, fmt.Sprintf("• `%s` → <#%s> - %s", s.Name, s.ChannelID, last))
+		if buttons && len(archive) < maxQueueButtons {
+			archive = append(archive, Element{
+				Type:     "button",
+				Text:     &TextObject{Type: "plain_text", Text: "Archive " + truncateRunes(s.Name, 60)},
+				ActionID: "idle_archive_" + s.ChannelID,
+				Value:    s.ChannelID,
+			})
+		}
+	}
+	return strings.Join(lines, "\n"), archive
+}
+
+// idleDigestDays returns the idle threshold of the digest
+func idleDigestDays(config *Config) int {
+	if config.IdleDigestDays > 0 {
+		return config.IdleDigestDays
+	}
+	return defaultIdleDigestDays
+}
+
+// idleDigestChannel returns where the weekly digest goes: idle_digest_channel,
+// or the first authorized user's DM with the bot
+func idleDigestChannel(config *Config) string {
+	if config.IdleDigestChannel != "" {
+		return config.IdleDigestChannel
+	}
+	if len(config.UserIDs) > 0 {
+		return config.UserIDs[0]
+	}
+	return config.UserID
+}
+
+// startIdleDigest posts the weekly list of idle sessions until stop is closed
+func startIdleDigest(cfgMgr *ConfigManager, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				config := cfgMgr.Get()
+				if config == nil || config.IdleDigestDays < 0 || !activityStore.DigestDue(now) {
+					continue
+				}
+				postIdleDigest(config, cfgMgr.GetAllSessions(), now)
+			}
+		}
+	}()
+}
+
+// postIdleDigest posts the idle sessions with Archive buttons (nothing when none is idle)
+func postIdleDigest(config *Config, sessions map[string]string, now time.Time) {
+	idle := time.Duration(idleDigestDays(config)) * 24 * time.Hour
+	list := idleSessions(sessions, activityStore.Last, idle, now)
+	target := idleDigestChannel(config)
+	if len(list) == 0 || target == "" {
+		return
+	}
+	text, buttons := formatIdleSessions(list, idle, now, true)
+	if err := sendMessageWithButtons(config, target, text+"\n_Weekly digest - archiving removes the session and archives its channel; the folder is kept._", buttons, "idle_digest"); err != nil {
+		logf("Failed to post idle digest: %v", err)
+	}
+}
+
+// handleIdleArchiveAction archives an idle session from the digest and refreshes it
+func handleIdleArchiveAction(cfgMgr *ConfigManager, config *Config, action BlockActionPayload, act BlockAction) {
+	channelID := act.Value
+	note := fmt.Sprintf(":information_source: <#%s> is no longer a session", channelID)
+	if name := removeSession(cfgMgr, channelID); name != "" {
+		note = fmt.Sprintf(":wastebasket: `%s` removed by <@%s>", name, action.User.ID)
+		if err := archiveChannel(config, channelID); err != nil {
+			logf("Failed to archive channel: %v", err)
+			note += fmt.Sprintf(" (channel archive failed: %v)", err)
+		} else {
+			note += " and its channel archived"
+		}
+	}
+
+	now := time.Now()
+	idle := time.Duration(idleDigestDays(config)) * 24 * time.Hour
+	text, buttons := formatIdleSessions(idleSessions(cfgMgr.GetAllSessions(), activityStore.Last, idle, now), idle, now, true)
+	updateMessageWithButtons(config, action.Channel.ID, action.Message.TS, text+"\n\n"+note, buttons, "idle_digest")
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Remote executor agents: another machine runs `agent`, connects to the
+// listener's agent_listen address, and runs Claude for the sessions mapped to
+// it in session_hosts. Output streams back line by line, so the Slack side
+// (threads, heartbeats, tool messages) is the same as for local runs.
+
+// agentMessage is the wire format between the listener and an agent
+type agentMessage struct {
+	Type  string   `json:"type"` // hello, welcome, run, cancel, mkdir, line, exit
+	ID    string   `json:"id,omitempty"`
+	Name  string   `json:"name,omitempty"`  // hello: agent name
+	Token string   `json:"token,omitempty"` // hello: shared agent_token
+	Dir   string   `json:"dir,omitempty"`   // run/mkdir: session directory, relative to the agent's projects dir
+	Args  []string `json:"args,omitempty"`  // run: claude arguments
+	Env   []string `json:"env,omitempty"`   // run: extra environment (scoped credentials)
+	Data  string   `json:"data,omitempty"`  // line: one stdout line; exit: error message
+	Code  int      `json:"code,omitempty"`  // exit: exit code
+}
+
+// AgentHub tracks connected agents (listener side)
+type AgentHub struct {
+	mu     sync.Mutex
+	agents map[string]*agentConn
+	nextID int
+}
+
+var agentHub = &AgentHub{agents: make(map[string]*agentConn)}
+
+// agentConn is one connected agent
+type agentConn struct {
+	name        string
+	ws          *websocket.Conn
+	sendMu      sync.Mutex
+	mu          sync.Mutex
+	runs        map[string]*remoteRun
+	connectedAt time.Time
+}
+
+func (a *agentConn) send(msg agentMessage) error {
+	a.sendMu.Lock()
+	defer a.sendMu.Unlock()
+	return websocket.JSON.Send(a.ws, msg)
+}
+
+// remoteRun is a Claude process running on an agent. Its stdout is exposed
+// through a pipe so callers read it like a local process.
+type remoteRun struct {
+	id     string
+	agent  *agentConn
+	stdout *io.PipeReader
+	writer *io.PipeWriter
+	done   chan struct{}
+	once   sync.Once
+	err    error
+}
+
+// finish records the outcome and unblocks readers and Wait
+func (r *remoteRun) finish(err error) {
+	r.once.Do(func() {
+		r.err = err
+		r.writer.Close()
+		close(r.done)
+	})
+}
+
+// Wait blocks until the remote process exits
+func (r *remoteRun) Wait() error {
+	<-r.done
+	return r.err
+}
+
+// Exited reports whether the remote process is done
+func (r *remoteRun) Exited() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Kill asks the agent to stop the process
+func (r *remoteRun) Kill() error {
+	if r.Exited() {
+		return nil
+	}
+	return r.agent.send(agentMessage{Type: "cancel", ID: r.id})
+}
+
+// Start runs claude with args in the session directory on the named agent
+func (h *AgentHub) Start(host, dir string, args, env []string) (*remoteRun, error) {
+	return h.start(host, agentMessage{Type: "run", Dir: dir, Args: args, Env: env})
+}
+
+// Mkdir creates a session directory on the named agent
+func (h *AgentHub) Mkdir(host, dir string) error {
+	run, err := h.start(host, agentMessage{Type: "mkdir", Dir: dir})
+	if err != nil {
+		return err
+	}
+	go io.Copy(io.Discard, run.stdout)
+	select {
+	case <-run.done:
+		return run.err
+	case <-time.After(30 * time.Second):
+		return fmt.Errorf("agent `%s` did not answer", host)
+	}
+}
+
+func (h *AgentHub) start(host string, msg agentMessage) (*remoteRun, error) {
+	h.mu.Lock()
+	agent, ok := h.agents[host]
+	h.nextID++
+	msg.ID = strconv.Itoa(h.nextID)
+	h.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("agent `%s` is not connected", host)
+	}
+
+	pr, pw := io.Pipe()
+	run := &remoteRun{id: msg.ID, agent: agent, stdout: pr, writer: pw, done: make(chan struct{})}
+	agent.mu.Lock()
+	agent.runs[run.id] = run
+	agent.mu.Unlock()
+
+	if err := agent.send(msg); err != nil {
+		agent.mu.Lock()
+		delete(agent.runs, run.id)
+		agent.mu.Unlock()
+		return nil, fmt.Errorf("agent `%s`: %w", host, err)
+	}
+	return run, nil
+}
+
+// Connected lists connected agents and since when
+func (h *AgentHub) Connected() map[string]time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	agents := make(map[string]time.Time, len(h.agents))
+	for name, a := range h.agents {
+		agents[name] = a.connectedAt
+	}
+	return agents
+}
+
+// handleAgent authenticates an agent and relays its run output
+func (h *AgentHub) handleAgent(cfgMgr *ConfigManager, ws *websocket.Conn) {
+	defer ws.Close()
+
+	var hello agentMessage
+	ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if err := websocket.JSON.Receive(ws, &hello); err != nil || hello.Type != "hello" || hello.Name == "" {
+		return
+	}
+	ws.SetReadDeadline(time.Time{})
+	token := cfgMgr.Get().AgentToken
+	if token == "" || !hmac.Equal([]byte(hello.Token), []byte(token)) {
+		logf("Agent %q rejected: bad token (from %s)", hello.Name, ws.Request().RemoteAddr)
+		websocket.JSON.Send(ws, agentMessage{Type: "exit", Code: 1, Data: "invalid agent token"})
+		return
+	}
+
+	agent := &agentConn{name: hello.Name, ws: ws, runs: make(map[string]*remoteRun), connectedAt: time.Now()}
+	h.mu.Lock()
+	if old, ok := h.agents[hello.Name]; ok {
+		old.ws.Close()
+	}
+	h.agents[hello.Name] = agent
+	h.mu.Unlock()
+	agent.send(agentMessage{Type: "welcome"})
+	logf("Agent %s connected from %s", hello.Name, ws.Request().RemoteAddr)
+
+	defer func() {
+		h.mu.Lock()
+		if h.agents[hello.Name] == agent {
+			delete(h.agents, hello.Name)
+		}
+		h.mu.Unlock()
+		agent.mu.Lock()
+		for _, run := range agent.runs {
+			run.finish(fmt.Errorf("agent `%s` disconnected", hello.Name))
+		}
+		agent.mu.Unlock()
+		logf("Agent %s disconnected", hello.Name)
+	}()
+
+	for {
+		var msg agentMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return
+		}
+		agent.mu.Lock()
+		run := agent.runs[msg.ID]
+		if msg.Type == "exit" {
+			delete(agent.runs, msg.ID)
+		}
+		agent.mu.Unlock()
+		if run == nil {
+			continue
+		}
+
+		switch msg.Type {
+		case "line":
+			run.writer.Write([]byte(msg.Data + "\n"))
+		case "exit":
+			var err error
+			if msg.Code != 0 {
+				err = fmt.Errorf("exit code %d on agent `%s`: %s", msg.Code, hello.Name, msg.Data)
+			}
+			run.finish(err)
+		}
+	}
+}
+
+// serveAgents accepts agent connections on agent_listen until ctx is cancelled
+func serveAgents(ctx context.Context, cfgMgr *ConfigManager) {
+	config := cfgMgr.Get()
+	if config.AgentListen == "" {
+		return
+	}
+	if config.AgentToken == "" {
+		logf("agent_listen is set but agent_token is empty - not accepting agents")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/agent", websocket.Server{
+		Handler: func(ws *websocket.Conn) { agentHub.handleAgent(cfgMgr, ws) },
+		// Agents aren't browsers: skip the Origin check
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+	})
+	server := &http.Server{Addr: config.AgentListen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logf("Accepting agents on %s", config.AgentListen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logf("Agent server: %v", err)
+	}
+}
+
+// sessionHost returns the agent a channel's session runs on ("" = local)
+func sessionHost(config *Config, channelID string) string {
+	if config == nil || len(config.SessionHosts) == 0 {
+		return ""
+	}
+	return config.SessionHosts[getSessionByChannel(config, channelID)]
+}
+
+// formatAgentStatus lists agents for !agents
+func formatAgentStatus(config *Config) string {
+	if config.AgentListen == "" {
+		return ":satellite: No agents configured (set `agent_listen` and `agent_token` in the config)"
+	}
+	connected := agentHub.Connected()
+	names := make(map[string]bool)
+	for name := range connected {
+		names[name] = true
+	}
+	sessionsByHost := make(map[string][]string)
+	for session, host := range config.SessionHosts {
+		names[host] = true
+		sessionsByHost[host] = append(sessionsByHost[host], session)
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf(":satellite: No agents connected to `%s` yet", config.AgentListen)
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	lines := []string{":satellite: *Agents*"}
+	for _, name := range sorted {
+		status := ":red_circle: offline"
+		if since, ok := connected[name]; ok {
+			status = ":large_green_circle: connected for " + formatDuration(time.Since(since))
+		}
+		line := fmt.Sprintf("• `%s` - %s", name, status)
+		if sessions := sessionsByHost[name]; len(sessions) > 0 {
+			sort.Strings(sessions)
+			line += " - sessions: " + strings.Join(sessions, ", ")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ============================================================================
+// Agent side
+// ============================================================================
+
+type agentOpts struct {
+	primary     string // ws://host:port/agent
+	name        string
+	token       string
+	projectsDir string
+}
+
+// runAgent connects to the primary listener and runs Claude for it, reconnecting with backoff
+func runAgent(opts agentOpts) error {
+	if opts.primary == "" || opts.token == "" {
+		return fmt.Errorf("usage: agent --primary ws://<listener-host>:<port>/agent --token <agent_token> [--name <name>] [--projects-dir <dir>]")
+	}
+	if opts.name == "" {
+		opts.name, _ = os.Hostname()
+	}
+	if opts.projectsDir == "" {
+		home, _ := os.UserHomeDir()
+		opts.projectsDir = filepath.Join(home, "code")
+	}
+	if claudePath == "" {
+		return fmt.Errorf("claude binary not found")
+	}
+
+	logf("Agent %s starting (projects: %s, primary: %s)", opts.name, opts.projectsDir, opts.primary)
+	attempt := 0
+	for {
+		connected, err := agentSession(opts)
+		if connected {
+			attempt = 0
+		}
+		if err != nil && strings.Contains(err.Error(), "invalid agent token") {
+			return err
+		}
+		delay := reconnectDelay(attempt, rand.Float64())
+		attempt++
+		logf("Agent: %v (reconnecting in %v)", err, delay.Round(100*time.Millisecond))
+		time.Sleep(delay)
+	}
+}
+
+// agentSession serves one connection to the primary. connected is true once it was accepted.
+func agentSession(opts agentOpts) (connected bool, err error) {
+	ws, err := websocket.Dial(opts.primary, "", "http://"+opts.name)
+	if err != nil {
+		return false, err
+	}
+	defer ws.Close()
+
+	var sendMu sync.Mutex
+	send := func(msg agentMessage) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		websocket.JSON.Send(ws, msg)
+	}
+
+	send(agentMessage{Type: "hello", Name: opts.name, Token: opts.token})
+	var reply agentMessage
+	if err := websocket.JSON.Receive(ws, &reply); err != nil {
+		return false, err
+	}
+	if reply.Type != "welcome" {
+		return false, fmt.Errorf("rejected by primary: %s", reply.Data)
+	}
+	logf("Agent: connected to %s", opts.primary)
+
+	var mu sync.Mutex
+	cancels := make(map[string]context.CancelFunc)
+	defer func() {
+		// Lost the primary: nobody reads the output anymore
+		mu.Lock()
+		for _, cancel := range cancels {
+			cancel()
+		}
+		mu.Unlock()
+	}()
+
+	for {
+		var msg agentMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return true, fmt.Errorf("connection lost: %w", err)
+		}
+
+		dir := filepath.Join(opts.projectsDir, msg.Dir)
+		if (msg.Type == "run" || msg.Type == "mkdir") && (msg.Dir == "" || !filepath.IsLocal(msg.Dir)) {
+			send(agentMessage{Type: "exit", ID: msg.ID, Code: 1, Data: fmt.Sprintf("invalid session directory %q", msg.Dir)})
+			continue
+		}
+
+		switch msg.Type {
+		case "mkdir":
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				send(agentMessage{Type: "exit", ID: msg.ID, Code: 1, Data: err.Error()})
+			} else {
+				send(agentMessage{Type: "exit", ID: msg.ID})
+			}
+
+		case "run":
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			mu.Lock()
+			cancels[msg.ID] = cancel
+			mu.Unlock()
+			go func(msg agentMessage) {
+				defer func() {
+					mu.Lock()
+					delete(cancels, msg.ID)
+					mu.Unlock()
+					cancel()
+				}()
+				code, errText := agentRunClaude(ctx, dir, msg.Args, msg.Env, func(line string) {
+					send(agentMessage{Type: "line", ID: msg.ID, Data: line})
+				})
+				send(agentMessage{Type: "exit", ID: msg.ID, Code: code, Data: errText})
+			}(msg)
+
+		case "cancel":
+			mu.Lock()
+			if cancel, ok := cancels[msg.ID]; ok {
+				logf("Agent: cancelling run %s", msg.ID)
+				cancel()
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// agentRunClaude runs claude in dir and hands every stdout line to emit
+func agentRunClaude(ctx context.Context, dir string, args, env []string, emit func(string)) (int, string) {
+	cmd := exec.CommandContext(ctx, claudePath, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 1, err.Error()
+	}
+	if err := cmd.Start(); err != nil {
+		return 1, err.Error()
+	}
+	logf("Agent: running claude in %s", dir)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			emit(line)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+			return exitErr.ExitCode(), msg
+		}
+		return 1, msg
+	}
+	return 0, ""
+}
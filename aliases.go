@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// helpCommandRe finds the commands documented in getHelpText
+var helpCommandRe = regexp.MustCompile("`(![a-z_]+)")
+
+var (
+	builtinOnce sync.Once
+	builtinSet  map[string]bool
+)
+
+// isBuiltinCommand reports whether name ("!x") is one of our own commands,
+// which aliases can't override
+func isBuiltinCommand(name string) bool {
+	builtinOnce.Do(func() {
+		builtinSet = map[string]bool{"!list": true}
+		for _, m := range helpCommandRe.FindAllStringSubmatch(getHelpText(), -1) {
+			builtinSet[m[1]] = true
+		}
+	})
+	return builtinSet[name] || strings.HasPrefix(name, "!claude_")
+}
+
+// aliasName normalizes an alias key to "!name"
+func aliasName(key string) string {
+	return "!" + strings.TrimPrefix(strings.TrimSpace(key), "!")
+}
+
+// expandAlias replaces a leading alias in text by what it stands for, keeping
+// the arguments after it. Aliases aren't expanded recursively, and those named
+// like a built-in command are ignored.
+func expandAlias(aliases map[string]string, text string) (string, bool) {
+	if len(aliases) == 0 || !strings.HasPrefix(text, "!") {
+		return text, false
+	}
+	name, args := text, ""
+	if i := strings.IndexAny(text, " \n"); i >= 0 {
+		name, args = text[:i], text[i:]
+	}
+	if isBuiltinCommand(name) {
+		return text, false
+	}
+	for key, expansion := range aliases {
+		if aliasName(key) == name && strings.TrimSpace(expansion) != "" {
+			return strings.TrimSpace(expansion) + args, true
+		}
+	}
+	return text, false
+}
+
+// formatAliases answers !aliases
+func formatAliases(aliases map[string]string) string {
+	if len(aliases) == 0 {
+		return "No aliases. Add some to `aliases` in the config: `{\"!deploy\": \"!c make deploy\", \"!t\": \"run the tests and summarize failures\"}`"
+	}
+	names := make([]string, 0, len(aliases))
+	expansions := make(map[string]string, len(aliases))
+	for key, expansion := range aliases {
+		name := aliasName(key)
+		names = append(names, name)
+		expansions[name] = strings.TrimSpace(expansion)
+	}
+	sort.Strings(names)
+
+	lines := []string{"*Aliases:*"}
+	for _, name := range names {
+		line := fmt.Sprintf("• `%s` → `%s`", name, timelinePromptText(expansions[name]))
+		if isBuiltinCommand(name) {
+			line += " :warning: _ignored: `" + name + "` is a built-in command_"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
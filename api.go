@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiSession is a session as returned by the REST API
+type apiSession struct {
+	Name      string    `json:"name"`
+	ChannelID string    `json:"channel_id"`
+	Host      string    `json:"host,omitempty"`    // agent running it, "" = local
+	Sandbox   bool      `json:"sandbox,omitempty"` // runs in Docker
+	Running   bool      `json:"running"`
+	Since     time.Time `json:"since,omitempty"`
+	Activity  string    `json:"activity,omitempty"`
+	Queue     int       `json:"queue"`
+}
+
+// apiError writes a JSON error
+func apiError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// apiHandler serves the REST control API:
+//
+//	GET  /api/sessions                 sessions with their run and queue state
+//	POST /api/sessions/{name}/prompt   run a prompt ({"text": ...} or a plain text body)
+//	POST /api/sessions/{name}/cancel   cancel the current run
+//
+// Every request needs "Authorization: Bearer <api_token>".
+func apiHandler(cfgMgr *ConfigManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := cfgMgr.Get()
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if config == nil || config.APIToken == "" || !hmac.Equal([]byte(token), []byte(config.APIToken)) {
+			apiError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		if r.URL.Path == "/api/sessions" {
+			if r.Method != http.MethodGet {
+				apiError(w, http.StatusMethodNotAllowed, "use GET")
+				return
+			}
+			writeJSON(w, apiSessions(config))
+			return
+		}
+
+		// Session names may contain slashes (money/shop): the action is the last segment
+		rest, ok := strings.CutPrefix(r.URL.Path, "/api/sessions/")
+		i := strings.LastIndex(rest, "/")
+		if !ok || i <= 0 {
+			apiError(w, http.StatusNotFound, "not found")
+			return
+		}
+		name, action := rest[:i], rest[i+1:]
+		if r.Method != http.MethodPost {
+			apiError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		channelID, ok := config.Sessions[name]
+		if !ok {
+			apiError(w, http.StatusNotFound, "unknown session %q", name)
+			return
+		}
+
+		switch action {
+		case "prompt":
+			text, err := readAPIPrompt(r)
+			if err != nil {
+				apiError(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+			_, running := activeProcesses.Load(channelID)
+			// The listener ignores bot messages: the post is for the record
+			ts, err := sendMessage(config, channelID, ":incoming_envelope: *Sent from the API:*\n"+text)
+			if err != nil {
+				apiError(w, http.StatusBadGateway, "posting to Slack: %v", err)
+				return
+			}
+			logf("API: prompt for %s", name)
+			runExternalPrompt(config, name, channelID, text, ts)
+			status := "started"
+			if running {
+				status = "queued"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]string{"status": status, "channel_id": channelID, "ts": ts})
+		case "cancel":
+			cancelled := CancelClaudeProcess(channelID)
+			if cancelled {
+				logf("API: cancelled the run of %s", name)
+				sendMessage(config, channelID, ":stop_sign: Task cancelled from the API")
+			}
+			writeJSON(w, map[string]bool{"cancelled": cancelled})
+		default:
+			apiError(w, http.StatusNotFound, "unknown action %q (prompt, cancel)", action)
+		}
+	})
+}
+
+// apiSessions lists the sessions, sorted by name
+func apiSessions(config *Config) []apiSession {
+	live := snapshotLiveState(config)
+	sessions := []apiSession{}
+	for name, channelID := range config.Sessions {
+		ch := live.Channels[channelID]
+		sessions = append(sessions, apiSession{
+			Name: name, ChannelID: channelID, Host: config.SessionHosts[name], Sandbox: config.SandboxSessions[name],
+			Running: ch.Running, Since: ch.Since, Activity: ch.Activity, Queue: ch.Queue,
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Name < sessions[j].Name })
+	return sessions
+}
+
+// readAPIPrompt reads the prompt of a request: JSON {"text": ...} or the raw body
+func readAPIPrompt(r *http.Request) (string, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	text := string(body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var req struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return "", fmt.Errorf("invalid JSON: %v", err)
+		}
+		text = req.Text
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", fmt.Errorf("empty prompt")
+	}
+	return text, nil
+}
+
+// serveAPI exposes the REST control API on api_listen
+func serveAPI(ctx context.Context, cfgMgr *ConfigManager) {
+	config := cfgMgr.Get()
+	if config.APIListen == "" {
+		return
+	}
+	if config.APIToken == "" {
+		logf("api_listen is set but api_token is empty - not serving the API")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", apiHandler(cfgMgr))
+	server := &http.Server{Addr: config.APIListen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logf("Serving the REST API on %s/api", config.APIListen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logf("API server: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// apiErrorWindow is how far back Slack API failures are counted
+	apiErrorWindow = 15 * time.Minute
+	// apiDegradeWindow is the recent slice used to decide whether to slow down
+	apiDegradeWindow = 5 * time.Minute
+	// apiDegradeMinFailures and apiDegradeRatio trip degraded mode together
+	apiDegradeMinFailures = 5
+	apiDegradeRatio       = 0.2
+)
+
+// Slower cadences used while Slack is failing or rate limiting us
+const (
+	degradedStreamInterval    = 2 * time.Second
+	degradedToolBatchDelay    = 3 * time.Second
+	degradedHeartbeatInterval = 10 * time.Second
+	degradedBatchWindow       = 5 * time.Second
+)
+
+// apiErrorBucket holds one minute of Slack API calls
+type apiErrorBucket struct {
+	calls    int
+	failures map[string]map[string]int // method -> class -> count
+}
+
+// APIErrorBudget counts Slack API failures per method and class over a rolling
+// window, and reports when the error rate is high enough to send less
+type APIErrorBudget struct {
+	mu          sync.Mutex
+	buckets     map[int64]*apiErrorBucket // unix minute -> bucket
+	rateLimited time.Time                 // last rate_limited response
+	degraded    bool                      // last reported state, to log changes
+}
+
+var apiErrors = &APIErrorBudget{buckets: make(map[int64]*apiErrorBucket)}
+
+// APIErrorCount is the number of failures of one class for one method
+type APIErrorCount struct {
+	Method string
+	Class  string
+	Count  int
+}
+
+// benignAPIErrors are Slack error codes for calls that changed nothing because
+// the state was already what we wanted; they are not failures
+var benignAPIErrors = map[string]bool{
+	"already_reacted":    true,
+	"no_reaction":        true,
+	"name_taken":         true,
+	"already_in_channel": true,
+}
+
+// classifyAPIError maps a Slack error code (or a transport failure) to the class
+// that is counted; Slack's own codes are kept as-is except for rate limiting,
+// and benign ones count as successes
+func classifyAPIError(slackError string, httpStatus int, networkErr error) string {
+	switch {
+	case networkErr != nil:
+		return "network"
+	case httpStatus == 429 || slackError == "ratelimited" || slackError == "rate_limited":
+		return "rate_limited"
+	case benignAPIErrors[slackError]:
+		return ""
+	case slackError != "":
+		return slackError
+	case httpStatus >= 500:
+		return fmt.Sprintf("http_%d", httpStatus)
+	}
+	return ""
+}
+
+// Record counts one API call; class is "" for a successful one
+func (b *APIErrorBudget) Record(method, class string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	minute := now.Unix() / 60
+	bucket := b.buckets[minute]
+	if bucket == nil {
+		bucket = &apiErrorBucket{failures: make(map[string]map[string]int)}
+		b.buckets[minute] = bucket
+		b.pruneLocked(now)
+	}
+	bucket.calls++
+	if class == "" {
+		return
+	}
+	if bucket.failures[method] == nil {
+		bucket.failures[method] = make(map[string]int)
+	}
+	bucket.failures[method][class]++
+	if class == "rate_limited" {
+		b.rateLimited = now
+	}
+}
+
+func (b *APIErrorBudget) pruneLocked(now time.Time) {
+	oldest := now.Add(-apiErrorWindow).Unix() / 60
+	for minute := range b.buckets {
+		if minute < oldest {
+			delete(b.buckets, minute)
+		}
+	}
+}
+
+// totalsLocked sums calls and failures for buckets within window
+func (b *APIErrorBudget) totalsLocked(now time.Time, window time.Duration) (calls, failures int) {
+	oldest := now.Add(-window).Unix() / 60
+	for minute, bucket := range b.buckets {
+		if minute < oldest {
+			continue
+		}
+		calls += bucket.calls
+		for _, classes := range bucket.failures {
+			for _, n := range classes {
+				failures += n
+			}
+		}
+	}
+	return calls, failures
+}
+
+// Degraded reports whether Slack is failing enough that we should post less:
+// rate limited in the recent window, or too many failed calls
+func (b *APIErrorBudget) Degraded(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	calls, failures := b.totalsLocked(now, apiDegradeWindow)
+	degraded := now.Sub(b.rateLimited) < apiDegradeWindow ||
+		(failures >= apiDegradeMinFailures && float64(failures) >= apiDegradeRatio*float64(calls))
+	if degraded != b.degraded {
+		b.degraded = degraded
+		if degraded {
+			logf("Slack API error rate high (%d/%d failed) - slowing down updates", failures, calls)
+		} else {
+			logf("Slack API error rate back to normal")
+		}
+	}
+	return degraded
+}
+
+// Snapshot returns calls, failures and per-method counts over the whole window,
+// most frequent first
+func (b *APIErrorBudget) Snapshot(now time.Time) (int, int, []APIErrorCount) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	calls, failures := b.totalsLocked(now, apiErrorWindow)
+	merged := make(map[[2]string]int)
+	oldest := now.Add(-apiErrorWindow).Unix() / 60
+	for minute, bucket := range b.buckets {
+		if minute < oldest {
+			continue
+		}
+		for method, classes := range bucket.failures {
+			for class, n := range classes {
+				merged[[2]string{method, class}] += n
+			}
+		}
+	}
+
+	counts := make([]APIErrorCount, 0, len(merged))
+	for key, n := range merged {
+		counts = append(counts, APIErrorCount{Method: key[0], Class: key[1], Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Method+counts[i].Class < counts[j].Method+counts[j].Class
+	})
+	return calls, failures, counts
+}
+
+// apiDegraded is Degraded for the current time
+func apiDegraded() bool {
+	return apiErrors.Degraded(time.Now())
+}
+
+// formatAPIErrorStats renders the error budget for Slack
+func formatAPIErrorStats(now time.Time) string {
+	calls, failures, counts := apiErrors.Snapshot(now)
+	header := fmt.Sprintf("*Slack API (last %d min)*: %d calls, %d failed", int(apiErrorWindow.Minutes()), calls, failures)
+	if apiErrors.Degraded(now) {
+		header += " - :warning: degraded (fewer updates, bigger batches)"
+	}
+	lines := []string{header}
+	for i, c := range counts {
+		if i == 8 {
+			lines = append(lines, fmt.Sprintf("• ...and %d more", len(counts)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("• `%s` %s: %d", c.Method, c.Class, c.Count))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RuntimeStats is a snapshot of the daemon's resource usage
+type RuntimeStats struct {
+	Goroutines      int
+	HeapAllocMB     float64
+	ClaudeSessions  int // claudeSessionIDs entries
+	ActiveProcesses int // activeProcesses entries
+	Persistent      int // persistent Claude processes
+	VerboseEntries  int // verboseMode entries
+	PinnedChannels  int // pinnedGitHubChannels entries
+	QueuedChannels  int // channels with a queue entry
+	PrunedTotal     int // entries removed by the auditor since start
+	LastAudit       time.Time
+}
+
+// runtimeAuditor periodically inspects and prunes in-memory state
+type runtimeAuditor struct {
+	mu          sync.Mutex
+	prunedTotal int
+	lastAudit   time.Time
+}
+
+var auditor = &runtimeAuditor{}
+
+// goroutineWarnThreshold logs a warning when the goroutine count exceeds it
+const goroutineWarnThreshold = 500
+
+// syncMapLen counts entries in a sync.Map
+func syncMapLen(m *sync.Map) int {
+	n := 0
+	m.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// collectRuntimeStats gathers current goroutine and map sizes
+func collectRuntimeStats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		Goroutines:      runtime.NumGoroutine(),
+		HeapAllocMB:     float64(mem.HeapAlloc) / (1024 * 1024),
+		ClaudeSessions:  syncMapLen(&claudeSessionIDs),
+		ActiveProcesses: syncMapLen(&activeProcesses),
+		Persistent:      persistentPool.Len(),
+		VerboseEntries:  syncMapLen(&verboseMode),
+		PinnedChannels:  syncMapLen(&pinnedGitHubChannels),
+	}
+	if messageQueue != nil {
+		stats.QueuedChannels = messageQueue.ChannelCount()
+	}
+
+	auditor.mu.Lock()
+	stats.PrunedTotal = auditor.prunedTotal
+	stats.LastAudit = auditor.lastAudit
+	auditor.mu.Unlock()
+	return stats
+}
+
+// startRuntimeAuditor runs the audit loop until stop is closed
+func startRuntimeAuditor(cfgMgr *ConfigManager, interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				runRuntimeAudit(cfgMgr)
+			}
+		}
+	}()
+}
+
+// runRuntimeAudit prunes stale entries and logs a summary
+func runRuntimeAudit(cfgMgr *ConfigManager) {
+	pruned := pruneFinishedProcesses()
+	if messageQueue != nil {
+		pruned += messageQueue.PruneIdle()
+	}
+	if config := cfgMgr.Get(); config != nil {
+		pruned += pruneArchivedChannels(config, cfgMgr.GetAllSessions())
+	}
+
+	auditor.mu.Lock()
+	auditor.prunedTotal += pruned
+	auditor.lastAudit = time.Now()
+	auditor.mu.Unlock()
+
+	stats := collectRuntimeStats()
+	logf("Audit: goroutines=%d heap=%.1fMB sessions=%d processes=%d queues=%d pruned=%d",
+		stats.Goroutines, stats.HeapAllocMB, stats.ClaudeSessions, stats.ActiveProcesses, stats.QueuedChannels, pruned)
+	if stats.Goroutines > goroutineWarnThreshold {
+		logf("WARNING: goroutine count %d exceeds %d - possible leak", stats.Goroutines, goroutineWarnThreshold)
+	}
+}
+
+// pruneFinishedProcesses removes activeProcesses entries whose process already
+// exited. Local and sandboxed runs delete their own entry once Wait returns
+// (reading their ProcessState here would race with it), so only runs with a
+// synchronized exit signal are checked.
+func pruneFinishedProcesses() int {
+	pruned := 0
+	activeProcesses.Range(func(key, value interface{}) bool {
+		finished := false
+		switch p := value.(type) {
+		case *remoteRun:
+			finished = p.Exited()
+		case *persistentClaude:
+			finished = !p.alive()
+		}
+		if finished {
+			activeProcesses.Delete(key)
+			pruned++
+		}
+		return true
+	})
+	return pruned
+}
+
+// pruneArchivedChannels drops per-channel state for channels that are archived or gone.
+// Channels still mapped to a session are never touched, nor those of other
+// chat backends (only Slack is asked whether a channel is archived).
+func pruneArchivedChannels(config *Config, sessions map[string]string) int {
+	mapped := make(map[string]bool, len(sessions))
+	for _, cid := range sessions {
+		mapped[cid] = true
+	}
+
+	candidates := make(map[string]bool)
+	collect := func(key, _ interface{}) bool {
+		if cid, ok := key.(string); ok && !mapped[cid] && onSlack(cid) {
+			candidates[cid] = true
+		}
+		return true
+	}
+	claudeSessionIDs.Range(collect)
+	verboseMode.Range(collect)
+	pinnedGitHubChannels.Range(collect)
+
+	pruned := 0
+	sessionsChanged := false
+	verboseChanged := false
+	pinnedChanged := false
+	for cid := range candidates {
+		if !isChannelArchived(config, cid) {
+			continue
+		}
+		if _, ok := claudeSessionIDs.LoadAndDelete(cid); ok {
+			sessionsChanged = true
+			pruned++
+		}
+		if _, ok := verboseMode.LoadAndDelete(cid); ok {
+			verboseChanged = true
+			pruned++
+		}
+		if _, ok := pinnedGitHubChannels.LoadAndDelete(cid); ok {
+			pinnedChanged = true
+			pruned++
+		}
+	}
+	if sessionsChanged {
+		saveSessionsToDisk()
+	}
+	if verboseChanged {
+		saveVerboseToDisk()
+	}
+	if pinnedChanged {
+		savePinnedChannelsToDisk()
+	}
+	return pruned
+}
+
+// isChannelArchived reports whether a channel is archived or no longer exists
+func isChannelArchived(config *Config, channelID string) bool {
+	params := url.Values{"channel": {channelID}}
+	req, err := http.NewRequest("GET", slackMethodURL(config, "conversations.info")+"?"+params.Encode(), nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Authorization", "Bearer "+config.BotToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK      bool `json:"ok"`
+		Channel struct {
+			IsArchived bool `json:"is_archived"`
+		} `json:"channel"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false
+	}
+	if !result.OK {
+		return result.Error == "channel_not_found"
+	}
+	return result.Channel.IsArchived
+}
+
+// formatRuntimeStats renders stats for Slack
+func formatRuntimeStats(stats RuntimeStats) string {
+	lines := []string{
+		fmt.Sprintf("• Goroutines: *%d*", stats.Goroutines),
+		fmt.Sprintf("• Heap: *%.1f MB*", stats.HeapAllocMB),
+		fmt.Sprintf("• Claude sessions: %d", stats.ClaudeSessions),
+		fmt.Sprintf("• Active processes: %d", stats.ActiveProcesses),
+		fmt.Sprintf("• Persistent Claude processes: %d", stats.Persistent),
+		fmt.Sprintf("• Verbose overrides: %d", stats.VerboseEntries),
+		fmt.Sprintf("• Pinned channels: %d", stats.PinnedChannels),
+		fmt.Sprintf("• Queue entries: %d", stats.QueuedChannels),
+		fmt.Sprintf("• Pruned since start: %d", stats.PrunedTotal),
+	}
+	if !stats.LastAudit.IsZero() {
+		lines = append(lines, fmt.Sprintf("• Last audit: %s ago", formatDuration(time.Since(stats.LastAudit))))
+	}
+	return ":bar_chart: *Runtime metrics*\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBatchWindow is how long we wait for follow-up messages before running Claude
+const defaultBatchWindow = 2 * time.Second
+
+// MessageBatcher coalesces rapid consecutive messages (same channel/thread) into one
+type MessageBatcher struct {
+	mu      sync.Mutex
+	pending map[string]*pendingBatch // channelID:threadTS -> batch
+}
+
+type pendingBatch struct {
+	messages []*QueuedMessage
+	timer    *time.Timer
+}
+
+// NewMessageBatcher creates a new batcher
+func NewMessageBatcher() *MessageBatcher {
+	return &MessageBatcher{
+		pending: make(map[string]*pendingBatch),
+	}
+}
+
+// Add buffers msg and calls flush with the combined message once no new message
+// arrived for the given window. A window <= 0 flushes immediately.
+func (mb *MessageBatcher) Add(msg *QueuedMessage, window time.Duration, flush func(*QueuedMessage)) {
+	if window <= 0 {
+		flush(msg)
+		return
+	}
+
+	key := msg.ChannelID + ":" + msg.ThreadTS
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	batch, ok := mb.pending[key]
+	if !ok {
+		batch = &pendingBatch{}
+		mb.pending[key] = batch
+	}
+	batch.messages = append(batch.messages, msg)
+
+	if batch.timer != nil {
+		batch.timer.Stop()
+	}
+	batch.timer = time.AfterFunc(window, func() {
+		mb.mu.Lock()
+		current := mb.pending[key]
+		if current != batch {
+			mb.mu.Unlock()
+			return
+		}
+		delete(mb.pending, key)
+		messages := batch.messages
+		mb.mu.Unlock()
+
+		flush(combineMessages(messages))
+	})
+}
+
+// Flush stops all pending timers and returns the combined pending messages
+func (mb *MessageBatcher) Flush() []*QueuedMessage {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	var flushed []*QueuedMessage
+	for key, batch := range mb.pending {
+		if batch.timer != nil {
+			batch.timer.Stop()
+		}
+		flushed = append(flushed, combineMessages(batch.messages))
+		delete(mb.pending, key)
+	}
+	return flushed
+}
+
+// combineMessages merges several queued messages into one prompt
+func combineMessages(messages []*QueuedMessage) *QueuedMessage {
+	if len(messages) == 1 {
+		return messages[0]
+	}
+
+	first := messages[0]
+	combined := &QueuedMessage{
+		ChannelID: first.ChannelID,
+		ThreadTS:  first.ThreadTS,
+		EventTS:   first.EventTS,
+		UserID:    first.UserID,
+		WorkDir:   first.WorkDir,
+	}
+
+	var texts []string
+	for i, m := range messages {
+		texts = append(texts, m.Text)
+		combined.FilePaths = append(combined.FilePaths, m.FilePaths...)
+		combined.Urgent = combined.Urgent || m.Urgent
+		if i > 0 {
+			combined.BatchedEventTS = append(combined.BatchedEventTS, m.EventTS)
+		}
+	}
+	combined.Text = strings.Join(texts, "\n\n")
+	return combined
+}
+
+// getBatchWindow returns the configured batching window
+func getBatchWindow(config *Config) time.Duration {
+	if config == nil || config.BatchWindowMs == 0 {
+		return defaultBatchWindow
+	}
+	if config.BatchWindowMs < 0 {
+		return 0
+	}
+	return time.Duration(config.BatchWindowMs) * time.Millisecond
+}
+
+// effectiveBatchWindow widens the batching window while Slack is failing, so
+// bursts of messages become one run (and one set of replies)
+func effectiveBatchWindow(config *Config) time.Duration {
+	window := getBatchWindow(config)
+	if window > 0 && window < degradedBatchWindow && apiDegraded() {
+		return degradedBatchWindow
+	}
+	return window
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// gitOutput runs git in dir and returns trimmed stdout
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// validBranchName checks a branch name with git's own rules
+func validBranchName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return false
+	}
+	return exec.Command("git", "check-ref-format", "--branch", name).Run() == nil
+}
+
+// ensureBranch checks out branch in workDir (creating it from HEAD if needed).
+// It refuses to switch when the repo is on another branch with uncommitted work.
+func ensureBranch(workDir, branch string) error {
+	current, err := gitOutput(workDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		// Fresh repo without commits still has a symbolic HEAD
+		current, err = gitOutput(workDir, "symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return fmt.Errorf("branch `%s` is set for this channel but %s is not a git repository", branch, workDir)
+		}
+	}
+	if current == branch {
+		return nil
+	}
+
+	status, err := gitOutput(workDir, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		return fmt.Errorf("repo is on `%s` with uncommitted changes, refusing to switch to `%s`. Commit or stash them, or use `!branch clear`", current, branch)
+	}
+
+	if _, err := gitOutput(workDir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		_, err = gitOutput(workDir, "checkout", branch)
+		return err
+	}
+	_, err = gitOutput(workDir, "checkout", "-b", branch)
+	return err
+}
+
+// ensureChannelBranch switches workDir to the channel's branch, if one is set
+func ensureChannelBranch(config *Config, channelID, workDir string) error {
+	if config == nil {
+		return nil
+	}
+	branch := config.ChannelBranches[channelID]
+	if branch == "" {
+		return nil
+	}
+	if err := ensureBranch(workDir, branch); err != nil {
+		return err
+	}
+	logf("Channel %s on branch %s", channelID, branch)
+	return nil
+}
+
+// autoCommitMessage derives a checkpoint commit message from the run's prompt
+func autoCommitMessage(prompt string) string {
+	prompt = strings.TrimSpace(strings.TrimPrefix(prompt, slackUserPrefix))
+	subject, _, multiline := strings.Cut(prompt, "\n")
+	subject = strings.TrimSpace(subject)
+	if r := []rune(subject); len(r) > 60 {
+		subject = strings.TrimSpace(string(r[:60])) + "..."
+		multiline = true
+	}
+	if subject == "" {
+		return "ccsa: checkpoint after Slack run"
+	}
+	msg := "ccsa: " + subject
+	if multiline {
+		if r := []rune(prompt); len(r) > 2000 {
+			prompt = string(r[:2000]) + "..."
+		}
+		msg += "\n\nPrompt:\n" + prompt
+	}
+	return msg
+}
+
+// autoCommit commits everything in workDir and returns the short hash ("" when
+// there was nothing to commit)
+func autoCommit(workDir, prompt string) (string, error) {
+	status, err := gitOutput(workDir, "status", "--porcelain")
+	if err != nil {
+		return "", err
+	}
+	if status == "" {
+		return "", nil
+	}
+	if _, err := gitOutput(workDir, "add", "-A"); err != nil {
+		return "", err
+	}
+	// Checkpoints must not be blocked by the project's pre-commit hooks
+	if _, err := gitOutput(workDir, "commit", "-q", "--no-verify", "-m", autoCommitMessage(prompt)); err != nil {
+		return "", err
+	}
+	return gitOutput(workDir, "rev-parse", "--short", "HEAD")
+}
+
+// checkpointRun commits what a successful run changed, if autocommit is on for the channel
+func checkpointRun(config *Config, msg *QueuedMessage, reply func(string)) {
+	if config == nil || !config.AutoCommitChannels[msg.ChannelID] || sessionHost(config, msg.ChannelID) != "" {
+		return
+	}
+	hash, err := autoCommit(msg.WorkDir, msg.Text)
+	if err != nil {
+		logf("Auto-commit failed in %s: %v", msg.WorkDir, err)
+		reply(fmt.Sprintf(":warning: Auto-commit failed: %v", err))
+		return
+	}
+	if hash != "" {
+		logf("Auto-commit %s in %s", hash, msg.WorkDir)
+		reply(fmt.Sprintf(":floppy_disk: Checkpoint `%s` (undo with `!c git reset --hard %s~1`)", hash, hash))
+	}
+}
+
+// cloneTimeout bounds `!new --clone`
+const cloneTimeout = 10 * time.Minute
+
+// isCloneURL reports whether s is a git URL `!new --clone` accepts
+// (https://, ssh://, git://, or scp-like git@host:org/repo)
+func isCloneURL(s string) bool {
+	if s == "" || strings.HasPrefix(s, "-") || strings.ContainsAny(s, " \t\n") {
+		return false
+	}
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://"} {
+		if strings.HasPrefix(s, prefix) {
+			return len(s) > len(prefix)
+		}
+	}
+	user, rest, ok := strings.Cut(s, "@")
+	return ok && user != "" && strings.Contains(rest, ":")
+}
+
+// repoNameFromCloneURL returns the repository name of a git URL
+// (git@github.com:org/api.git -> api)
+func repoNameFromCloneURL(url string) string {
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	if url == "." || url == ".." {
+		return ""
+	}
+	return url
+}
+
+// cloneRepo clones url into dir; prompts for credentials fail instead of hanging
+func cloneRepo(config *Config, url, dir string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cloneTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "clone", "--", url, dir)
+	cmd.Env = append(runEnv(config), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(sanitizeTerminalOutput(string(out))))
+	}
+	return nil
+}
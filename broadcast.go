@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const broadcastUsage = "Usage: `!broadcast <session,session,...|all> <prompt>` - run the same prompt in several sessions and report when they're done"
+
+const (
+	// defaultBroadcastConcurrency is how many sessions of a broadcast run at once
+	defaultBroadcastConcurrency = 3
+	// broadcastTimeout stops waiting for a session of a broadcast
+	broadcastTimeout = 30 * time.Minute
+)
+
+// broadcastResult is how one session of a broadcast ended
+type broadcastResult struct {
+	Session   string
+	ChannelID string
+	Resp      *ClaudeResponse
+	Err       error
+}
+
+// parseBroadcastArgs reads "<sessions|all> <prompt>" against the configured sessions
+func parseBroadcastArgs(sessions map[string]string, arg string) ([]string, string, error) {
+	target, prompt, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	prompt = strings.TrimSpace(prompt)
+	if target == "" || prompt == "" {
+		return nil, "", fmt.Errorf("%s", broadcastUsage)
+	}
+	var names []string
+	if target == "all" {
+		for name := range sessions {
+			names = append(names, name)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, name := range strings.Split(target, ",") {
+			if name == "" || seen[name] {
+				continue
+			}
+			if _, ok := sessions[name]; !ok {
+				return nil, "", fmt.Errorf("no session `%s` (see `!sessions`)", name)
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, "", fmt.Errorf("no sessions to broadcast to")
+	}
+	sort.Strings(names)
+	return names, prompt, nil
+}
+
+// broadcastConcurrency returns how many sessions of a broadcast run at once
+func broadcastConcurrency(config *Config) int {
+	if config.BroadcastConcurrency > 0 {
+		return config.BroadcastConcurrency
+	}
+	return defaultBroadcastConcurrency
+}
+
+// runBroadcast sends prompt to every session in names, at most
+// broadcast_concurrency at a time, and posts a report in the thread of
+// reportTS once all of them are done
+func runBroadcast(config *Config, sessions map[string]string, names []string, prompt, userID, originChannel, reportTS string) {
+	sem := make(chan struct{}, broadcastConcurrency(config))
+	results := make([]broadcastResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i] = broadcastResult{Session: name, ChannelID: sessions[name]}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(r *broadcastResult) {
+			defer func() { <-sem; wg.Done() }()
+			r.Resp, r.Err = broadcastToSession(config, r.Session, r.ChannelID, prompt, userID, originChannel)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	if err := sendMessageToThread(config, originChannel, reportTS, formatBroadcastReport(results)); err != nil {
+		logf("Failed to post broadcast report: %v", err)
+	}
+}
+
+// broadcastToSession runs the prompt in one session's channel and waits for it
+func broadcastToSession(config *Config, name, channelID, prompt, userID, originChannel string) (*ClaudeResponse, error) {
+	ts, err := sendMessage(config, channelID, fmt.Sprintf(":mega: *Broadcast* from <#%s> by <@%s>:\n%s", originChannel, userID, prompt))
+	if err != nil {
+		return nil, err
+	}
+
+	type outcome struct {
+		resp *ClaudeResponse
+		err  error
+	}
+	done := make(chan outcome, 1)
+	var once sync.Once
+	msg := &QueuedMessage{
+		Text:      slackUserPrefix + prompt,
+		ChannelID: channelID,
+		ThreadTS:  ts,
+		EventTS:   ts,
+		UserID:    userID,
+		WorkDir:   sessionWorkDir(config, name),
+		NoCache:   true,
+		OnFinish: func(resp *ClaudeResponse, err error) {
+			once.Do(func() { done <- outcome{resp, err} })
+		},
+	}
+	addReaction(config, channelID, ts, "eyes")
+	if offerStaleSessionResume(msg, config) {
+		return nil, fmt.Errorf("waiting on a choice in <#%s> (stale session)", channelID)
+	}
+	dispatchClaudeMessage(msg, config)
+
+	select {
+	case o := <-done:
+		return o.resp, o.err
+	case <-time.After(broadcastTimeout):
+		return nil, fmt.Errorf("still running after %s", formatDuration(broadcastTimeout))
+	}
+}
+
+// formatBroadcastReport renders the outcome of every session of a broadcast
+func formatBroadcastReport(results []broadcastResult) string {
+	ok := 0
+	var cost float64
+	var lines []string
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			lines = append(lines, fmt.Sprintf("• :x: <#%s> `%s`: %v", r.ChannelID, r.Session, r.Err))
+		case r.Resp.IsError:
+			cost += r.Resp.CostUSD
+			lines = append(lines, fmt.Sprintf("• :x: <#%s> `%s`: ended with an error", r.ChannelID, r.Session))
+		default:
+			ok++
+			cost += r.Resp.CostUSD
+			line := fmt.Sprintf("• :white_check_mark: <#%s> `%s`", r.ChannelID, r.Session)
+			if r.Resp.DurationMs > 0 {
+				line += fmt.Sprintf(" (%s)", formatDuration(time.Duration(r.Resp.DurationMs)*time.Millisecond))
+			}
+			if summary := timelinePromptText(r.Resp.Result); summary != "" {
+				line += ": " + summary
+			}
+			lines = append(lines, line)
+		}
+	}
+	header := fmt.Sprintf(":mega: *Broadcast finished* - %d/%d succeeded", ok, len(results))
+	if cost > 0 {
+		header += fmt.Sprintf(", $%.2f", cost)
+	}
+	return header + "\n" + strings.Join(lines, "\n")
+}
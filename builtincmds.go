@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Informational commands, as command plugins
+
+func init() {
+	registerCommand(pingCommand{})
+	registerCommand(metricsCommand{})
+	registerCommand(credsCommand{})
+	registerCommand(agentsCommand{})
+	registerCommand(versionCommand{})
+}
+
+type pingCommand struct{}
+
+func (pingCommand) Name() string { return "ping" }
+func (pingCommand) Help() string { return "Check if bot is alive" }
+func (pingCommand) Run(cc *CommandContext) error {
+	cc.Reply("pong!")
+	return nil
+}
+
+type metricsCommand struct{}
+
+func (metricsCommand) Name() string { return "metrics" }
+func (metricsCommand) Help() string {
+	return "Show runtime metrics (goroutines, memory, state sizes, Slack API errors)"
+}
+func (metricsCommand) Run(cc *CommandContext) error {
+	cc.Reply(formatRuntimeStats(collectRuntimeStats()) + "\n\n" + formatAPIErrorStats(time.Now()))
+	return nil
+}
+
+type credsCommand struct{}
+
+func (credsCommand) Name() string { return "creds" }
+func (credsCommand) Help() string { return "Show scoped credentials and their expiry" }
+func (credsCommand) Run(cc *CommandContext) error {
+	cc.Reply(formatCredentialStatus(cc.Config))
+	return nil
+}
+
+type agentsCommand struct{}
+
+func (agentsCommand) Name() string { return "agents" }
+func (agentsCommand) Help() string { return "Show remote executor agents and their sessions" }
+func (agentsCommand) Run(cc *CommandContext) error {
+	cc.Reply(formatAgentStatus(cc.Config))
+	return nil
+}
+
+type versionCommand struct{}
+
+func (versionCommand) Name() string { return "version" }
+func (versionCommand) Help() string { return "Show version" }
+func (versionCommand) Run(cc *CommandContext) error {
+	cc.Reply(fmt.Sprintf("v%s (build: %s)", version, buildTime))
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// readOnlyPromptRe matches prompts that ask about the code rather than change it
+	readOnlyPromptRe = regexp.MustCompile(`^(explain|describe|summari[sz]e|what|whats|why|how does|how do(es)? .* work|where|which|who|when|is there|are there|does|tell me about|can you explain)\b`)
+	// mutatingPromptRe vetoes anything that sounds like it acts on the repo
+	mutatingPromptRe = regexp.MustCompile(`\b(fix|change|edit|update|add|remove|delete|create|write|implement|refactor|rename|run|install|commit|push|deploy|build|test|generate|modify|move|apply|revert|merge)\b`)
+	whitespaceRe     = regexp.MustCompile(`\s+`)
+)
+
+// CachedResult is a previous answer to a read-only prompt
+type CachedResult struct {
+	Result    string
+	CreatedAt time.Time
+}
+
+// ResultCache holds answers per channel, keyed by prompt + git state
+type ResultCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]CachedResult // channel -> key -> result
+}
+
+var resultCache = &ResultCache{entries: make(map[string]map[string]CachedResult)}
+
+// pendingCacheReruns holds messages answered from cache, for the "Re-run fresh" button (channel:eventTS -> *QueuedMessage)
+var pendingCacheReruns sync.Map
+
+// Get returns a cached result no older than ttl
+func (c *ResultCache) Get(channelID, key string, ttl time.Duration) (CachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[channelID][key]
+	if !ok {
+		return CachedResult{}, false
+	}
+	if time.Since(entry.CreatedAt) > ttl {
+		delete(c.entries[channelID], key)
+		return CachedResult{}, false
+	}
+	return entry, true
+}
+
+// Store records the answer for a key
+func (c *ResultCache) Store(channelID, key, result string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[channelID] == nil {
+		c.entries[channelID] = make(map[string]CachedResult)
+	}
+	c.entries[channelID][key] = CachedResult{Result: result, CreatedAt: time.Now()}
+}
+
+// Clear drops all cached answers for a channel
+func (c *ResultCache) Clear(channelID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, channelID)
+}
+
+// normalizePrompt strips the Slack prefix, case and punctuation noise so
+// the same question typed on another device hits the same entry
+func normalizePrompt(text string) string {
+	text = strings.TrimPrefix(text, slackUserPrefix)
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = whitespaceRe.ReplaceAllString(text, " ")
+	return strings.TrimRight(text, "?!. ")
+}
+
+// isReadOnlyPrompt reports whether a prompt clearly only asks a question
+func isReadOnlyPrompt(text string) bool {
+	prompt := normalizePrompt(text)
+	if prompt == "" || strings.HasPrefix(prompt, "/") {
+		return false
+	}
+	return readOnlyPromptRe.MatchString(prompt) && !mutatingPromptRe.MatchString(prompt)
+}
+
+// gitStateRef identifies the code a question was asked against: HEAD plus
+// a hash of uncommitted changes. Returns false outside a git repository.
+func gitStateRef(workDir string) (string, bool) {
+	head, err := gitOutput(workDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", false
+	}
+	status, err := gitOutput(workDir, "status", "--porcelain")
+	if err != nil {
+		return "", false
+	}
+	if status == "" {
+		return head, true
+	}
+	diff, _ := gitOutput(workDir, "diff", "HEAD")
+	sum := sha256.Sum256([]byte(status + "\x00" + diff))
+	return head + "+" + hex.EncodeToString(sum[:8]), true
+}
+
+// resultCacheKey combines the normalized prompt and the git state
+func resultCacheKey(prompt, ref string) string {
+	sum := sha256.Sum256([]byte(normalizePrompt(prompt) + "\x00" + ref))
+	return hex.EncodeToString(sum[:])
+}
+
+// serveCachedResult answers msg from the cache when caching is enabled and the
+// prompt is read-only. Otherwise (or with msg.NoCache) it sets msg.CacheKey so
+// the fresh answer gets stored. Returns true when msg was answered.
+func serveCachedResult(msg *QueuedMessage, config *Config) bool {
+	if config.ResultCacheMinutes <= 0 || len(msg.FilePaths) > 0 || !isReadOnlyPrompt(msg.Text) {
+		return false
+	}
+	ref, ok := gitStateRef(msg.WorkDir)
+	if !ok {
+		return false
+	}
+	msg.CacheKey = resultCacheKey(msg.Text, ref)
+	if msg.NoCache {
+		return false
+	}
+	cached, hit := resultCache.Get(msg.ChannelID, msg.CacheKey, time.Duration(config.ResultCacheMinutes)*time.Minute)
+	if !hit {
+		return false
+	}
+	logf("Answering from cache for channel %s", msg.ChannelID)
+
+	answer := markdownToSlack(cached.Result)
+	if msg.ThreadTS != "" {
+		sendMessageToThread(config, msg.ChannelID, msg.ThreadTS, answer)
+	} else {
+		sendMessage(config, msg.ChannelID, answer)
+	}
+
+	rerunID := msg.ChannelID + ":" + msg.EventTS
+	pendingCacheReruns.Store(rerunID, msg)
+	text := fmt.Sprintf(":zap: _Cached answer from %s ago (same commit, no tokens used)_", formatDuration(time.Since(cached.CreatedAt)))
+	buttons := []Element{{
+		Type:     "button",
+		Text:     &TextObject{Type: "plain_text", Text: "Re-run fresh"},
+		ActionID: "cache_rerun",
+		Value:    rerunID,
+	}}
+	if err := sendMessageWithButtonsToThread(config, msg.ChannelID, msg.ThreadTS, text, buttons, "cache_"+msg.EventTS); err != nil {
+		logf("Failed to post re-run button: %v", err)
+		pendingCacheReruns.Delete(rerunID)
+	}
+
+	for _, ts := range msg.EventTimestamps() {
+		removeReaction(config, msg.ChannelID, ts, "eyes")
+		addReaction(config, msg.ChannelID, ts, "zap")
+	}
+	return true
+}
+
+// handleCacheRerun runs a cache-answered message through Claude after all
+func handleCacheRerun(config *Config, action BlockActionPayload, act BlockAction) {
+	pending, ok := pendingCacheReruns.LoadAndDelete(act.Value)
+	if !ok {
+		updateMessage(config, action.Channel.ID, action.Message.TS, ":information_source: Already re-run")
+		return
+	}
+	msg := pending.(*QueuedMessage)
+	msg.NoCache = true
+	updateMessage(config, action.Channel.ID, action.Message.TS, ":arrows_counterclockwise: Re-running fresh")
+	for _, ts := range msg.EventTimestamps() {
+		removeReaction(config, msg.ChannelID, ts, "zap")
+		addReaction(config, msg.ChannelID, ts, "eyes")
+	}
+	dispatchClaudeMessage(msg, config)
+}
@@ -0,0 +1,74 @@
+package main
+
+import "time"
+
+// Default cadence of a run's Slack updates
+const (
+	defaultStreamInterval = 500 * time.Millisecond
+	defaultStreamChars    = 500
+	defaultToolBatchDelay = 1 * time.Second
+)
+
+// StreamingConfig tunes how often a run's thread is updated; zero fields
+// keep the defaults. Slower values help when hitting Slack rate limits.
+type StreamingConfig struct {
+	// UpdateMs is the interval between edits of a streamed answer (default 500)
+	UpdateMs int `json:"update_ms,omitempty"`
+	// UpdateChars triggers an earlier edit once this much text is pending (default 500)
+	UpdateChars int `json:"update_chars,omitempty"`
+	// ToolBatchMs is how long tool calls are gathered into one message (default 1000)
+	ToolBatchMs int `json:"tool_batch_ms,omitempty"`
+	// CoalesceMs merges small posts made this close together into one
+	// message (default 1500, negative = post each one right away)
+	CoalesceMs int `json:"coalesce_ms,omitempty"`
+}
+
+// streamCadence is the resolved cadence of a channel's runs
+type streamCadence struct {
+	interval   time.Duration
+	chars      int
+	batchDelay time.Duration
+	coalesce   time.Duration // 0 = off
+}
+
+// streamingCadence resolves a channel's cadence: channel_streaming, then
+// streaming, then the defaults
+func streamingCadence(config *Config, channelID string) streamCadence {
+	c := streamCadence{interval: defaultStreamInterval, chars: defaultStreamChars, batchDelay: defaultToolBatchDelay, coalesce: defaultCoalesceWindow}
+	if config == nil {
+		return c
+	}
+	layers := []*StreamingConfig{config.Streaming}
+	if s, ok := config.ChannelStreaming[channelID]; ok {
+		layers = append(layers, &s)
+	}
+	for _, s := range layers {
+		if s == nil {
+			continue
+		}
+		if s.UpdateMs > 0 {
+			c.interval = time.Duration(s.UpdateMs) * time.Millisecond
+		}
+		if s.UpdateChars > 0 {
+			c.chars = s.UpdateChars
+		}
+		if s.ToolBatchMs > 0 {
+			c.batchDelay = time.Duration(s.ToolBatchMs) * time.Millisecond
+		}
+		if s.CoalesceMs > 0 {
+			c.coalesce = time.Duration(s.CoalesceMs) * time.Millisecond
+		} else if s.CoalesceMs < 0 {
+			c.coalesce = 0
+		}
+	}
+	// Never faster than the degraded cadence while Slack is struggling
+	if apiDegraded() {
+		c.interval = max(c.interval, degradedStreamInterval)
+		c.chars = 0
+		c.batchDelay = max(c.batchDelay, degradedToolBatchDelay)
+		if c.coalesce > 0 {
+			c.coalesce = max(c.coalesce, degradedToolBatchDelay)
+		}
+	}
+	return c
+}
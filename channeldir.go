@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// channelDirectoryTTL is how long the channel list is trusted without a
+// channel event (older installs aren't subscribed to them)
+const channelDirectoryTTL = time.Hour
+
+// channelEventTypes change channel names: they invalidate the channel directory
+var channelEventTypes = map[string]bool{
+	"channel_created":   true,
+	"channel_rename":    true,
+	"channel_deleted":   true,
+	"channel_archive":   true,
+	"channel_unarchive": true,
+	"group_rename":      true,
+	"group_deleted":     true,
+	"group_archive":     true,
+	"group_unarchive":   true,
+}
+
+// ChannelDirectory caches the workspace's channel name -> ID map
+type ChannelDirectory struct {
+	mu     sync.Mutex
+	ids    map[string]string
+	loaded time.Time
+}
+
+var channelDirectory = &ChannelDirectory{}
+
+// Lookup returns a cached channel ID; ok is false when the cache is stale
+// or doesn't know the name
+func (d *ChannelDirectory) Lookup(name string, now time.Time) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ids == nil || now.Sub(d.loaded) > channelDirectoryTTL {
+		return "", false
+	}
+	id, ok := d.ids[name]
+	return id, ok
+}
+
+// Fresh reports whether the cache was loaded recently enough to trust a miss
+func (d *ChannelDirectory) Fresh(now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ids != nil && now.Sub(d.loaded) <= channelDirectoryTTL
+}
+
+// Store replaces the cache with a full channel list
+func (d *ChannelDirectory) Store(ids map[string]string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ids = ids
+	d.loaded = now
+}
+
+// Add records one channel, e.g. one the bot just created
+func (d *ChannelDirectory) Add(name, id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ids != nil {
+		d.ids[name] = id
+	}
+}
+
+// Invalidate drops the cache; the next lookup lists the channels again
+func (d *ChannelDirectory) Invalidate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ids = nil
+}
+
+// findChannelByName returns the ID of a channel, from the cache when
+// possible, listing every channel of the workspace otherwise
+func findChannelByName(config *Config, name string) (string, error) {
+	now := time.Now()
+	if id, ok := channelDirectory.Lookup(name, now); ok {
+		return id, nil
+	}
+	if !channelDirectory.Fresh(now) {
+		ids, err := listChannels(config)
+		if err != nil {
+			return "", err
+		}
+		channelDirectory.Store(ids, now)
+		if id, ok := ids[name]; ok {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("channel not found: %s", name)
+}
+
+// listChannels pages through conversations.list (archived channels
+// included, since their names stay taken)
+func listChannels(config *Config) (map[string]string, error) {
+	ids := make(map[string]string)
+	cursor := ""
+	for {
+		params := url.Values{
+			"types": {"public_channel,private_channel"},
+			"limit": {"1000"},
+		}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+
+		req, err := http.NewRequest("GET", slackMethodURL(config, "conversations.list")+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+config.BotToken)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			OK               bool           `json:"ok"`
+			Channels         []SlackChannel `json:"channels"`
+			Error            string         `json:"error"`
+			ResponseMetadata struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"response_metadata"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+
+		if !result.OK {
+			return nil, fmt.Errorf("failed to list channels: %s", result.Error)
+		}
+		for _, ch := range result.Channels {
+			ids[ch.Name] = ch.ID
+		}
+		if result.ResponseMetadata.NextCursor == "" {
+			return ids, nil
+		}
+		cursor = result.ResponseMetadata.NextCursor
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChatBackend is a chat service sessions can live in. Slack is the default;
+// channels of other backends are told apart by their ID (see chatFor), so the
+// session, queue and Claude machinery stays the same for all of them.
+// Text is Slack mrkdwn, already redacted; backends convert it as needed.
+type ChatBackend interface {
+	// Name identifies the backend in logs ("slack", "discord")
+	Name() string
+	// MaxMessageLen is where longer messages are split
+	MaxMessageLen() int
+	// PostMessage posts one message (threadTS "" = in the channel) and returns its ID
+	PostMessage(config *Config, channelID, threadTS, text string) (string, error)
+	// UpdateMessage replaces a message's text, dropping its buttons
+	UpdateMessage(config *Config, channelID, ts, text string) error
+	DeleteMessage(config *Config, channelID, ts string) error
+	AddReaction(config *Config, channelID, ts, emoji string) error
+	RemoveReaction(config *Config, channelID, ts, emoji string) error
+	// PostButtons posts text with buttons (values already signed) and returns the message ID
+	PostButtons(config *Config, channelID, threadTS, text string, buttons []Element, blockID string) (string, error)
+	// UpdateButtons replaces a message's text and buttons
+	UpdateButtons(config *Config, channelID, ts, text string, buttons []Element, blockID string) error
+	// UploadSnippet posts content as a file and returns its URL
+	UploadSnippet(config *Config, channelID, threadTS, filename, content, title string) (string, error)
+	// UploadFile posts a local file and returns its URL
+	UploadFile(config *Config, channelID, threadTS, filePath, comment string) (string, error)
+	// ChannelName returns a channel's name (used to match project folders)
+	ChannelName(config *Config, channelID string) (string, error)
+	ArchiveChannel(config *Config, channelID string) error
+	// Listen delivers the backend's events until ctx is done
+	Listen(ctx context.Context, cfgMgr *ConfigManager)
+}
+
+var (
+	slackBackend      ChatBackend = slackChat{}
+	discordBackend    ChatBackend = discordChat{}
+	telegramBackend   ChatBackend = telegramChat{}
+	mattermostBackend ChatBackend = mattermostChat{}
+)
+
+// chatFor returns the backend of a channel: Discord channel IDs are numeric
+// snowflakes, Telegram ones are "tg<chat id>", Mattermost ones 26 lowercase
+// letters and digits, Slack ones start with C, D or G
+func chatFor(channelID string) ChatBackend {
+	switch {
+	case isDiscordID(channelID):
+		return discordBackend
+	case isTelegramID(channelID):
+		return telegramBackend
+	case isMattermostID(channelID):
+		return mattermostBackend
+	}
+	return slackBackend
+}
+
+// onSlack reports whether a channel is on Slack: channel renames, topics,
+// pins and bookmarks have no counterpart on the other backends
+func onSlack(channelID string) bool {
+	return chatFor(channelID) == slackBackend
+}
+
+// shortcodeEmoji maps the Slack shortcodes the bot uses to unicode, for
+// backends that only render shortcodes typed in their client
+var shortcodeEmoji = map[string]string{
+	"x":                         "❌",
+	"warning":                   "⚠️",
+	"white_check_mark":          "✅",
+	"information_source":        "ℹ️",
+	"wastebasket":               "🗑️",
+	"arrows_counterclockwise":   "🔄",
+	"page_facing_up":            "📄",
+	"mag":                       "🔍",
+	"file_folder":               "📁",
+	"eyes":                      "👀",
+	"shrug":                     "🤷",
+	"rocket":                    "🚀",
+	"question":                  "❓",
+	"label":                     "🏷️",
+	"hourglass":                 "⌛",
+	"hourglass_flowing_sand":    "⏳",
+	"floppy_disk":               "💾",
+	"arrow_forward":             "▶️",
+	"arrow_right":               "➡️",
+	"zap":                       "⚡",
+	"stop_sign":                 "🛑",
+	"robot_face":                "🤖",
+	"pencil2":                   "✏️",
+	"no_entry_sign":             "🚫",
+	"no_entry":                  "⛔",
+	"herb":                      "🌿",
+	"calendar":                  "📆",
+	"spiral_calendar_pad":       "🗓️",
+	"zzz":                       "💤",
+	"twisted_rightwards_arrows": "🔀",
+	"speech_balloon":            "💬",
+	"sparkles":                  "✨",
+	"scroll":                    "📜",
+	"satellite":                 "📡",
+	"rotating_light":            "🚨",
+	"repeat":                    "🔁",
+	"mega":                      "📣",
+	"broom":                     "🧹",
+	"alarm_clock":               "⏰",
+	"stopwatch":                 "⏱️",
+	"red_circle":                "🔴",
+	"white_circle":              "⚪",
+	"ok":                        "🆗",
+	"microphone":                "🎤",
+	"key":                       "🔑",
+	"incoming_envelope":         "📨",
+	"inbox_tray":                "📥",
+	"electric_plug":             "🔌",
+	"bulb":                      "💡",
+	"building_construction":     "🏗️",
+	"books":                     "📚",
+	"book":                      "📖",
+	"bookmark_tabs":             "📑",
+	"jigsaw":                    "🧩",
+	"+1":                        "👍",
+}
+
+var (
+	mrkdwnCodeRe      = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+	mrkdwnShortcodeRe = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+	mrkdwnLinkRe      = regexp.MustCompile(`<(https?://[^|>\s]+)(?:\|([^>]+))?>`)
+	mrkdwnBoldRe      = regexp.MustCompile(`\*([^*\n]+)\*`)
+	mrkdwnStrikeRe    = regexp.MustCompile(`~([^~\n]+)~`)
+)
+
+// convertMrkdwn applies convert to the parts of text outside code spans
+func convertMrkdwn(text string, convert func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range mrkdwnCodeRe.FindAllStringIndex(text, -1) {
+		b.WriteString(convert(text[last:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(convert(text[last:]))
+	return b.String()
+}
+
+// markdownProse converts mrkdwn links, bold and strike to Markdown
+func markdownProse(s string) string {
+	s = mrkdwnLinkRe.ReplaceAllStringFunc(s, func(link string) string {
+		m := mrkdwnLinkRe.FindStringSubmatch(link)
+		if m[2] == "" {
+			return m[1]
+		}
+		return "[" + m[2] + "](" + m[1] + ")"
+	})
+	s = mrkdwnBoldRe.ReplaceAllString(s, "**$1**")
+	s = mrkdwnStrikeRe.ReplaceAllString(s, "~~$1~~")
+	return unescapeMrkdwn(s)
+}
+
+// replaceShortcodes turns the :emoji: shortcodes of shortcodeEmoji into unicode
+func replaceShortcodes(s string) string {
+	return mrkdwnShortcodeRe.ReplaceAllStringFunc(s, func(code string) string {
+		if emoji, ok := shortcodeEmoji[strings.Trim(code, ":")]; ok {
+			return emoji
+		}
+		return code
+	})
+}
+
+// unescapeMrkdwn undoes Slack's &lt; &gt; &amp; escaping
+func unescapeMrkdwn(s string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(s)
+}
+
+// replyRoots maps "channel:message" to the message a reply chain started
+// from, on backends where a thread is a chain of replies (Discord, Telegram)
+var replyRoots sync.Map
+
+// threadRoot returns the thread a reply to messageID belongs to
+func threadRoot(channelID, messageID string) string {
+	if root, ok := replyRoots.Load(channelID + ":" + messageID); ok {
+		return root.(string)
+	}
+	return messageID
+}
+
+// recordReply files messageID, a reply to replyTo, under replyTo's thread
+func recordReply(channelID, messageID, replyTo string) {
+	replyRoots.Store(channelID+":"+messageID, threadRoot(channelID, replyTo))
+}
+
+// chatButton is what a button's short ID stands for on backends whose button
+// payloads are too small for signed values (Discord: 100 characters,
+// Telegram: 64 bytes). They live in memory: a restart forgets them.
+type chatButton struct {
+	ActionID string
+	Value    string
+	BlockID  string
+	Posted   time.Time
+}
+
+var chatButtons sync.Map
+
+// rememberButton stores a button and returns its short ID
+func rememberButton(btn Element, blockID string) string {
+	now := time.Now()
+	chatButtons.Range(func(key, value interface{}) bool {
+		if now.Sub(value.(chatButton).Posted) > buttonValueTTL {
+			chatButtons.Delete(key)
+		}
+		return true
+	})
+	id := make([]byte, 12)
+	rand.Read(id)
+	key := hex.EncodeToString(id)
+	chatButtons.Store(key, chatButton{ActionID: btn.ActionID, Value: btn.Value, BlockID: blockID, Posted: now})
+	return key
+}
+
+// recallButton returns the button behind a short ID
+func recallButton(id string) (chatButton, bool) {
+	btn, ok := chatButtons.Load(id)
+	if !ok {
+		return chatButton{}, false
+	}
+	return btn.(chatButton), true
+}
+
+// chatButtonAction turns a click on a remembered button into the block action
+// Slack would have sent
+func chatButtonAction(channelID, userID, messageID, text string, btn chatButton) BlockActionPayload {
+	var action BlockActionPayload
+	action.Type = "block_actions"
+	action.User.ID = userID
+	action.Channel.ID = channelID
+	action.Message = SlackMessage{Type: "message", Channel: channelID, Text: text, TS: messageID}
+	if root := threadRoot(channelID, messageID); root != messageID {
+		action.Message.ThreadTS = root
+	}
+	action.Actions = []BlockAction{{ActionID: btn.ActionID, BlockID: btn.BlockID, Value: btn.Value, Type: "button"}}
+	return action
+}
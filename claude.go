@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -23,18 +25,21 @@ type HookData struct {
 	ToolName       string `json:"tool_name"`
 	Prompt         string `json:"prompt"`
 	ToolInput      struct {
-		Questions []struct {
-			Question    string `json:"question"`
-			Header      string `json:"header"`
-			MultiSelect bool   `json:"multiSelect"`
-			Options     []struct {
-				Label       string `json:"label"`
-				Description string `json:"description"`
-			} `json:"options"`
-		} `json:"questions"`
+		Questions []HookQuestion `json:"questions"`
 	} `json:"tool_input"`
 }
 
+// HookQuestion is one question of an AskUserQuestion tool call
+type HookQuestion struct {
+	Question    string `json:"question"`
+	Header      string `json:"header"`
+	MultiSelect bool   `json:"multiSelect"`
+	Options     []struct {
+		Label       string `json:"label"`
+		Description string `json:"description"`
+	} `json:"options"`
+}
+
 // ClaudeResponse represents the final response from Claude
 type ClaudeResponse struct {
 	Result    string `json:"result"`
@@ -45,10 +50,12 @@ type ClaudeResponse struct {
 		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 	} `json:"usage"`
-	DurationMs   int  `json:"duration_ms"`
-	IsError      bool `json:"is_error"`
-	NumTurns     int  `json:"num_turns"`
-	NeedsCompact bool `json:"-"` // Internal flag for auto-compact
+	DurationMs   int     `json:"duration_ms"`
+	IsError      bool    `json:"is_error"`
+	NumTurns     int     `json:"num_turns"`
+	CostUSD      float64 `json:"total_cost_usd"`
+	NeedsCompact bool    `json:"-"` // Internal flag for auto-compact
+	CLIScreen    string  `json:"-"` // Blocking CLI screen the run ended on (cliScreenLogin, ...)
 }
 
 // ============================================================================
@@ -67,6 +74,7 @@ type StreamEvent struct {
 	Usage     *ClaudeUsage    `json:"usage,omitempty"`
 	DurationMs int            `json:"duration_ms,omitempty"`
 	NumTurns  int             `json:"num_turns,omitempty"`
+	TotalCostUSD float64      `json:"total_cost_usd,omitempty"`
 	// For tool_use events
 	ToolName  string          `json:"tool_name,omitempty"`
 	ToolInput json.RawMessage `json:"input,omitempty"`
@@ -74,6 +82,46 @@ type StreamEvent struct {
 	Cwd   string   `json:"cwd,omitempty"`
 	Model string   `json:"model,omitempty"`
 	Tools []string `json:"tools,omitempty"`
+	// Set on events of a subagent: the Task tool call that spawned it
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"`
+	// For stream_event (--include-partial-messages): the raw API stream event
+	Event json.RawMessage `json:"event,omitempty"`
+}
+
+// partialEvent is the part of a stream_event we read
+type partialEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+}
+
+// partialTextDelta returns the text of a content_block_delta event ("" for
+// other events: thinking and tool input deltas arrive whole anyway)
+func partialTextDelta(raw json.RawMessage) string {
+	var ev partialEvent
+	if json.Unmarshal(raw, &ev) != nil || ev.Type != "content_block_delta" || ev.Delta.Type != "text_delta" {
+		return ""
+	}
+	return ev.Delta.Text
+}
+
+// unstreamedText returns the part of a complete text block that its deltas
+// didn't already deliver
+func unstreamedText(full, streamed string) string {
+	if streamed == "" {
+		return full
+	}
+	if strings.HasPrefix(full, streamed) {
+		return full[len(streamed):]
+	}
+	return "" // shown from the deltas already
+}
+
+// usePartialMessages reports whether runs ask for token-level deltas
+func usePartialMessages(config *Config) bool {
+	return config == nil || config.PartialMessages == nil || *config.PartialMessages
 }
 
 // ClaudeMessage represents an assistant or user message
@@ -159,33 +207,98 @@ var (
 )
 
 // Active Claude processes per channel (for !cancel)
-var activeProcesses sync.Map // channelID -> *exec.Cmd
+var activeProcesses sync.Map // channelID -> *exec.Cmd, *remoteRun (agent), *sandboxRun (Docker) or *persistentClaude
 
 // Verbose mode per channel (default: true = verbose)
 var verboseMode sync.Map // channelID -> bool
 
-// IsVerbose returns whether verbose mode is enabled for a channel (default: true)
+// IsVerbose returns whether verbose mode is enabled for a channel (default:
+// verbose_default from the config, or true)
 func IsVerbose(channelID string) bool {
 	if v, ok := verboseMode.Load(channelID); ok {
 		return v.(bool)
 	}
+	if configMgr != nil {
+		if config := configMgr.Get(); config != nil && config.VerboseDefault != nil {
+			return *config.VerboseDefault
+		}
+	}
 	return true // default verbose
 }
 
-// SetVerbose sets the verbose mode for a channel
+// SetVerbose sets the verbose mode for a channel and persists it
 func SetVerbose(channelID string, verbose bool) {
 	verboseMode.Store(channelID, verbose)
+	saveVerboseToDisk()
+}
+
+// getVerboseFilePath returns the path to the verbose modes file (~/.ccsa/verbose.json)
+func getVerboseFilePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "verbose.json")
+}
+
+// loadVerboseFromDisk loads persisted verbose modes from disk
+func loadVerboseFromDisk() {
+	data, err := os.ReadFile(getVerboseFilePath())
+	if err != nil {
+		return // File doesn't exist yet
+	}
+	var modes map[string]bool
+	if err := json.Unmarshal(data, &modes); err != nil {
+		return
+	}
+	for k, v := range modes {
+		verboseMode.Store(k, v)
+	}
+}
+
+// saveVerboseToDisk persists verbose modes to disk
+func saveVerboseToDisk() {
+	filePath := getVerboseFilePath()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return
+	}
+	modes := make(map[string]bool)
+	verboseMode.Range(func(key, value interface{}) bool {
+		modes[key.(string)] = value.(bool)
+		return true
+	})
+	data, err := json.Marshal(modes)
+	if err != nil {
+		return
+	}
+	os.WriteFile(filePath, data, 0600)
 }
 
 // CancelClaudeProcess cancels any running Claude process for a channel
 func CancelClaudeProcess(channelID string) bool {
-	if cmd, ok := activeProcesses.Load(channelID); ok {
-		if process := cmd.(*exec.Cmd); process != nil && process.Process != nil {
-			process.Process.Kill()
+	if proc, ok := activeProcesses.Load(channelID); ok {
+		switch p := proc.(type) {
+		case *exec.Cmd:
+			if p != nil && p.Process != nil {
+				p.Process.Kill()
+				activeProcesses.Delete(channelID)
+				return true
+			}
+		case *remoteRun:
+			p.Kill()
+			activeProcesses.Delete(channelID)
+			return true
+		case *sandboxRun:
+			p.Kill()
+			activeProcesses.Delete(channelID)
+			return true
+		case *persistentClaude:
+			p.Kill()
 			activeProcesses.Delete(channelID)
 			return true
 		}
 	}
+	// PTY sessions: Escape interrupts Claude's turn, keeping the session
+	if s := ptyPool.Get(channelID); s != nil {
+		return s.Keys("\x1b") == nil
+	}
 	return false
 }
 
@@ -196,7 +309,7 @@ func init() {
 
 	home, _ := os.UserHomeDir()
 	claudePaths := []string{
-		filepath.Join(home, ".local", "bin", "claude"),
+		filepath.Join(home, ".local", "bin", "claude"+exeSuffix),
 		filepath.Join(home, ".claude", "local", "claude"), // legacy
 		"/usr/local/bin/claude",
 	}
@@ -218,7 +331,7 @@ func init() {
 	}
 
 	if claudePath == "" {
-		if p, err := exec.LookPath("claude"); err == nil {
+		if p := claudeLookup(); p != "" {
 			claudePath = p
 		}
 	}
@@ -226,6 +339,9 @@ func init() {
 	// Load persisted sessions
 	loadSessionsFromDisk()
 
+	// Load persisted verbose/quiet modes
+	loadVerboseFromDisk()
+
 	// Load persisted pinned channels
 	loadPinnedChannelsFromDisk()
 }
@@ -253,19 +369,21 @@ func runClaude(prompt string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	config, _ := loadConfig()
-	baseDir := getProjectsDir(config)
-	workDir := baseDir
+	config, _ := currentConfig()
+	workDir := getProjectsDir(config)
 
 	words := strings.Fields(prompt)
 	if len(words) > 0 {
 		firstWord := words[0]
-		potentialDir := filepath.Join(baseDir, firstWord)
-		if info, err := os.Stat(potentialDir); err == nil && info.IsDir() {
-			workDir = potentialDir
-			prompt = strings.TrimSpace(strings.TrimPrefix(prompt, firstWord))
-			if prompt == "" {
-				return "Error: no prompt provided after directory name", nil
+		for _, baseDir := range getProjectsDirs(config) {
+			potentialDir := filepath.Join(baseDir, firstWord)
+			if info, err := os.Stat(potentialDir); err == nil && info.IsDir() {
+				workDir = potentialDir
+				prompt = strings.TrimSpace(strings.TrimPrefix(prompt, firstWord))
+				if prompt == "" {
+					return "Error: no prompt provided after directory name", nil
+				}
+				break
 			}
 		}
 	}
@@ -275,6 +393,7 @@ func runClaude(prompt string) (string, error) {
 	}
 	cmd := exec.CommandContext(ctx, claudePath, "--dangerously-skip-permissions", "-p", prompt)
 	cmd.Dir = workDir
+	cmd.Env = runEnv(config)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -282,13 +401,14 @@ func runClaude(prompt string) (string, error) {
 
 	err := cmd.Run()
 
-	output := stdout.String()
+	output := sanitizeTerminalOutput(stdout.String())
 	if stderr.Len() > 0 {
 		if output != "" {
 			output += "\n"
 		}
-		output += stderr.String()
+		output += sanitizeTerminalOutput(stderr.String())
 	}
+	output = filterOutputLines(config, output)
 
 	if output == "" {
 		if err != nil {
@@ -310,25 +430,31 @@ func callClaudeJSON(prompt string, channelID string, workDir string) (*ClaudeRes
 		return nil, fmt.Errorf("claude binary not found")
 	}
 
+	config, _ := currentConfig()
 	args := []string{
 		"-p", prompt,
 		"--dangerously-skip-permissions",
 		"--output-format", "json",
-		"--append-system-prompt", SlackSystemPromptAppend,
+		"--append-system-prompt", appendSystemPrompt(config, channelID),
 	}
 
 	if sid, ok := claudeSessionIDs.Load(channelID); ok {
 		args = append(args, "--resume", sid.(string))
 	}
 
+	env, err := sessionRunEnv(config, getSessionByChannel(config, channelID), workDir)
+	if err != nil {
+		return nil, err
+	}
 	cmd := exec.CommandContext(ctx, claudePath, args...)
 	cmd.Dir = workDir
+	cmd.Env = env
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
 		if stderr.Len() > 0 {
 			return nil, fmt.Errorf("claude error: %w - %s", err, stderr.String())
@@ -347,6 +473,7 @@ func callClaudeJSON(prompt string, channelID string, workDir string) (*ClaudeRes
 	if resp.SessionID != "" {
 		claudeSessionIDs.Store(channelID, resp.SessionID)
 		saveSessionsToDisk()
+		forkStore.Clear(channelID)
 	}
 
 	return &resp, nil
@@ -361,6 +488,7 @@ type SlackThreadManager struct {
 	config    *Config
 	channelID string
 	threadTS  string
+	runID     string // Claude session ID, once known (see threadRegistry)
 
 	// Current assistant message accumulator
 	currentAssistantTS      string
@@ -374,19 +502,38 @@ type SlackThreadManager struct {
 	batchedToolName   string
 	batchedToolInputs []string
 	batchedToolTimer  *time.Timer
+	batchedSnippets   [][2]string // full diffs (name, content) to attach after the batch
 
 	// Track if system init was already posted
 	systemInitPosted bool
 
+	// Subagents in progress, by the tool call that spawned them (see subagent.go)
+	subagents map[string]*subagentView
+
 	// Heartbeat timer for long operations
 	heartbeatTicker  *time.Ticker
 	heartbeatStop    chan struct{}
 	heartbeatTS      string // Message TS for the heartbeat message
+	heartbeatAt      time.Time
 	lastActivityTime time.Time
 
+	// Small posts waiting to be merged into one message (see coalesce.go)
+	coalesced     []string
+	coalesceTimer *time.Timer
+
+	// Phase shown in the heartbeat's progress message (see progress.go)
+	progressTool string
+	progressTodo string
+	turns        int
+	lastTurnID   string
+
 	// Track if any assistant text was posted (to avoid double-posting from result)
 	assistantTextPosted bool
 
+	// requestedBy is mentioned in the stats message of long or failed runs (mention_after_minutes)
+	requestedBy string
+	started     time.Time
+
 	mu sync.Mutex
 }
 
@@ -398,11 +545,48 @@ func NewSlackThreadManager(config *Config, channelID, threadTS string) *SlackThr
 		threadTS:         threadTS,
 		activeTools:      make(map[string]string),
 		lastActivityTime: time.Now(),
+		started:          time.Now(),
 	}
+	startLiveRun(channelID)
+	runMessages.Start(channelID, threadTS)
 	m.startHeartbeat()
 	return m
 }
 
+// post sends text to the run's thread (split when long), remembering the
+// messages so they can be removed if the prompt is deleted
+func (m *SlackThreadManager) post(text string) {
+	m.flushCoalescedLocked()
+	m.send(text)
+}
+
+// send posts text right away, split when long
+func (m *SlackThreadManager) send(text string) {
+	for _, part := range splitMessage(redactSecrets(m.config, text), chatFor(m.channelID).MaxMessageLen()) {
+		m.sendGetTS(part)
+	}
+}
+
+// postGetTS sends one message to the run's thread and returns its timestamp
+func (m *SlackThreadManager) postGetTS(text string) string {
+	m.flushCoalescedLocked()
+	return m.sendGetTS(text)
+}
+
+// sendGetTS posts one message right away and returns its timestamp
+func (m *SlackThreadManager) sendGetTS(text string) string {
+	ts, err := sendMessageToThreadGetTS(m.config, m.channelID, m.threadTS, text)
+	if err == nil {
+		runMessages.Add(m.channelID, m.threadTS, ts)
+	}
+	return ts
+}
+
+// quiet reports whether tool chatter and heartbeats are held back (quiet_hours)
+func (m *SlackThreadManager) quiet() bool {
+	return inQuietHours(m.config, time.Now())
+}
+
 // startHeartbeat starts the heartbeat ticker
 func (m *SlackThreadManager) startHeartbeat() {
 	m.heartbeatTicker = time.NewTicker(1 * time.Second)
@@ -414,17 +598,21 @@ func (m *SlackThreadManager) startHeartbeat() {
 			case <-m.heartbeatTicker.C:
 				m.mu.Lock()
 				elapsed := time.Since(m.lastActivityTime)
-				// Only show heartbeat after 5s of silence
-				if elapsed >= 5*time.Second {
-					elapsedStr := formatDuration(elapsed)
-					heartbeatMsg := fmt.Sprintf(":hourglass_flowing_sand: Working... (%s)", elapsedStr)
+				// Only show heartbeat after 5s of silence, less often while Slack is struggling
+				if elapsed >= 5*time.Second && (time.Since(m.heartbeatAt) >= degradedHeartbeatInterval || !apiDegraded()) && !m.quiet() {
+					m.heartbeatAt = time.Now()
+					heartbeatMsg := formatProgress(elapsed, m.progressTool, m.progressTodo, m.turns)
 					if m.heartbeatTS == "" {
-						// Create new heartbeat message
-						ts, _ := sendMessageToThreadGetTS(m.config, m.channelID, m.threadTS, heartbeatMsg)
-						m.heartbeatTS = ts
+						// Create new progress message, with a Stop button
+						ts, err := sendMessageWithButtonsGetTS(m.config, m.channelID, m.threadTS, heartbeatMsg, progressButtons(m.channelID), progressBlockID)
+						if err == nil {
+							runMessages.Add(m.channelID, m.threadTS, ts)
+							m.heartbeatTS = ts
+							threadRegistry.SetHeartbeat(m.runID, ts)
+						}
 					} else {
-						// Update existing heartbeat message
-						updateMessage(m.config, m.channelID, m.heartbeatTS, heartbeatMsg)
+						// Update existing progress message in place
+						updateMessageWithButtons(m.config, m.channelID, m.heartbeatTS, heartbeatMsg, progressButtons(m.channelID), progressBlockID)
 					}
 				}
 				m.mu.Unlock()
@@ -462,7 +650,19 @@ func (m *SlackThreadManager) recordActivityLocked() {
 	if m.heartbeatTS != "" {
 		deleteMessage(m.config, m.channelID, m.heartbeatTS)
 		m.heartbeatTS = ""
+		threadRegistry.SetHeartbeat(m.runID, "")
+	}
+}
+
+// SetRunID registers the run's thread under its Claude session ID
+func (m *SlackThreadManager) SetRunID(runID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.runID == runID {
+		return
 	}
+	m.runID = runID
+	threadRegistry.Register(runID, m.channelID, m.threadTS)
 }
 
 // formatDuration formats a duration as "Xs" or "Xm Ys"
@@ -479,8 +679,11 @@ func formatDuration(d time.Duration) string {
 func (m *SlackThreadManager) PostThinking() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.quiet() {
+		return
+	}
 
-	ts, _ := sendMessageToThreadGetTS(m.config, m.channelID, m.threadTS, ":hourglass_flowing_sand: _Thinking..._")
+	ts := m.postGetTS(":hourglass_flowing_sand: _Thinking..._")
 	m.currentAssistantTS = ts
 }
 
@@ -494,6 +697,9 @@ func (m *SlackThreadManager) PostSystemInit(event *StreamEvent) {
 		return
 	}
 	m.systemInitPosted = true
+	if m.quiet() {
+		return
+	}
 
 	// Delete the "Thinking..." message and replace with system init
 	if m.currentAssistantTS != "" {
@@ -504,7 +710,7 @@ func (m *SlackThreadManager) PostSystemInit(event *StreamEvent) {
 	// Compact format on one line
 	msg := fmt.Sprintf(":zap: `%s` · %s · `%s`",
 		event.SessionID[:8], event.Model, event.Cwd)
-	sendMessageToThread(m.config, m.channelID, m.threadTS, msg)
+	m.post(msg)
 }
 
 // UpdateAssistantText accumulates and updates assistant text (batched)
@@ -520,13 +726,15 @@ func (m *SlackThreadManager) UpdateAssistantText(text string) {
 
 	m.currentAssistantContent.WriteString(text)
 
-	// Micro-batch: update every 500ms or 500 chars
+	// Micro-batch: update every 500ms or 500 chars by default (see cadence.go;
+	// every 2s while Slack is struggling)
 	sinceLastUpdate := time.Since(m.lastAssistantUpdate)
 	contentLen := m.currentAssistantContent.Len()
+	cadence := streamingCadence(m.config, m.channelID)
 
 	shouldUpdate := m.currentAssistantTS == "" || // First content
-		sinceLastUpdate >= 500*time.Millisecond ||
-		(contentLen >= 500 && sinceLastUpdate >= 200*time.Millisecond)
+		sinceLastUpdate >= cadence.interval ||
+		(cadence.chars > 0 && contentLen >= cadence.chars && sinceLastUpdate >= min(200*time.Millisecond, cadence.interval))
 
 	if shouldUpdate && contentLen > 0 {
 		m.flushAssistantText(false)
@@ -546,7 +754,7 @@ func (m *SlackThreadManager) flushAssistantText(final bool) {
 	}
 
 	if m.currentAssistantTS == "" {
-		ts, _ := sendMessageToThreadGetTS(m.config, m.channelID, m.threadTS, displayContent)
+		ts := m.postGetTS(displayContent)
 		m.currentAssistantTS = ts
 	} else {
 		updateMessage(m.config, m.channelID, m.currentAssistantTS, displayContent)
@@ -571,6 +779,9 @@ func (m *SlackThreadManager) FinalizeAssistantText() {
 func (m *SlackThreadManager) PostThinkingBlock(thinking string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.quiet() {
+		return
+	}
 
 	// Truncate if too long
 	if len(thinking) > 500 {
@@ -578,7 +789,7 @@ func (m *SlackThreadManager) PostThinkingBlock(thinking string) {
 	}
 
 	msg := fmt.Sprintf(":brain: _Thinking..._\n```\n%s\n```", thinking)
-	sendMessageToThread(m.config, m.channelID, m.threadTS, msg)
+	m.post(msg)
 }
 
 // getToolBatchGroup returns the batch group for a tool (tools in same group are batched together)
@@ -586,7 +797,7 @@ func getToolBatchGroup(toolName string) string {
 	switch toolName {
 	case "Read", "Bash", "Grep", "Glob":
 		return "read"
-	case "Write", "Edit":
+	case "Write", "Edit", "MultiEdit":
 		return "write"
 	case "WebFetch", "WebSearch":
 		return "web"
@@ -601,6 +812,9 @@ func (m *SlackThreadManager) PostToolUseStart(toolName string, toolID string, in
 
 	// Record activity
 	m.recordActivityLocked()
+	summary, _, _ := strings.Cut(formatToolInput(toolName, input), "\n") // file of a diff
+	setLiveActivity(m.channelID, toolName+" "+summary)
+	m.setProgressToolLocked(toolName, summary, input)
 
 	// In quiet mode, skip read-only tools (Bash, Read, Grep, Glob)
 	// Only show write operations (Edit, Write) and important tools
@@ -618,6 +832,12 @@ func (m *SlackThreadManager) PostToolUseStart(toolName string, toolID string, in
 		m.currentAssistantContent.Reset()
 	}
 
+	// Quiet hours: hold the call for the morning digest
+	if m.quiet() {
+		quietDigest.Add(m.channelID, getToolEmoji(toolName)+" "+summary)
+		return
+	}
+
 	inputStr := formatToolInput(toolName, input)
 
 	// Check if we can batch this tool with the current batch (same group)
@@ -625,11 +845,14 @@ func (m *SlackThreadManager) PostToolUseStart(toolName string, toolID string, in
 
 	if canBatch {
 		m.batchedToolInputs = append(m.batchedToolInputs, fmt.Sprintf("%s %s", getToolEmoji(toolName), inputStr))
+		if name, content, ok := diffSnippet(toolName, input); ok {
+			m.batchedSnippets = append(m.batchedSnippets, [2]string{name, content})
+		}
 		// Reset timer
 		if m.batchedToolTimer != nil {
 			m.batchedToolTimer.Stop()
 		}
-		m.batchedToolTimer = time.AfterFunc(1*time.Second, func() {
+		m.batchedToolTimer = time.AfterFunc(m.toolBatchDelay(), func() {
 			m.flushToolBatch()
 		})
 		return
@@ -643,11 +866,19 @@ func (m *SlackThreadManager) PostToolUseStart(toolName string, toolID string, in
 	// Start new batch
 	m.batchedToolName = toolName
 	m.batchedToolInputs = []string{fmt.Sprintf("%s %s", getToolEmoji(toolName), inputStr)}
-	m.batchedToolTimer = time.AfterFunc(1*time.Second, func() {
+	if name, content, ok := diffSnippet(toolName, input); ok {
+		m.batchedSnippets = append(m.batchedSnippets, [2]string{name, content})
+	}
+	m.batchedToolTimer = time.AfterFunc(m.toolBatchDelay(), func() {
 		m.flushToolBatch()
 	})
 }
 
+// toolBatchDelay is how long tool calls are gathered before posting
+func (m *SlackThreadManager) toolBatchDelay() time.Duration {
+	return streamingCadence(m.config, m.channelID).batchDelay
+}
+
 // flushToolBatch flushes the batched tool calls (acquires lock)
 func (m *SlackThreadManager) flushToolBatch() {
 	m.mu.Lock()
@@ -668,10 +899,26 @@ func (m *SlackThreadManager) flushToolBatchLocked() {
 
 	// Each input already has its emoji prefix, just join them
 	msg := strings.Join(m.batchedToolInputs, "\n")
-	sendMessageToThread(m.config, m.channelID, m.threadTS, msg)
+	if len(m.batchedSnippets) > 0 {
+		m.post(msg) // the diffs are uploaded right after it
+	} else {
+		m.postCoalesced(msg)
+	}
+
+	// Full diffs of cut previews, after the message they belong to
+	if snippets := m.batchedSnippets; len(snippets) > 0 {
+		go func() {
+			for _, sn := range snippets {
+				if _, err := uploadSnippet(m.config, m.channelID, m.threadTS, sn[0], sn[1], "Full diff"); err != nil {
+					logf("Failed to upload diff: %v", err)
+				}
+			}
+		}()
+	}
 
 	m.batchedToolName = ""
 	m.batchedToolInputs = nil
+	m.batchedSnippets = nil
 }
 
 // PostToolResult posts a tool result
@@ -686,12 +933,18 @@ func (m *SlackThreadManager) PostToolResult(toolUseID string, result json.RawMes
 	if !IsVerbose(m.channelID) && !isError {
 		return
 	}
+	if m.quiet() {
+		if isError {
+			quietDigest.Add(m.channelID, ":x: "+truncateRunes(firstLine(sanitizeTerminalOutput(toolResultText(result))), 200))
+		}
+		return
+	}
 
 	// Flush any pending tool batch
 	m.flushToolBatchLocked()
 
-	// Format result
-	fullResult := string(result)
+	// Format result (tool output is terminal output: drop colors and redraws)
+	fullResult := filterOutputLines(m.config, sanitizeTerminalOutput(toolResultText(result)))
 	const previewLimit = 500
 	const snippetThreshold = 1000
 
@@ -707,7 +960,7 @@ func (m *SlackThreadManager) PostToolResult(toolUseID string, result json.RawMes
 		// Long output: upload as snippet, show preview
 		preview := fullResult[:previewLimit] + "..."
 		msg = fmt.Sprintf(":white_check_mark: ```\n%s\n```\n_(%d chars total - uploading full output...)_", preview, len(fullResult))
-		sendMessageToThread(m.config, m.channelID, m.threadTS, msg)
+		m.post(msg)
 
 		// Upload full result as snippet (async, outside lock)
 		go func() {
@@ -731,13 +984,26 @@ func (m *SlackThreadManager) PostToolResult(toolUseID string, result json.RawMes
 	if _, ok := m.activeTools[toolUseID]; ok {
 		delete(m.activeTools, toolUseID)
 	}
-	sendMessageToThread(m.config, m.channelID, m.threadTS, msg)
+	m.postCoalesced(msg)
+}
+
+// completionMention returns the mention of the user who asked, when the run
+// took at least mention_after_minutes or failed ("" = stay silent)
+func completionMention(config *Config, userID string, elapsed time.Duration, failed bool) string {
+	if config.MentionAfterMinutes <= 0 || userID == "" {
+		return ""
+	}
+	if failed || elapsed >= time.Duration(config.MentionAfterMinutes)*time.Minute {
+		return "<@" + userID + ">"
+	}
+	return ""
 }
 
 // PostFinalResult posts the final result with stats
 func (m *SlackThreadManager) PostFinalResult(resp *ClaudeResponse) {
 	// Stop heartbeat first (outside lock to avoid deadlock)
 	m.stopHeartbeat()
+	threadRegistry.Finish(m.runID)
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -754,7 +1020,7 @@ func (m *SlackThreadManager) PostFinalResult(resp *ClaudeResponse) {
 	// This handles cases where Claude returns text directly in the result without streaming
 	if resp.Result != "" && !m.assistantTextPosted {
 		text := convertBold(resp.Result)
-		sendMessageToThread(m.config, m.channelID, m.threadTS, text)
+		m.post(text)
 	}
 
 	// Check if context is getting large (warn at 150k tokens, typical limit is ~200k)
@@ -779,8 +1045,11 @@ func (m *SlackThreadManager) PostFinalResult(resp *ClaudeResponse) {
 		resp.Usage.OutputTokens,
 		durationStr,
 		warningMsg)
+	if mention := completionMention(m.config, m.requestedBy, time.Since(m.started), resp.IsError); mention != "" {
+		statsMsg = mention + " " + statsMsg
+	}
 
-	sendMessageToThread(m.config, m.channelID, m.threadTS, statsMsg)
+	m.post(statsMsg)
 }
 
 // PostError posts an error message
@@ -792,7 +1061,7 @@ func (m *SlackThreadManager) PostError(errMsg string) {
 	defer m.mu.Unlock()
 
 	msg := fmt.Sprintf(":rotating_light: *Error*\n```\n%s\n```", errMsg)
-	sendMessageToThread(m.config, m.channelID, m.threadTS, msg)
+	m.post(msg)
 }
 
 // PostAutoCompactNotice posts a notice that auto-compact will be triggered
@@ -801,7 +1070,7 @@ func (m *SlackThreadManager) PostAutoCompactNotice() {
 	defer m.mu.Unlock()
 
 	msg := ":warning: *Context too long!* Auto-compacting conversation..."
-	sendMessageToThread(m.config, m.channelID, m.threadTS, msg)
+	m.post(msg)
 }
 
 // getToolEmoji returns an emoji for a tool name
@@ -811,7 +1080,7 @@ func getToolEmoji(toolName string) string {
 		return "" // No emoji for bash - command itself is self-explanatory
 	case "read", "readfile":
 		return ":page_facing_up:"
-	case "write", "writefile", "edit":
+	case "write", "writefile", "edit", "multiedit":
 		return ":pencil:"
 	case "glob", "find":
 		return ":mag:"
@@ -862,32 +1131,9 @@ func formatToolInput(toolName string, input json.RawMessage) string {
 		if path, ok := data["file_path"].(string); ok {
 			return fmt.Sprintf("`%s`", path)
 		}
-	case "write", "writefile":
-		if path, ok := data["file_path"].(string); ok {
-			return fmt.Sprintf("`%s`", path)
-		}
-	case "edit":
-		if path, ok := data["file_path"].(string); ok {
-			// Show a preview of the change
-			oldStr, _ := data["old_string"].(string)
-			newStr, _ := data["new_string"].(string)
-
-			// Truncate for display
-			if len(oldStr) > 50 {
-				oldStr = oldStr[:50] + "..."
-			}
-			if len(newStr) > 50 {
-				newStr = newStr[:50] + "..."
-			}
-
-			// Escape backticks and newlines for inline display
-			oldStr = strings.ReplaceAll(strings.ReplaceAll(oldStr, "`", "'"), "\n", "↵")
-			newStr = strings.ReplaceAll(strings.ReplaceAll(newStr, "`", "'"), "\n", "↵")
-
-			if oldStr != "" && newStr != "" {
-				return fmt.Sprintf("`%s`\n`-%s`\n`+%s`", path, oldStr, newStr)
-			}
-			return fmt.Sprintf("`%s`", path)
+	case "write", "writefile", "edit", "multiedit":
+		if path, lines, ok := toolDiff(toolName, input); ok {
+			return formatDiffBlock(path, lines, toolLower != "edit" && toolLower != "multiedit")
 		}
 	case "glob":
 		if pattern, ok := data["pattern"].(string); ok {
@@ -986,6 +1232,7 @@ func formatToolInput(toolName string, input json.RawMessage) string {
 // ClaudeStreamingOptions contains options for callClaudeStreamingWithOptions
 type ClaudeStreamingOptions struct {
 	ForkFromChannel string // If set, fork session from this channel instead of resuming
+	RequestedBy     string // User who sent the prompt, for mention_after_minutes
 }
 
 // callClaudeStreaming calls Claude with streaming output and posts separate Slack messages
@@ -1009,12 +1256,25 @@ func callClaudeStreamingWithOptions(prompt string, channelID string, threadTS st
 		return nil, fmt.Errorf("claude binary not found")
 	}
 
+	// Sessions mapped to an agent run on that machine
+	host := sessionHost(config, channelID)
+
+	// Keep Slack-driven work on the channel's branch
+	if host == "" {
+		if err := ensureChannelBranch(config, channelID, workDir); err != nil {
+			return nil, err
+		}
+	}
+
 	args := []string{
 		"-p", prompt,
 		"--dangerously-skip-permissions",
 		"--output-format", "stream-json",
 		"--verbose",
-		"--append-system-prompt", SlackSystemPromptAppend,
+		"--append-system-prompt", appendSystemPrompt(config, channelID),
+	}
+	if usePartialMessages(config) {
+		args = append(args, "--include-partial-messages")
 	}
 
 	// Handle fork vs normal resume
@@ -1027,30 +1287,107 @@ func callClaudeStreamingWithOptions(prompt string, channelID string, threadTS st
 		// Normal: resume from this channel's session
 		if sid, ok := claudeSessionIDs.Load(channelID); ok {
 			args = append(args, "--resume", sid.(string))
+		} else if sid, ok := forkStore.Get(channelID); ok {
+			// First run of a channel created by !fork --channel
+			args = append(args, "--resume", sid, "--fork-session")
 		}
 	}
 
-	cmd := exec.CommandContext(ctx, claudePath, args...)
-	cmd.Dir = workDir
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	// Local runs get the session's variables and setup commands
+	env := runEnv(config)
+	if host == "" && !sessionSandboxed(config, channelID) {
+		var err error
+		if env, err = sessionRunEnv(config, getSessionByChannel(config, channelID), workDir); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start claude: %w", err)
-	}
+	var stdout io.Reader
+	var wait func() error
+	var stderr *tailBuffer           // local runs: shown if the run fails
+	var persistent *persistentClaude // run fed to the channel's long-lived process
+	if usePersistentClaude(config, channelID, host, opts) {
+		sid, _ := getClaudeSessionID(channelID)
+		p, err := persistentPool.Acquire(channelID, workDir, args[2:], env, sid)
+		if err == nil {
+			if err = p.Send(prompt); err != nil {
+				// Died while idle: start over once, resuming the conversation
+				persistentPool.Release(p, "")
+				persistentPool.Stop(channelID)
+				if p, err = persistentPool.Acquire(channelID, workDir, args[2:], env, sid); err == nil {
+					err = p.Send(prompt)
+				}
+			}
+		}
+		// Busy with a queued turn: this run (outside the queue) is a one-off CLI
+		if err != nil && !errors.Is(err, errPersistentBusy) {
+			return nil, fmt.Errorf("persistent claude: %w", err)
+		}
+		persistent = p
+	}
+	if persistent != nil {
+		p := persistent
+		stdout, stderr, wait = &turnReader{p: p}, p.stderr, p.Wait
+		stop := context.AfterFunc(ctx, p.Kill)
+		defer stop()
+		activeProcesses.Store(channelID, p)
+	} else if host != "" {
+		sessionName := getSessionByChannel(config, channelID)
+		run, err := agentHub.Start(host, sessionName, args, append(credentialEnv(config), sessionEnvVars(config, sessionName, os.Environ())...))
+		if err != nil {
+			return nil, err
+		}
+		stop := context.AfterFunc(ctx, func() { run.Kill() })
+		defer stop()
+		logf("Running on agent %s for channel %s", host, channelID)
+
+		// Store run for !cancel
+		activeProcesses.Store(channelID, run)
+		stdout, wait = run.stdout, run.Wait
+	} else {
+		cmd := exec.CommandContext(ctx, claudePath, args...)
+		cmd.Dir = workDir
+		cmd.Env = env
+		var process any = cmd
+
+		// Sandboxed sessions run the same CLI in a container
+		if sessionSandboxed(config, channelID) {
+			run, err := sandboxCommand(ctx, config, getSessionByChannel(config, channelID), workDir, args)
+			if err != nil {
+				return nil, err
+			}
+			cmd, process = run.cmd, run
+			logf("Running in sandbox %s for channel %s", run.container, channelID)
+		}
+
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		}
+		stderr = newTailBuffer(cliOutputTailSize)
+		cmd.Stderr = stderr
+
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start claude: %w", err)
+		}
 
-	// Store process for !cancel
-	activeProcesses.Store(channelID, cmd)
+		// Store process for !cancel
+		activeProcesses.Store(channelID, process)
+		stdout, wait = pipe, cmd.Wait
+	}
 	defer activeProcesses.Delete(channelID)
 
 	// Create thread manager for separate messages
 	manager := NewSlackThreadManager(config, channelID, threadTS)
+	if opts != nil {
+		manager.requestedBy = opts.RequestedBy
+	}
 	manager.PostThinking()
 
 	var finalResponse ClaudeResponse
+	gotResult := false
+	partialText := "" // text block streamed as deltas, until its complete version arrives
+	var resultError string
 	scanner := bufio.NewScanner(stdout)
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
@@ -1071,6 +1408,14 @@ func callClaudeStreamingWithOptions(prompt string, channelID string, threadTS st
 			finalResponse.SessionID = event.SessionID
 			claudeSessionIDs.Store(channelID, event.SessionID)
 			saveSessionsToDisk()
+			forkStore.Clear(channelID)
+			manager.SetRunID(event.SessionID)
+		}
+
+		// Subagent (Task) events are folded into the subagent's own message
+		if event.ParentToolUseID != "" {
+			manager.SubagentEvent(event.ParentToolUseID, &event)
+			continue
 		}
 
 		switch event.Type {
@@ -1081,25 +1426,49 @@ func callClaudeStreamingWithOptions(prompt string, channelID string, threadTS st
 
 		case "assistant":
 			if event.Message != nil {
+				manager.CountTurn(event.Message.ID)
 				for _, content := range event.Message.Content {
 					switch content.Type {
 					case "text":
-						if content.Text != "" {
-							manager.UpdateAssistantText(content.Text)
+						// Already shown from its deltas, except what they missed
+						if text := unstreamedText(content.Text, partialText); text != "" {
+							manager.UpdateAssistantText(text)
 						}
+						partialText = ""
 					case "thinking":
 						if content.Thinking != "" {
 							manager.PostThinkingBlock(content.Thinking)
 						}
 					case "tool_use":
 						manager.FinalizeAssistantText()
-						manager.PostToolUseStart(content.Name, content.ID, content.Input)
+						if isSubagentTool(content.Name) {
+							manager.StartSubagent(content.ID, content.Input)
+						} else {
+							manager.PostToolUseStart(content.Name, content.ID, content.Input)
+						}
 					case "tool_result":
 						manager.PostToolResult(content.ToolUseID, content.Content, content.IsError)
 					}
 				}
 			}
 
+		case "user":
+			// Tool results: a subagent's report ends its message
+			if event.Message != nil {
+				for _, content := range event.Message.Content {
+					if content.Type == "tool_result" {
+						manager.FinishSubagent(content.ToolUseID, content.Content, content.IsError)
+					}
+				}
+			}
+
+		case "stream_event":
+			// Token-level text, so long paragraphs show up as they are written
+			if text := partialTextDelta(event.Event); text != "" {
+				partialText += text
+				manager.UpdateAssistantText(text)
+			}
+
 		case "tool_use":
 			manager.FinalizeAssistantText()
 			manager.PostToolUseStart(event.ToolName, "", event.ToolInput)
@@ -1108,9 +1477,11 @@ func callClaudeStreamingWithOptions(prompt string, channelID string, threadTS st
 			manager.PostToolResult("", event.Result, event.IsError)
 
 		case "result":
+			gotResult = true
 			finalResponse.IsError = event.IsError
 			finalResponse.DurationMs = event.DurationMs
 			finalResponse.NumTurns = event.NumTurns
+			finalResponse.CostUSD = event.TotalCostUSD
 			if event.Usage != nil {
 				finalResponse.Usage.InputTokens = event.Usage.InputTokens
 				finalResponse.Usage.OutputTokens = event.Usage.OutputTokens
@@ -1118,6 +1489,7 @@ func callClaudeStreamingWithOptions(prompt string, channelID string, threadTS st
 				finalResponse.Usage.CacheReadInputTokens = event.Usage.CacheReadInputTokens
 			}
 			if event.Error != "" {
+				resultError = event.Error
 				// Check if context is too long - trigger auto-compact
 				if strings.Contains(event.Error, "Prompt is too long") || strings.Contains(event.Error, "too long") {
 					manager.PostAutoCompactNotice()
@@ -1134,9 +1506,32 @@ func callClaudeStreamingWithOptions(prompt string, channelID string, threadTS st
 				}
 			}
 		}
+
+		// A persistent process stays up: its turn ends with the result
+		if gotResult && persistent != nil {
+			break
+		}
+	}
+	if persistent != nil {
+		persistentPool.Release(persistent, finalResponse.SessionID)
 	}
 
-	cmd.Wait()
+	waitErr := wait()
+	if waitErr != nil && host != "" {
+		manager.PostError(waitErr.Error())
+	} else if exitErr, ok := waitErr.(*exec.ExitError); ok && exitErr.ExitCode() == sandboxDockerExitCode && sessionSandboxed(config, channelID) {
+		manager.PostError("docker could not start the sandbox container - is the sandbox image built? (see README)")
+	}
+	// Show what the CLI is stuck on (login, trust prompt, crash), unless !cancel killed it
+	_, stillActive := activeProcesses.Load(channelID)
+	if stillActive && !finalResponse.NeedsCompact && (waitErr != nil || !gotResult || finalResponse.IsError) {
+		var output string
+		if stderr != nil {
+			output = stderr.String()
+			manager.PostCLIOutput(output)
+		}
+		finalResponse.CLIScreen = detectCLIScreen(output + "\n" + resultError + "\n" + finalResponse.Result)
+	}
 
 	// Finalize any remaining content
 	manager.FinalizeAssistantText()
@@ -1145,10 +1540,14 @@ func callClaudeStreamingWithOptions(prompt string, channelID string, threadTS st
 	return &finalResponse, nil
 }
 
-// resetClaudeSession removes the stored session ID for a channel
+// resetClaudeSession removes the stored session ID (and cached answers) for a channel
 func resetClaudeSession(channelID string) {
 	claudeSessionIDs.Delete(channelID)
+	persistentPool.Stop(channelID)
+	ptyPool.Stop(channelID)
 	saveSessionsToDisk()
+	resultCache.Clear(channelID)
+	forkStore.Clear(channelID)
 }
 
 // getClaudeSessionID returns the current session ID for a channel, if any
@@ -1359,8 +1758,10 @@ func executeCommand(cmdStr string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "bash", "-c", cmdStr)
+	cmd := shellCommand(ctx, cmdStr)
 	cmd.Dir, _ = os.UserHomeDir()
+	config, _ := currentConfig()
+	cmd.Env = runEnv(config)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -1368,13 +1769,14 @@ func executeCommand(cmdStr string) (string, error) {
 
 	err := cmd.Run()
 
-	output := stdout.String()
+	output := sanitizeTerminalOutput(stdout.String())
 	if stderr.Len() > 0 {
 		if output != "" {
 			output += "\n"
 		}
-		output += stderr.String()
+		output += sanitizeTerminalOutput(stderr.String())
 	}
+	output = filterOutputLines(config, output)
 
 	if output == "" {
 		if err != nil {
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// repeatClickWindow ignores repeated taps on a reusable button for this long
+const repeatClickWindow = 3 * time.Second
+
+// oneShotActionPrefixes are buttons whose message is consumed by the first tap
+// (answering a question, picking a resume option): later taps are ignored.
+// Stop and Restart are not: a tap whose action failed can be tried again.
+var oneShotActionPrefixes = []string{"option_", "stale_", "cache_rerun", "recover_", "gh_handle", "mcp_answer_", "shell_", "purge_", "edit_rerun"}
+
+// ClickGuard remembers handled button clicks so double-taps act only once
+type ClickGuard struct {
+	mu   sync.Mutex
+	seen map[string]time.Time // key -> until when repeats are ignored
+}
+
+var clickGuard = &ClickGuard{seen: make(map[string]time.Time)}
+
+// isOneShotAction reports whether a tap consumes the whole message
+func isOneShotAction(actionID string) bool {
+	for _, prefix := range oneShotActionPrefixes {
+		if strings.HasPrefix(actionID, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Claim returns true for the first tap and false for repeats. One-shot buttons
+// are claimed per message (any option counts) for as long as buttons stay valid;
+// others per button, for repeatClickWindow.
+func (g *ClickGuard) Claim(channelID, messageTS, actionID string, now time.Time) bool {
+	key := channelID + ":" + messageTS
+	hold := buttonValueTTL
+	if !isOneShotAction(actionID) {
+		key += ":" + actionID
+		hold = repeatClickWindow
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until, ok := g.seen[key]; ok && now.Before(until) {
+		return false
+	}
+	g.seen[key] = now.Add(hold)
+
+	if len(g.seen) > 1000 {
+		for k, until := range g.seen {
+			if now.After(until) {
+				delete(g.seen, k)
+			}
+		}
+	}
+	return true
+}
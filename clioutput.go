@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// cliOutputTailSize is how much of the CLI's stderr is kept per run
+const cliOutputTailSize = 8 * 1024
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// Screens the CLI shows instead of running the prompt
+const (
+	cliScreenLogin  = "login"
+	cliScreenTrust  = "trust"
+	cliScreenUpdate = "update"
+	cliScreenLimit  = "limit"
+)
+
+// cliScreens recognizes what the CLI was stuck on instead of running the prompt
+var cliScreens = []struct {
+	kind string
+	re   *regexp.Regexp
+	hint string
+}{
+	{cliScreenLogin, regexp.MustCompile(`(?i)invalid api key|please run /login|not logged in|oauth token has expired`),
+		"Claude isn't logged in on this machine - run `claude` there once and `/login`"},
+	{cliScreenTrust, regexp.MustCompile(`(?i)do you trust the files|trust this folder`),
+		"Claude is asking whether to trust this folder - open it once with `claude` and accept"},
+	{cliScreenUpdate, regexp.MustCompile(`(?i)update available|new version of claude|auto-update failed`),
+		"Claude printed an update notice - run `claude update` on this machine"},
+	{cliScreenLimit, regexp.MustCompile(`(?i)credit balance is too low|usage limit reached|rate limit`),
+		"The Anthropic account hit a usage or credit limit"},
+}
+
+// detectCLIScreen returns the kind of a recognized CLI screen ("" if none matches)
+func detectCLIScreen(output string) string {
+	for _, screen := range cliScreens {
+		if screen.re.MatchString(output) {
+			return screen.kind
+		}
+	}
+	return ""
+}
+
+// cliScreenHint explains a recognized CLI screen ("" if none matches)
+func cliScreenHint(output string) string {
+	kind := detectCLIScreen(output)
+	for _, screen := range cliScreens {
+		if screen.kind == kind {
+			return screen.hint
+		}
+	}
+	return ""
+}
+
+// PostCLIOutput shows what the CLI printed on stderr when a run failed or ended
+// without a result, with a hint when the screen is a known blocker
+func (m *SlackThreadManager) PostCLIOutput(output string) {
+	output = sanitizeTerminalOutput(output)
+	if output == "" {
+		return
+	}
+
+	msg := ":camera: *What the Claude CLI printed*"
+	// Login and trust screens get their own message with recovery steps
+	if hint := cliScreenHint(output); hint != "" && !hasCLIRecovery(detectCLIScreen(output)) {
+		msg += "\n:bulb: " + hint
+	}
+	if len(output) <= 1500 {
+		sendMessageToThread(m.config, m.channelID, m.threadTS, fmt.Sprintf("%s\n```\n%s\n```", msg, output))
+		return
+	}
+	sendMessageToThread(m.config, m.channelID, m.threadTS, msg)
+	if _, err := uploadSnippet(m.config, m.channelID, m.threadTS, "claude-output.txt", output, "Claude CLI output"); err != nil {
+		logf("Failed to upload CLI output: %v", err)
+	}
+}
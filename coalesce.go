@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// defaultCoalesceWindow is how long small posts wait for company
+	defaultCoalesceWindow = 1500 * time.Millisecond
+	// coalesceMaxLen is the largest post worth merging with others
+	coalesceMaxLen = 1000
+)
+
+// postCoalesced queues a small post (tool batch, short result) so posts
+// made within the coalescing window go out as one message. Any other post
+// sends the queue first, keeping the thread in order. Caller must hold mutex.
+func (m *SlackThreadManager) postCoalesced(text string) {
+	window := streamingCadence(m.config, m.channelID).coalesce
+	if window <= 0 || len(text) > coalesceMaxLen {
+		m.post(text)
+		return
+	}
+	if coalescedLen(m.coalesced)+len(text)+1 > chatFor(m.channelID).MaxMessageLen() {
+		m.flushCoalescedLocked()
+	}
+	m.coalesced = append(m.coalesced, text)
+	if m.coalesceTimer == nil {
+		m.coalesceTimer = time.AfterFunc(window, func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.flushCoalescedLocked()
+		})
+	}
+}
+
+// flushCoalescedLocked sends the queued posts as one message (caller must hold mutex)
+func (m *SlackThreadManager) flushCoalescedLocked() {
+	if m.coalesceTimer != nil {
+		m.coalesceTimer.Stop()
+		m.coalesceTimer = nil
+	}
+	if len(m.coalesced) == 0 {
+		return
+	}
+	text := strings.Join(m.coalesced, "\n")
+	m.coalesced = nil
+	m.send(text)
+}
+
+// coalescedLen is the length of the queued posts once joined
+func coalescedLen(posts []string) int {
+	n := 0
+	for _, p := range posts {
+		n += len(p) + 1
+	}
+	return n
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxButtonsPerBlock is Slack's limit of elements in an actions block
+const maxButtonsPerBlock = 25
+
+// commandArgsCallbackID identifies the custom command arguments modal
+const commandArgsCallbackID = "slash_args"
+
+var (
+	argumentHintRe  = regexp.MustCompile(`[\[<]([^\]>]+)[\]>]`)
+	positionalArgRe = regexp.MustCompile(`\$[1-9]`)
+)
+
+// ClaudeCommand is a custom slash command defined in a .claude/commands markdown file
+type ClaudeCommand struct {
+	Name         string // e.g. "review" or "frontend:component" for subdirectories
+	Description  string
+	ArgumentHint string
+	TakesArgs    bool   // argument-hint set, or body uses $ARGUMENTS / $1..$9
+	Scope        string // "project" or "user"
+	Path         string
+}
+
+// listClaudeCommands returns the project commands (workDir/.claude/commands)
+// followed by the user commands (~/.claude/commands). Project commands win on name clashes.
+func listClaudeCommands(workDir string) []ClaudeCommand {
+	var commands []ClaudeCommand
+	seen := make(map[string]bool)
+
+	dirs := []struct{ dir, scope string }{
+		{filepath.Join(workDir, ".claude", "commands"), "project"},
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, struct{ dir, scope string }{filepath.Join(home, ".claude", "commands"), "user"})
+	}
+
+	for _, d := range dirs {
+		for _, cmd := range scanCommandsDir(d.dir, d.scope) {
+			if seen[cmd.Name] {
+				continue
+			}
+			seen[cmd.Name] = true
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+// scanCommandsDir walks a commands directory and parses every .md file
+func scanCommandsDir(dir, scope string) []ClaudeCommand {
+	var commands []ClaudeCommand
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		name := strings.ReplaceAll(strings.TrimSuffix(rel, ".md"), string(filepath.Separator), ":")
+
+		cmd := ClaudeCommand{Name: name, Scope: scope, Path: path}
+		if data, err := os.ReadFile(path); err == nil {
+			meta, body := parseFrontmatter(string(data))
+			cmd.Description = meta["description"]
+			cmd.ArgumentHint = meta["argument-hint"]
+			if cmd.Description == "" {
+				cmd.Description = firstLine(body)
+			}
+			cmd.TakesArgs = cmd.ArgumentHint != "" || strings.Contains(body, "$ARGUMENTS") || positionalArgRe.MatchString(body)
+		}
+		commands = append(commands, cmd)
+		return nil
+	})
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// parseFrontmatter splits a "---" delimited YAML header (flat key: value pairs only) from the body
+func parseFrontmatter(content string) (map[string]string, string) {
+	meta := make(map[string]string)
+	if !strings.HasPrefix(content, "---") {
+		return meta, content
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Scan() // opening ---
+	consumed := len(scanner.Text()) + 1
+	for scanner.Scan() {
+		line := scanner.Text()
+		consumed += len(line) + 1
+		if strings.TrimSpace(line) == "---" {
+			if consumed > len(content) {
+				consumed = len(content)
+			}
+			return meta, content[consumed:]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		meta[strings.TrimSpace(key)] = value
+	}
+	// No closing delimiter: treat everything as body
+	return map[string]string{}, content
+}
+
+// firstLine returns the first non-empty line of text, without markdown heading marks
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "# "))
+		if line != "" {
+			if len(line) > 80 {
+				line = line[:77] + "..."
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// sendSlashCatalog posts the available custom commands as buttons (chunked per Slack limits)
+func sendSlashCatalog(config *Config, channelID string, commands []ClaudeCommand) error {
+	var lines []string
+	for _, cmd := range commands {
+		line := fmt.Sprintf("• `/%s`", cmd.Name)
+		if cmd.ArgumentHint != "" {
+			line += " " + cmd.ArgumentHint
+		}
+		if cmd.Description != "" {
+			line += " - " + cmd.Description
+		}
+		if cmd.Scope == "user" {
+			line += " _(user)_"
+		}
+		lines = append(lines, line)
+	}
+
+	for start := 0; start < len(commands); start += maxButtonsPerBlock {
+		end := start + maxButtonsPerBlock
+		if end > len(commands) {
+			end = len(commands)
+		}
+
+		var buttons []Element
+		for i, cmd := range commands[start:end] {
+			label := "/" + cmd.Name
+			if len(label) > 75 {
+				label = label[:72] + "..."
+			}
+			buttons = append(buttons, Element{
+				Type:     "button",
+				Text:     &TextObject{Type: "plain_text", Text: label},
+				ActionID: fmt.Sprintf("slash_%d", start+i),
+				Value:    cmd.Name,
+			})
+		}
+
+		text := ":zap: *Custom commands* (tap to run)\n" + strings.Join(lines[start:end], "\n")
+		if start > 0 {
+			text = strings.Join(lines[start:end], "\n")
+		}
+		if err := sendMessageWithButtons(config, channelID, text, buttons, fmt.Sprintf("slash_catalog_%d", start/maxButtonsPerBlock)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findClaudeCommand looks up a custom command by name
+func findClaudeCommand(workDir, name string) (ClaudeCommand, bool) {
+	for _, cmd := range listClaudeCommands(workDir) {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return ClaudeCommand{}, false
+}
+
+// commandArgumentNames returns one field name per argument in the hint
+// ("[pr-number] [priority]"), or a single free-form "arguments" field
+func commandArgumentNames(cmd ClaudeCommand) []string {
+	var names []string
+	for _, m := range argumentHintRe.FindAllStringSubmatch(cmd.ArgumentHint, -1) {
+		names = append(names, strings.TrimSpace(m[1]))
+	}
+	if len(names) == 0 {
+		names = []string{"arguments"}
+	}
+	return names
+}
+
+// composeCommandLine builds "/name arg1 arg2"; positional args containing spaces are quoted
+func composeCommandLine(name string, args []string) string {
+	line := "/" + name
+	for _, arg := range args {
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			continue
+		}
+		if len(args) > 1 && strings.ContainsAny(arg, " \t") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+		line += " " + arg
+	}
+	return line
+}
+
+// commandModalMeta is carried through the modal in private_metadata
+type commandModalMeta struct {
+	Command   string `json:"command"`
+	ChannelID string `json:"channel_id"`
+	ThreadTS  string `json:"thread_ts"`
+	ArgCount  int    `json:"arg_count"`
+}
+
+// openCommandArgsModal opens a modal with one input per command argument
+func openCommandArgsModal(config *Config, triggerID string, cmd ClaudeCommand, channelID, threadTS string) error {
+	names := commandArgumentNames(cmd)
+	meta, _ := json.Marshal(commandModalMeta{Command: cmd.Name, ChannelID: channelID, ThreadTS: threadTS, ArgCount: len(names)})
+
+	blocks := []map[string]interface{}{}
+	if cmd.Description != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": fmt.Sprintf("`/%s` - %s", cmd.Name, cmd.Description)},
+		})
+	}
+	for i, name := range names {
+		blocks = append(blocks, map[string]interface{}{
+			"type":     "input",
+			"block_id": fmt.Sprintf("arg_%d", i),
+			"optional": len(names) == 1,
+			"label":    map[string]string{"type": "plain_text", "text": name},
+			"element": map[string]interface{}{
+				"type":      "plain_text_input",
+				"action_id": "value",
+				"multiline": len(names) == 1,
+			},
+		})
+	}
+
+	title := "/" + cmd.Name
+	if len(title) > 24 {
+		title = title[:21] + "..."
+	}
+	payload := map[string]interface{}{
+		"trigger_id": triggerID,
+		"view": map[string]interface{}{
+			"type":             "modal",
+			"callback_id":      commandArgsCallbackID,
+			"private_metadata": string(meta),
+			"title":            map[string]string{"type": "plain_text", "text": title},
+			"submit":           map[string]string{"type": "plain_text", "text": "Run"},
+			"close":            map[string]string{"type": "plain_text", "text": "Cancel"},
+			"blocks":           blocks,
+		},
+	}
+
+	result, err := slackAPIJSON(config, "views.open", payload)
+	if err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("views.open failed: %s", result.Error)
+	}
+	return nil
+}
+
+// parseCommandModalSubmission extracts the target and composed command line from a submitted modal
+func parseCommandModalSubmission(view *ViewPayload) (commandModalMeta, string, error) {
+	var meta commandModalMeta
+	if err := json.Unmarshal([]byte(view.PrivateMetadata), &meta); err != nil {
+		return meta, "", fmt.Errorf("invalid modal metadata: %w", err)
+	}
+	var args []string
+	for i := 0; i < meta.ArgCount; i++ {
+		args = append(args, view.State.Values[fmt.Sprintf("arg_%d", i)]["value"].Value)
+	}
+	return meta, composeCommandLine(meta.Command, args), nil
+}
@@ -5,7 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 // Config stores bot configuration and session mappings
@@ -16,6 +22,156 @@ type Config struct {
 	UserIDs     []string          `json:"user_ids,omitempty"`     // Authorized Slack user IDs
 	Sessions    map[string]string `json:"sessions"`               // session name -> channel ID
 	ProjectsDir string            `json:"projects_dir,omitempty"` // Base directory for projects
+	// ProjectsDirs are more base directories, searched in order after
+	// ProjectsDir; new session folders go in the first one
+	ProjectsDirs []string `json:"projects_dirs,omitempty"`
+	// SessionEnv sets up the environment of a session's runs (session name -> env and setup commands)
+	SessionEnv map[string]SessionEnvironment `json:"session_env,omitempty"`
+	// ChannelVisibility is "private" to create session channels as private
+	// channels ("public" by default)
+	ChannelVisibility string `json:"channel_visibility,omitempty"`
+	// InviteUserIDs are invited to every new session channel
+	// (nil = the authorized users, empty = nobody)
+	InviteUserIDs *[]string `json:"invite_user_ids,omitempty"`
+	// GCIntervalHours runs the garbage collector of orphaned state this often
+	// (0 = daily, negative = only with the gc command); GCUploadDays is how long
+	// downloaded Slack uploads are kept (0 = 14 days)
+	GCIntervalHours int `json:"gc_interval_hours,omitempty"`
+	GCUploadDays    int `json:"gc_upload_days,omitempty"`
+	// BroadcastConcurrency is how many sessions of a !broadcast run at once (0 = 3)
+	BroadcastConcurrency int `json:"broadcast_concurrency,omitempty"`
+	// IdleDigestDays lists sessions unused this long in a weekly digest
+	// (0 = 7, negative = no digest); IdleDigestChannel is where it is posted
+	// (empty = the first authorized user's DM with the bot)
+	IdleDigestDays    int    `json:"idle_digest_days,omitempty"`
+	IdleDigestChannel string `json:"idle_digest_channel,omitempty"`
+	// SessionTags group sessions for !list --tag (session name -> tags)
+	SessionTags map[string][]string `json:"session_tags,omitempty"`
+	// Aliases are shortcuts typed in Slack, expanded to a command or a prompt
+	// before anything else ("!deploy" -> "!c make deploy"); built-in commands win
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// PluginCommands are Slack commands run by executables (name without "!" ->
+	// command); they can't replace a built-in command
+	PluginCommands map[string]PluginCommand `json:"plugin_commands,omitempty"`
+	// Discord also runs sessions in Discord channels (nil = Slack only)
+	Discord *DiscordConfig `json:"discord,omitempty"`
+	// Telegram runs sessions in Telegram groups; without Slack tokens it is
+	// the only backend (nil = no Telegram)
+	Telegram *TelegramConfig `json:"telegram,omitempty"`
+	// Mattermost runs sessions in a self-hosted Mattermost (nil = no Mattermost)
+	Mattermost *MattermostConfig `json:"mattermost,omitempty"`
+	// MentionAfterMinutes mentions the prompt's author in the completion
+	// message of runs this long or that failed (0 = never mention)
+	MentionAfterMinutes int `json:"mention_after_minutes,omitempty"`
+	// QuietHours holds tool chatter and heartbeats during this local time range
+	// ("23:00-08:00") and posts them as one digest per channel afterwards;
+	// answers, questions and permission requests still come through
+	QuietHours string `json:"quiet_hours,omitempty"`
+	// Push sends ntfy/Pushover notifications for finished runs, permission
+	// requests and crashed sessions (nil = none)
+	Push *PushConfig `json:"push,omitempty"`
+	// MacNotifications mirrors these events (run_done, waiting, session_died)
+	// to macOS Notification Center when the listener runs on a Mac
+	MacNotifications []string `json:"mac_notifications,omitempty"`
+	// CheckUpdates looks for a new release at startup and daily (nil = on) and
+	// announces it once in UpdateChannel (empty = the first authorized user's DM)
+	CheckUpdates  *bool  `json:"check_updates,omitempty"`
+	UpdateChannel string `json:"update_channel,omitempty"`
+	// Streaming tunes how often run updates are posted (nil = defaults);
+	// ChannelStreaming overrides it in a channel (channel ID -> settings)
+	Streaming        *StreamingConfig           `json:"streaming,omitempty"`
+	ChannelStreaming map[string]StreamingConfig `json:"channel_streaming,omitempty"`
+	// SlackAPIURL points the Web API at a Slack-compatible server or proxy
+	// (empty = https://slack.com/api)
+	SlackAPIURL string `json:"slack_api_url,omitempty"`
+	// Templates scaffold new sessions with !new --template (name -> template)
+	Templates map[string]SessionTemplate `json:"templates,omitempty"`
+	// BatchWindowMs coalesces messages sent within this window into one prompt
+	// (0 = default 2000ms, negative = disabled)
+	BatchWindowMs int `json:"batch_window_ms,omitempty"`
+	// Voice message transcription: a local command (e.g. whisper.cpp, "{file}" = audio path)
+	// or an OpenAI-compatible /v1/audio/transcriptions endpoint
+	TranscribeCommand string `json:"transcribe_command,omitempty"`
+	TranscribeAPIURL  string `json:"transcribe_api_url,omitempty"`
+	TranscribeAPIKey  string `json:"transcribe_api_key,omitempty"`
+	// ChannelBranches pins runs in a channel to a git branch (channel ID -> branch)
+	ChannelBranches map[string]string `json:"channel_branches,omitempty"`
+	// SummaryEveryRuns regenerates a channel's pinned summary after this many successful runs (0 = only !summarize)
+	SummaryEveryRuns int `json:"summary_every_runs,omitempty"`
+	// AutoCommitChannels commits the workdir after every successful run (channel ID -> on)
+	AutoCommitChannels map[string]bool `json:"autocommit_channels,omitempty"`
+	// Credentials are short-lived secrets issued per run (see CredentialSource)
+	Credentials []CredentialSource `json:"credentials,omitempty"`
+	// ResultCacheMinutes answers repeated read-only questions from cache for this
+	// long, as long as the repo is unchanged (0 = disabled)
+	ResultCacheMinutes int `json:"result_cache_minutes,omitempty"`
+	// PartialMessages streams answers token by token (--include-partial-messages,
+	// nil = on); turn off for Claude CLIs that don't know the flag
+	PartialMessages *bool `json:"partial_messages,omitempty"`
+	// PersistentClaude keeps one Claude process per channel and feeds it prompts
+	// over stdin instead of starting the CLI for every run; idle processes
+	// stop after PersistentIdleMinutes (default 15)
+	PersistentClaude      bool `json:"persistent_claude,omitempty"`
+	PersistentIdleMinutes int  `json:"persistent_idle_minutes,omitempty"`
+	// AutoRestart restarts a session's crashed persistent Claude process,
+	// resuming its conversation, instead of offering a Restart button
+	// (session name -> true)
+	AutoRestart map[string]bool `json:"auto_restart,omitempty"`
+	// SystemPrompt is appended to Claude's system prompt after the built-in
+	// remote-work rules, in channels without their own !sysprompt
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// ChannelSystemPrompts replace SystemPrompt in a channel (channel ID -> instructions)
+	ChannelSystemPrompts map[string]string `json:"channel_system_prompts,omitempty"`
+	// AgentListen accepts remote executor agents on this address (e.g. ":7411");
+	// agents authenticate with AgentToken
+	AgentListen string `json:"agent_listen,omitempty"`
+	AgentToken  string `json:"agent_token,omitempty"`
+	// SessionHosts maps a session to the agent that runs it (session name -> agent name)
+	SessionHosts map[string]string `json:"session_hosts,omitempty"`
+	// SandboxSessions run Claude in a Docker container (session name -> true),
+	// using SandboxImage (default "ccsa-sandbox")
+	SandboxSessions map[string]bool `json:"sandbox_sessions,omitempty"`
+	SandboxImage    string          `json:"sandbox_image,omitempty"`
+	// PTYSessions run an interactive Claude on a pseudo-terminal of the
+	// listener instead of a `claude -p` per message (session name -> true)
+	PTYSessions map[string]bool `json:"pty_sessions,omitempty"`
+	// OutputFilters are regexes for lines dropped from tool and !c output
+	// (nil = defaultOutputFilters, empty = none)
+	OutputFilters *[]string `json:"output_filters,omitempty"`
+	// ShellDisabled turns off !c. ShellAllow (when set) are regexes every
+	// command chained in a !c must match, ShellDeny regexes it must not match;
+	// commands matching ShellConfirm run after a confirmation tap
+	// (nil = defaultShellConfirm, empty = never ask)
+	ShellDisabled bool      `json:"shell_disabled,omitempty"`
+	ShellAllow    []string  `json:"shell_allow,omitempty"`
+	ShellDeny     []string  `json:"shell_deny,omitempty"`
+	ShellConfirm  *[]string `json:"shell_confirm,omitempty"`
+	// RedactSecrets masks credentials (AWS keys, tokens, private keys...) in
+	// everything posted to Slack (nil = on); RedactPatterns are extra regexes
+	// to mask, only their first group if they have one
+	RedactSecrets  *bool    `json:"redact_secrets,omitempty"`
+	RedactPatterns []string `json:"redact_patterns,omitempty"`
+	// VerboseDefault is the verbosity of channels without !verbose/!quiet (nil = verbose)
+	VerboseDefault *bool `json:"verbose_default,omitempty"`
+	// AdminUserIDs may change settings with !config (empty = every authorized user)
+	AdminUserIDs []string `json:"admin_user_ids,omitempty"`
+	// GitHubWebhookListen accepts GitHub webhooks on this address (e.g. ":7412"),
+	// signed with GitHubWebhookSecret
+	GitHubWebhookListen string `json:"github_webhook_listen,omitempty"`
+	GitHubWebhookSecret string `json:"github_webhook_secret,omitempty"`
+	// APIListen serves the REST control API on this address (e.g. "127.0.0.1:7413");
+	// requests authenticate with "Authorization: Bearer <APIToken>"
+	APIListen string `json:"api_listen,omitempty"`
+	APIToken  string `json:"api_token,omitempty"`
+	// GitHubToken is used by !review to read PRs and submit reviews (the gh CLI's login otherwise)
+	GitHubToken string `json:"github_token,omitempty"`
+
+	// secretRefs are the keychain references the secrets above were read from
+	// and the secrets they resolved to, written back when the config is saved
+	secretRefs map[string]secretRef
+	// overridden holds the file's values of the settings replaced by
+	// environment or flag overrides, written back in their place
+	overridden *overriddenSettings
 }
 
 // IsAuthorizedUser checks if a user ID is in the authorized list
@@ -26,15 +182,62 @@ func (c *Config) IsAuthorizedUser(userID string) bool {
 			return true
 		}
 	}
+	if c.Discord != nil && slices.Contains(c.Discord.UserIDs, userID) {
+		return true
+	}
+	if c.Mattermost != nil && slices.Contains(c.Mattermost.UserIDs, userID) {
+		return true
+	}
+	if c.Telegram != nil {
+		if id, err := strconv.ParseInt(userID, 10, 64); err == nil && slices.Contains(c.Telegram.UserIDs, id) {
+			return true
+		}
+	}
 	// Fallback to old single UserID for backward compat
 	return c.UserID != "" && c.UserID == userID
 }
 
+// HasOtherChat reports whether a chat backend other than Slack is set up
+func (c *Config) HasOtherChat() bool {
+	return c.Discord != nil && c.Discord.BotToken != "" ||
+		c.Telegram != nil && c.Telegram.BotToken != "" ||
+		c.Mattermost != nil && c.Mattermost.Token != ""
+}
+
+// ChannelInvitees returns the users invited to new session channels
+func (c *Config) ChannelInvitees() []string {
+	if c.InviteUserIDs != nil {
+		return *c.InviteUserIDs
+	}
+	ids := append([]string(nil), c.UserIDs...)
+	if c.UserID != "" && !slices.Contains(ids, c.UserID) {
+		ids = append(ids, c.UserID)
+	}
+	return ids
+}
+
+// IsAdmin checks if a user may change settings from Slack
+func (c *Config) IsAdmin(userID string) bool {
+	if !c.IsAuthorizedUser(userID) {
+		return false
+	}
+	if len(c.AdminUserIDs) == 0 {
+		return true
+	}
+	for _, id := range c.AdminUserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
 // ConfigManager provides thread-safe access to Config
 type ConfigManager struct {
-	mu     sync.RWMutex
-	config *Config
-	path   string
+	mu        sync.RWMutex
+	config    *Config
+	path      string
+	overrides func(*Config) // CLI flags, re-applied after every reload
 }
 
 func NewConfigManager(configPath string) *ConfigManager {
@@ -59,13 +262,84 @@ func (cm *ConfigManager) Load() error {
 	if err := json.Unmarshal(data, &config); err != nil {
 		return err
 	}
+	if err := resolveSecretRefs(&config); err != nil {
+		return err
+	}
 	if config.Sessions == nil {
 		config.Sessions = make(map[string]string)
 	}
+	if cm.overrides != nil {
+		cm.overrides(&config)
+	}
 	cm.config = &config
 	return nil
 }
 
+// SetOverrides applies fn to the current config and to every reloaded one
+func (cm *ConfigManager) SetOverrides(fn func(*Config)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.overrides = fn
+	if cm.config != nil {
+		fn(cm.config)
+	}
+}
+
+// Watch reloads the config whenever the file changes on disk, until stop is closed.
+// The directory is watched (not the file) so editors that replace the file are handled.
+// An invalid file is logged and the previous config is kept.
+func (cm *ConfigManager) Watch(stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(cm.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		var debounce *time.Timer
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(cm.path) || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				// Editors often write in several steps: reload once things settle
+				if debounce != nil {
+					debounce.Stop()
+				}
+				debounce = time.AfterFunc(250*time.Millisecond, cm.reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logf("Config watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// reload re-reads the config file after a change
+func (cm *ConfigManager) reload() {
+	if _, err := os.Stat(cm.path); err != nil {
+		return // removed or mid-rename; wait for the next event
+	}
+	if err := cm.Load(); err != nil {
+		logf("Config reload failed, keeping previous config: %v", err)
+		return
+	}
+	logf("Config reloaded from %s (%d sessions)", cm.path, len(cm.GetAllSessions()))
+}
+
 func (cm *ConfigManager) Get() *Config {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -102,6 +376,67 @@ func (cm *ConfigManager) DeleteSession(name string) error {
 	return cm.saveLocked()
 }
 
+// PurgeSession deletes a session and every setting kept for it or its channel
+func (cm *ConfigManager) PurgeSession(name string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if channelID, ok := cm.config.Sessions[name]; ok {
+		delete(cm.config.ChannelBranches, channelID)
+		delete(cm.config.AutoCommitChannels, channelID)
+		delete(cm.config.ChannelSystemPrompts, channelID)
+	}
+	delete(cm.config.Sessions, name)
+	delete(cm.config.SessionHosts, name)
+	delete(cm.config.SandboxSessions, name)
+	delete(cm.config.PTYSessions, name)
+	delete(cm.config.SessionEnv, name)
+	delete(cm.config.AutoRestart, name)
+	delete(cm.config.SessionTags, name)
+	return cm.saveLocked()
+}
+
+// RenameSession moves a session and its per-session settings to a new name
+func (cm *ConfigManager) RenameSession(oldName, newName string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	channelID, ok := cm.config.Sessions[oldName]
+	if !ok {
+		return fmt.Errorf("session '%s' not found", oldName)
+	}
+	if _, taken := cm.config.Sessions[newName]; taken {
+		return fmt.Errorf("session '%s' already exists", newName)
+	}
+	delete(cm.config.Sessions, oldName)
+	cm.config.Sessions[newName] = channelID
+	if host, ok := cm.config.SessionHosts[oldName]; ok {
+		delete(cm.config.SessionHosts, oldName)
+		cm.config.SessionHosts[newName] = host
+	}
+	if cm.config.SandboxSessions[oldName] {
+		delete(cm.config.SandboxSessions, oldName)
+		cm.config.SandboxSessions[newName] = true
+	}
+	if cm.config.PTYSessions[oldName] {
+		delete(cm.config.PTYSessions, oldName)
+		cm.config.PTYSessions[newName] = true
+	}
+	if cm.config.AutoRestart[oldName] {
+		delete(cm.config.AutoRestart, oldName)
+		cm.config.AutoRestart[newName] = true
+	}
+	if tags, ok := cm.config.SessionTags[oldName]; ok {
+		delete(cm.config.SessionTags, oldName)
+		cm.config.SessionTags[newName] = tags
+	}
+	return cm.saveLocked()
+}
+
 func (cm *ConfigManager) GetSessionByChannel(channelID string) string {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -116,6 +451,153 @@ func (cm *ConfigManager) GetSessionByChannel(channelID string) string {
 	return ""
 }
 
+// SetChannelBranch pins a channel's runs to a branch ("" removes it)
+func (cm *ConfigManager) SetChannelBranch(channelID, branch string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if branch == "" {
+		delete(cm.config.ChannelBranches, channelID)
+	} else {
+		if cm.config.ChannelBranches == nil {
+			cm.config.ChannelBranches = make(map[string]string)
+		}
+		cm.config.ChannelBranches[channelID] = branch
+	}
+	return cm.saveLocked()
+}
+
+// SetSessionTags replaces a session's tags (none removes them)
+func (cm *ConfigManager) SetSessionTags(name string, tags []string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if len(tags) == 0 {
+		delete(cm.config.SessionTags, name)
+	} else {
+		if cm.config.SessionTags == nil {
+			cm.config.SessionTags = make(map[string][]string)
+		}
+		cm.config.SessionTags[name] = tags
+	}
+	return cm.saveLocked()
+}
+
+// SetAutoCommit turns checkpoint commits after each run on or off for a channel
+func (cm *ConfigManager) SetAutoCommit(channelID string, on bool) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if !on {
+		delete(cm.config.AutoCommitChannels, channelID)
+	} else {
+		if cm.config.AutoCommitChannels == nil {
+			cm.config.AutoCommitChannels = make(map[string]bool)
+		}
+		cm.config.AutoCommitChannels[channelID] = true
+	}
+	return cm.saveLocked()
+}
+
+// SetChannelSystemPrompt sets a channel's system prompt instructions ("" = use system_prompt)
+func (cm *ConfigManager) SetChannelSystemPrompt(channelID, prompt string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if prompt == "" {
+		delete(cm.config.ChannelSystemPrompts, channelID)
+	} else {
+		if cm.config.ChannelSystemPrompts == nil {
+			cm.config.ChannelSystemPrompts = make(map[string]string)
+		}
+		cm.config.ChannelSystemPrompts[channelID] = prompt
+	}
+	return cm.saveLocked()
+}
+
+// SetSessionHost records the agent a session runs on ("" = this machine)
+func (cm *ConfigManager) SetSessionHost(name, host string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if host == "" {
+		delete(cm.config.SessionHosts, name)
+	} else {
+		if cm.config.SessionHosts == nil {
+			cm.config.SessionHosts = make(map[string]string)
+		}
+		cm.config.SessionHosts[name] = host
+	}
+	return cm.saveLocked()
+}
+
+// SetSessionSandbox records whether a session runs in a Docker container
+func (cm *ConfigManager) SetSessionSandbox(name string, sandbox bool) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if !sandbox {
+		delete(cm.config.SandboxSessions, name)
+	} else {
+		if cm.config.SandboxSessions == nil {
+			cm.config.SandboxSessions = make(map[string]bool)
+		}
+		cm.config.SandboxSessions[name] = true
+	}
+	return cm.saveLocked()
+}
+
+// SetOutputFilters replaces the output filters (nil restores the defaults)
+func (cm *ConfigManager) SetOutputFilters(patterns []string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if patterns == nil {
+		cm.config.OutputFilters = nil
+	} else {
+		cm.config.OutputFilters = &patterns
+	}
+	return cm.saveLocked()
+}
+
+// Update applies fn to a copy of the config, then saves it and makes it current.
+// Callers holding the previous config keep a consistent snapshot.
+func (cm *ConfigManager) Update(fn func(*Config) error) (*Config, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.config == nil {
+		return nil, fmt.Errorf("config not loaded")
+	}
+	updated := *cm.config
+	if err := fn(&updated); err != nil {
+		return nil, err
+	}
+	if cm.overrides != nil {
+		cm.overrides(&updated)
+	}
+	previous := cm.config
+	cm.config = &updated
+	if err := cm.saveLocked(); err != nil {
+		cm.config = previous
+		return nil, err
+	}
+	return &updated, nil
+}
+
 func (cm *ConfigManager) GetAllSessions() map[string]string {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -131,25 +613,169 @@ func (cm *ConfigManager) GetAllSessions() map[string]string {
 }
 
 func (cm *ConfigManager) saveLocked() error {
-	data, err := json.MarshalIndent(cm.config, "", "  ")
+	data, err := marshalConfig(cm.config)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(cm.path, data, 0600)
 }
 
+// expandHome replaces a leading "~/" (or "~\" on Windows) with the home directory
+func expandHome(path string) string {
+	if len(path) > 2 && path[0] == '~' && (path[1] == '/' || path[1] == filepath.Separator) {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
 func getConfigPath() string {
+	if path := os.Getenv("CCSA_CONFIG"); path != "" {
+		return path
+	}
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".ccsa.json")
 }
 
+// configOverrides are settings given outside the config file, for containers
+// and CI where a JSON file in the home directory is awkward: CCSA_*
+// environment variables, then CLI flags on top. Both win over the file.
+type configOverrides struct {
+	configPath  string
+	projectsDir string
+	botToken    string
+	appToken    string
+	userIDs     []string
+}
+
+// envConfigOverrides reads CCSA_CONFIG, CCSA_BOT_TOKEN, CCSA_APP_TOKEN,
+// CCSA_PROJECTS_DIR and CCSA_USER_IDS (comma-separated)
+func envConfigOverrides() configOverrides {
+	o := configOverrides{
+		configPath:  os.Getenv("CCSA_CONFIG"),
+		projectsDir: os.Getenv("CCSA_PROJECTS_DIR"),
+		botToken:    os.Getenv("CCSA_BOT_TOKEN"),
+		appToken:    os.Getenv("CCSA_APP_TOKEN"),
+	}
+	o.userIDs = splitUserIDs(os.Getenv("CCSA_USER_IDS"))
+	return o
+}
+
+// splitUserIDs parses a comma-separated user ID list, ignoring spaces and
+// empty entries ("U1, U2," is U1 and U2)
+func splitUserIDs(list string) []string {
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// resolveConfigOverrides layers CLI flags over the environment
+func resolveConfigOverrides(flags configOverrides) configOverrides {
+	o := envConfigOverrides()
+	if flags.configPath != "" {
+		o.configPath = flags.configPath
+	}
+	if flags.projectsDir != "" {
+		o.projectsDir = flags.projectsDir
+	}
+	if flags.botToken != "" {
+		o.botToken = flags.botToken
+	}
+	if flags.appToken != "" {
+		o.appToken = flags.appToken
+	}
+	if len(flags.userIDs) > 0 {
+		o.userIDs = flags.userIDs
+	}
+	return o
+}
+
+// overriddenSettings are the overrides applied to a config and the values
+// they replaced
+type overriddenSettings struct {
+	by   configOverrides
+	file configOverrides
+}
+
+// apply sets the overridden values on a config, remembering the file's own
+// values so saving the config doesn't persist the overrides
+func (o configOverrides) apply(config *Config) {
+	if config.overridden == nil {
+		config.overridden = &overriddenSettings{file: configOverrides{
+			projectsDir: config.ProjectsDir,
+			botToken:    config.BotToken,
+			appToken:    config.AppToken,
+			userIDs:     config.UserIDs,
+		}}
+	}
+	by := &config.overridden.by
+	if o.projectsDir != "" {
+		config.ProjectsDir, by.projectsDir = o.projectsDir, o.projectsDir
+	}
+	if o.botToken != "" {
+		config.BotToken, by.botToken = o.botToken, o.botToken
+	}
+	if o.appToken != "" {
+		config.AppToken, by.appToken = o.appToken, o.appToken
+	}
+	if len(o.userIDs) > 0 {
+		config.UserIDs, by.userIDs = o.userIDs, o.userIDs
+	}
+}
+
+// restore puts the file's values back in the fields that still hold an
+// override (a setting changed since, e.g. by setup, is kept)
+func (s *overriddenSettings) restore(config *Config) {
+	if s.by.projectsDir != "" && config.ProjectsDir == s.by.projectsDir {
+		config.ProjectsDir = s.file.projectsDir
+	}
+	if s.by.botToken != "" && config.BotToken == s.by.botToken {
+		config.BotToken = s.file.botToken
+	}
+	if s.by.appToken != "" && config.AppToken == s.by.appToken {
+		config.AppToken = s.file.appToken
+	}
+	if len(s.by.userIDs) > 0 && slices.Equal(config.UserIDs, s.by.userIDs) {
+		config.UserIDs = s.file.userIDs
+	}
+}
+
+// hasTokens reports whether both Slack tokens are given, enough to run
+// without a config file
+func (o configOverrides) hasTokens() bool {
+	return o.botToken != "" && o.appToken != ""
+}
+
+// currentConfig returns the listener's live config, or reads the file in CLI/hook processes
+func currentConfig() (*Config, error) {
+	if configMgr != nil {
+		if config := configMgr.Get(); config != nil {
+			return config, nil
+		}
+	}
+	return loadConfig()
+}
+
 func loadConfig() (*Config, error) {
+	env := envConfigOverrides()
+	var config Config
 	data, err := os.ReadFile(getConfigPath())
-	if err != nil {
+	switch {
+	case err == nil:
+		err = json.Unmarshal(data, &config)
+		if err == nil {
+			err = resolveSecretRefs(&config)
+		}
+	case os.IsNotExist(err) && env.hasTokens():
+		err = nil // configured by the environment alone
+	default:
 		return nil, err
 	}
-	var config Config
-	err = json.Unmarshal(data, &config)
+	env.apply(&config)
 	if config.Sessions == nil {
 		config.Sessions = make(map[string]string)
 	}
@@ -157,25 +783,92 @@ func loadConfig() (*Config, error) {
 }
 
 func saveConfig(config *Config) error {
-	data, err := json.MarshalIndent(config, "", "  ")
+	data, err := marshalConfig(config)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(getConfigPath(), data, 0600)
 }
 
-// getProjectsDir returns the base directory for projects from config
-// Returns empty string if not configured (mandatory field)
+// getProjectsDir returns the base directory new projects are created in
+// (the first of getProjectsDirs). Returns empty string if not configured (mandatory field)
 func getProjectsDir(config *Config) string {
-	if config == nil || config.ProjectsDir == "" {
-		return ""
+	if dirs := getProjectsDirs(config); len(dirs) > 0 {
+		return dirs[0]
 	}
-	// Expand ~ if present
-	if len(config.ProjectsDir) > 2 && config.ProjectsDir[:2] == "~/" {
-		home, _ := os.UserHomeDir()
-		return filepath.Join(home, config.ProjectsDir[2:])
+	return ""
+}
+
+// getProjectsDirs returns every base directory, in lookup order:
+// projects_dir, then projects_dirs
+func getProjectsDirs(config *Config) []string {
+	if config == nil {
+		return nil
+	}
+	var dirs []string
+	for _, dir := range append([]string{config.ProjectsDir}, config.ProjectsDirs...) {
+		if dir == "" {
+			continue
+		}
+		dir = expandHome(dir)
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// sessionWorkDir returns a session's folder: the first base directory that
+// has it, or where it would be created
+func sessionWorkDir(config *Config, name string) string {
+	dirs := getProjectsDirs(config)
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+	}
+	if len(dirs) == 0 {
+		return name
+	}
+	return filepath.Join(dirs[0], name)
+}
+
+// findProjectForChannelName returns the project folder matching a channel
+// name in any base directory (handles dots, spaces, underscores)
+func findProjectForChannelName(config *Config, channelName string) (string, bool) {
+	for _, dir := range getProjectsDirs(config) {
+		name := fromSlackChannelName(channelName, dir)
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// findSessionForCwd returns the session name and channel whose project dir matches cwd
+func findSessionForCwd(config *Config, cwd string) (string, string) {
+	if config == nil {
+		return "", ""
+	}
+	dirs := getProjectsDirs(config)
+	for name, channelID := range config.Sessions {
+		if name == "" {
+			continue
+		}
+		for _, dir := range dirs {
+			if cwd == filepath.Join(dir, name) {
+				return name, channelID
+			}
+		}
+	}
+	// A folder of that name elsewhere (symlinked paths, agents' folders)
+	for name, channelID := range config.Sessions {
+		if name != "" && strings.HasSuffix(filepath.ToSlash(cwd), "/"+name) {
+			return name, channelID
+		}
 	}
-	return config.ProjectsDir
+	return "", ""
 }
 
 // getSessionByChannel returns session name for a channel (used in tests)
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCredentialTTL applies when a source reports no expiry and sets no ttl_minutes
+const defaultCredentialTTL = 15 * time.Minute
+
+// credentialRefreshMargin re-issues credentials this long before they expire
+const credentialRefreshMargin = 2 * time.Minute
+
+// credentialFailureBackoff is how long a failed source waits before it is retried
+const credentialFailureBackoff = time.Minute
+
+// CredentialSource issues a short-lived secret that is injected into Claude runs and !c.
+//
+// Formats:
+//   - "env" (default): stdout is the value of Env, valid for TTLMinutes (e.g. `gh auth token`)
+//   - "aws-sts": stdout is `aws sts ... --output json`; sets AWS_ACCESS_KEY_ID,
+//     AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, expiry from the response
+//   - "json": stdout is {"env": {"VAR": "value"}, "expires_at": "RFC3339"}
+type CredentialSource struct {
+	Name       string `json:"name"`
+	Command    string `json:"command"`
+	Format     string `json:"format,omitempty"`
+	Env        string `json:"env,omitempty"`
+	TTLMinutes int    `json:"ttl_minutes,omitempty"`
+}
+
+// issuedCredential is a cached credential and when it stops being valid; for a
+// failed issue, expiresAt is when the source is retried
+type issuedCredential struct {
+	env       map[string]string
+	expiresAt time.Time
+	err       error
+}
+
+// stale reports whether the source has to be issued again
+func (c *issuedCredential) stale(now time.Time) bool {
+	switch {
+	case c == nil:
+		return true
+	case c.err != nil:
+		return !now.Before(c.expiresAt)
+	}
+	return c.expiresAt.Sub(now) < credentialRefreshMargin
+}
+
+// credentialCache holds issued credentials by source name. The lock only guards
+// the maps: commands run under their source's issuing lock, so a slow source
+// holds up callers of that source and nothing else
+var credentialCache = struct {
+	sync.Mutex
+	byName  map[string]*issuedCredential
+	issuing map[string]*sync.Mutex
+}{byName: make(map[string]*issuedCredential), issuing: make(map[string]*sync.Mutex)}
+
+// issueCredential runs the source's command and parses its output
+func issueCredential(src CredentialSource) (*issuedCredential, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	out, err := shellCommand(ctx, src.Command).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("%s: %v - %s", src.Name, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %v", src.Name, err)
+	}
+	return parseCredentialOutput(src, out, time.Now())
+}
+
+// parseCredentialOutput turns a source's command output into env vars and an expiry
+func parseCredentialOutput(src CredentialSource, out []byte, now time.Time) (*issuedCredential, error) {
+	ttl := defaultCredentialTTL
+	if src.TTLMinutes > 0 {
+		ttl = time.Duration(src.TTLMinutes) * time.Minute
+	}
+	cred := &issuedCredential{env: make(map[string]string), expiresAt: now.Add(ttl)}
+
+	switch src.Format {
+	case "", "env":
+		value := strings.TrimSpace(string(out))
+		if src.Env == "" || value == "" {
+			return nil, fmt.Errorf("%s: needs \"env\" and a non-empty command output", src.Name)
+		}
+		cred.env[src.Env] = value
+
+	case "aws-sts":
+		var sts struct {
+			Credentials struct {
+				AccessKeyID     string    `json:"AccessKeyId"`
+				SecretAccessKey string    `json:"SecretAccessKey"`
+				SessionToken    string    `json:"SessionToken"`
+				Expiration      time.Time `json:"Expiration"`
+			} `json:"Credentials"`
+		}
+		if err := json.Unmarshal(out, &sts); err != nil || sts.Credentials.AccessKeyID == "" {
+			return nil, fmt.Errorf("%s: unexpected aws sts output", src.Name)
+		}
+		cred.env["AWS_ACCESS_KEY_ID"] = sts.Credentials.AccessKeyID
+		cred.env["AWS_SECRET_ACCESS_KEY"] = sts.Credentials.SecretAccessKey
+		cred.env["AWS_SESSION_TOKEN"] = sts.Credentials.SessionToken
+		if !sts.Credentials.Expiration.IsZero() {
+			cred.expiresAt = sts.Credentials.Expiration
+		}
+
+	case "json":
+		var generic struct {
+			Env       map[string]string `json:"env"`
+			ExpiresAt time.Time         `json:"expires_at"`
+		}
+		if err := json.Unmarshal(out, &generic); err != nil || len(generic.Env) == 0 {
+			return nil, fmt.Errorf("%s: expected {\"env\": {...}, \"expires_at\": ...}", src.Name)
+		}
+		cred.env = generic.Env
+		if !generic.ExpiresAt.IsZero() {
+			cred.expiresAt = generic.ExpiresAt
+		}
+
+	default:
+		return nil, fmt.Errorf("%s: unknown format %q", src.Name, src.Format)
+	}
+	return cred, nil
+}
+
+// credentialEnv returns the env vars of all configured sources, issuing or refreshing
+// credentials close to expiry. Failing sources are logged and skipped.
+func credentialEnv(config *Config) []string {
+	if config == nil || len(config.Credentials) == 0 {
+		return nil
+	}
+
+	var env []string
+	for _, src := range config.Credentials {
+		cred := currentCredential(src)
+		if cred.err != nil {
+			continue
+		}
+		for k, v := range cred.env {
+			env = append(env, k+"="+v)
+		}
+	}
+	return env
+}
+
+// currentCredential returns the source's cached credential, issuing it first when
+// it is missing, close to expiry, or failed longer than the backoff ago. Concurrent
+// callers of one source wait for a single issue.
+func currentCredential(src CredentialSource) *issuedCredential {
+	credentialCache.Lock()
+	cached := credentialCache.byName[src.Name]
+	issuing := credentialCache.issuing[src.Name]
+	if issuing == nil {
+		issuing = &sync.Mutex{}
+		credentialCache.issuing[src.Name] = issuing
+	}
+	credentialCache.Unlock()
+	if !cached.stale(time.Now()) {
+		return cached
+	}
+
+	issuing.Lock()
+	defer issuing.Unlock()
+
+	// Another caller may have issued it while we waited
+	credentialCache.Lock()
+	cached = credentialCache.byName[src.Name]
+	credentialCache.Unlock()
+	if !cached.stale(time.Now()) {
+		return cached
+	}
+
+	cred, err := issueCredential(src)
+	if err != nil {
+		logf("Credential issue failed (retry in %s): %v", formatDuration(credentialFailureBackoff), err)
+		cred = &issuedCredential{err: err, expiresAt: time.Now().Add(credentialFailureBackoff)}
+	} else {
+		logf("Issued credential %s (expires %s)", src.Name, cred.expiresAt.Format("15:04:05"))
+	}
+	credentialCache.Lock()
+	credentialCache.byName[src.Name] = cred
+	credentialCache.Unlock()
+	return cred
+}
+
+// runEnv is the environment for Claude runs and !c: ours plus scoped credentials
+func runEnv(config *Config) []string {
+	return append(os.Environ(), credentialEnv(config)...)
+}
+
+// CredentialState is a configured source as last issued, for !creds and !status
+type CredentialState struct {
+	Name      string
+	Vars      []string // env vars set, sorted
+	Issued    bool
+	ExpiresAt time.Time
+	Err       error
+}
+
+// credentialStates reports every configured source from the cache, without issuing
+func credentialStates(config *Config) []CredentialState {
+	if config == nil {
+		return nil
+	}
+
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+
+	var states []CredentialState
+	for _, src := range config.Credentials {
+		st := CredentialState{Name: src.Name}
+		if cached := credentialCache.byName[src.Name]; cached != nil {
+			st.Issued, st.ExpiresAt, st.Err = true, cached.expiresAt, cached.err
+			for k := range cached.env {
+				st.Vars = append(st.Vars, k)
+			}
+			sort.Strings(st.Vars)
+		}
+		states = append(states, st)
+	}
+	return states
+}
+
+// formatCredentialStatus lists configured credentials with their expiry
+func formatCredentialStatus(config *Config) string {
+	states := credentialStates(config)
+	if len(states) == 0 {
+		return ":key: No scoped credentials configured (see `credentials` in the config)"
+	}
+
+	var lines []string
+	for _, st := range states {
+		switch {
+		case !st.Issued:
+			lines = append(lines, fmt.Sprintf("• `%s` - not issued yet (issued on next run)", st.Name))
+		case st.Err != nil:
+			lines = append(lines, fmt.Sprintf("• `%s` - :x: %v (retried after %s)", st.Name, st.Err, st.ExpiresAt.Local().Format("15:04:05")))
+		case time.Now().After(st.ExpiresAt):
+			lines = append(lines, fmt.Sprintf("• `%s` - expired (re-issued on next run)", st.Name))
+		default:
+			lines = append(lines, fmt.Sprintf("• `%s` (%s) - expires in %s", st.Name, strings.Join(st.Vars, ", "), formatDuration(time.Until(st.ExpiresAt))))
+		}
+	}
+	return ":key: *Scoped credentials*\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cronSchedule is a parsed 5-field cron expression: minute hour day-of-month month day-of-week
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit i set = value i matches
+	domAny, dowAny                bool   // field was "*"
+}
+
+// cronMacros are the @-shortcuts accepted instead of five fields
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@weekdays": "0 9 * * 1-5",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// parseCronSchedule parses "0 9 * * 1-5" (lists, ranges and */n steps) or an @macro
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	if expanded, ok := cronMacros[strings.ToLower(strings.TrimSpace(spec))]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	limits := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	names := [5]string{"minute", "hour", "day", "month", "weekday"}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, limits[i][0], limits[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", names[i], err)
+		}
+		sets[i] = set
+	}
+	// Sunday is 0 or 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &cronSchedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses one comma-separated field into a bit set
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if r, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", s)
+			}
+			rangePart, step = r, n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				hi = max // "5/15" = from 5 every 15
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// matchesDay applies cron's rule: when both day fields are restricted, either may match
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first matching minute strictly after t (zero if none within 5 years)
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// CronJob is a recurring prompt registered with !schedule
+type CronJob struct {
+	ID        string    `json:"id"`
+	ChannelID string    `json:"channel_id"`
+	Spec      string    `json:"spec"`
+	Prompt    string    `json:"prompt"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	NextRun   time.Time `json:"next_run"`
+}
+
+// CronStore persists recurring prompts (~/.ccsa/schedules.json)
+type CronStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	jobs   []*CronJob
+	nextID int
+}
+
+var cronStore = &CronStore{path: getCronStorePath()}
+
+// getCronStorePath returns the path to the recurring prompts file
+func getCronStorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "schedules.json")
+}
+
+type cronStoreFile struct {
+	NextID int        `json:"next_id"`
+	Jobs   []*CronJob `json:"jobs"`
+}
+
+func (s *CronStore) loadLocked() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	var file cronStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		logf("Schedules: ignoring unreadable %s: %v", s.path, err)
+		return
+	}
+	s.jobs, s.nextID = file.Jobs, file.NextID
+}
+
+func (s *CronStore) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cronStoreFile{NextID: s.nextID, Jobs: s.jobs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Add registers a recurring prompt for a channel
+func (s *CronStore) Add(channelID, userID, spec, prompt string, now time.Time) (*CronJob, error) {
+	sched, err := parseCronSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+	next := sched.Next(now)
+	if next.IsZero() {
+		return nil, fmt.Errorf("`%s` never matches", spec)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	s.nextID++
+	job := &CronJob{
+		ID:        fmt.Sprintf("cron-%d", s.nextID),
+		ChannelID: channelID,
+		Spec:      spec,
+		Prompt:    prompt,
+		UserID:    userID,
+		CreatedAt: now,
+		NextRun:   next,
+	}
+	s.jobs = append(s.jobs, job)
+	return job, s.saveLocked()
+}
+
+// Remove deletes a channel's recurring prompt
+func (s *CronStore) Remove(channelID, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	for i, job := range s.jobs {
+		if job.ID == id && job.ChannelID == channelID {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			s.saveLocked()
+			return true
+		}
+	}
+	return false
+}
+
+// List returns a channel's recurring prompts by next run
+func (s *CronStore) List(channelID string) []CronJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	var jobs []CronJob
+	for _, job := range s.jobs {
+		if job.ChannelID == channelID {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].NextRun.Before(jobs[j].NextRun) })
+	return jobs
+}
+
+// Due returns the jobs whose next run has come and moves them to their
+// following run. Runs missed while the listener was down fire once.
+func (s *CronStore) Due(now time.Time) []CronJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	var due []CronJob
+	for _, job := range s.jobs {
+		if job.NextRun.After(now) {
+			continue
+		}
+		sched, err := parseCronSchedule(job.Spec)
+		if err != nil {
+			continue
+		}
+		job.LastRun = now
+		job.NextRun = sched.Next(now)
+		due = append(due, *job)
+	}
+	if len(due) > 0 {
+		s.saveLocked()
+	}
+	return due
+}
+
+// runCronJob posts the scheduled prompt in its channel and submits it like a message
+func runCronJob(config *Config, job CronJob) {
+	sessionName := getSessionByChannel(config, job.ChannelID)
+	if sessionName == "" {
+		logf("Schedule %s: channel %s is no longer a session, skipping", job.ID, job.ChannelID)
+		return
+	}
+	logf("Running schedule %s in %s: %s", job.ID, sessionName, job.Prompt)
+
+	eventTS, err := sendMessage(config, job.ChannelID, fmt.Sprintf(":repeat: *Scheduled prompt* `%s` (`%s`): %s", job.ID, job.Spec, job.Prompt))
+	if err != nil {
+		logf("Schedule %s: failed to post: %v", job.ID, err)
+		return
+	}
+	msg := &QueuedMessage{
+		Text:      job.Prompt,
+		ChannelID: job.ChannelID,
+		EventTS:   eventTS,
+		UserID:    job.UserID,
+		WorkDir:   sessionWorkDir(config, sessionName),
+	}
+	addReaction(config, msg.ChannelID, msg.EventTS, "eyes")
+	submitClaudeMessage(msg, config)
+}
+
+// parseScheduleArgs splits `"0 9 * * 1-5" <prompt>` or `@daily <prompt>`
+func parseScheduleArgs(arg string) (spec, prompt string, ok bool) {
+	arg = strings.TrimSpace(arg)
+	// Slack turns straight quotes into curly ones
+	arg = strings.NewReplacer("“", `"`, "”", `"`).Replace(arg)
+	if strings.HasPrefix(arg, `"`) {
+		end := strings.Index(arg[1:], `"`)
+		if end < 0 {
+			return "", "", false
+		}
+		spec, prompt = arg[1:end+1], arg[end+2:]
+	} else if strings.HasPrefix(arg, "@") {
+		spec, prompt, _ = strings.Cut(arg, " ")
+	} else {
+		return "", "", false
+	}
+	prompt = strings.TrimSpace(prompt)
+	return strings.TrimSpace(spec), prompt, spec != "" && prompt != ""
+}
+
+// handleScheduleCommand handles `!schedule "<cron>" <prompt>`, `!schedule list` and `!schedule rm <id>`
+func handleScheduleCommand(config *Config, channelID, userID, args string) string {
+	usage := "Usage: `!schedule \"0 9 * * 1-5\" <prompt>` (or `@hourly`, `@daily`, `@weekdays`, `@weekly`, `@monthly`), `!schedule list`, `!schedule rm <id>`"
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return usage
+	}
+	if getSessionByChannel(config, channelID) == "" {
+		return ":x: Not in a session channel. Use `!schedule` in a session channel."
+	}
+
+	switch fields[0] {
+	case "list":
+		jobs := cronStore.List(channelID)
+		if len(jobs) == 0 {
+			return ":calendar: No recurring prompts for this channel"
+		}
+		lines := []string{":calendar: *Recurring prompts:*"}
+		for _, job := range jobs {
+			lines = append(lines, fmt.Sprintf("• `%s` `%s` - next *%s*: %s", job.ID, job.Spec, job.NextRun.Local().Format("Mon Jan 2 15:04"), job.Prompt))
+		}
+		return strings.Join(lines, "\n")
+	case "rm", "remove":
+		if len(fields) != 2 {
+			return usage
+		}
+		if cronStore.Remove(channelID, fields[1]) {
+			return fmt.Sprintf(":wastebasket: Removed `%s`", fields[1])
+		}
+		return fmt.Sprintf(":shrug: No recurring prompt `%s` in this channel", fields[1])
+	}
+
+	spec, prompt, ok := parseScheduleArgs(args)
+	if !ok {
+		return usage
+	}
+	job, err := cronStore.Add(channelID, userID, spec, prompt, time.Now())
+	if err != nil {
+		return fmt.Sprintf(":x: Invalid schedule: %v", err)
+	}
+	return fmt.Sprintf(":repeat: Scheduled `%s` (`%s`), next run *%s*", job.ID, job.Spec, job.NextRun.Local().Format("Mon Jan 2 15:04"))
+}
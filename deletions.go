@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// runMessageLog remembers the messages posted by the current run of each
+// channel thread, so a deleted prompt can take its output with it
+type runMessageLog struct {
+	mu   sync.Mutex
+	runs map[string][]string // "channel:threadTS" -> message timestamps
+}
+
+var runMessages = &runMessageLog{runs: make(map[string][]string)}
+
+// Start forgets the messages of the thread's previous run
+func (l *runMessageLog) Start(channelID, threadTS string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.runs, channelID+":"+threadTS)
+}
+
+// Add records a message posted by the thread's run
+func (l *runMessageLog) Add(channelID, threadTS, ts string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := channelID + ":" + threadTS
+	l.runs[key] = append(l.runs[key], ts)
+}
+
+// Take returns and forgets the messages posted by the thread's run
+func (l *runMessageLog) Take(channelID, threadTS string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := channelID + ":" + threadTS
+	messages := l.runs[key]
+	delete(l.runs, key)
+	return messages
+}
+
+// deletedPrompts marks running prompts whose Slack message was deleted ("channel:ts")
+var deletedPrompts sync.Map
+
+// messageDeletion is the part of a message_deleted event we use
+type messageDeletion struct {
+	Channel         string `json:"channel"`
+	DeletedTS       string `json:"deleted_ts"`
+	PreviousMessage struct {
+		User  string `json:"user"`
+		BotID string `json:"bot_id"`
+	} `json:"previous_message"`
+}
+
+// handleMessageDeletion cancels the run of a deleted prompt: a queued one is
+// dropped, a running one is stopped and its output removed once it ends
+func handleMessageDeletion(cfgMgr *ConfigManager, eventData json.RawMessage) {
+	var ev messageDeletion
+	if err := json.Unmarshal(eventData, &ev); err != nil || ev.DeletedTS == "" || ev.PreviousMessage.BotID != "" {
+		return
+	}
+	config := cfgMgr.Get()
+	if config == nil || !config.IsAuthorizedUser(ev.PreviousMessage.User) {
+		return
+	}
+
+	_, queued := messageQueue.Snapshot(ev.Channel)
+	for _, m := range queued {
+		if !slices.Contains(m.EventTimestamps(), ev.DeletedTS) {
+			continue
+		}
+		if messageQueue.Cancel(ev.Channel, m.QueueID) == nil {
+			break // started meanwhile
+		}
+		logf("Dropped queued message for channel %s: prompt deleted", ev.Channel)
+		for _, ts := range m.EventTimestamps() {
+			removeReaction(config, ev.Channel, ts, "hourglass_flowing_sand")
+		}
+		if m.OnFinish != nil {
+			m.OnFinish(nil, fmt.Errorf("cancelled: prompt deleted"))
+		}
+		return
+	}
+
+	if running, _ := messageQueue.Snapshot(ev.Channel); running != nil && slices.Contains(running.EventTimestamps(), ev.DeletedTS) {
+		deletedPrompts.Store(running.ChannelID+":"+running.EventTS, true)
+		if CancelClaudeProcess(ev.Channel) {
+			logf("Cancelled run for channel %s: prompt deleted", ev.Channel)
+		}
+	}
+}
+
+// promptDeleted reports (once) whether msg's prompt was deleted while it ran
+func promptDeleted(msg *QueuedMessage) bool {
+	_, ok := deletedPrompts.LoadAndDelete(msg.ChannelID + ":" + msg.EventTS)
+	return ok
+}
+
+// cleanupDeletedRun removes what the run of a deleted prompt posted, and its reactions
+func cleanupDeletedRun(config *Config, msg *QueuedMessage) {
+	posted := runMessages.Take(msg.ChannelID, msg.ThreadTS)
+	for _, ts := range posted {
+		deleteMessage(config, msg.ChannelID, ts)
+	}
+	for _, ts := range msg.EventTimestamps() {
+		for _, emoji := range []string{"eyes", "hourglass_flowing_sand", "x", "white_check_mark"} {
+			removeReaction(config, msg.ChannelID, ts, emoji)
+		}
+	}
+	logf("Removed %d message(s) of the deleted prompt's run in channel %s", len(posted), msg.ChannelID)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	diffPreviewLines = 20  // diff lines shown in the tool call message
+	diffLineMax      = 150 // runes per shown line
+	diffContext      = 2   // unchanged lines kept around changes
+	diffMaxLCS       = 400 // lines per side compared line by line; beyond, old then new
+)
+
+// diffLines returns a line diff of two texts: "- " removed, "+ " added and
+// "  " unchanged lines, with unchanged runs cut to diffContext lines around
+// changes ("  ⋯" marks a cut)
+func diffLines(oldText, newText string) []string {
+	a := splitDiffLines(oldText)
+	b := splitDiffLines(newText)
+
+	var ops []string
+	if len(a) > diffMaxLCS || len(b) > diffMaxLCS {
+		for _, l := range a {
+			ops = append(ops, "- "+l)
+		}
+		for _, l := range b {
+			ops = append(ops, "+ "+l)
+		}
+		return ops
+	}
+
+	// Longest common subsequence, walked from the start
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, "- "+a[i])
+			i++
+		default:
+			ops = append(ops, "+ "+b[j])
+			j++
+		}
+	}
+	return trimDiffContext(ops)
+}
+
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// trimDiffContext keeps diffContext unchanged lines around changes
+func trimDiffContext(ops []string) []string {
+	keep := make([]bool, len(ops))
+	for i, op := range ops {
+		if strings.HasPrefix(op, "  ") {
+			continue
+		}
+		for k := max(0, i-diffContext); k <= min(len(ops)-1, i+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+	var out []string
+	for i, op := range ops {
+		if keep[i] {
+			out = append(out, op)
+		} else if len(out) == 0 || out[len(out)-1] != "  ⋯" {
+			out = append(out, "  ⋯")
+		}
+	}
+	return out
+}
+
+// toolDiff returns the diff of an Edit, MultiEdit or Write call and the file
+// it changes; ok is false for other tools
+func toolDiff(toolName string, input json.RawMessage) (path string, lines []string, ok bool) {
+	var data struct {
+		FilePath  string `json:"file_path"`
+		OldString string `json:"old_string"`
+		NewString string `json:"new_string"`
+		Content   string `json:"content"`
+		Edits     []struct {
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+		} `json:"edits"`
+	}
+	if json.Unmarshal(input, &data) != nil || data.FilePath == "" {
+		return "", nil, false
+	}
+	switch strings.ToLower(toolName) {
+	case "edit":
+		return data.FilePath, diffLines(data.OldString, data.NewString), true
+	case "multiedit":
+		for i, e := range data.Edits {
+			if i > 0 {
+				lines = append(lines, "@@")
+			}
+			lines = append(lines, diffLines(e.OldString, e.NewString)...)
+		}
+		return data.FilePath, lines, true
+	case "write", "writefile":
+		return data.FilePath, diffLines("", data.Content), true
+	}
+	return "", nil, false
+}
+
+// formatDiffBlock renders a diff as a fenced block under the file name, cut
+// to diffPreviewLines
+func formatDiffBlock(path string, lines []string, isWrite bool) string {
+	header := fmt.Sprintf("`%s`", path)
+	if isWrite {
+		header += fmt.Sprintf(" _(%d lines)_", len(lines))
+	}
+	if len(lines) == 0 {
+		return header
+	}
+	shown := lines
+	if len(shown) > diffPreviewLines {
+		shown = shown[:diffPreviewLines]
+	}
+	var b strings.Builder
+	b.WriteString(header + "\n```\n")
+	for _, l := range shown {
+		// A fence inside the diff would end the block
+		b.WriteString(strings.ReplaceAll(truncateRunes(l, diffLineMax), "```", "`​``") + "\n")
+	}
+	if hidden := len(lines) - len(shown); hidden > 0 {
+		fmt.Fprintf(&b, "... %d more line(s)\n", hidden)
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+// diffSnippet returns the full diff to attach when the preview was cut
+func diffSnippet(toolName string, input json.RawMessage) (name, content string, ok bool) {
+	path, lines, ok := toolDiff(toolName, input)
+	if !ok || len(lines) <= diffPreviewLines {
+		return "", "", false
+	}
+	return filepath.Base(path) + ".diff", fmt.Sprintf("--- %s\n+++ %s\n%s\n", path, path, strings.Join(lines, "\n")), true
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mrand "math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	discordAPIBase    = "https://discord.com/api/v10"
+	discordGatewayURL = "wss://gateway.discord.gg/?v=10&encoding=json"
+	// discordIntents: GUILD_MESSAGES | DIRECT_MESSAGES | MESSAGE_CONTENT
+	discordIntents = 1<<9 | 1<<12 | 1<<15
+)
+
+// DiscordConfig connects the bot to Discord next to Slack
+type DiscordConfig struct {
+	// BotToken is the Discord application's bot token
+	BotToken string `json:"bot_token"`
+	// UserIDs are the Discord users allowed to talk to Claude
+	UserIDs []string `json:"user_ids,omitempty"`
+}
+
+// isDiscordID reports whether a channel ID is a Discord snowflake
+func isDiscordID(id string) bool {
+	if len(id) < 15 {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var discordChannelRe = regexp.MustCompile(`<#(\d+)\|[^>]*>`)
+
+// slackToDiscord converts Slack mrkdwn to Discord markdown, leaving code alone
+func slackToDiscord(text string) string {
+	return convertMrkdwn(text, convertSlackProse)
+}
+
+// convertSlackProse converts mrkdwn outside code spans
+func convertSlackProse(s string) string {
+	s = discordChannelRe.ReplaceAllString(s, "<#$1>")
+	return replaceShortcodes(markdownProse(s))
+}
+
+// discordAPIError is a non-2xx answer of the REST API
+type discordAPIError struct {
+	Status  int
+	Message string
+}
+
+func (e *discordAPIError) Error() string {
+	return fmt.Sprintf("discord error %d: %s", e.Status, e.Message)
+}
+
+// discordRequest performs one REST call, waiting out rate limits
+func discordRequest(config *Config, method, path, contentType string, body []byte, out interface{}) error {
+	if config.Discord == nil || config.Discord.BotToken == "" {
+		return errors.New("discord is not configured")
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, discordAPIBase+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bot "+config.Discord.BotToken)
+		req.Header.Set("User-Agent", "DiscordBot (https://github.com/sderosiaux/claude-code-slack-anywhere, "+version+")")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < 3 {
+			var limit struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			json.Unmarshal(data, &limit)
+			time.Sleep(time.Duration(math.Max(limit.RetryAfter, 0.5) * float64(time.Second)))
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			var apiErr struct {
+				Message string `json:"message"`
+			}
+			json.Unmarshal(data, &apiErr)
+			if apiErr.Message == "" {
+				apiErr.Message = resp.Status
+			}
+			return &discordAPIError{Status: resp.StatusCode, Message: apiErr.Message}
+		}
+		if out != nil && len(data) > 0 {
+			return json.Unmarshal(data, out)
+		}
+		return nil
+	}
+}
+
+// discordJSON performs a REST call with a JSON body
+func discordJSON(config *Config, method, path string, payload, out interface{}) error {
+	var body []byte
+	contentType := ""
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	return discordRequest(config, method, path, contentType, body, out)
+}
+
+type discordMessage struct {
+	ID          string `json:"id"`
+	Attachments []struct {
+		URL string `json:"url"`
+	} `json:"attachments"`
+}
+
+// discordComponents lays buttons out in action rows of five
+func discordComponents(buttons []Element, blockID string) []map[string]interface{} {
+	var rows []map[string]interface{}
+	var row []map[string]interface{}
+	for _, btn := range buttons {
+		if btn.Type != "button" || btn.Text == nil {
+			continue // checkboxes have no Discord counterpart
+		}
+		customID := rememberButton(btn, blockID)
+
+		style := 2 // secondary
+		switch btn.Style {
+		case "primary":
+			style = 1
+		case "danger":
+			style = 4
+		}
+		row = append(row, map[string]interface{}{
+			"type":      2,
+			"style":     style,
+			"label":     truncateRunes(convertSlackProse(btn.Text.Text), 80),
+			"custom_id": customID,
+		})
+		if len(row) == 5 {
+			rows = append(rows, map[string]interface{}{"type": 1, "components": row})
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, map[string]interface{}{"type": 1, "components": row})
+	}
+	return rows
+}
+
+// discordChat is the Discord ChatBackend (REST API, events over the Gateway).
+// Threads are reply chains (see threadRoot).
+type discordChat struct{}
+
+func (discordChat) Name() string { return "discord" }
+
+func (discordChat) MaxMessageLen() int { return 2000 }
+
+// postDiscord creates a message and records it in its reply chain
+func postDiscord(config *Config, channelID, threadTS string, payload map[string]interface{}) (string, error) {
+	payload["allowed_mentions"] = map[string]interface{}{"parse": []string{}}
+	if threadTS != "" {
+		payload["message_reference"] = map[string]interface{}{"message_id": threadTS, "fail_if_not_exists": false}
+	}
+	var msg discordMessage
+	if err := discordJSON(config, "POST", "/channels/"+channelID+"/messages", payload, &msg); err != nil {
+		return "", err
+	}
+	if threadTS != "" {
+		recordReply(channelID, msg.ID, threadTS)
+	}
+	return msg.ID, nil
+}
+
+func (discordChat) PostMessage(config *Config, channelID, threadTS, text string) (string, error) {
+	return postDiscord(config, channelID, threadTS, map[string]interface{}{
+		"content": slackToDiscord(text),
+	})
+}
+
+func (discordChat) UpdateMessage(config *Config, channelID, ts, text string) error {
+	return discordJSON(config, "PATCH", "/channels/"+channelID+"/messages/"+ts, map[string]interface{}{
+		"content":    truncateRunes(slackToDiscord(text), 2000),
+		"components": []interface{}{},
+	}, nil)
+}
+
+func (discordChat) DeleteMessage(config *Config, channelID, ts string) error {
+	err := discordJSON(config, "DELETE", "/channels/"+channelID+"/messages/"+ts, nil, nil)
+	var apiErr *discordAPIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return nil // already deleted
+	}
+	return err
+}
+
+// discordReactionPath returns a reaction's endpoint, or "" for emoji Discord lacks
+func discordReactionPath(channelID, ts, emoji string) string {
+	unicode, ok := shortcodeEmoji[emoji]
+	if !ok {
+		return ""
+	}
+	return "/channels/" + channelID + "/messages/" + ts + "/reactions/" + url.PathEscape(unicode) + "/@me"
+}
+
+func (discordChat) AddReaction(config *Config, channelID, ts, emoji string) error {
+	path := discordReactionPath(channelID, ts, emoji)
+	if path == "" {
+		return nil
+	}
+	return discordJSON(config, "PUT", path, nil, nil)
+}
+
+func (discordChat) RemoveReaction(config *Config, channelID, ts, emoji string) error {
+	path := discordReactionPath(channelID, ts, emoji)
+	if path == "" {
+		return nil
+	}
+	err := discordJSON(config, "DELETE", path, nil, nil)
+	var apiErr *discordAPIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func (discordChat) PostButtons(config *Config, channelID, threadTS, text string, buttons []Element, blockID string) (string, error) {
+	return postDiscord(config, channelID, threadTS, map[string]interface{}{
+		"content":    truncateRunes(slackToDiscord(text), 2000),
+		"components": discordComponents(buttons, blockID),
+	})
+}
+
+func (discordChat) UpdateButtons(config *Config, channelID, ts, text string, buttons []Element, blockID string) error {
+	return discordJSON(config, "PATCH", "/channels/"+channelID+"/messages/"+ts, map[string]interface{}{
+		"content":    truncateRunes(slackToDiscord(text), 2000),
+		"components": discordComponents(buttons, blockID),
+	}, nil)
+}
+
+// uploadDiscord posts data as an attachment and returns its URL
+func uploadDiscord(config *Config, channelID, threadTS, filename string, data []byte, comment string) (string, error) {
+	payload := map[string]interface{}{
+		"content":          slackToDiscord(comment),
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	}
+	if threadTS != "" {
+		payload["message_reference"] = map[string]interface{}{"message_id": threadTS, "fail_if_not_exists": false}
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("payload_json", string(payloadJSON))
+	part, err := w.CreateFormFile("files[0]", filename)
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	w.Close()
+
+	var msg discordMessage
+	if err := discordRequest(config, "POST", "/channels/"+channelID+"/messages", w.FormDataContentType(), body.Bytes(), &msg); err != nil {
+		return "", err
+	}
+	if threadTS != "" {
+		recordReply(channelID, msg.ID, threadTS)
+	}
+	if len(msg.Attachments) == 0 {
+		return "", nil
+	}
+	return msg.Attachments[0].URL, nil
+}
+
+func (discordChat) UploadSnippet(config *Config, channelID, threadTS, filename, content, title string) (string, error) {
+	return uploadDiscord(config, channelID, threadTS, filename, []byte(content), "Full output:")
+}
+
+func (discordChat) UploadFile(config *Config, channelID, threadTS, filePath, comment string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return uploadDiscord(config, channelID, threadTS, filepath.Base(filePath), data, comment)
+}
+
+func (discordChat) ChannelName(config *Config, channelID string) (string, error) {
+	var channel struct {
+		Name string `json:"name"`
+	}
+	if err := discordJSON(config, "GET", "/channels/"+channelID, nil, &channel); err != nil {
+		return "", err
+	}
+	return channel.Name, nil
+}
+
+func (discordChat) ArchiveChannel(config *Config, channelID string) error {
+	return errors.New("archiving channels is not supported on Discord")
+}
+
+// discordGatewayPayload is a Gateway frame
+type discordGatewayPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// Listen connects to the Gateway and reconnects with backoff until ctx is done
+func (discordChat) Listen(ctx context.Context, cfgMgr *ConfigManager) {
+	attempt := 0
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := connectDiscordGateway(ctx, cfgMgr)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > reconnectMaxDelay {
+			attempt = 0
+		}
+		delay := reconnectDelay(attempt, mrand.Float64())
+		attempt++
+		logf("Discord gateway error: %v (reconnecting in %v, attempt %d)", err, delay.Round(100*time.Millisecond), attempt)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+}
+
+// connectDiscordGateway runs one Gateway session: identify, heartbeat, and
+// hand messages and button clicks to the Slack handlers
+func connectDiscordGateway(ctx context.Context, cfgMgr *ConfigManager) error {
+	discord := cfgMgr.Get().Discord
+	if discord == nil || discord.BotToken == "" {
+		return errors.New("discord is not configured")
+	}
+	ws, err := websocket.Dial(discordGatewayURL, "", "https://discord.com")
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	// Unblock Receive on shutdown
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	var sendMu sync.Mutex
+	send := func(p interface{}) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return websocket.JSON.Send(ws, p)
+	}
+
+	var seqMu sync.Mutex
+	var seq *int64
+	done := make(chan struct{})
+	defer close(done)
+
+	for {
+		var frame discordGatewayPayload
+		if err := websocket.JSON.Receive(ws, &frame); err != nil {
+			return err
+		}
+		if frame.S != nil {
+			seqMu.Lock()
+			seq = frame.S
+			seqMu.Unlock()
+		}
+
+		switch frame.Op {
+		case 10: // Hello: start heartbeating, then identify
+			var hello struct {
+				HeartbeatInterval int `json:"heartbeat_interval"`
+			}
+			json.Unmarshal(frame.D, &hello)
+			go func(interval time.Duration) {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						seqMu.Lock()
+						s := seq
+						seqMu.Unlock()
+						if err := send(map[string]interface{}{"op": 1, "d": s}); err != nil {
+							ws.Close()
+							return
+						}
+					}
+				}
+			}(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+
+			err := send(map[string]interface{}{
+				"op": 2,
+				"d": map[string]interface{}{
+					"token":   discord.BotToken,
+					"intents": discordIntents,
+					"properties": map[string]string{
+						"os":      "linux",
+						"browser": "ccsa",
+						"device":  "ccsa",
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+		case 1: // Heartbeat requested
+			seqMu.Lock()
+			s := seq
+			seqMu.Unlock()
+			send(map[string]interface{}{"op": 1, "d": s})
+
+		case 7: // Reconnect
+			return errors.New("reconnect requested")
+
+		case 9: // Invalid session
+			return errors.New("invalid session")
+
+		case 0: // Dispatch
+			switch frame.T {
+			case "READY":
+				logf("Discord gateway connected")
+			case "MESSAGE_CREATE":
+				if event, ok := discordMessageEvent(frame.D); ok {
+					workerPool.Submit(func() {
+						handleSlackEvent(ctx, cfgMgr, event)
+					})
+				}
+			case "INTERACTION_CREATE":
+				var interaction discordInteraction
+				json.Unmarshal(frame.D, &interaction)
+				go handleDiscordInteraction(cfgMgr, interaction)
+			}
+		}
+	}
+}
+
+// discordMessageEvent converts a MESSAGE_CREATE into a Slack message event
+func discordMessageEvent(data json.RawMessage) (json.RawMessage, bool) {
+	var msg struct {
+		ID        string `json:"id"`
+		ChannelID string `json:"channel_id"`
+		Content   string `json:"content"`
+		Author    struct {
+			ID  string `json:"id"`
+			Bot bool   `json:"bot"`
+		} `json:"author"`
+		MessageReference *struct {
+			MessageID string `json:"message_id"`
+		} `json:"message_reference"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Content == "" {
+		return nil, false
+	}
+	event := map[string]string{
+		"type":    "message",
+		"channel": msg.ChannelID,
+		"user":    msg.Author.ID,
+		"text":    msg.Content,
+		"ts":      msg.ID,
+	}
+	if msg.Author.Bot {
+		event["bot_id"] = msg.Author.ID
+	}
+	if msg.MessageReference != nil && msg.MessageReference.MessageID != "" {
+		event["thread_ts"] = threadRoot(msg.ChannelID, msg.MessageReference.MessageID)
+		recordReply(msg.ChannelID, msg.ID, msg.MessageReference.MessageID)
+	}
+	out, _ := json.Marshal(event)
+	return out, true
+}
+
+// discordInteraction is a component (button) click
+type discordInteraction struct {
+	ID        string `json:"id"`
+	Token     string `json:"token"`
+	Type      int    `json:"type"`
+	ChannelID string `json:"channel_id"`
+	Member    *struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	} `json:"member"`
+	User *struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Message struct {
+		ID      string `json:"id"`
+		Content string `json:"content"`
+	} `json:"message"`
+	Data struct {
+		CustomID string `json:"custom_id"`
+	} `json:"data"`
+}
+
+// handleDiscordInteraction acknowledges a button click and runs it as a Slack block action
+func handleDiscordInteraction(cfgMgr *ConfigManager, in discordInteraction) {
+	config := cfgMgr.Get()
+	if in.Type != 3 { // MESSAGE_COMPONENT
+		return
+	}
+	// Deferred update: keep the message, the action edits it
+	if err := discordJSON(config, "POST", "/interactions/"+in.ID+"/"+in.Token+"/callback", map[string]int{"type": 6}, nil); err != nil {
+		logf("Discord interaction ack failed: %v", err)
+	}
+
+	btn, ok := recallButton(in.Data.CustomID)
+	if !ok {
+		discordChat{}.UpdateMessage(config, in.ChannelID, in.Message.ID, in.Message.Content+"\n\n:hourglass: These buttons expired - run the command again")
+		return
+	}
+	userID := ""
+	if in.Member != nil {
+		userID = in.Member.User.ID
+	} else if in.User != nil {
+		userID = in.User.ID
+	}
+	handleBlockAction(cfgMgr, chatButtonAction(in.ChannelID, userID, in.Message.ID, in.Message.Content, btn))
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// editedPrompt is a finished prompt that was edited, waiting for its Re-run button
+type editedPrompt struct {
+	msg   *QueuedMessage
+	files []SlackFile
+}
+
+// pendingEditReruns holds edited prompts by "channel:ts" until Re-run is clicked
+var pendingEditReruns sync.Map
+
+// messageEdit is the part of a message_changed event we use
+type messageEdit struct {
+	Channel string `json:"channel"`
+	Message struct {
+		User     string      `json:"user"`
+		Text     string      `json:"text"`
+		TS       string      `json:"ts"`
+		ThreadTS string      `json:"thread_ts"`
+		BotID    string      `json:"bot_id"`
+		Files    []SlackFile `json:"files"`
+	} `json:"message"`
+	PreviousMessage struct {
+		Text string `json:"text"`
+	} `json:"previous_message"`
+}
+
+// editedClaudeText turns an edited prompt into the text sent to Claude, like a new message
+func editedClaudeText(config *Config, text string) string {
+	claudeText := preprocessSlackText(config, text)
+	if strings.HasPrefix(claudeText, "//") {
+		claudeText = claudeText[1:]
+	}
+	if !strings.HasPrefix(claudeText, "/") {
+		claudeText = slackUserPrefix + claudeText
+	}
+	return claudeText
+}
+
+// handleMessageEdit follows an edited prompt in a session channel: a queued one
+// runs with the new text, a finished one gets a "Re-run with edited prompt" button
+func handleMessageEdit(cfgMgr *ConfigManager, eventData json.RawMessage) {
+	var ev messageEdit
+	if err := json.Unmarshal(eventData, &ev); err != nil {
+		return
+	}
+	m := ev.Message
+	text := strings.TrimSpace(m.Text)
+	// Unfurls and file changes also come as message_changed: only text edits count
+	if m.BotID != "" || text == "" || text == strings.TrimSpace(ev.PreviousMessage.Text) || strings.HasPrefix(text, "!") {
+		return
+	}
+	config := cfgMgr.Get()
+	if config == nil || !config.IsAuthorizedUser(m.User) {
+		return
+	}
+	sessionName := cfgMgr.GetSessionByChannel(ev.Channel)
+	if sessionName == "" {
+		return
+	}
+	logf("[edit] @%s in %s: %s", m.User, ev.Channel, text)
+	replyTS := m.TS
+	if m.ThreadTS != "" {
+		replyTS = m.ThreadTS
+	}
+
+	if len(m.Files) > 0 && isQueued(ev.Channel, m.TS) {
+		sendMessageToThread(config, ev.Channel, replyTS, ":pencil2: This queued message has attachments, so the edit can't be applied - cancel it with `!queue` and send it again")
+		return
+	}
+	found, updated := messageQueue.EditQueued(ev.Channel, m.TS, editedClaudeText(config, text))
+	switch {
+	case updated:
+		addReaction(config, ev.Channel, m.TS, "pencil2")
+		sendMessageToThread(config, ev.Channel, replyTS, ":pencil2: Queued message updated - the edited text will run")
+		return
+	case found:
+		sendMessageToThread(config, ev.Channel, replyTS, ":pencil2: This queued message was combined with others, so the edit can't be applied - cancel it with `!queue` and send it again")
+		return
+	}
+
+	threadTS := m.ThreadTS
+	if threadTS == m.TS {
+		threadTS = "" // a top-level message with replies
+	}
+	key := ev.Channel + ":" + m.TS
+	pendingEditReruns.Store(key, &editedPrompt{
+		msg: &QueuedMessage{
+			Text:      text,
+			ChannelID: ev.Channel,
+			ThreadTS:  threadTS,
+			EventTS:   m.TS,
+			UserID:    m.User,
+			WorkDir:   sessionWorkDir(config, sessionName),
+		},
+		files: m.Files,
+	})
+	buttons := []Element{{
+		Type:     "button",
+		Text:     &TextObject{Type: "plain_text", Text: "Re-run with edited prompt"},
+		ActionID: "edit_rerun",
+		Value:    key,
+		Style:    "primary",
+	}}
+	if err := sendMessageWithButtonsToThread(config, ev.Channel, replyTS, ":pencil2: Prompt edited. Run it again with the new text?", buttons, "edit_"+m.TS); err != nil {
+		logf("Failed to post edit re-run button: %v", err)
+		pendingEditReruns.Delete(key)
+	}
+}
+
+// isQueued reports whether the message sent as eventTS is waiting in the queue
+func isQueued(channelID, eventTS string) bool {
+	_, queued := messageQueue.Snapshot(channelID)
+	return slices.ContainsFunc(queued, func(m *QueuedMessage) bool {
+		return slices.Contains(m.EventTimestamps(), eventTS)
+	})
+}
+
+// handleEditRerun runs an edited prompt again
+func handleEditRerun(config *Config, action BlockActionPayload, act BlockAction) {
+	pending, ok := pendingEditReruns.LoadAndDelete(act.Value)
+	if !ok {
+		updateMessage(config, action.Channel.ID, action.Message.TS, ":information_source: Already re-run")
+		return
+	}
+	edit := pending.(*editedPrompt)
+	msg := edit.msg
+	updateMessage(config, action.Channel.ID, action.Message.TS, fmt.Sprintf(":arrows_counterclockwise: Re-running the edited prompt (<@%s>)", action.User.ID))
+
+	msg.Text = preprocessSlackText(config, msg.Text)
+	if strings.HasPrefix(msg.Text, "//") {
+		msg.Text = msg.Text[1:]
+	}
+	if len(edit.files) > 0 {
+		msg.Text = processAttachments(config, msg.ChannelID, msg.EventTS, msg.Text, edit.files, msg.WorkDir)
+	}
+	removeReaction(config, msg.ChannelID, msg.EventTS, "white_check_mark")
+	addReaction(config, msg.ChannelID, msg.EventTS, "eyes")
+	submitClaudeMessage(msg, config)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultOutputFilters drop noise lines from tool and !c output when
+// output_filters is not set
+var defaultOutputFilters = []string{
+	`^Shell cwd was reset to `,
+	`^npm (notice|WARN deprecated) `,
+}
+
+// compiledFilters caches the compiled output_filters for the current list
+var compiledFilters struct {
+	sync.Mutex
+	source string
+	res    []*regexp.Regexp
+}
+
+// getOutputFilters returns the active filter patterns
+func getOutputFilters(config *Config) []string {
+	if config == nil || config.OutputFilters == nil {
+		return defaultOutputFilters
+	}
+	return *config.OutputFilters
+}
+
+// outputFilterRegexps compiles the active filters; invalid ones are logged and skipped
+func outputFilterRegexps(config *Config) []*regexp.Regexp {
+	patterns := getOutputFilters(config)
+	source := strings.Join(patterns, "\n")
+
+	compiledFilters.Lock()
+	defer compiledFilters.Unlock()
+	if compiledFilters.res != nil && compiledFilters.source == source {
+		return compiledFilters.res
+	}
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			logf("Ignoring invalid output filter %q: %v", p, err)
+			continue
+		}
+		res = append(res, re)
+	}
+	compiledFilters.source, compiledFilters.res = source, res
+	return res
+}
+
+// filterOutputLines removes lines matching any output filter
+func filterOutputLines(config *Config, output string) string {
+	res := outputFilterRegexps(config)
+	if len(res) == 0 {
+		return output
+	}
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		drop := false
+		for _, re := range res {
+			if re.MatchString(line) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// handleFiltersCommand implements !filters [add <regex> | remove <n> | reset]
+func handleFiltersCommand(cfgMgr *ConfigManager, config *Config, args string) string {
+	patterns := append([]string(nil), getOutputFilters(config)...)
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(args), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch cmd {
+	case "":
+		if len(patterns) == 0 {
+			return ":mag: No output filters - tool and `!c` output is posted as is\nUsage: `!filters add <regex>` / `!filters remove <n>` / `!filters reset`"
+		}
+		var lines []string
+		for i, p := range patterns {
+			lines = append(lines, fmt.Sprintf("%d. `%s`", i+1, p))
+		}
+		title := "*Output filters* (lines matching these are dropped from tool and `!c` output)"
+		if config == nil || config.OutputFilters == nil {
+			title += " - defaults"
+		}
+		return title + "\n" + strings.Join(lines, "\n")
+
+	case "add":
+		arg = cleanSlackMarkup(strings.Trim(arg, "`"))
+		if arg == "" {
+			return "Usage: `!filters add <regex>`"
+		}
+		if _, err := regexp.Compile(arg); err != nil {
+			return fmt.Sprintf(":x: Invalid regex: %v", err)
+		}
+		patterns = append(patterns, arg)
+		if err := cfgMgr.SetOutputFilters(patterns); err != nil {
+			return fmt.Sprintf(":x: Failed to save filters: %v", err)
+		}
+		return fmt.Sprintf(":white_check_mark: Added filter %d: `%s`", len(patterns), arg)
+
+	case "remove":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(patterns) {
+			return fmt.Sprintf(":x: No filter `%s` - see `!filters`", arg)
+		}
+		removed := patterns[n-1]
+		patterns = append(patterns[:n-1], patterns[n:]...)
+		if err := cfgMgr.SetOutputFilters(patterns); err != nil {
+			return fmt.Sprintf(":x: Failed to save filters: %v", err)
+		}
+		return fmt.Sprintf(":wastebasket: Removed filter `%s`", removed)
+
+	case "reset":
+		if err := cfgMgr.SetOutputFilters(nil); err != nil {
+			return fmt.Sprintf(":x: Failed to save filters: %v", err)
+		}
+		return ":leftwards_arrow_with_hook: Output filters reset to defaults"
+	}
+	return "Usage: `!filters` / `!filters add <regex>` / `!filters remove <n>` / `!filters reset`"
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const forkChannelUsage = "Usage: `!fork --channel <name> [--shared]` - fork this session into a new channel (in a git worktree, or in the same folder with `--shared`)"
+
+// ForkStore remembers the session a forked channel starts from until its first
+// run creates its own (channel ID -> source Claude session ID)
+type ForkStore struct {
+	mu      sync.Mutex
+	path    string
+	pending map[string]string
+}
+
+var forkStore = &ForkStore{path: getForkStorePath()}
+
+// getForkStorePath returns the path to the pending forks file
+func getForkStorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "forks.json")
+}
+
+func (s *ForkStore) loadLocked() {
+	if s.pending != nil {
+		return
+	}
+	s.pending = make(map[string]string)
+	if data, err := os.ReadFile(s.path); err == nil {
+		json.Unmarshal(data, &s.pending)
+	}
+}
+
+func (s *ForkStore) saveLocked() {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return
+	}
+	data, err := json.Marshal(s.pending)
+	if err != nil {
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, s.path)
+}
+
+// Set records that channelID's first run forks sessionID
+func (s *ForkStore) Set(channelID, sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	s.pending[channelID] = sessionID
+	s.saveLocked()
+}
+
+// Get returns the session channelID should fork from, if any
+func (s *ForkStore) Get(channelID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	sid, ok := s.pending[channelID]
+	return sid, ok
+}
+
+// Clear forgets a channel's pending fork (its run got its own session)
+func (s *ForkStore) Clear(channelID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	if _, ok := s.pending[channelID]; ok {
+		delete(s.pending, channelID)
+		s.saveLocked()
+	}
+}
+
+// parseForkChannelArgs reads "!fork --channel <name> [--shared]"
+func parseForkChannelArgs(arg string) (name string, shared bool, ok bool) {
+	fields := strings.Fields(arg)
+	for i := 0; i < len(fields); i++ {
+		switch {
+		case fields[i] == "--shared":
+			shared = true
+		case fields[i] == "--channel" && i+1 < len(fields) && name == "":
+			name = fields[i+1]
+			i++
+		default:
+			return "", false, false
+		}
+	}
+	if name == "" {
+		return "", false, false
+	}
+	return name, shared, true
+}
+
+// copyFile copies src to dst, creating dst's directory
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// forkSessionToChannel creates session newName in its own channel, starting from
+// the current conversation of channelID. The new session gets a git worktree on
+// branch newName, or with shared a link to the same folder.
+func forkSessionToChannel(cfgMgr *ConfigManager, config *Config, channelID, newName string, shared bool) (string, error) {
+	sessionName := cfgMgr.GetSessionByChannel(channelID)
+	if sessionName == "" {
+		return "", fmt.Errorf("not in a session channel")
+	}
+	sessionID, ok := getClaudeSessionID(channelID)
+	if !ok {
+		return "", fmt.Errorf("no conversation to fork yet - start one first")
+	}
+	if sessionHost(config, channelID) != "" || sessionSandboxed(config, channelID) {
+		return "", fmt.Errorf("forking into a channel only works for sessions running on this machine")
+	}
+	if _, taken := cfgMgr.GetSession(newName); taken {
+		return "", fmt.Errorf("session `%s` already exists", newName)
+	}
+
+	srcDir := sessionWorkDir(config, sessionName)
+	newDir := filepath.Join(filepath.Dir(srcDir), newName) // next to the original
+	if _, err := os.Stat(newDir); err == nil {
+		return "", fmt.Errorf("`%s` already exists", newDir)
+	}
+
+	var where string
+	if shared {
+		if err := os.Symlink(srcDir, newDir); err != nil {
+			return "", fmt.Errorf("link folder: %v", err)
+		}
+		where = fmt.Sprintf("shares `%s` with `%s`", srcDir, sessionName)
+	} else {
+		if _, err := gitOutput(srcDir, "rev-parse", "--git-dir"); err != nil {
+			return "", fmt.Errorf("`%s` is not a git repository - use `--shared` to fork in the same folder", srcDir)
+		}
+		if !validBranchName(newName) {
+			return "", fmt.Errorf("`%s` is not a valid branch name for the worktree", newName)
+		}
+		if _, err := gitOutput(srcDir, "worktree", "add", "-b", newName, newDir); err != nil {
+			return "", err
+		}
+		where = fmt.Sprintf("works in `%s` (git worktree on branch `%s`)", newDir, newName)
+
+		// Claude looks transcripts up by folder: give the fork a copy to resume
+		src := filepath.Join(claudeTranscriptDir(srcDir), sessionID+".jsonl")
+		if err := copyFile(src, filepath.Join(claudeTranscriptDir(newDir), sessionID+".jsonl")); err != nil && !os.IsNotExist(err) {
+			logf("Failed to copy transcript for fork %s: %v", newName, err)
+		}
+	}
+
+	newChannelID, err := createChannel(config, newName)
+	if err != nil {
+		return "", fmt.Errorf("failed to create channel: %v (folder `%s` was kept)", err, newDir)
+	}
+	if other := cfgMgr.GetSessionByChannel(newChannelID); other != "" {
+		return "", fmt.Errorf("channel <#%s> already belongs to session `%s` (folder `%s` was kept)", newChannelID, other, newDir)
+	}
+	if err := cfgMgr.SetSession(newName, newChannelID); err != nil {
+		return "", err
+	}
+	forkStore.Set(newChannelID, sessionID)
+
+	logf("Session forked: %s -> %s (session %s, shared: %v)", sessionName, newName, shortID(sessionID), shared)
+	sendMessage(config, newChannelID, fmt.Sprintf(":twisted_rightwards_arrows: *Forked from <#%s>* (`%s`) - this session %s and starts with its full conversation. Messages here don't affect the original.",
+		channelID, sessionName, where))
+	go provisionSessionChannel(config, newChannelID, newDir, "", false)
+	go PinGitHubRepoIfExists(config, newChannelID, newDir)
+	return fmt.Sprintf(":twisted_rightwards_arrows: Forked into <#%s>", newChannelID), nil
+}
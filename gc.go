@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultGCUploadDays is how long downloaded Slack uploads are kept (gc_upload_days)
+	defaultGCUploadDays = 14
+	// gcHeartbeatAge is when a "Working..." message of a run that never finished is leftover
+	gcHeartbeatAge = 12 * time.Hour
+)
+
+// gcUploadDirs are the session subfolders Slack uploads are downloaded to
+var gcUploadDirs = []string{"uploads", ".slack-uploads"}
+
+const gcUsage = "Usage: claude-code-slack-anywhere gc [--yes]"
+
+// gcFinding is a piece of orphaned state and how to clean it up. Risky
+// findings are only cleaned when asked: the background collector logs them.
+type gcFinding struct {
+	What  string
+	Risky bool
+	clean func() error
+}
+
+// findGarbage collects orphaned state: sessions whose channel is archived or
+// whose folder is gone, Claude session IDs of archived channels, old downloaded
+// uploads and leftover heartbeat messages
+func findGarbage(cfgMgr *ConfigManager, now time.Time) []gcFinding {
+	config := cfgMgr.Get()
+	sessions := cfgMgr.GetAllSessions()
+	names := make([]string, 0, len(sessions))
+	for name := range sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings []gcFinding
+	mapped := make(map[string]bool, len(sessions))
+	for _, name := range names {
+		name, channelID := name, sessions[name]
+		mapped[channelID] = true
+		purge := func() error {
+			resetClaudeSession(channelID)
+			summaryStore.Remove(channelID)
+			return cfgMgr.PurgeSession(name)
+		}
+		// Only Slack is asked: other backends' IDs would read as deleted
+		if onSlack(channelID) && isChannelArchived(config, channelID) {
+			findings = append(findings, gcFinding{
+				What:  fmt.Sprintf("session `%s`: channel %s is archived or deleted", name, channelID),
+				clean: purge,
+			})
+			continue
+		}
+		if config.SessionHosts[name] == "" {
+			dir := sessionWorkDir(config, name)
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				findings = append(findings, gcFinding{
+					What:  fmt.Sprintf("session `%s`: folder %s no longer exists (channel %s stays)", name, dir, channelID),
+					Risky: true,
+					clean: purge,
+				})
+				continue
+			}
+			findings = append(findings, findOldUploads(dir, uploadRetention(config), now)...)
+		}
+	}
+
+	var orphanIDs []string
+	claudeSessionIDs.Range(func(key, _ interface{}) bool {
+		if cid := key.(string); !mapped[cid] {
+			orphanIDs = append(orphanIDs, cid)
+		}
+		return true
+	})
+	sort.Strings(orphanIDs)
+	for _, cid := range orphanIDs {
+		if !onSlack(cid) || !isChannelArchived(config, cid) {
+			continue
+		}
+		cid := cid
+		findings = append(findings, gcFinding{
+			What:  fmt.Sprintf("Claude session ID of archived channel %s", cid),
+			clean: func() error { resetClaudeSession(cid); return nil },
+		})
+	}
+
+	for _, run := range threadRegistry.Stale(now.Add(-gcHeartbeatAge)) {
+		if _, running := activeProcesses.Load(run.ChannelID); running {
+			continue
+		}
+		run := run
+		findings = append(findings, gcFinding{
+			What: fmt.Sprintf("leftover \"Working...\" message in channel %s (run started %s)", run.ChannelID, run.StartedAt.Local().Format("Jan 2 15:04")),
+			clean: func() error {
+				err := deleteMessage(config, run.ChannelID, run.HeartbeatTS)
+				threadRegistry.Finish(run.RunID)
+				return err
+			},
+		})
+	}
+	return findings
+}
+
+// uploadRetention returns how long downloaded uploads are kept
+func uploadRetention(config *Config) time.Duration {
+	days := config.GCUploadDays
+	if days <= 0 {
+		days = defaultGCUploadDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// findOldUploads returns one finding per upload folder of workDir holding
+// files older than maxAge
+func findOldUploads(workDir string, maxAge time.Duration, now time.Time) []gcFinding {
+	var findings []gcFinding
+	for _, sub := range gcUploadDirs {
+		dir := filepath.Join(workDir, sub)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		var old []string
+		var size int64
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || !info.Mode().IsRegular() || now.Sub(info.ModTime()) < maxAge {
+				continue
+			}
+			old = append(old, filepath.Join(dir, e.Name()))
+			size += info.Size()
+		}
+		if len(old) == 0 {
+			continue
+		}
+		findings = append(findings, gcFinding{
+			What: fmt.Sprintf("%d upload(s) older than %d days in %s (%s)", len(old), int(maxAge.Hours()/24), dir, formatBytes(size)),
+			clean: func() error {
+				for _, path := range old {
+					if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+	return findings
+}
+
+// formatBytes renders a size for humans
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// runGC is the gc command: it lists orphaned state and cleans it after
+// asking for each item, or all of it with --yes
+func runGC(args []string) error {
+	yes := false
+	for _, arg := range args {
+		if arg != "--yes" && arg != "-y" {
+			return fmt.Errorf("%s", gcUsage)
+		}
+		yes = true
+	}
+	cfgMgr := NewConfigManager("")
+	if err := cfgMgr.Load(); err != nil {
+		return fmt.Errorf("not configured. Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+	}
+	cfgMgr.SetOverrides(envConfigOverrides().apply)
+	loadSessionsFromDisk()
+
+	findings := findGarbage(cfgMgr, time.Now())
+	if len(findings) == 0 {
+		fmt.Println("Nothing to clean up")
+		return nil
+	}
+	fmt.Printf("Found %d item(s) to clean up:\n", len(findings))
+	reader := bufio.NewReader(os.Stdin)
+	cleaned, failed := 0, 0
+	for _, f := range findings {
+		fmt.Printf("• %s\n", f.What)
+		if !yes {
+			fmt.Print("  Clean up? [y/N/a(ll)] ")
+			answer, _ := reader.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "a", "all":
+				yes = true
+			case "y", "yes":
+			default:
+				continue
+			}
+		}
+		if err := f.clean(); err != nil {
+			fmt.Printf("  Failed: %v\n", err)
+			failed++
+			continue
+		}
+		cleaned++
+	}
+	fmt.Printf("Cleaned up %d item(s)", cleaned)
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println()
+	return nil
+}
+
+// startGarbageCollector cleans orphaned state every gc_interval_hours (risky
+// findings are only logged) until stop is closed
+func startGarbageCollector(cfgMgr *ConfigManager, stop <-chan struct{}) {
+	go func() {
+		for {
+			// Disabled: look again later in case the config changes
+			interval, enabled := 24*time.Hour, true
+			if config := cfgMgr.Get(); config != nil && config.GCIntervalHours > 0 {
+				interval = time.Duration(config.GCIntervalHours) * time.Hour
+			} else if config != nil && config.GCIntervalHours < 0 {
+				interval, enabled = time.Hour, false
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(interval):
+				if enabled {
+					collectGarbage(cfgMgr)
+				}
+			}
+		}
+	}()
+}
+
+// collectGarbage runs one background collection
+func collectGarbage(cfgMgr *ConfigManager) {
+	cleaned := 0
+	for _, f := range findGarbage(cfgMgr, time.Now()) {
+		if f.Risky {
+			logf("GC: left for the gc command: %s", f.What)
+			continue
+		}
+		if err := f.clean(); err != nil {
+			logf("GC: failed to clean %s: %v", f.What, err)
+			continue
+		}
+		cleaned++
+	}
+	if cleaned > 0 {
+		logf("GC: cleaned up %d item(s)", cleaned)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// githubBodyMax caps the issue/PR description carried into a prompt
+const githubBodyMax = 4000
+
+// pendingGitHubEvents holds the prompt behind each "Ask Claude to handle"
+// button (delivery ID -> *QueuedMessage without Slack timestamps)
+var pendingGitHubEvents sync.Map
+
+// githubEvent is the part of an issues / pull_request webhook payload we use
+type githubEvent struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+	Issue       *githubItem `json:"issue"`
+	PullRequest *githubItem `json:"pull_request"`
+	// review_requested: a user or a team
+	RequestedReviewer *struct {
+		Login string `json:"login"`
+	} `json:"requested_reviewer"`
+	RequestedTeam *struct {
+		Name string `json:"name"`
+	} `json:"requested_team"`
+}
+
+type githubItem struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	User    struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head *struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base *struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// verifyGitHubSignature checks X-Hub-Signature-256 ("sha256=<hex hmac>") against the secret
+func verifyGitHubSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok || secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal([]byte(sig), []byte(hex.EncodeToString(mac.Sum(nil))))
+}
+
+// githubRepoFromURL returns "owner/repo" from a https://github.com/owner/repo URL
+func githubRepoFromURL(url string) string {
+	_, path, ok := strings.Cut(url, "github.com/")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+}
+
+// findSessionForRepo returns the local session whose folder's origin is the GitHub repo
+func findSessionForRepo(config *Config, fullName string) (string, string) {
+	for name, channelID := range config.Sessions {
+		if config.SessionHosts[name] != "" {
+			continue // folder lives on an agent
+		}
+		if strings.EqualFold(githubRepoFromURL(getGitHubURL(sessionWorkDir(config, name))), fullName) {
+			return name, channelID
+		}
+	}
+	return "", ""
+}
+
+// formatGitHubEvent returns the Slack notice and the prompt for a supported
+// event (issue opened, PR review requested); ok is false for anything else
+func formatGitHubEvent(kind string, ev githubEvent) (notice, prompt string, ok bool) {
+	repo := ev.Repository.FullName
+	switch {
+	case kind == "issues" && ev.Action == "opened" && ev.Issue != nil:
+		item := ev.Issue
+		notice = fmt.Sprintf(":memo: *New issue* <%s|#%d %s> in `%s` by %s", item.HTMLURL, item.Number, item.Title, repo, item.User.Login)
+		prompt = fmt.Sprintf("GitHub issue #%d was opened in %s by %s: %s\n%s\n\n%s\n\nInvestigate this issue in the codebase and handle it: fix it if it's a bug, or explain what would be needed.",
+			item.Number, repo, item.User.Login, item.Title, item.HTMLURL, truncateGitHubBody(item.Body))
+	case kind == "pull_request" && ev.Action == "review_requested" && ev.PullRequest != nil:
+		item := ev.PullRequest
+		reviewer := "someone"
+		if ev.RequestedReviewer != nil {
+			reviewer = ev.RequestedReviewer.Login
+		} else if ev.RequestedTeam != nil {
+			reviewer = "team " + ev.RequestedTeam.Name
+		}
+		branches := ""
+		if item.Head != nil && item.Base != nil {
+			branches = fmt.Sprintf(" (`%s` → `%s`)", item.Head.Ref, item.Base.Ref)
+		}
+		notice = fmt.Sprintf(":eyes: *Review requested* from %s on <%s|#%d %s>%s in `%s`", reviewer, item.HTMLURL, item.Number, item.Title, branches, repo)
+		prompt = fmt.Sprintf("A review of pull request #%d in %s was requested: %s%s\n%s\n\n%s\n\nReview this pull request: look at its diff against the base branch (e.g. `gh pr diff %d`) and summarize problems, risks and suggestions. Don't push changes.",
+			item.Number, repo, item.Title, branches, item.HTMLURL, truncateGitHubBody(item.Body), item.Number)
+	default:
+		return "", "", false
+	}
+	return notice, prompt, true
+}
+
+// truncateGitHubBody caps a description for the prompt
+func truncateGitHubBody(body string) string {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "(no description)"
+	}
+	if r := []rune(body); len(r) > githubBodyMax {
+		return string(r[:githubBodyMax]) + "\n[...]"
+	}
+	return body
+}
+
+// handleGitHubWebhook verifies and posts one webhook delivery
+func handleGitHubWebhook(cfgMgr *ConfigManager, w http.ResponseWriter, r *http.Request) {
+	config := cfgMgr.Get()
+	if r.Method != http.MethodPost || config == nil {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+	if err != nil {
+		http.Error(w, "read error", http.StatusBadRequest)
+		return
+	}
+	if !verifyGitHubSignature(config.GitHubWebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		logf("GitHub webhook: rejected delivery with a bad signature")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	kind := r.Header.Get("X-GitHub-Event")
+	if kind == "ping" {
+		fmt.Fprintln(w, "pong")
+		return
+	}
+	var ev githubEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	notice, prompt, ok := formatGitHubEvent(kind, ev)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent) // not an event we post
+		return
+	}
+	sessionName, channelID := findSessionForRepo(config, ev.Repository.FullName)
+	if channelID == "" {
+		logf("GitHub webhook: no session for %s (%s %s)", ev.Repository.FullName, kind, ev.Action)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	delivery := r.Header.Get("X-GitHub-Delivery")
+	if delivery == "" {
+		delivery = fmt.Sprintf("%s-%s-%d", kind, ev.Action, len(body))
+	}
+	pendingGitHubEvents.Store(delivery, &QueuedMessage{
+		Text:      prompt,
+		ChannelID: channelID,
+		WorkDir:   sessionWorkDir(config, sessionName),
+	})
+	buttons := []Element{{
+		Type:     "button",
+		Text:     &TextObject{Type: "plain_text", Text: "Ask Claude to handle"},
+		ActionID: "gh_handle",
+		Value:    delivery,
+		Style:    "primary",
+	}}
+	if err := sendMessageWithButtons(config, channelID, notice, buttons, "gh_"+delivery); err != nil {
+		logf("GitHub webhook: failed to post to %s: %v", sessionName, err)
+		pendingGitHubEvents.Delete(delivery)
+		http.Error(w, "slack error", http.StatusBadGateway)
+		return
+	}
+	logf("GitHub webhook: %s %s for %s posted to %s", kind, ev.Action, ev.Repository.FullName, sessionName)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleGitHubAction turns a posted event into a prompt, answered in its thread
+func handleGitHubAction(config *Config, action BlockActionPayload, act BlockAction) {
+	pending, ok := pendingGitHubEvents.LoadAndDelete(act.Value)
+	if !ok {
+		updateMessage(config, action.Channel.ID, action.Message.TS, action.Message.Text+"\n\n:information_source: This event is no longer available (already handled, or the listener restarted)")
+		return
+	}
+	msg := *pending.(*QueuedMessage)
+	msg.ThreadTS = action.Message.TS
+	msg.EventTS = action.Message.TS
+	msg.UserID = action.User.ID
+
+	updateMessage(config, action.Channel.ID, action.Message.TS, action.Message.Text+fmt.Sprintf("\n\n:robot_face: Handed to Claude by <@%s> - see the thread", action.User.ID))
+	addReaction(config, msg.ChannelID, msg.EventTS, "eyes")
+	submitClaudeMessage(&msg, config)
+}
+
+// serveGitHubWebhooks accepts GitHub webhooks on github_webhook_listen until ctx is cancelled
+func serveGitHubWebhooks(ctx context.Context, cfgMgr *ConfigManager) {
+	config := cfgMgr.Get()
+	if config.GitHubWebhookListen == "" {
+		return
+	}
+	if config.GitHubWebhookSecret == "" {
+		logf("github_webhook_listen is set but github_webhook_secret is empty - not accepting webhooks")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/github", func(w http.ResponseWriter, r *http.Request) { handleGitHubWebhook(cfgMgr, w, r) })
+	server := &http.Server{Addr: config.GitHubWebhookListen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logf("Accepting GitHub webhooks on %s/github", config.GitHubWebhookListen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logf("GitHub webhook server: %v", err)
+	}
+}
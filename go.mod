@@ -2,4 +2,9 @@ module github.com/sderosiaux/claude-code-slack-anywhere
 
 go 1.21
 
-require golang.org/x/net v0.34.0
+require (
+	github.com/creack/pty v1.1.13
+	github.com/fsnotify/fsnotify v1.7.0
+	golang.org/x/net v0.34.0
+	golang.org/x/sys v0.29.0
+)
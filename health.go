@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// crashNoticeMinDelay is the wait after a crash notice before the next
+	// one; it doubles with every notice up to crashNoticeMaxDelay
+	crashNoticeMinDelay = time.Minute
+	crashNoticeMaxDelay = time.Hour
+	// crashQuietPeriod resets the backoff of a channel without crashes this long
+	crashQuietPeriod = time.Hour
+	// crashOutputLines is how much of a crashed process's output is shown
+	crashOutputLines = 30
+	// healthRestartPrompt is sent by the Restart button: the conversation resumes
+	healthRestartPrompt = "continue"
+)
+
+// crashState is the notification backoff of one channel
+type crashState struct {
+	delay      time.Duration // wait after the next notice
+	next       time.Time     // no notice before this
+	last       time.Time     // last crash
+	suppressed int           // crashes not reported since the last notice
+}
+
+// crashBackoff spaces out crash notices so a flapping session doesn't spam its channel
+type crashBackoff struct {
+	mu       sync.Mutex
+	channels map[string]*crashState
+}
+
+var crashNotices = &crashBackoff{channels: make(map[string]*crashState)}
+
+// Allow records a crash and reports whether to post about it, with the number
+// of crashes that weren't reported since the last notice
+func (b *crashBackoff) Allow(channelID string, now time.Time) (bool, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.channels[channelID]
+	if st == nil || now.Sub(st.last) > crashQuietPeriod {
+		st = &crashState{delay: crashNoticeMinDelay}
+		b.channels[channelID] = st
+	}
+	st.last = now
+	if now.Before(st.next) {
+		st.suppressed++
+		return false, 0
+	}
+	suppressed := st.suppressed
+	st.suppressed = 0
+	st.next = now.Add(st.delay)
+	st.delay = min(st.delay*2, crashNoticeMaxDelay)
+	return true, suppressed
+}
+
+// reportPersistentCrash tells a channel its Claude process died, with the end
+// of its output and a Restart button. Sessions with auto_restart get a new
+// process right away instead; while they flap (notices held back by the
+// backoff) the next message restarts them.
+func reportPersistentCrash(config *Config, p *persistentClaude, now time.Time) {
+	status := "exited"
+	if err := p.Wait(); err != nil {
+		status = err.Error()
+	}
+	logf("Persistent Claude for channel %s died unexpectedly (%s)", p.channelID, status)
+	ok, suppressed := crashNotices.Allow(p.channelID, now)
+	if !ok || config == nil {
+		return
+	}
+
+	go notifyEvent(config, pushSessionDied, p.channelID, fmt.Sprintf("Claude's process stopped unexpectedly (%s)", status))
+
+	text := fmt.Sprintf(":skull: Claude's process for this channel stopped unexpectedly (%s).", status)
+	if suppressed > 0 {
+		text += fmt.Sprintf(" It crashed %d more time(s) since the last notice.", suppressed)
+	}
+	if output := strings.TrimSpace(sanitizeTerminalOutput(p.stderr.String())); output != "" {
+		text += "\nLast output:\n```\n" + lastLines(output, crashOutputLines) + "\n```"
+	}
+	if config.AutoRestart[getSessionByChannel(config, p.channelID)] {
+		_, err := persistentPool.Restart(p)
+		if err == nil {
+			sendMessage(config, p.channelID, text+"\n:recycle: Restarted automatically (`auto_restart`), resuming the conversation.")
+			return
+		}
+		logf("Auto-restart failed for channel %s: %v", p.channelID, err)
+		text += fmt.Sprintf("\nAutomatic restart failed: %v", err)
+	}
+	text += "\nThe conversation is kept: *Restart* resumes it, or just send a message."
+	buttons := []Element{
+		{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Restart"},
+			ActionID: "health_restart",
+			Value:    p.channelID,
+			Style:    "primary",
+		},
+	}
+	if err := sendMessageWithButtons(config, p.channelID, text, buttons, "health_"+p.channelID); err != nil {
+		logf("Failed to post crash notice: %v", err)
+	}
+}
+
+// handleHealthRestartAction resumes a crashed session's conversation
+func handleHealthRestartAction(cfgMgr *ConfigManager, config *Config, action BlockActionPayload, act BlockAction) {
+	channelID := act.Value
+	sessionName := cfgMgr.GetSessionByChannel(channelID)
+	if sessionName == "" {
+		updateMessage(config, action.Channel.ID, action.Message.TS, action.Message.Text+"\n\n:information_source: This channel no longer has a session")
+		return
+	}
+	updateMessage(config, action.Channel.ID, action.Message.TS, fmt.Sprintf(":arrows_counterclockwise: Restarted by <@%s>", action.User.ID))
+	runExternalPrompt(config, sessionName, channelID, healthRestartPrompt, action.Message.TS)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	historyDefault   = 5
+	historyMax       = 20
+	historyPromptMax = 500  // runes of each prompt shown
+	historyReplyMax  = 1500 // runes of each answer shown
+)
+
+// transcriptExchange is one user prompt and Claude's text answer to it
+type transcriptExchange struct {
+	At     time.Time
+	Prompt string
+	Reply  string
+	Tools  int // tool calls made while answering
+}
+
+// transcriptLine is the part of a Claude transcript entry we read
+type transcriptLine struct {
+	Type        string    `json:"type"`
+	IsMeta      bool      `json:"isMeta"`
+	IsSidechain bool      `json:"isSidechain"`
+	Timestamp   time.Time `json:"timestamp"`
+	Message     struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// transcriptBlocks decodes message content, which is a string or a list of blocks
+func transcriptBlocks(raw json.RawMessage) []ClaudeContentItem {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []ClaudeContentItem{{Type: "text", Text: text}}
+	}
+	var blocks []ClaudeContentItem
+	json.Unmarshal(raw, &blocks)
+	return blocks
+}
+
+// readTranscriptExchanges reads a transcript file into exchanges, oldest first.
+// Tool calls are counted, tool results and Claude's internal messages skipped.
+func readTranscriptExchanges(path string) ([]transcriptExchange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var exchanges []transcriptExchange
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 32*1024*1024) // tool results can be huge
+	for scanner.Scan() {
+		var line transcriptLine
+		if json.Unmarshal(scanner.Bytes(), &line) != nil || line.IsMeta || line.IsSidechain {
+			continue
+		}
+		blocks := transcriptBlocks(line.Message.Content)
+		switch line.Type {
+		case "user":
+			var parts []string
+			for _, b := range blocks {
+				if b.Type == "text" && b.Text != "" {
+					parts = append(parts, b.Text)
+				}
+			}
+			prompt := strings.TrimSpace(strings.TrimPrefix(strings.Join(parts, "\n"), slackUserPrefix))
+			// Tool results, slash command wrappers and interruption notes aren't prompts
+			if prompt == "" || strings.HasPrefix(prompt, "<") || strings.HasPrefix(prompt, "[Request interrupted") {
+				continue
+			}
+			exchanges = append(exchanges, transcriptExchange{At: line.Timestamp, Prompt: prompt})
+		case "assistant":
+			if len(exchanges) == 0 {
+				continue
+			}
+			ex := &exchanges[len(exchanges)-1]
+			for _, b := range blocks {
+				switch b.Type {
+				case "text":
+					if t := strings.TrimSpace(b.Text); t != "" {
+						if ex.Reply != "" {
+							ex.Reply += "\n\n"
+						}
+						ex.Reply += t
+					}
+				case "tool_use":
+					ex.Tools++
+				}
+			}
+		}
+	}
+	return exchanges, scanner.Err()
+}
+
+// truncateRunes shortens s to max runes
+func truncateRunes(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return strings.TrimSpace(string(r[:max])) + " [...]"
+	}
+	return s
+}
+
+// formatHistory renders the last n exchanges for Slack
+func formatHistory(sessionName string, exchanges []transcriptExchange, n int) string {
+	if len(exchanges) == 0 {
+		return fmt.Sprintf(":scroll: No exchanges in the transcript of `%s` yet", sessionName)
+	}
+	if len(exchanges) > n {
+		exchanges = exchanges[len(exchanges)-n:]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ":scroll: *Last %d exchange(s) of `%s`*", len(exchanges), sessionName)
+	for _, ex := range exchanges {
+		when := ""
+		if !ex.At.IsZero() {
+			when = " " + ex.At.Local().Format("Jan 2 15:04")
+		}
+		prompt := truncateRunes(ex.Prompt, historyPromptMax)
+		fmt.Fprintf(&b, "\n\n*You*%s\n> %s", when, strings.ReplaceAll(prompt, "\n", "\n> "))
+		reply := ex.Reply
+		if reply == "" {
+			reply = "_(no text answer)_"
+		}
+		b.WriteString("\n*Claude*")
+		if ex.Tools > 0 {
+			fmt.Fprintf(&b, " _(%d tool call(s))_", ex.Tools)
+		}
+		b.WriteString("\n" + convertBold(truncateRunes(reply, historyReplyMax)))
+	}
+	return b.String()
+}
+
+// handleHistoryCommand handles "!history [n]"
+func handleHistoryCommand(config *Config, channelID, args string) string {
+	n := historyDefault
+	if arg := strings.TrimSpace(args); arg != "" {
+		v, err := strconv.Atoi(arg)
+		if err != nil || v < 1 {
+			return "Usage: `!history [n]` - last n exchanges of this session (default 5, max 20)"
+		}
+		n = min(v, historyMax)
+	}
+
+	sessionName := getSessionByChannel(config, channelID)
+	if sessionName == "" {
+		return ":x: Not in a session channel. Use `!history` in a session channel."
+	}
+	if host := sessionHost(config, channelID); host != "" {
+		return fmt.Sprintf(":x: This session runs on agent `%s`: its transcript isn't stored here", host)
+	}
+	if config.SandboxSessions[sessionName] {
+		return ":x: Sandboxed sessions keep their transcript inside the container"
+	}
+	sessionID, ok := getClaudeSessionID(channelID)
+	if !ok {
+		// A forked channel shows its source until its first run
+		if sessionID, ok = forkStore.Get(channelID); !ok {
+			return ":scroll: No conversation yet in this session"
+		}
+	}
+
+	workDir := sessionWorkDir(config, sessionName)
+	exchanges, err := readTranscriptExchanges(filepath.Join(claudeTranscriptDir(workDir), sessionID+".jsonl"))
+	if os.IsNotExist(err) {
+		return fmt.Sprintf(":x: Transcript of session `%s` not found (it may have been cleaned up by Claude)", shortID(sessionID))
+	}
+	if err != nil {
+		return fmt.Sprintf(":x: Could not read the transcript: %v", err)
+	}
+	return formatHistory(sessionName, exchanges, n)
+}
@@ -29,17 +29,7 @@ func handleHook() error {
 
 	fmt.Fprintf(os.Stderr, "hook: cwd=%s transcript=%s\n", hookData.Cwd, hookData.TranscriptPath)
 
-	var sessionName string
-	var channelID string
-	baseDir := getProjectsDir(config)
-	for name, cid := range config.Sessions {
-		expectedPath := filepath.Join(baseDir, name)
-		if hookData.Cwd == expectedPath || strings.HasSuffix(hookData.Cwd, "/"+name) {
-			sessionName = name
-			channelID = cid
-			break
-		}
-	}
+	sessionName, channelID := findSessionForCwd(config, hookData.Cwd)
 	if sessionName == "" || channelID == "" {
 		fmt.Fprintf(os.Stderr, "hook: no session found for cwd=%s\n", hookData.Cwd)
 		return nil
@@ -54,8 +44,27 @@ func handleHook() error {
 		}
 	}
 
+	// The listener's PTY session: record its conversation so a restarted
+	// session resumes it, and !status, !history and !fork see it
+	if ptyChannel := os.Getenv(ptyChannelEnv); ptyChannel != "" && hookData.SessionID != "" {
+		if err := writeInboxMessage(getInboxDir(), InboxMessage{
+			Action:    inboxSession,
+			Session:   sessionName,
+			ChannelID: ptyChannel,
+			Text:      hookData.SessionID,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "hook: recording the session ID failed: %v\n", err)
+		}
+	}
+
+	text := fmt.Sprintf(":white_check_mark: *%s*\n\n%s", sessionName, lastMessage)
+	if run, ok := threadRegistry.Lookup(hookData.SessionID); ok {
+		fmt.Fprintf(os.Stderr, "hook: sending message to run thread %s\n", run.ThreadTS)
+		return postToRunThread(config, run, text)
+	}
 	fmt.Fprintf(os.Stderr, "hook: sending message to slack\n")
-	_, err = sendMessage(config, channelID, fmt.Sprintf(":white_check_mark: *%s*\n\n%s", sessionName, lastMessage))
+	_, err = sendMessage(config, channelID, text)
 	return err
 }
 
@@ -98,20 +107,7 @@ func handlePermissionHook() error {
 		return nil
 	}
 
-	var sessionName string
-	var channelID string
-	baseDir := getProjectsDir(config)
-	for name, cid := range config.Sessions {
-		if name == "" {
-			continue
-		}
-		expectedPath := filepath.Join(baseDir, name)
-		if hookData.Cwd == expectedPath || strings.HasSuffix(hookData.Cwd, "/"+name) {
-			sessionName = name
-			channelID = cid
-			break
-		}
-	}
+	sessionName, channelID := findSessionForCwd(config, hookData.Cwd)
 
 	if sessionName == "" || channelID == "" {
 		return nil
@@ -119,42 +115,15 @@ func handlePermissionHook() error {
 
 	fmt.Fprintf(os.Stderr, "hook-permission: tool=%s questions=%d\n", hookData.ToolName, len(hookData.ToolInput.Questions))
 	if hookData.ToolName == "AskUserQuestion" && len(hookData.ToolInput.Questions) > 0 {
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					logf("PANIC in AskUserQuestion handler: %v", r)
-				}
-			}()
-			for qIdx, q := range hookData.ToolInput.Questions {
-				if q.Question == "" {
-					continue
-				}
-				msg := fmt.Sprintf(":question: *%s*\n\n%s", q.Header, q.Question)
-
-				var buttons []Element
-				for i, opt := range q.Options {
-					if opt.Label == "" {
-						continue
-					}
-					// Value format: session:questionIndex:optionIndex
-					value := fmt.Sprintf("%s:%d:%d", sessionName, qIdx, i)
-					buttons = append(buttons, Element{
-						Type:     "button",
-						Text:     &TextObject{Type: "plain_text", Text: opt.Label},
-						ActionID: fmt.Sprintf("option_%d_%d", qIdx, i),
-						Value:    value,
-					})
-				}
-
-				if len(buttons) > 0 {
-					blockID := fmt.Sprintf("question_%s_%d", sessionName, qIdx)
-					sendMessageWithButtons(config, channelID, msg, buttons, blockID)
-				}
-			}
-		}()
+		// Synchronous: the set must be posted and recorded before the hook process exits
+		postQuestionSet(config, sessionName, channelID, hookData.SessionID, hookData.ToolInput.Questions)
 		return nil
 	}
 
+	if hookData.ToolName != "" {
+		notifyEvent(config, pushWaiting, channelID, "Permission requested: "+hookData.ToolName)
+	}
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -163,7 +132,11 @@ func handlePermissionHook() error {
 		}()
 		if hookData.ToolName != "" {
 			msg := fmt.Sprintf(":lock: Permission requested: %s", hookData.ToolName)
-			sendMessage(config, channelID, msg)
+			if run, ok := threadRegistry.Lookup(hookData.SessionID); ok {
+				postToRunThread(config, run, msg)
+			} else {
+				sendMessage(config, channelID, msg)
+			}
 		}
 	}()
 
@@ -225,15 +198,7 @@ func handlePromptHook() error {
 		return nil
 	}
 
-	var channelID string
-	baseDir := getProjectsDir(config)
-	for name, cid := range config.Sessions {
-		expectedPath := filepath.Join(baseDir, name)
-		if hookData.Cwd == expectedPath || strings.HasSuffix(hookData.Cwd, "/"+name) {
-			channelID = cid
-			break
-		}
-	}
+	_, channelID := findSessionForCwd(config, hookData.Cwd)
 
 	if channelID == "" {
 		fmt.Fprintf(os.Stderr, "hook-prompt: no channel found for cwd=%s\n", hookData.Cwd)
@@ -273,15 +238,7 @@ func handleOutputHook() error {
 		return nil
 	}
 
-	var channelID string
-	baseDir := getProjectsDir(config)
-	for name, cid := range config.Sessions {
-		expectedPath := filepath.Join(baseDir, name)
-		if hookData.Cwd == expectedPath || strings.HasSuffix(hookData.Cwd, "/"+name) {
-			channelID = cid
-			break
-		}
-	}
+	_, channelID := findSessionForCwd(config, hookData.Cwd)
 
 	if channelID == "" {
 		return nil
@@ -315,49 +272,13 @@ func handleQuestionHook() error {
 		return nil
 	}
 
-	var sessionName string
-	var channelID string
-	baseDir := getProjectsDir(config)
-	for name, cid := range config.Sessions {
-		expectedPath := filepath.Join(baseDir, name)
-		if hookData.Cwd == expectedPath || strings.HasSuffix(hookData.Cwd, "/"+name) {
-			sessionName = name
-			channelID = cid
-			break
-		}
-	}
+	sessionName, channelID := findSessionForCwd(config, hookData.Cwd)
 
 	if sessionName == "" || channelID == "" {
 		return nil
 	}
 
-	for qIdx, q := range hookData.ToolInput.Questions {
-		if q.Question == "" {
-			continue
-		}
-		msg := fmt.Sprintf(":question: *%s*\n\n%s", q.Header, q.Question)
-
-		var buttons []Element
-		for i, opt := range q.Options {
-			if opt.Label == "" {
-				continue
-			}
-			value := fmt.Sprintf("%s:%d:%d", sessionName, qIdx, i)
-			buttons = append(buttons, Element{
-				Type:     "button",
-				Text:     &TextObject{Type: "plain_text", Text: opt.Label},
-				ActionID: fmt.Sprintf("option_%d_%d", qIdx, i),
-				Value:    value,
-			})
-		}
-
-		if len(buttons) > 0 {
-			blockID := fmt.Sprintf("question_%s_%d", sessionName, qIdx)
-			sendMessageWithButtons(config, channelID, msg, buttons, blockID)
-		} else {
-			sendMessage(config, channelID, msg)
-		}
-	}
+	postQuestionSet(config, sessionName, channelID, hookData.SessionID, hookData.ToolInput.Questions)
 
 	return nil
 }
@@ -366,7 +287,10 @@ func handleQuestionHook() error {
 func installHook() error {
 	home, _ := os.UserHomeDir()
 	settingsPath := filepath.Join(home, ".claude", "settings.json")
-	hookBinPath := filepath.Join(home, "bin", "claude-code-slack-anywhere")
+	hookBinPath := filepath.Join(home, "bin", "claude-code-slack-anywhere"+exeSuffix)
+	if strings.Contains(hookBinPath, " ") {
+		hookBinPath = `"` + hookBinPath + `"` // C:\Users\First Last
+	}
 
 	data, err := os.ReadFile(settingsPath)
 	if err != nil {
@@ -31,10 +31,21 @@ if launchctl list | grep -q com.ccsa; then
     launchctl unload "$PLIST_DEST" 2>/dev/null || true
 fi
 
+# launchd doesn't read shell profiles: give the service this shell's PATH, with
+# claude's own directory first (nvm) and both Homebrew prefixes (arm64, x86_64)
+SERVICE_PATH="$PATH:/opt/homebrew/bin:/usr/local/bin:/usr/bin:/bin:/usr/sbin:/sbin"
+if CLAUDE_BIN="$(command -v claude)"; then
+    SERVICE_PATH="$(dirname "$CLAUDE_BIN"):$SERVICE_PATH"
+else
+    echo "Warning: claude not found in PATH, the service won't be able to run it"
+fi
+
 # Install plist with paths substituted
 echo "Installing launchd plist..."
 sed -e "s|__BINARY_PATH__|$BINARY_DEST|g" \
     -e "s|__HOME__|$HOME|g" \
+    -e "s|__PATH__|$SERVICE_PATH|g" \
+    -e "s|__NVM_DIR__|${NVM_DIR:-$HOME/.nvm}|g" \
     "$PLIST_TEMPLATE" > "$PLIST_DEST"
 
 # Load service
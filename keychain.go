@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// keychainRefPrefix marks a config value stored in the OS keychain:
+// "bot_token": "keychain:bot_token" reads the secret from the item of that account
+const keychainRefPrefix = "keychain:"
+
+// keychainService is the keychain item service of our secrets
+const keychainService = "claude-code-slack-anywhere"
+
+// secretStore reads and writes secrets in the OS keychain
+type secretStore interface {
+	Get(account string) (string, error)
+	Set(account, secret string) error
+}
+
+// keychain is the macOS Keychain (security) or the Secret Service on Linux
+// (secret-tool, libsecret)
+var keychain secretStore = osKeychain{}
+
+type osKeychain struct{}
+
+func (osKeychain) Get(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", account, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", account)
+	default:
+		return "", fmt.Errorf("no keychain support on %s", runtime.GOOS)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keychain lookup of %q failed: %v %s", account, err, strings.TrimSpace(stderr.String()))
+	}
+	secret := strings.TrimRight(string(out), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("no keychain item for %q", account)
+	}
+	return secret, nil
+}
+
+func (osKeychain) Set(account, secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security -i reads the command from stdin, keeping the secret out of
+		// ps; -U updates an existing item
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(keychainService), securityQuote(account), securityQuote(secret)))
+	case "linux":
+		// secret-tool reads the secret from stdin, keeping it out of ps
+		cmd = exec.Command("secret-tool", "store", "--label", keychainService+" "+account, "service", keychainService, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		return fmt.Errorf("no keychain support on %s", runtime.GOOS)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("keychain store of %q failed: %v %s", account, err, strings.TrimSpace(string(out)))
+	}
+	// security -i reports a failed command on its output, not in its exit status
+	if runtime.GOOS == "darwin" {
+		if stored, err := (osKeychain{}).Get(account); err != nil || stored != secret {
+			return fmt.Errorf("keychain store of %q failed: %s", account, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// secretRef is a config value read from the keychain: its reference
+// ("keychain:<account>") and the secret it resolved to
+type secretRef struct {
+	ref    string
+	secret string
+}
+
+// securityQuote quotes an argument of a `security -i` command line
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// secretFields are the config values that may be keychain references
+func secretFields(c *Config) map[string]*string {
+	return map[string]*string{
+		"bot_token":             &c.BotToken,
+		"app_token":             &c.AppToken,
+		"agent_token":           &c.AgentToken,
+		"api_token":             &c.APIToken,
+		"github_token":          &c.GitHubToken,
+		"github_webhook_secret": &c.GitHubWebhookSecret,
+		"transcribe_api_key":    &c.TranscribeAPIKey,
+	}
+}
+
+// resolveSecretRefs replaces keychain references with their secrets,
+// remembering the references so saving the config writes them back
+func resolveSecretRefs(c *Config) error {
+	for key, p := range secretFields(c) {
+		account, ok := strings.CutPrefix(*p, keychainRefPrefix)
+		if !ok {
+			continue
+		}
+		secret, err := keychain.Get(account)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if c.secretRefs == nil {
+			c.secretRefs = make(map[string]secretRef)
+		}
+		c.secretRefs[key] = secretRef{ref: *p, secret: secret}
+		*p = secret
+	}
+	return nil
+}
+
+// marshalConfig encodes the config for its file, with the file's own values
+// in place of environment and flag overrides, and keychain references in
+// place of the secrets they resolved to. A secret changed since (e.g. by
+// setup) is stored in its keychain item first; a cleared one drops its
+// reference.
+func marshalConfig(c *Config) ([]byte, error) {
+	if c.overridden != nil {
+		stored := *c
+		c.overridden.restore(&stored)
+		c = &stored
+	}
+	if len(c.secretRefs) > 0 {
+		stored := *c
+		fields := secretFields(&stored)
+		for key, r := range c.secretRefs {
+			p := fields[key]
+			if *p == "" {
+				continue
+			}
+			if *p != r.secret {
+				if err := keychain.Set(strings.TrimPrefix(r.ref, keychainRefPrefix), *p); err != nil {
+					return nil, fmt.Errorf("%s: %w", key, err)
+				}
+				c.secretRefs[key] = secretRef{ref: r.ref, secret: *p}
+			}
+			*p = r.ref
+		}
+		c = &stored
+	}
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// migrateSecretsToKeychain moves every plaintext secret of the config to the
+// keychain and returns the keys moved
+func migrateSecretsToKeychain(c *Config) ([]string, error) {
+	refs := make(map[string]secretRef, len(c.secretRefs))
+	for key, r := range c.secretRefs {
+		refs[key] = r
+	}
+	var moved []string
+	for key, p := range secretFields(c) {
+		if _, ok := refs[key]; *p == "" || ok {
+			continue
+		}
+		if err := keychain.Set(key, *p); err != nil {
+			return moved, err
+		}
+		refs[key] = secretRef{ref: keychainRefPrefix + key, secret: *p}
+		moved = append(moved, key)
+	}
+	sort.Strings(moved)
+	c.secretRefs = refs
+	return moved, nil
+}
+
+const keychainUsage = "Usage: claude-code-slack-anywhere keychain migrate"
+
+// runKeychain implements `claude-code-slack-anywhere keychain migrate`
+func runKeychain(args []string) error {
+	if len(args) != 1 || args[0] != "migrate" {
+		return fmt.Errorf("%s", keychainUsage)
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("not configured. Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+	}
+	moved, err := migrateSecretsToKeychain(config)
+	if len(moved) > 0 {
+		// Save what was stored even if a later secret failed
+		if saveErr := saveConfig(config); saveErr != nil {
+			return saveErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(moved) == 0 {
+		fmt.Println("No plaintext secrets left in " + getConfigPath())
+		return nil
+	}
+	fmt.Printf("Moved to the keychain: %s\n%s now holds only references\n", strings.Join(moved, ", "), getConfigPath())
+	return nil
+}
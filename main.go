@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -57,13 +60,18 @@ USE INSTEAD:
 - pm2 start "npm run dev" --name myapp
 - screen -dmS myapp npm run dev
 - tmux new-session -d -s myapp 'npm run dev'
+
+SHARING FILES WITH THE USER:
+- To send a file (image, report, log) to the user's Slack channel, run:
+  claude-code-slack-anywhere share <path> [comment]
 `
 
-// Global config manager, worker pool and message queue
+// Global config manager, worker pool, message queue and batcher
 var (
-	configMgr    *ConfigManager
-	workerPool   *WorkerPool
-	messageQueue *ChannelQueue
+	configMgr      *ConfigManager
+	workerPool     *WorkerPool
+	messageQueue   *ChannelQueue
+	messageBatcher *MessageBatcher
 )
 
 func logf(format string, args ...interface{}) {
@@ -71,139 +79,211 @@ func logf(format string, args ...interface{}) {
 	fmt.Printf("[%s] %s\n", ts, fmt.Sprintf(format, args...))
 }
 
-// toSlackChannelName converts a folder name to a valid Slack channel name
-// Slack channel names: lowercase, no spaces, no dots, max 80 chars
+// slackChannelNameMax is Slack's channel name length limit
+const slackChannelNameMax = 80
+
+// transliterations spells common accented and special letters in ASCII
+var transliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// toSlackChannelName converts a folder name to a valid Slack channel name.
+// Slack channel names: lowercase a-z, 0-9, - and _, max 80 chars. Accented
+// letters are transliterated, anything else (dots, spaces, emoji, symbols)
+// becomes a dash; names with nothing left get a stable hash-based name.
 func toSlackChannelName(name string) string {
-	result := strings.ToLower(name)
-	result = strings.ReplaceAll(result, ".", "-")
-	result = strings.ReplaceAll(result, " ", "-")
-	result = strings.ReplaceAll(result, "_", "-")
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		default:
+			// Underscores too, as before, so existing channels keep matching
+			b.WriteByte('-')
+		}
+	}
+	result := b.String()
 	// Remove consecutive dashes
 	for strings.Contains(result, "--") {
 		result = strings.ReplaceAll(result, "--", "-")
 	}
 	// Trim dashes from start/end
 	result = strings.Trim(result, "-")
-	// Max 80 chars
-	if len(result) > 80 {
-		result = result[:80]
+	if result == "" {
+		sum := sha256.Sum256([]byte(name))
+		return "session-" + hex.EncodeToString(sum[:4])
+	}
+	if len(result) > slackChannelNameMax {
+		result = strings.TrimRight(result[:slackChannelNameMax], "-")
 	}
 	return result
 }
 
-// fromSlackChannelName attempts to find a matching folder name from a Slack channel name
-// Tries multiple variations: as-is, with dots instead of dashes, with spaces
+// fromSlackChannelName finds the folder a Slack channel name was made from: the
+// folder whose toSlackChannelName matches, so any name (dots, spaces, accents)
+// maps back. Falls back to the channel name itself.
 func fromSlackChannelName(channelName string, baseDir string) string {
 	// Try exact match first
 	if _, err := os.Stat(filepath.Join(baseDir, channelName)); err == nil {
 		return channelName
 	}
-	// Try with dots instead of dashes (e.g., "conduktor-txt" -> "conduktor.txt")
-	withDots := strings.ReplaceAll(channelName, "-", ".")
-	if _, err := os.Stat(filepath.Join(baseDir, withDots)); err == nil {
-		return withDots
-	}
-	// Try with spaces instead of dashes
-	withSpaces := strings.ReplaceAll(channelName, "-", " ")
-	if _, err := os.Stat(filepath.Join(baseDir, withSpaces)); err == nil {
-		return withSpaces
-	}
-	// Try with underscores
-	withUnderscores := strings.ReplaceAll(channelName, "-", "_")
-	if _, err := os.Stat(filepath.Join(baseDir, withUnderscores)); err == nil {
-		return withUnderscores
+	if entries, err := os.ReadDir(baseDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && toSlackChannelName(entry.Name()) == channelName {
+				return entry.Name()
+			}
+		}
 	}
 	// Return original if no match
 	return channelName
 }
 
+// newSessionArgs are the parsed "!new" arguments
+type newSessionArgs struct {
+	name     string
+	host     string // --host <agent>
+	sandbox  bool   // --sandbox
+	cloneURL string // --clone <git url>
+	template string // --template <name>
+}
+
+// parseNewSessionArgs splits "!new" arguments into the session name and its
+// flags: --host <agent>, --sandbox, --clone <url> and --template <name>, in
+// any order after the name. With --clone the name defaults to the repository's.
+func parseNewSessionArgs(arg string) newSessionArgs {
+	var args newSessionArgs
+	fields := strings.Fields(arg)
+	var rest []string
+	for i := 0; i < len(fields); i++ {
+		switch {
+		case fields[i] == "--sandbox":
+			args.sandbox = true
+		case fields[i] == "--host" && i+1 < len(fields):
+			args.host = fields[i+1]
+			i++
+		case fields[i] == "--clone" && i+1 < len(fields):
+			args.cloneURL = cleanSlackMarkup(fields[i+1])
+			i++
+		case fields[i] == "--template" && i+1 < len(fields):
+			args.template = fields[i+1]
+			i++
+		default:
+			rest = append(rest, fields[i])
+		}
+	}
+	// Names may contain spaces
+	args.name = strings.Join(rest, " ")
+	if args.name == "" && args.cloneURL != "" {
+		args.name = repoNameFromCloneURL(args.cloneURL)
+	}
+	return args
+}
+
 func getHelpText() string {
 	return "*claudeslack - Commands*\n\n" +
 		":rocket: *Session Management*\n" +
-		"• `!new <name>` - Create new session with channel\n" +
+		"• `!new <name> [--host <agent> | --sandbox] [--clone <git url> | --template <name>]` - Create new session with channel (optionally on an agent, in Docker, cloned from a repo or scaffolded from a template)\n" +
 		"• `!reset` - Reset conversation context (start fresh)\n" +
 		"• `!kill` - Remove and archive current session\n" +
-		"• `!sessions` - List active sessions\n" +
+		"• `!kill --purge` - Also forget its settings, optionally delete its folder\n" +
+		"• `!rename <old> <new> [--move]` - Rename a session and its channel (`--move` renames the folder too)\n" +
+		"• `!sessions` - List active sessions (`--tag <tag>` to filter, `--idle 7d` for unused ones)\n" +
+		"• `!tag [add|rm] <tag>` - Tag this session\n" +
+		"• `!status` - Run, queue, Claude session, usage, workdir and branch of this session\n" +
+		"• `!search <query>` - Find prompts, answers, commits and sessions matching all words, across sessions\n" +
+		"• `!history [n]` - Last n prompts and answers of this session, without tool noise (default 5)\n" +
+		"• `!output [lines]` - End of the terminal of a PTY session (default 40)\n" +
+		"• `!keys <keys>` - Keystrokes for a PTY session (`down enter`, `1`, `esc`, `ctrl-c`)\n" +
+		"• `!esc` - Escape for a PTY session (same as `!keys esc`)\n" +
+		"• `!summarize` - Update the pinned summary (goal, decisions, files, TODOs) of this session\n" +
+		"• `!timeline [days]` - Prompts, runs, commits and costs of this session (default 7 days)\n" +
 		"• `!projects` - List projects in projects folder\n\n" +
 		":computer: *Utilities*\n" +
 		"• `!c <cmd>` - Execute shell command\n" +
+		"• `!review <pr-url> [--submit]` - Review a GitHub pull request (`--submit` posts it on GitHub)\n" +
 		"• `!cancel` - Cancel running task\n" +
-		"• `!verbose` / `!quiet` - Toggle output verbosity\n\n" +
+		"• `!queue` - Show queued messages (cancel them)\n" +
+		"• `!broadcast <sessions|all> <prompt>` - Run a prompt in several sessions\n" +
+		"• `!urgent <prompt>` - Queue ahead of the other messages\n" +
+		"• `!verbose` / `!quiet` - Toggle output verbosity (remembered across restarts)\n" +
+		"• `!autocommit on|off` - Commit the workdir after every successful run\n" +
+		"• `!sysprompt [set <text> | clear]` - Extra system prompt instructions for this channel\n" +
+		"• `!aliases` - List the command aliases from the config\n\n" +
 		":alarm_clock: *Scheduled Tasks*\n" +
 		"• `!at <time> <cmd>` - Schedule a task (e.g., `!at 5m run tests`)\n" +
 		"• `!scheduled` - List scheduled tasks\n" +
-		"• `!unschedule <id>` - Cancel a scheduled task\n\n" +
+		"• `!unschedule <id>` - Cancel a scheduled task\n" +
+		"• `!schedule \"0 9 * * 1-5\" <prompt>` - Recurring prompt (`!schedule list`, `!schedule rm <id>`)\n" +
+		"• `!watch <glob> \"<prompt>\"` - Run a prompt when matching files change (`!watch list`, `!watch stop <id>`)\n\n" +
 		":information_source: *Other*\n" +
-		"• `!ping` - Check if bot is alive\n" +
-		"• `!version` - Show version\n" +
+		commandPluginHelp() +
+		"• `!filters [add <regex> | remove <n> | reset]` - Lines dropped from tool and `!c` output\n" +
+		"• `!config [get <key> | set <key> <value>]` - View or change settings (admins)\n" +
 		"• `!help` - Show this help\n\n" +
 		":speech_balloon: *In a session channel:*\n" +
 		"• Type messages → Claude responds in channel\n" +
 		"• `!task <prompt>` - Start a fresh task in a thread\n" +
 		"• `!fork <prompt>` - Fork session into a thread (keeps context)\n" +
+		"• `!fork --channel <name> [--shared]` - Fork session into a new channel (git worktree, or same folder)\n" +
+		"• `!slash list` - Show custom commands (.claude/commands) as buttons\n" +
+		"• `!branch set <name>` - Run everything in this channel on a git branch\n" +
+		"• `//cmd args` - Run a Claude slash command\n" +
 		"• `!claude_compact` - Summarize conversation (reduce tokens)\n" +
 		"• `!claude_clear` - Clear session and start fresh\n" +
 		"• `!claude_help` - Show Claude-specific commands"
 }
 
-type listenOpts struct {
-	configPath  string
-	projectsDir string
-	botToken    string
-	appToken    string
-	userIDs     []string
-}
-
 // Main listen loop using Socket Mode
-func listen(opts listenOpts) error {
+func listen(opts configOverrides) error {
 	myPid := os.Getpid()
 	logf("Starting v%s (build: %s) PID %d", version, buildTime, myPid)
 
-	cmd := exec.Command("pgrep", "-f", "claude-code-slack-anywhere listen")
-	output, _ := cmd.Output()
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-		if pid, err := strconv.Atoi(line); err == nil && pid != myPid {
-			logf("Killing old instance (PID %d)", pid)
-			syscall.Kill(pid, syscall.SIGTERM)
-		}
+	for _, pid := range otherListenerPIDs(myPid) {
+		logf("Killing old instance (PID %d)", pid)
+		terminateProcess(pid)
+	}
+
+	// Hooks and tools started by Claude read the same config file
+	if opts.configPath != "" {
+		os.Setenv("CCSA_CONFIG", opts.configPath)
 	}
 
 	// Initialize config manager
 	configMgr = NewConfigManager(opts.configPath)
 	if err := configMgr.Load(); err != nil {
-		// If no config file and no CLI tokens, fail
-		if opts.botToken == "" || opts.appToken == "" {
+		// If no config file and no tokens from flags or the environment, fail
+		if !opts.hasTokens() {
 			return fmt.Errorf("not configured. Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
 		}
-		// Create minimal config from CLI args
+		// Create minimal config from flags and environment
 		configMgr.config = &Config{Sessions: make(map[string]string)}
 	}
 
+	// Flag and environment overrides take precedence (also after a config reload)
+	configMgr.SetOverrides(opts.apply)
 	config := configMgr.Get()
 
-	// CLI overrides take precedence
-	if opts.projectsDir != "" {
-		config.ProjectsDir = opts.projectsDir
-	}
-	if opts.botToken != "" {
-		config.BotToken = opts.botToken
-	}
-	if opts.appToken != "" {
-		config.AppToken = opts.appToken
-	}
-	if len(opts.userIDs) > 0 {
-		config.UserIDs = opts.userIDs
-	}
-
 	// Validate mandatory config
-	if config.ProjectsDir == "" {
-		return fmt.Errorf("projects_dir is required: use --projects-dir or set in config file")
+	if getProjectsDir(config) == "" {
+		return fmt.Errorf("projects_dir is required: use --projects-dir, CCSA_PROJECTS_DIR or set in config file")
 	}
-	if config.BotToken == "" {
-		return fmt.Errorf("bot_token is required: use --bot-token or set in config file")
+	// Slack is optional when another chat backend is set up
+	slackEnabled := config.BotToken != "" || config.AppToken != "" || !config.HasOtherChat()
+	if slackEnabled && config.BotToken == "" {
+		return fmt.Errorf("bot_token is required: use --bot-token, CCSA_BOT_TOKEN or set in config file")
 	}
-	if config.AppToken == "" {
-		return fmt.Errorf("app_token is required: use --app-token or set in config file")
+	if slackEnabled && config.AppToken == "" {
+		return fmt.Errorf("app_token is required: use --app-token, CCSA_APP_TOKEN or set in config file")
 	}
 	logf("Bot listening... (user: %s)", config.UserID)
 	logf("Active sessions: %d", len(configMgr.GetAllSessions()))
@@ -219,11 +299,62 @@ func listen(opts listenOpts) error {
 	// Initialize message queue for automatic queuing
 	messageQueue = NewChannelQueue()
 
+	// Coalesce rapid consecutive messages into a single prompt
+	messageBatcher = NewMessageBatcher()
+
 	// Initialize scheduler for !at commands
-	scheduler = NewScheduler(config)
+	scheduler = NewScheduler(configMgr)
+
+	// Pick up edits to the config file (projects_dir, sessions, ...) without a restart
+	if err := configMgr.Watch(ctx.Done()); err != nil {
+		logf("Config hot-reload disabled: %v", err)
+	}
+
+	// Periodically audit goroutines/maps and prune state for dead channels
+	startRuntimeAuditor(configMgr, 10*time.Minute, ctx.Done())
+
+	// Clean up orphaned sessions, uploads and heartbeats (gc_interval_hours)
+	startGarbageCollector(configMgr, ctx.Done())
+	startIdleDigest(configMgr, ctx.Done())
+	startQuietDigest(configMgr, ctx.Done())
+	startUpdateCheck(configMgr, ctx.Done())
+
+	// Accept remote executor agents (agent_listen)
+	go serveAgents(ctx, configMgr)
+
+	// Post GitHub issue/PR events to their sessions (github_webhook_listen)
+	go serveGitHubWebhooks(ctx, configMgr)
+
+	// Let local tooling drive sessions over HTTP (api_listen)
+	go serveAPI(ctx, configMgr)
+
+	// Stop idle persistent Claude processes (persistent_claude)
+	startPersistentReaper(configMgr, ctx.Done())
+
+	// PTY sessions run under the listener: they end with it
+	go func() {
+		<-ctx.Done()
+		ptyPool.StopAll()
+	}()
+
+	// Publish runs and queues for the top command
+	startLiveStateWriter(configMgr, ctx.Done())
+
+	// Run prompts handed over by the send command
+	if err := startInboxWatcher(configMgr, ctx.Done()); err != nil {
+		logf("send inbox disabled: %v", err)
+	}
+
+	// Deliver messages buffered while Slack was unreachable (also from hooks)
+	startOutboxReplayer(configMgr, ctx.Done())
+
+	timeline.Record(TimelineEvent{Kind: timelineRestart})
+
+	// Close out runs a previous listener left mid-flight (stale heartbeats)
+	go repairInterruptedRuns(config)
 
-	// WaitGroup for background goroutines
-	var wg sync.WaitGroup
+	// Run messages the previous listener saved on shutdown
+	go restorePendingMessages(config)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -231,58 +362,47 @@ func listen(opts listenOpts) error {
 	go func() {
 		sig := <-sigChan
 		logf("Received signal: %v - Shutting down gracefully...", sig)
-		cancel() // Cancel context to stop all goroutines
+		cancel() // Stop accepting events; gracefulShutdown drains the rest
 
-		// Wait for workers to finish (with timeout)
-		done := make(chan struct{})
-		go func() {
-			workerPool.Wait()
-			wg.Wait()
-			close(done)
-		}()
-
-		select {
-		case <-done:
-			logf("Graceful shutdown complete")
-		case <-time.After(30 * time.Second):
-			logf("Shutdown timeout, forcing exit")
-		}
-		os.Exit(0)
+		sig = <-sigChan
+		logf("Received signal: %v - Forcing exit", sig)
+		os.Exit(1)
 	}()
 
-	// Connect via Socket Mode
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-		}
+	// Other chat backends run alongside Slack
+	if config.Discord != nil && config.Discord.BotToken != "" {
+		go discordBackend.Listen(ctx, configMgr)
+	}
+	if config.Telegram != nil && config.Telegram.BotToken != "" {
+		go telegramBackend.Listen(ctx, configMgr)
+	}
+	if config.Mattermost != nil && config.Mattermost.Token != "" {
+		go mattermostBackend.Listen(ctx, configMgr)
+	}
 
-		if err := connectSocketMode(ctx, configMgr); err != nil {
-			fmt.Fprintf(os.Stderr, "Socket Mode error: %v (reconnecting in 5s...)\n", err)
-			select {
-			case <-ctx.Done():
-				return nil
-			case <-time.After(5 * time.Second):
-			}
-		}
+	if slackEnabled {
+		// Connect via Socket Mode (reconnects with backoff until shutdown)
+		slackBackend.Listen(ctx, configMgr)
+	} else {
+		<-ctx.Done()
 	}
-}
 
-func connectSocketMode(ctx context.Context, cfgMgr *ConfigManager) error {
-	config := cfgMgr.Get()
+	gracefulShutdown(configMgr.Get())
+	return nil
+}
 
-	// Get WebSocket URL
-	req, err := newRequest("POST", "https://slack.com/api/apps.connections.open", nil)
+// openSocketModeURL asks Slack for a Socket Mode WebSocket URL
+func openSocketModeURL(config *Config, appToken string) (string, error) {
+	req, err := newRequest("POST", slackMethodURL(config, "apps.connections.open"), nil)
 	if err != nil {
-		return err
+		return "", err
 	}
-	req.Header.Set("Authorization", "Bearer "+config.AppToken)
+	req.Header.Set("Authorization", "Bearer "+appToken)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
@@ -290,18 +410,53 @@ func connectSocketMode(ctx context.Context, cfgMgr *ConfigManager) error {
 	json.NewDecoder(resp.Body).Decode(&connResult)
 
 	if !connResult.OK {
-		return fmt.Errorf("failed to open connection: %s", connResult.Error)
+		return "", fmt.Errorf("failed to open connection: %s", connResult.Error)
+	}
+	return connResult.URL, nil
+}
+
+func connectSocketMode(ctx context.Context, cfgMgr *ConfigManager) error {
+	config := cfgMgr.Get()
+
+	// Get WebSocket URL
+	wsURL, err := openSocketModeURL(config, config.AppToken)
+	if err != nil {
+		return err
 	}
 
-	wsURL := connResult.URL
 	// Connect WebSocket
-	ws, err := websocket.Dial(wsURL, "", "https://slack.com")
+	ws, conn, err := dialSocketMode(ctx, wsURL)
 	if err != nil {
 		return fmt.Errorf("websocket dial failed: %w", err)
 	}
 	defer ws.Close()
 
+	// Unblock Receive when shutting down, or when the link is due for a refresh
+	connDone := make(chan struct{})
+	defer close(connDone)
+	var refreshing atomic.Bool
+	refresh := make(chan time.Duration, 1)
+	go func() {
+		var expiry <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				ws.Close()
+				return
+			case d := <-refresh:
+				expiry = time.After(d)
+			case <-expiry:
+				refreshing.Store(true)
+				ws.Close()
+				return
+			case <-connDone:
+				return
+			}
+		}
+	}()
+
 	var wsMutex sync.Mutex
+	go keepSocketAlive(ws, conn, &wsMutex, connDone)
 
 	// Handle messages
 	for {
@@ -315,6 +470,9 @@ func connectSocketMode(ctx context.Context, cfgMgr *ConfigManager) error {
 
 		var envelope SocketModeEnvelope
 		if err := websocket.JSON.Receive(ws, &envelope); err != nil {
+			if refreshing.Load() {
+				return errSocketRefresh
+			}
 			return fmt.Errorf("websocket receive failed: %w", err)
 		}
 
@@ -329,6 +487,15 @@ func connectSocketMode(ctx context.Context, cfgMgr *ConfigManager) error {
 		switch envelope.Type {
 		case "hello":
 			logf("Socket Mode connected")
+			connectionOutage.Up(config)
+			go replayOutbox(config)
+			// Reconnect on our terms shortly before Slack drops the link
+			if lifetime := time.Duration(envelope.DebugInfo.ApproximateConnectionTime) * time.Second; lifetime > 2*connectionRefreshMargin {
+				select {
+				case refresh <- lifetime - connectionRefreshMargin:
+				default:
+				}
+			}
 
 		case "events_api":
 			var eventCallback EventCallback
@@ -341,15 +508,27 @@ func connectSocketMode(ctx context.Context, cfgMgr *ConfigManager) error {
 				})
 			}
 
+		case "slash_commands":
+			var cmd SlashCommandPayload
+			json.Unmarshal(envelope.Payload, &cmd)
+			workerPool.Submit(func() {
+				handleSlashCommand(ctx, cfgMgr, cmd)
+			})
+
 		case "interactive":
 			var action BlockActionPayload
 			json.Unmarshal(envelope.Payload, &action)
 			workerPool.Submit(func() {
-				handleBlockAction(cfgMgr.Get(), action)
+				handleBlockAction(cfgMgr, action)
 			})
 
 		case "disconnect":
-			return fmt.Errorf("disconnected by server")
+			// "warning" and "refresh_requested" are routine: Slack is rotating the link
+			if envelope.Reason == "link_disabled" {
+				return fmt.Errorf("disconnected by server: Socket Mode was disabled for this app")
+			}
+			logf("Socket Mode: server requested reconnect (%s)", envelope.Reason)
+			return errSocketRefresh
 		}
 	}
 }
@@ -368,6 +547,12 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 	}
 	json.Unmarshal(eventData, &event)
 
+	// Channels created, renamed or removed: names must be listed again
+	if channelEventTypes[event.Type] {
+		channelDirectory.Invalidate()
+		return
+	}
+
 	// Debug: log raw event when files are present
 	if len(event.Files) > 0 {
 		logf("DEBUG: Event with files: %s", string(eventData))
@@ -378,6 +563,18 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		return
 	}
 
+	// Edited prompts update the queue or offer a re-run
+	if event.Subtype == "message_changed" {
+		handleMessageEdit(cfgMgr, eventData)
+		return
+	}
+
+	// Deleting a prompt cancels its run
+	if event.Subtype == "message_deleted" {
+		handleMessageDeletion(cfgMgr, eventData)
+		return
+	}
+
 	// Ignore system messages (joins, leaves, topic changes, etc.)
 	// But allow file_share subtype (when user uploads a file with a message)
 	if event.Subtype != "" && event.Subtype != "file_share" {
@@ -401,7 +598,7 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 	}
 
 	text := strings.TrimSpace(event.Text)
-	if text == "" {
+	if text == "" && len(event.Files) == 0 {
 		return
 	}
 
@@ -419,19 +616,54 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		}
 	}
 
+	// A reply to a question Claude asked with slack_ask_user answers it
+	if answerMCPAskFromThread(config, channelID, threadTS, event.TS, text) {
+		return
+	}
+
+	// Aliases from the config stand for a command or a prompt
+	if expanded, ok := expandAlias(config.Aliases, text); ok {
+		logf("Alias %s -> %s", strings.Fields(text)[0], expanded)
+		text = expanded
+	}
+
+	// Commands living in their own files, and plugin_commands from the config
+	pluginSession := cfgMgr.GetSessionByChannel(channelID)
+	pluginCtx := &CommandContext{
+		Config:        config,
+		ConfigManager: cfgMgr,
+		ChannelID:     channelID,
+		ThreadTS:      threadTS,
+		EventTS:       event.TS,
+		UserID:        event.User,
+		SessionName:   pluginSession,
+		Reply:         reply,
+	}
+	if pluginSession != "" {
+		pluginCtx.WorkDir = sessionWorkDir(config, pluginSession)
+	}
+	if runCommandPlugin(pluginCtx, text) {
+		return
+	}
+
 	// Handle commands
-	if strings.HasPrefix(text, "!ping") {
-		reply("pong!")
+	if text == "!config" || strings.HasPrefix(text, "!config ") {
+		reply(handleConfigCommand(cfgMgr, config, event.User, strings.TrimPrefix(text, "!config")))
+		return
+	}
+
+	if text == "!filters" || strings.HasPrefix(text, "!filters ") {
+		reply(handleFiltersCommand(cfgMgr, config, strings.TrimPrefix(text, "!filters")))
 		return
 	}
 
-	if strings.HasPrefix(text, "!version") {
-		reply(fmt.Sprintf("v%s (build: %s)", version, buildTime))
+	if text == "!aliases" {
+		reply(formatAliases(config.Aliases))
 		return
 	}
 
 	if strings.HasPrefix(text, "!help") {
-		reply(getHelpText())
+		reply(getHelpText() + externalPluginHelp(config))
 		return
 	}
 
@@ -449,14 +681,10 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			// Try auto-detect from channel name
 			channelName, err := getChannelName(config, channelID)
 			if err == nil && channelName != "" {
-				baseDir := getProjectsDir(config)
 				// Try to find matching folder (handles dots, spaces, underscores)
-				sessionName = fromSlackChannelName(channelName, baseDir)
-				projectDir := filepath.Join(baseDir, sessionName)
-				if _, err := os.Stat(projectDir); err == nil {
+				if name, ok := findProjectForChannelName(config, channelName); ok {
+					sessionName = name
 					cfgMgr.SetSession(sessionName, channelID)
-				} else {
-					sessionName = "" // Reset if folder doesn't exist
 				}
 			}
 		}
@@ -466,14 +694,13 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			return
 		}
 
-		baseDir := getProjectsDir(config)
-		workDir := filepath.Join(baseDir, sessionName)
+		workDir := sessionWorkDir(config, sessionName)
 
 		// Auto-pin GitHub repo if exists
 		go PinGitHubRepoIfExists(config, channelID, workDir)
 
 		addReaction(config, channelID, event.TS, "eyes")
-		prompt := slackUserPrefix + taskPrompt
+		prompt := slackUserPrefix + preprocessSlackText(config, taskPrompt)
 
 		workerPool.Submit(func() {
 			// Pass event.TS as threadTS to create a thread
@@ -493,6 +720,22 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		return
 	}
 
+	// !fork --channel <name> - fork current session into a new channel
+	if strings.HasPrefix(text, "!fork --channel") || strings.HasPrefix(text, "!fork --shared") {
+		name, shared, ok := parseForkChannelArgs(strings.TrimPrefix(text, "!fork"))
+		if !ok {
+			reply(forkChannelUsage)
+			return
+		}
+		msg, err := forkSessionToChannel(cfgMgr, config, channelID, name, shared)
+		if err != nil {
+			reply(fmt.Sprintf(":x: Fork failed: %v", err))
+			return
+		}
+		reply(msg)
+		return
+	}
+
 	// !fork <prompt> - fork current session into a new thread
 	if strings.HasPrefix(text, "!fork ") {
 		// Only works from channel, not from thread
@@ -520,11 +763,10 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			return
 		}
 
-		baseDir := getProjectsDir(config)
-		workDir := filepath.Join(baseDir, sessionName)
+		workDir := sessionWorkDir(config, sessionName)
 
 		addReaction(config, channelID, event.TS, "twisted_rightwards_arrows")
-		prompt := slackUserPrefix + forkPrompt
+		prompt := slackUserPrefix + preprocessSlackText(config, forkPrompt)
 
 		workerPool.Submit(func() {
 			sendMessageToThread(config, channelID, event.TS, ":twisted_rightwards_arrows: *Forked session* - continuing with full context in this thread")
@@ -546,15 +788,54 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 	}
 
 	if strings.HasPrefix(text, "!sessions") || strings.HasPrefix(text, "!list") {
+		arg := strings.TrimPrefix(strings.TrimPrefix(text, "!sessions"), "!list")
+		filter, ok := parseListArgs(arg)
+		if !ok {
+			reply("Usage: `!list [--tag <tag>] [--idle <7d>]`")
+			return
+		}
 		sessions := cfgMgr.GetAllSessions()
-		if len(sessions) == 0 {
-			reply("No active sessions")
-		} else {
-			var list []string
-			for name, cid := range sessions {
-				list = append(list, fmt.Sprintf("• `%s` → <#%s>", name, cid))
+		if filter.tag != "" {
+			sessions = filterSessionsByTag(sessions, config.SessionTags, filter.tag)
+		}
+		if filter.idle > 0 {
+			text, _ := formatIdleSessions(idleSessions(sessions, activityStore.Last, filter.idle, time.Now()), filter.idle, time.Now(), false)
+			reply(text)
+			return
+		}
+		reply(formatSessionList(sessions, config.SessionTags, filter.tag))
+		return
+	}
+
+	if text == "!tag" || strings.HasPrefix(text, "!tag ") {
+		sessionName := cfgMgr.GetSessionByChannel(channelID)
+		if sessionName == "" {
+			reply(":x: Not in a session channel. Use `!tag` in a session channel.")
+			return
+		}
+		current := config.SessionTags[sessionName]
+		args := strings.Fields(strings.TrimPrefix(text, "!tag"))
+		if len(args) == 0 {
+			if len(current) == 0 {
+				reply(fmt.Sprintf(":label: `%s` has no tags\n%s", sessionName, tagUsage))
+			} else {
+				reply(fmt.Sprintf(":label: `%s`: %s", sessionName, formatTags(current)))
 			}
-			reply("*Active Sessions:*\n" + strings.Join(list, "\n"))
+			return
+		}
+		tags, err := applyTagCommand(current, args)
+		if err != nil {
+			reply(fmt.Sprintf(":x: %v", err))
+			return
+		}
+		if err := cfgMgr.SetSessionTags(sessionName, tags); err != nil {
+			reply(fmt.Sprintf(":x: Failed to save tags: %v", err))
+			return
+		}
+		if len(tags) == 0 {
+			reply(fmt.Sprintf(":label: `%s` has no tags now", sessionName))
+		} else {
+			reply(fmt.Sprintf(":label: `%s`: %s", sessionName, formatTags(tags)))
 		}
 		return
 	}
@@ -567,17 +848,46 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			return
 		}
 		resetClaudeSession(channelID)
+		timeline.Record(TimelineEvent{ChannelID: channelID, Kind: timelineReset})
 		reply(":arrows_counterclockwise: Conversation reset! Next message starts a fresh context.")
 		return
 	}
 
-	if text == "!kill" {
+	// !timeline [days] - what happened in this session, day by day
+	if text == "!timeline" || strings.HasPrefix(text, "!timeline ") {
+		sessionName := cfgMgr.GetSessionByChannel(channelID)
+		if sessionName == "" {
+			reply(":x: Not in a session channel. Use `!timeline` in a session channel.")
+			return
+		}
+		days := 7
+		if arg := strings.TrimSpace(strings.TrimPrefix(text, "!timeline")); arg != "" {
+			n, err := strconv.Atoi(strings.TrimSuffix(arg, "d"))
+			if err != nil || n < 1 {
+				reply("Usage: `!timeline [days]` (default 7)")
+				return
+			}
+			days = n
+		}
+		events := timeline.Events(channelID, time.Now().AddDate(0, 0, -days))
+		reply(formatTimeline(sessionName, events, days))
+		return
+	}
+
+	if text == "!kill --purge" {
 		name := cfgMgr.GetSessionByChannel(channelID)
-		// Reset Claude session ID and remove from config if exists
-		resetClaudeSession(channelID)
-		if name != "" {
-			cfgMgr.DeleteSession(name)
+		if name == "" {
+			reply(":x: Not in a session channel - use `!kill` to archive it")
+			return
 		}
+		if err := askPurgeConfirmation(config, channelID, threadTS, event.TS, name); err != nil {
+			reply(fmt.Sprintf(":x: %v", err))
+		}
+		return
+	}
+
+	if text == "!kill" {
+		name := removeSession(cfgMgr, channelID)
 		// Archive the channel
 		if err := archiveChannel(config, channelID); err != nil {
 			logf("Failed to archive channel: %v", err)
@@ -596,6 +906,29 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		return
 	}
 
+	if text == "!rename" || strings.HasPrefix(text, "!rename ") {
+		oldName, newName, move, ok := parseRenameArgs(strings.TrimPrefix(text, "!rename"))
+		if !ok {
+			reply(renameUsage)
+			return
+		}
+		msg, err := renameSession(cfgMgr, config, oldName, newName, move)
+		if err != nil {
+			reply(fmt.Sprintf(":x: %v", err))
+			return
+		}
+		reply(msg)
+		return
+	}
+
+	if text == "!status" {
+		if err := postChannelStatus(config, channelID, threadTS); err != nil {
+			logf("Failed to post status: %v", err)
+			reply(fmt.Sprintf(":x: Could not post status: %v", err))
+		}
+		return
+	}
+
 	if text == "!cancel" {
 		if CancelClaudeProcess(channelID) {
 			reply(":stop_sign: Task cancelled")
@@ -605,6 +938,38 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		return
 	}
 
+	if text == "!broadcast" || strings.HasPrefix(text, "!broadcast ") {
+		if !config.IsAdmin(event.User) {
+			reply(":no_entry: `!broadcast` is limited to the users in `admin_user_ids`")
+			return
+		}
+		sessions := cfgMgr.GetAllSessions()
+		names, prompt, err := parseBroadcastArgs(sessions, strings.TrimPrefix(text, "!broadcast"))
+		if err != nil {
+			reply(fmt.Sprintf(":x: %v", err))
+			return
+		}
+		ts, err := sendMessageToThreadGetTS(config, channelID, threadTS, fmt.Sprintf(":mega: Broadcasting to %d session(s) (%d at a time): `%s`",
+			len(names), broadcastConcurrency(config), strings.Join(names, "`, `")))
+		if err != nil {
+			logf("Failed to start broadcast: %v", err)
+			return
+		}
+		if threadTS != "" {
+			ts = threadTS
+		}
+		logf("Broadcast to %d session(s) by %s", len(names), event.User)
+		go runBroadcast(config, sessions, names, prompt, event.User, channelID, ts)
+		return
+	}
+
+	if text == "!queue" {
+		if err := postQueue(config, channelID, threadTS); err != nil {
+			reply(fmt.Sprintf(":x: %v", err))
+		}
+		return
+	}
+
 	if text == "!verbose" {
 		SetVerbose(channelID, true)
 		reply(":loud_sound: Verbose mode ON - showing all tool calls")
@@ -633,8 +998,7 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			reply(":x: Not in a session channel. Use `!at` in a session channel.")
 			return
 		}
-		baseDir := getProjectsDir(config)
-		workDir := filepath.Join(baseDir, sessionName)
+		workDir := sessionWorkDir(config, sessionName)
 
 		taskID, runAt, err := scheduler.Schedule(channelID, threadTS, workDir, timeSpec, command)
 		if err != nil {
@@ -663,6 +1027,28 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		return
 	}
 
+	// !watch <glob> "<prompt>" - prompt Claude when matching files change
+	if text == "!watch" || strings.HasPrefix(text, "!watch ") {
+		if msg := handleWatchCommand(config, channelID, event.User, strings.TrimPrefix(text, "!watch")); msg != "" {
+			reply(msg)
+		}
+		return
+	}
+
+	// !review <pr-url> [--submit] - one-shot review of a GitHub pull request
+	if text == "!review" || strings.HasPrefix(text, "!review ") {
+		if msg := handleReviewCommand(config, channelID, event.TS, strings.TrimPrefix(text, "!review")); msg != "" {
+			reply(msg)
+		}
+		return
+	}
+
+	// !schedule "<cron>" <prompt> - recurring prompt
+	if text == "!schedule" || strings.HasPrefix(text, "!schedule ") {
+		reply(handleScheduleCommand(config, channelID, event.User, strings.TrimPrefix(text, "!schedule")))
+		return
+	}
+
 	// !unschedule <task-id> - cancel a scheduled task
 	if strings.HasPrefix(text, "!unschedule ") {
 		taskID := strings.TrimPrefix(text, "!unschedule ")
@@ -674,49 +1060,277 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		return
 	}
 
-	if strings.HasPrefix(text, "!c ") {
-		cmdStr := strings.TrimPrefix(text, "!c ")
-		output, err := executeCommand(cmdStr)
-		if err != nil {
-			output = fmt.Sprintf(":warning: %s\n\nExit: %v", output, err)
+	// !search <query> - find prompts, answers and commits across sessions
+	if text == "!search" || strings.HasPrefix(text, "!search ") {
+		query := strings.TrimSpace(cleanSlackMarkup(strings.TrimPrefix(text, "!search")))
+		if query == "" {
+			reply("Usage: `!search <query>` - search prompts, answers, commits and session names across all sessions")
+			return
 		}
-		reply("```\n" + output + "\n```")
+		reply(formatSearchResults(query, searchSessions(config, query)))
 		return
 	}
 
-	if strings.HasPrefix(text, "!projects") {
-		baseDir := getProjectsDir(config)
-		entries, err := os.ReadDir(baseDir)
-		if err != nil {
-			reply(fmt.Sprintf(":x: Cannot read projects dir: %v", err))
+	// !output [lines] - the end of a PTY session's terminal
+	if text == "!output" || strings.HasPrefix(text, "!output ") {
+		reply(handleOutputCommand(config, channelID, strings.TrimPrefix(text, "!output")))
+		return
+	}
+
+	// !keys <keys> - keystrokes for a PTY session (menus, prompts)
+	if text == "!keys" || strings.HasPrefix(text, "!keys ") {
+		reply(handleKeysCommand(config, channelID, cleanSlackMarkup(strings.TrimPrefix(text, "!keys"))))
+		return
+	}
+
+	// !esc - Escape for a PTY session: closes a menu, interrupts a turn
+	if text == "!esc" {
+		reply(handleKeysCommand(config, channelID, "esc"))
+		return
+	}
+
+	// !history [n] - last exchanges from the session transcript
+	if text == "!history" || strings.HasPrefix(text, "!history ") {
+		reply(handleHistoryCommand(config, channelID, strings.TrimPrefix(text, "!history")))
+		return
+	}
+
+	// !summarize - regenerate the pinned session summary
+	if text == "!summarize" {
+		addReaction(config, channelID, event.TS, "bookmark_tabs")
+		workerPool.Submit(func() {
+			removeReaction(config, channelID, event.TS, "bookmark_tabs")
+			if err := summarizeChannel(config, channelID); err != nil {
+				reply(fmt.Sprintf(":x: Summary failed: %v", err))
+				return
+			}
+			addReaction(config, channelID, event.TS, "white_check_mark")
+		})
+		return
+	}
+
+	// !autocommit [on|off] - commit the workdir after every successful run
+	if text == "!autocommit" || strings.HasPrefix(text, "!autocommit ") {
+		if cfgMgr.GetSessionByChannel(channelID) == "" {
+			reply(":x: Not in a session channel. Use `!autocommit` in a session channel.")
 			return
 		}
-		var projects []string
-		for _, entry := range entries {
-			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
-				projects = append(projects, "• `"+entry.Name()+"`")
+		arg := strings.TrimSpace(strings.TrimPrefix(text, "!autocommit"))
+		if arg == "" {
+			if config.AutoCommitChannels[channelID] {
+				reply(":floppy_disk: Auto-commit is *on*: every successful run is committed as a checkpoint")
+			} else {
+				reply(":floppy_disk: Auto-commit is *off*.\nUsage: `!autocommit on` / `!autocommit off`")
 			}
+			return
+		}
+		on, err := parseOnOff(arg)
+		if err != nil {
+			reply("Usage: `!autocommit on` / `!autocommit off`")
+			return
+		}
+		if on && sessionHost(config, channelID) != "" {
+			reply(":x: This session runs on a remote agent: its folder can't be committed from here")
+			return
 		}
-		if len(projects) == 0 {
-			reply(fmt.Sprintf("No projects in `%s`", baseDir))
+		if err := cfgMgr.SetAutoCommit(channelID, on); err != nil {
+			reply(fmt.Sprintf(":x: Failed to save auto-commit: %v", err))
+			return
+		}
+		if on {
+			reply(":floppy_disk: Auto-commit *on*: every successful run will be committed as a checkpoint")
 		} else {
-			reply(fmt.Sprintf("*Projects in `%s`:*\n%s", baseDir, strings.Join(projects, "\n")))
+			reply(":floppy_disk: Auto-commit *off*")
 		}
 		return
 	}
 
-	if strings.HasPrefix(text, "!new ") {
-		arg := strings.TrimSpace(strings.TrimPrefix(text, "!new "))
-		if arg == "" {
-			sendMessage(config, channelID, "Usage: `!new <name>` - create a new session")
+	// !sysprompt [show | set <instructions> | clear] - this channel's system prompt instructions
+	if text == "!sysprompt" || strings.HasPrefix(text, "!sysprompt ") || strings.HasPrefix(text, "!sysprompt\n") {
+		if cfgMgr.GetSessionByChannel(channelID) == "" {
+			reply(":x: Not in a session channel. Use `!sysprompt` in a session channel.")
 			return
 		}
+		reply(handleSyspromptCommand(cfgMgr, config, channelID, strings.TrimPrefix(text, "!sysprompt")))
+		return
+	}
+
+	// !branch [set <name> | clear] - pin this channel's runs to a git branch
+	if text == "!branch" || strings.HasPrefix(text, "!branch ") {
+		sessionName := cfgMgr.GetSessionByChannel(channelID)
+		if sessionName == "" {
+			reply(":x: Not in a session channel. Use `!branch` in a session channel.")
+			return
+		}
+		args := strings.Fields(strings.TrimPrefix(text, "!branch"))
+		switch {
+		case len(args) == 0:
+			if branch := config.ChannelBranches[channelID]; branch != "" {
+				reply(fmt.Sprintf(":herb: Runs in this channel use branch `%s`", branch))
+			} else {
+				reply(":herb: No branch set - runs use whatever is checked out.\nUsage: `!branch set <name>` / `!branch clear`")
+			}
+		case args[0] == "set" && len(args) == 2:
+			if !validBranchName(args[1]) {
+				reply(fmt.Sprintf(":x: Invalid branch name `%s`", args[1]))
+				return
+			}
+			if err := cfgMgr.SetChannelBranch(channelID, args[1]); err != nil {
+				reply(fmt.Sprintf(":x: Failed to save branch: %v", err))
+				return
+			}
+			reply(fmt.Sprintf(":herb: Runs in this channel will check out `%s` first (created if missing)", args[1]))
+		case args[0] == "clear":
+			if err := cfgMgr.SetChannelBranch(channelID, ""); err != nil {
+				reply(fmt.Sprintf(":x: Failed to clear branch: %v", err))
+				return
+			}
+			reply(":herb: Branch cleared - runs use whatever is checked out")
+		default:
+			reply("Usage: `!branch` / `!branch set <name>` / `!branch clear`")
+		}
+		return
+	}
+
+	// !slash list - show the project's custom Claude commands as buttons
+	if text == "!slash" || text == "!slash list" {
+		sessionName := cfgMgr.GetSessionByChannel(channelID)
+		if sessionName == "" {
+			reply(":x: Not in a session channel. Use `!slash list` in a session channel.")
+			return
+		}
+		workDir := sessionWorkDir(config, sessionName)
+		commands := listClaudeCommands(workDir)
+		if len(commands) == 0 {
+			reply(":shrug: No custom commands found in `.claude/commands` (project or user)")
+			return
+		}
+		if err := sendSlashCatalog(config, channelID, commands); err != nil {
+			reply(fmt.Sprintf(":x: Failed to list commands: %v", err))
+		}
+		return
+	}
+
+	if strings.HasPrefix(text, "!c ") {
+		cmdStr := cleanSlackMarkup(strings.TrimPrefix(text, "!c "))
+		switch verdict, reason := checkShellCommand(config, cmdStr); verdict {
+		case shellRefused:
+			reply(":no_entry: " + reason)
+			return
+		case shellConfirm:
+			if err := askShellConfirmation(config, channelID, threadTS, event.TS, cmdStr, reason); err != nil {
+				reply(fmt.Sprintf(":x: Failed to ask for confirmation: %v", err))
+			}
+			return
+		}
+		reply(runShellCommand(cmdStr))
+		return
+	}
+
+	if strings.HasPrefix(text, "!projects") {
+		var sections []string
+		for _, baseDir := range getProjectsDirs(config) {
+			entries, err := os.ReadDir(baseDir)
+			if err != nil {
+				sections = append(sections, fmt.Sprintf(":x: Cannot read projects dir `%s`: %v", baseDir, err))
+				continue
+			}
+			var projects []string
+			for _, entry := range entries {
+				if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+					projects = append(projects, "• `"+entry.Name()+"`")
+				}
+			}
+			if len(projects) == 0 {
+				sections = append(sections, fmt.Sprintf("No projects in `%s`", baseDir))
+			} else {
+				sections = append(sections, fmt.Sprintf("*Projects in `%s`:*\n%s", baseDir, strings.Join(projects, "\n")))
+			}
+		}
+		if len(sections) == 0 {
+			reply(":x: No projects dir configured")
+			return
+		}
+		reply(strings.Join(sections, "\n\n"))
+		return
+	}
+
+	if strings.HasPrefix(text, "!new ") {
+		const newUsage = "Usage: `!new <name> [--host <agent> | --sandbox] [--clone <git url> | --template <name>]` - create a new session"
+		arg := strings.TrimSpace(strings.TrimPrefix(text, "!new "))
+		if arg == "" {
+			sendMessage(config, channelID, newUsage)
+			return
+		}
+
+		// --host <agent> creates the session on a remote agent, --sandbox in
+		// Docker, --clone from a git repository
+		args := parseNewSessionArgs(arg)
+		arg, host, sandbox := args.name, args.host, args.sandbox
+		if arg == "" {
+			sendMessage(config, channelID, newUsage)
+			return
+		}
+		if host != "" && sandbox {
+			sendMessage(config, channelID, ":x: `--sandbox` can't be combined with `--host`")
+			return
+		}
+		var tmpl SessionTemplate
+		if args.cloneURL != "" || args.template != "" {
+			flag := "--clone"
+			if args.template != "" {
+				flag = "--template"
+			}
+			if args.cloneURL != "" && args.template != "" {
+				sendMessage(config, channelID, ":x: `--clone` can't be combined with `--template`")
+				return
+			}
+			if host != "" {
+				sendMessage(config, channelID, fmt.Sprintf(":x: `%s` can't be combined with `--host` - prepare the folder on the agent's machine, then `!new <name> --host`", flag))
+				return
+			}
+			if args.cloneURL != "" && !isCloneURL(args.cloneURL) {
+				sendMessage(config, channelID, fmt.Sprintf(":x: `%s` doesn't look like a git URL", args.cloneURL))
+				return
+			}
+			if args.template != "" {
+				var err error
+				if tmpl, err = lookupTemplate(config, args.template); err != nil {
+					sendMessage(config, channelID, ":x: "+err.Error())
+					return
+				}
+			}
+			// Fill the folder before creating the channel, so a failure leaves nothing behind
+			newDir := sessionWorkDir(config, arg)
+			if _, err := os.Stat(newDir); err == nil {
+				sendMessage(config, channelID, fmt.Sprintf(":x: `%s` already exists - use `!new %s` without `%s`", newDir, arg, flag))
+				return
+			}
+			var err error
+			if args.cloneURL != "" {
+				sendMessage(config, channelID, fmt.Sprintf(":inbox_tray: Cloning `%s` into `%s`...", args.cloneURL, newDir))
+				err = cloneRepo(config, args.cloneURL, newDir)
+			} else {
+				err = scaffoldFromTemplate(config, tmpl, newDir)
+			}
+			if err != nil {
+				os.RemoveAll(newDir)
+				sendMessage(config, channelID, fmt.Sprintf(":x: `%s` failed: %v", flag, err))
+				return
+			}
+		}
+		if host != "" {
+			if _, ok := agentHub.Connected()[host]; !ok {
+				sendMessage(config, channelID, fmt.Sprintf(":x: Agent `%s` is not connected (see `!agents`)", host))
+				return
+			}
+		}
+
+		// Session name = folder name (can have dots, spaces, etc.)
+		sessionName := arg
+		// Channel name = Slack-friendly version (replace dots with dashes, etc.)
+		slackChannelName := toSlackChannelName(arg)
 
-		// Session name = folder name (can have dots, spaces, etc.)
-		sessionName := arg
-		// Channel name = Slack-friendly version (replace dots with dashes, etc.)
-		slackChannelName := toSlackChannelName(arg)
-
 		// Create channel if needed
 		var targetChannelID string
 		isNewChannel := false
@@ -728,6 +1342,11 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 				sendMessage(config, channelID, fmt.Sprintf(":x: Failed to create channel: %v", err))
 				return
 			}
+			// Different names can sanitize to the same channel ("café" / "cafe")
+			if other := cfgMgr.GetSessionByChannel(cid); other != "" && other != sessionName {
+				sendMessage(config, channelID, fmt.Sprintf(":x: Channel <#%s> already belongs to session `%s` - pick another name", cid, other))
+				return
+			}
 			targetChannelID = cid
 			// Store session with original name (folder name), not Slack name
 			if err := cfgMgr.SetSession(sessionName, cid); err != nil {
@@ -743,27 +1362,83 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			sendMessage(config, channelID, fmt.Sprintf(":arrow_right: Using existing <#%s>", targetChannelID))
 		}
 
+		if host != "" {
+			if err := cfgMgr.SetSessionHost(sessionName, host); err != nil {
+				logf("Failed to save session host: %v", err)
+			}
+			if err := agentHub.Mkdir(host, sessionName); err != nil {
+				sendMessage(config, targetChannelID, fmt.Sprintf(":x: Failed to create directory on `%s`: %v", host, err))
+				return
+			}
+			if isNewChannel {
+				go provisionSessionChannel(config, targetChannelID, sessionName, host, false)
+			}
+			logf("Session created: %s (agent: %s)", sessionName, host)
+			sendMessage(config, targetChannelID, fmt.Sprintf(":rocket: Session '%s' ready on agent `%s`!\n\nSend messages here to interact with Claude.", sessionName, host))
+			return
+		}
+		if config.SessionHosts[sessionName] != "" {
+			// Recreated without --host: back to this machine
+			cfgMgr.SetSessionHost(sessionName, "")
+		}
+		if sandbox != config.SandboxSessions[sessionName] {
+			if err := cfgMgr.SetSessionSandbox(sessionName, sandbox); err != nil {
+				logf("Failed to save session sandbox: %v", err)
+			}
+		}
+
 		// Find or create work directory (use original name with dots etc.)
-		baseDir := getProjectsDir(config)
-		workDir := filepath.Join(baseDir, sessionName)
+		workDir := sessionWorkDir(config, sessionName)
 		if _, err := os.Stat(workDir); os.IsNotExist(err) {
 			if err := os.MkdirAll(workDir, 0755); err != nil {
 				sendMessage(config, targetChannelID, fmt.Sprintf(":x: Failed to create directory %s: %v", workDir, err))
 				return
 			}
 			sendMessage(config, targetChannelID, fmt.Sprintf(":file_folder: Created `%s`", workDir))
+		} else if args.cloneURL != "" {
+			sendMessage(config, targetChannelID, fmt.Sprintf(":inbox_tray: Cloned `%s` into `%s`", args.cloneURL, workDir))
+		} else if args.template != "" {
+			sendMessage(config, targetChannelID, fmt.Sprintf(":building_construction: Scaffolded `%s` from template `%s`", workDir, args.template))
 		} else {
 			sendMessage(config, targetChannelID, fmt.Sprintf(":open_file_folder: Using existing `%s`", workDir))
 		}
 
-		logf("Session created: %s (dir: %s)", sessionName, workDir)
-		sendMessage(config, targetChannelID, fmt.Sprintf(":rocket: Session '%s' ready!\n\nSend messages here to interact with Claude.", sessionName))
+		if sandbox {
+			image := config.SandboxImage
+			if image == "" {
+				image = defaultSandboxImage
+			}
+			logf("Session created: %s (dir: %s, sandbox: %s)", sessionName, workDir, image)
+			sendMessage(config, targetChannelID, fmt.Sprintf(":rocket: Session '%s' ready in a Docker sandbox (`%s`)!\n\nClaude only sees `%s`. Send messages here to interact with Claude.", sessionName, image, workDir))
+		} else {
+			logf("Session created: %s (dir: %s)", sessionName, workDir)
+			sendMessage(config, targetChannelID, fmt.Sprintf(":rocket: Session '%s' ready!\n\nSend messages here to interact with Claude.", sessionName))
+		}
 
-		// Auto-pin GitHub repo if exists
+		// Topic and bookmarks, then the pinned GitHub repo if it exists
+		if isNewChannel {
+			go provisionSessionChannel(config, targetChannelID, workDir, "", sandbox)
+		}
 		go PinGitHubRepoIfExists(config, targetChannelID, workDir)
+
+		// A template's first instruction starts the work right away
+		if tmpl.Prompt != "" {
+			ts, err := sendMessage(config, targetChannelID, ":building_construction: *Template instruction:*\n"+tmpl.Prompt)
+			if err != nil {
+				logf("Failed to post template instruction: %v", err)
+				return
+			}
+			runExternalPrompt(config, sessionName, targetChannelID, tmpl.Prompt, ts)
+		}
 		return
 	}
 
+	// !urgent <prompt> is a prompt that waits ahead of the channel's other queued messages
+	urgent := false
+	if rest, ok := strings.CutPrefix(text, "!urgent "); ok && strings.TrimSpace(rest) != "" {
+		text, urgent = strings.TrimSpace(rest), true
+	}
+
 	// Unknown ! command (except !claude_* which is handled below in session context)
 	if strings.HasPrefix(text, "!") && !strings.HasPrefix(text, "!claude_") {
 		logf("Unknown command: %s", text)
@@ -783,8 +1458,7 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			case "compact":
 				addReaction(config, channelID, event.TS, "hourglass_flowing_sand")
 				workerPool.Submit(func() {
-					baseDir := getProjectsDir(config)
-					workDir := filepath.Join(baseDir, sessionName)
+					workDir := sessionWorkDir(config, sessionName)
 					resp, err := callClaudeStreaming("/compact", channelID, event.TS, workDir, config)
 					removeReaction(config, channelID, event.TS, "hourglass_flowing_sand")
 					if err != nil {
@@ -812,8 +1486,7 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 				// Get last response raw (no formatting)
 				addReaction(config, channelID, event.TS, "eyes")
 				workerPool.Submit(func() {
-					baseDir := getProjectsDir(config)
-					workDir := filepath.Join(baseDir, sessionName)
+					workDir := sessionWorkDir(config, sessionName)
 					// Ask Claude to repeat last response
 					resp, err := callClaudeJSON("Please repeat your last response exactly as you wrote it, without any changes.", channelID, workDir)
 					removeReaction(config, channelID, event.TS, "eyes")
@@ -834,12 +1507,21 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		}
 
 		addReaction(config, channelID, event.TS, "eyes")
-		claudeText := text
+		claudeText := preprocessSlackText(config, text)
+		// "//cmd args" runs Claude slash command /cmd (Slack swallows a single leading /)
+		if strings.HasPrefix(claudeText, "//") {
+			claudeText = claudeText[1:]
+		}
 
 		// Find work directory first (needed for file uploads)
-		baseDir := getProjectsDir(config)
-		workDir := filepath.Join(baseDir, sessionName)
-		if _, err := os.Stat(workDir); os.IsNotExist(err) {
+		workDir := sessionWorkDir(config, sessionName)
+		host := config.SessionHosts[sessionName]
+		if host != "" && len(event.Files) > 0 {
+			// Uploads are saved on this machine, which the agent can't read
+			reply(fmt.Sprintf(":warning: Attachments aren't forwarded to agent `%s` - sending the text only", host))
+			event.Files = nil
+		}
+		if _, err := os.Stat(workDir); host == "" && os.IsNotExist(err) {
 			if err := os.MkdirAll(workDir, 0755); err != nil {
 				logf("Failed to create directory %s: %v", workDir, err)
 				addReaction(config, channelID, event.TS, "x")
@@ -849,84 +1531,41 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			}
 		}
 
-		// Handle file attachments (images and text files)
-		// Save them in workDir/.slack-uploads/ so Claude can access them
-		var filePaths []string
+		// Handle file attachments (voice clips, images and text files)
+		// Text files land in workDir/uploads/, images in workDir/.slack-uploads/
 		if len(event.Files) > 0 {
-			uploadsDir := filepath.Join(workDir, ".slack-uploads")
-			os.MkdirAll(uploadsDir, 0755)
-
-			for _, file := range event.Files {
-				logf("File attached: name=%s mimetype=%s filetype=%s", file.Name, file.Mimetype, file.Filetype)
-				if isImageFile(file) || isTextFile(file) {
-					logf("Downloading file: %s (%s)", file.Name, file.Mimetype)
-					localPath, err := downloadSlackFileToDir(config, file, uploadsDir)
-					if err != nil {
-						logf("Failed to download file %s: %v", file.Name, err)
-						reply(fmt.Sprintf(":warning: Failed to download file %s: %v", file.Name, err))
-						continue
-					}
-					filePaths = append(filePaths, localPath)
-					logf("Saved file to: %s", localPath)
-				} else {
-					logf("Skipping unsupported file type: %s (mimetype=%s, filetype=%s)", file.Name, file.Mimetype, file.Filetype)
-				}
-			}
+			claudeText = processAttachments(config, channelID, event.TS, claudeText, event.Files, workDir)
 		}
 
-		// Build prompt with file paths (images and text files)
-		if len(filePaths) > 0 {
-			fileList := strings.Join(filePaths, " ")
-			if claudeText == "" {
-				claudeText = fmt.Sprintf("Please analyze these files: %s", fileList)
-			} else {
-				claudeText = fmt.Sprintf("%s\n\nAttached file(s): %s", claudeText, fileList)
-			}
-			logf("Added %d file(s) to prompt", len(filePaths))
+		// Nothing usable (e.g. only unsupported attachments)
+		if claudeText == "" {
+			removeReaction(config, channelID, event.TS, "eyes")
+			return
 		}
 
 		// Add remote context to help Claude understand the user's situation
-		prompt := slackUserPrefix + claudeText
-
 		// Determine threadTS: if already in a thread, continue there; otherwise respond in channel
 		// threadTS is already set from event.ThreadTS at the top
 		// If not in a thread (threadTS == ""), responses go to channel directly
-
-		// Submit to queue - will process immediately if channel is free, otherwise queue
 		msg := &QueuedMessage{
-			Text:      prompt,
+			Text:      claudeText,
 			ChannelID: channelID,
 			ThreadTS:  threadTS,
 			EventTS:   event.TS,
+			UserID:    event.User,
 			WorkDir:   workDir,
+			Urgent:    urgent,
 		}
-
-		queued, position := messageQueue.Submit(msg)
-		if queued {
-			// Message was queued, notify user
-			logf("Message queued for channel %s (position: %d)", channelID, position)
-			removeReaction(config, channelID, event.TS, "eyes")
-			addReaction(config, channelID, event.TS, "hourglass_flowing_sand")
-			sendMessageToThread(config, channelID, event.TS, fmt.Sprintf(":hourglass: Queued (position %d) - will run after current task", position))
-		} else {
-			// Process immediately
-			logf("Calling Claude in streaming mode for channel %s (thread: %v)", channelID, threadTS != "")
-			processClaudeMessage(msg, config, reply)
-		}
+		submitClaudeMessage(msg, config)
 		return
 	}
 
 	// Try to auto-detect session from channel name
 	channelName, err := getChannelName(config, channelID)
 	if err == nil && channelName != "" {
-		baseDir := getProjectsDir(config)
-
 		// Try to find matching folder (handles dots, spaces, underscores)
-		sessionName := fromSlackChannelName(channelName, baseDir)
-		projectDir := filepath.Join(baseDir, sessionName)
-
-		// Check if project directory exists
-		if _, err := os.Stat(projectDir); err == nil {
+		if sessionName, ok := findProjectForChannelName(config, channelName); ok {
+			projectDir := sessionWorkDir(config, sessionName)
 			logf("Auto-detected session '%s' from channel '%s' (project dir exists)", sessionName, channelName)
 
 			// Auto-add to sessions (use sessionName as key, which may have spaces)
@@ -940,27 +1579,25 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 			// Handle as session message using streaming mode
 			addReaction(config, channelID, event.TS, "eyes")
 
-			prompt := slackUserPrefix + text
+			claudeText := preprocessSlackText(config, text)
+			if len(event.Files) > 0 {
+				claudeText = processAttachments(config, channelID, event.TS, claudeText, event.Files, projectDir)
+			}
+			if claudeText == "" {
+				removeReaction(config, channelID, event.TS, "eyes")
+				return
+			}
 
-			// Submit to queue
 			msg := &QueuedMessage{
-				Text:      prompt,
+				Text:      claudeText,
 				ChannelID: channelID,
 				ThreadTS:  threadTS,
 				EventTS:   event.TS,
+				UserID:    event.User,
 				WorkDir:   projectDir,
+				Urgent:    urgent,
 			}
-
-			queued, position := messageQueue.Submit(msg)
-			if queued {
-				logf("Message queued for channel %s (position: %d)", channelID, position)
-				removeReaction(config, channelID, event.TS, "eyes")
-				addReaction(config, channelID, event.TS, "hourglass_flowing_sand")
-				sendMessageToThread(config, channelID, event.TS, fmt.Sprintf(":hourglass: Queued (position %d) - will run after current task", position))
-			} else {
-				logf("Calling Claude in streaming mode for channel %s (thread: %v)", channelID, threadTS != "")
-				processClaudeMessage(msg, config, reply)
-			}
+			submitClaudeMessage(msg, config)
 			return
 		}
 	}
@@ -971,10 +1608,15 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 		return
 	}
 
+	// File-only messages outside a session have nothing to run
+	if text == "" {
+		return
+	}
+
 	// Otherwise, run one-shot Claude
 	sendMessage(config, channelID, ":robot_face: Running Claude...")
 	workerPool.Submit(func() {
-		output, err := runClaude(text)
+		output, err := runClaude(preprocessSlackText(config, text))
 		if err != nil {
 			if strings.Contains(err.Error(), "context deadline exceeded") {
 				output = fmt.Sprintf(":stopwatch: Timeout (10min)\n\n%s", output)
@@ -986,53 +1628,237 @@ func handleSlackEvent(ctx context.Context, cfgMgr *ConfigManager, eventData json
 	})
 }
 
+// handleSlashCommand maps "/ccsa <cmd> [args]" onto the matching ! command
+func handleSlashCommand(ctx context.Context, cfgMgr *ConfigManager, cmd SlashCommandPayload) {
+	text := strings.TrimSpace(cmd.Text)
+	if text == "" {
+		text = "help"
+	}
+	config := cfgMgr.Get()
+	if config == nil || !config.IsAuthorizedUser(cmd.UserID) {
+		return
+	}
+
+	// Commands react to, thread under and key confirmations on their message:
+	// a slash command has none, so post one for it
+	ts, err := sendMessage(config, cmd.ChannelID, fmt.Sprintf("<@%s> ran `%s %s`", cmd.UserID, cmd.Command, text))
+	if err != nil {
+		logf("Slash command %s %s: failed to post to %s: %v", cmd.Command, text, cmd.ChannelID, err)
+		return
+	}
+	event, _ := json.Marshal(map[string]string{
+		"type":    "message",
+		"channel": cmd.ChannelID,
+		"user":    cmd.UserID,
+		"text":    "!" + strings.TrimPrefix(text, "!"),
+		"ts":      ts,
+	})
+	handleSlackEvent(ctx, cfgMgr, event)
+}
+
+// processAttachments transcribes voice clips and saves files into workDir,
+// posts confirmations in the message thread and returns the enriched prompt text
+func processAttachments(config *Config, channelID, eventTS, text string, files []SlackFile, workDir string) string {
+	hasAudio := false
+	for _, f := range files {
+		if isAudioFile(f) {
+			hasAudio = true
+			break
+		}
+	}
+
+	if hasAudio {
+		if !transcriptionConfigured(config) {
+			sendMessageToThread(config, channelID, eventTS, ":microphone: Voice message ignored - set `transcribe_command` or `transcribe_api_url` in config to enable transcription")
+		} else {
+			transcript, failures := transcribeAudioAttachments(config, files, workDir)
+			for _, f := range failures {
+				sendMessageToThread(config, channelID, eventTS, fmt.Sprintf(":warning: Transcription failed: %s", f))
+			}
+			if transcript != "" {
+				sendMessageToThread(config, channelID, eventTS, fmt.Sprintf(":microphone: _%s_", transcript))
+				if text == "" {
+					text = transcript
+				} else {
+					text = text + "\n\n" + transcript
+				}
+			}
+		}
+	}
+
+	saved, failures := saveAttachments(config, files, workDir)
+	if confirmation := attachmentConfirmation(saved, failures); confirmation != "" {
+		sendMessageToThread(config, channelID, eventTS, confirmation)
+	}
+	if len(saved) > 0 {
+		logf("Added %d file(s) to prompt", len(saved))
+	}
+	return attachmentPrompt(text, saved)
+}
+
+// submitClaudeMessage batches rapid messages, then queues (or runs) the combined prompt.
+// msg.Text is the raw user text; the Slack prefix is added here.
+func submitClaudeMessage(msg *QueuedMessage, config *Config) {
+	messageBatcher.Add(msg, effectiveBatchWindow(config), func(m *QueuedMessage) {
+		if len(m.BatchedEventTS) > 0 {
+			logf("Coalesced %d messages for channel %s", len(m.BatchedEventTS)+1, m.ChannelID)
+		}
+		// Slash commands must stay at the start of the prompt to be recognized
+		if !strings.HasPrefix(m.Text, "/") {
+			m.Text = slackUserPrefix + m.Text
+		}
+
+		// Session transcript gone: ask how to proceed instead of failing
+		if offerStaleSessionResume(m, config) {
+			return
+		}
+		dispatchClaudeMessage(m, config)
+	})
+}
+
+// dispatchClaudeMessage runs msg now, or queues it behind the channel's current task
+func dispatchClaudeMessage(m *QueuedMessage, config *Config) {
+	reply := func(text string) {
+		if m.ThreadTS != "" {
+			sendMessageToThread(config, m.ChannelID, m.ThreadTS, text)
+		} else {
+			sendMessage(config, m.ChannelID, text)
+		}
+	}
+
+	// PTY sessions type the prompt into the listener's interactive Claude;
+	// the Stop hook posts the answer
+	if usePTYSession(config, m.ChannelID) {
+		sendToPTYSession(config, m)
+		return
+	}
+
+	// Same read-only question against the same code: answer from cache
+	if serveCachedResult(m, config) {
+		return
+	}
+
+	// Submit to queue - will process immediately if channel is free, otherwise queue
+	queued, position, duplicate := messageQueue.Submit(m)
+	if duplicate {
+		logf("Dropped duplicate message for channel %s", m.ChannelID)
+		for _, ts := range m.EventTimestamps() {
+			removeReaction(config, m.ChannelID, ts, "eyes")
+			addReaction(config, m.ChannelID, ts, "repeat")
+		}
+		sendMessageToThread(config, m.ChannelID, m.EventTS, ":repeat: Same as the previous message - it's already running or queued, so this one is skipped")
+		if m.OnFinish != nil {
+			m.OnFinish(nil, fmt.Errorf("skipped: same as the previous message"))
+		}
+		return
+	}
+	if queued {
+		logf("Message queued for channel %s (position: %d)", m.ChannelID, position)
+		for _, ts := range m.EventTimestamps() {
+			removeReaction(config, m.ChannelID, ts, "eyes")
+			addReaction(config, m.ChannelID, ts, "hourglass_flowing_sand")
+		}
+		note := "will run after current task"
+		if m.Urgent {
+			note = "urgent, ahead of the other queued messages"
+		}
+		sendMessageToThread(config, m.ChannelID, m.EventTS, fmt.Sprintf(":hourglass: Queued (position %d) - %s. `!queue` to see or cancel", position, note))
+		return
+	}
+
+	logf("Calling Claude in streaming mode for channel %s (thread: %v)", m.ChannelID, m.ThreadTS != "")
+	processClaudeMessage(m, config, reply)
+}
+
 // processClaudeMessage handles a Claude request and processes the queue
 func processClaudeMessage(msg *QueuedMessage, config *Config, reply func(string)) {
 	workerPool.Submit(func() {
 		// Process the message
-		resp, err := callClaudeStreaming(msg.Text, msg.ChannelID, msg.ThreadTS, msg.WorkDir, config)
-
-		// Remove hourglass if it was queued
-		removeReaction(config, msg.ChannelID, msg.EventTS, "hourglass_flowing_sand")
+		headBefore, _ := gitOutput(msg.WorkDir, "rev-parse", "HEAD")
+		timeline.Record(TimelineEvent{ChannelID: msg.ChannelID, Kind: timelinePrompt, Text: timelinePromptText(msg.Text)})
+		activityStore.Touch(msg.ChannelID, time.Now())
+		resp, err := callClaudeStreamingWithOptions(msg.Text, msg.ChannelID, msg.ThreadTS, msg.WorkDir, config, &ClaudeStreamingOptions{RequestedBy: msg.UserID})
+		if err == nil && !resp.IsError {
+			checkpointRun(config, msg, reply)
+		}
+		recordRunTimeline(msg, resp, err, headBefore)
+		activityStore.Touch(msg.ChannelID, time.Now())
 
-		if err != nil {
-			logf("Claude error: %v", err)
-			addReaction(config, msg.ChannelID, msg.EventTS, "x")
-			removeReaction(config, msg.ChannelID, msg.EventTS, "eyes")
-			reply(fmt.Sprintf(":x: Claude error: %v", err))
+		if promptDeleted(msg) {
+			// The prompt was deleted while it ran: undo what the run posted
+			cleanupDeletedRun(config, msg)
 		} else {
-			// Success - update reactions (response already sent by streaming)
-			removeReaction(config, msg.ChannelID, msg.EventTS, "eyes")
-			addReaction(config, msg.ChannelID, msg.EventTS, "white_check_mark")
-			logf("Claude responded (session: %s, tokens: %d in / %d out)",
-				resp.SessionID, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+			// Remove hourglass if it was queued
+			for _, ts := range msg.EventTimestamps() {
+				removeReaction(config, msg.ChannelID, ts, "hourglass_flowing_sand")
+			}
+			go notifyEvent(config, pushRunDone, msg.ChannelID, runPushMessage(resp, err))
 
-			// Auto-compact if context was too long, then continue
-			if resp.NeedsCompact {
-				logf("Auto-compacting session for channel %s", msg.ChannelID)
-				compactResp, compactErr := callClaudeStreaming("/compact", msg.ChannelID, msg.ThreadTS, msg.WorkDir, config)
-				if compactErr != nil {
-					reply(fmt.Sprintf(":x: Auto-compact failed: %v", compactErr))
-				} else {
-					reply(fmt.Sprintf(":broom: *Auto-compacted!* New context: %d tokens. Continuing...", compactResp.Usage.InputTokens))
-					// Auto-continue after compact
-					continueResp, continueErr := callClaudeStreaming("continue where you left off", msg.ChannelID, msg.ThreadTS, msg.WorkDir, config)
-					if continueErr != nil {
-						reply(fmt.Sprintf(":x: Auto-continue failed: %v", continueErr))
+			if err != nil {
+				logf("Claude error: %v", err)
+				for _, ts := range msg.EventTimestamps() {
+					addReaction(config, msg.ChannelID, ts, "x")
+					removeReaction(config, msg.ChannelID, ts, "eyes")
+				}
+				errMsg := fmt.Sprintf(":x: Claude error: %v", err)
+				if mention := completionMention(config, msg.UserID, 0, true); mention != "" {
+					errMsg = mention + " " + errMsg
+				}
+				reply(errMsg)
+			} else {
+				// Success - update reactions (response already sent by streaming)
+				for _, ts := range msg.EventTimestamps() {
+					removeReaction(config, msg.ChannelID, ts, "eyes")
+					addReaction(config, msg.ChannelID, ts, "white_check_mark")
+				}
+				logf("Claude responded (session: %s, tokens: %d in / %d out)",
+					resp.SessionID, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+
+				if hasCLIRecovery(resp.CLIScreen) {
+					postCLIRecovery(config, msg, resp.CLIScreen)
+				}
+
+				if !resp.IsError {
+					maybeAutoSummarize(config, msg.ChannelID)
+				}
+
+				if msg.CacheKey != "" && !resp.IsError && !resp.NeedsCompact && resp.Result != "" {
+					resultCache.Store(msg.ChannelID, msg.CacheKey, resp.Result)
+				}
+
+				// Auto-compact if context was too long, then continue
+				if resp.NeedsCompact {
+					logf("Auto-compacting session for channel %s", msg.ChannelID)
+					compactResp, compactErr := callClaudeStreaming("/compact", msg.ChannelID, msg.ThreadTS, msg.WorkDir, config)
+					if compactErr != nil {
+						reply(fmt.Sprintf(":x: Auto-compact failed: %v", compactErr))
 					} else {
-						logf("Auto-continued after compact (tokens: %d in / %d out)",
-							continueResp.Usage.InputTokens, continueResp.Usage.OutputTokens)
+						reply(fmt.Sprintf(":broom: *Auto-compacted!* New context: %d tokens. Continuing...", compactResp.Usage.InputTokens))
+						// Auto-continue after compact
+						continueResp, continueErr := callClaudeStreaming("continue where you left off", msg.ChannelID, msg.ThreadTS, msg.WorkDir, config)
+						if continueErr != nil {
+							reply(fmt.Sprintf(":x: Auto-continue failed: %v", continueErr))
+						} else {
+							logf("Auto-continued after compact (tokens: %d in / %d out)",
+								continueResp.Usage.InputTokens, continueResp.Usage.OutputTokens)
+						}
 					}
 				}
 			}
 		}
 
+		if msg.OnFinish != nil {
+			msg.OnFinish(resp, err)
+		}
+
 		// Process next in queue
 		if next := messageQueue.Done(msg.ChannelID); next != nil {
 			logf("Processing next queued message for channel %s", msg.ChannelID)
 			// Update reaction on the queued message
-			removeReaction(config, next.ChannelID, next.EventTS, "hourglass_flowing_sand")
-			addReaction(config, next.ChannelID, next.EventTS, "eyes")
+			for _, ts := range next.EventTimestamps() {
+				removeReaction(config, next.ChannelID, ts, "hourglass_flowing_sand")
+				addReaction(config, next.ChannelID, ts, "eyes")
+			}
 			// Create reply function for the queued message
 			nextReply := func(text string) {
 				sendMessageToThread(config, next.ChannelID, next.ThreadTS, text)
@@ -1042,18 +1868,148 @@ func processClaudeMessage(msg *QueuedMessage, config *Config, reply func(string)
 	})
 }
 
-func handleBlockAction(config *Config, action BlockActionPayload) {
+func handleBlockAction(cfgMgr *ConfigManager, action BlockActionPayload) {
+	config := cfgMgr.Get()
 	// Only accept from authorized user
 	if !config.IsAuthorizedUser(action.User.ID) {
 		return
 	}
 
+	if action.Type == "view_submission" && action.View != nil && action.View.CallbackID == commandArgsCallbackID {
+		meta, commandLine, err := parseCommandModalSubmission(action.View)
+		if err != nil {
+			logf("Command modal: %v", err)
+			return
+		}
+		runCustomCommand(config, meta.ChannelID, meta.ThreadTS, action.User.ID, commandLine)
+		return
+	}
+
+	if action.Type == "view_submission" && action.View != nil && action.View.CallbackID == questionTextCallbackID {
+		handleQuestionTextSubmission(config, action)
+		return
+	}
+
 	if len(action.Actions) == 0 {
 		return
 	}
 
 	act := action.Actions[0]
 
+	// Checkbox toggles carry no value: their state is read when Submit is tapped
+	if act.Type == "checkboxes" {
+		return
+	}
+
+	// Button values are signed when posted; reject forged or stale clicks
+	if act.Value != "" {
+		value, err := verifyButtonValue(loadButtonKey(), act.Value, time.Now())
+		if errors.Is(err, errButtonExpired) {
+			logf("Expired button click: %s", act.ActionID)
+			updateMessage(config, action.Channel.ID, action.Message.TS, action.Message.Text+"\n\n:hourglass: These buttons expired - run the command again")
+			return
+		}
+		if err != nil {
+			logf("Rejected button click %s: %v", act.ActionID, err)
+			return
+		}
+		act.Value = value
+	}
+
+	// Double-taps: act once, and drop one-shot buttons right away
+	if !clickGuard.Claim(action.Channel.ID, action.Message.TS, act.ActionID, time.Now()) {
+		logf("Ignoring repeated click: %s", act.ActionID)
+		return
+	}
+	if isOneShotAction(act.ActionID) {
+		updateMessage(config, action.Channel.ID, action.Message.TS, action.Message.Text+"\n\n:hourglass_flowing_sand: _Working on it..._")
+	}
+
+	if strings.HasPrefix(act.ActionID, "stale_") {
+		handleStaleSessionAction(config, action, act)
+		return
+	}
+
+	if act.ActionID == "cache_rerun" {
+		handleCacheRerun(config, action, act)
+		return
+	}
+
+	if act.ActionID == "edit_rerun" {
+		handleEditRerun(config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "recover_") {
+		handleRecoveryAction(config, action, act)
+		return
+	}
+
+	if act.ActionID == "gh_handle" {
+		handleGitHubAction(config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "mcp_answer_") {
+		handleMCPAskAction(config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "shell_") {
+		handleShellConfirmAction(config, action, act)
+		return
+	}
+
+	if act.ActionID == "health_restart" {
+		handleHealthRestartAction(cfgMgr, config, action, act)
+		return
+	}
+
+	if act.ActionID == "progress_cancel" {
+		handleProgressCancelAction(config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "queue_cancel_") {
+		handleQueueCancelAction(config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "idle_archive_") {
+		handleIdleArchiveAction(cfgMgr, config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "purge_") {
+		handlePurgeAction(cfgMgr, config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "slash_") {
+		runSlashCommandButton(config, action, act.Value)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "option_") {
+		handleQuestionAnswer(config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "qtoggle_") {
+		handleQuestionToggle(config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "qsubmit_") {
+		handleQuestionSubmit(config, action, act)
+		return
+	}
+
+	if strings.HasPrefix(act.ActionID, "qtext_") {
+		openQuestionTextModal(config, action, act)
+		return
+	}
+
 	// Update message to show selection
 	originalText := action.Message.Text
 	newText := fmt.Sprintf("%s\n\n:white_check_mark: Selected option", originalText)
@@ -1061,30 +2017,113 @@ func handleBlockAction(config *Config, action BlockActionPayload) {
 	logf("Button clicked: %s (value: %s)", act.ActionID, act.Value)
 }
 
+// runSlashCommandButton runs a custom command tapped in the !slash list catalog,
+// asking for its arguments in a modal first when it takes any
+func runSlashCommandButton(config *Config, action BlockActionPayload, name string) {
+	channelID := action.Channel.ID
+	sessionName := getSessionByChannel(config, channelID)
+	if sessionName == "" {
+		sendMessage(config, channelID, ":x: This channel is no longer a session")
+		return
+	}
+
+	workDir := sessionWorkDir(config, sessionName)
+	if cmd, ok := findClaudeCommand(workDir, name); ok && cmd.TakesArgs && action.TriggerID != "" {
+		if err := openCommandArgsModal(config, action.TriggerID, cmd, channelID, action.Message.TS); err != nil {
+			logf("Failed to open arguments modal for /%s: %v", name, err)
+			sendMessageToThread(config, channelID, action.Message.TS, fmt.Sprintf(":x: Could not open arguments form: %v\nType `//%s <args>` instead.", err, name))
+		}
+		return
+	}
+
+	runCustomCommand(config, channelID, action.Message.TS, action.User.ID, "/"+name)
+}
+
+// runCustomCommand sends a composed slash command line to the channel's session
+func runCustomCommand(config *Config, channelID, threadTS, userID, commandLine string) {
+	sessionName := getSessionByChannel(config, channelID)
+	if sessionName == "" {
+		sendMessage(config, channelID, ":x: This channel is no longer a session")
+		return
+	}
+	logf("Custom command: %s in %s", commandLine, sessionName)
+
+	msg := &QueuedMessage{
+		Text:      commandLine,
+		ChannelID: channelID,
+		ThreadTS:  threadTS,
+		EventTS:   threadTS,
+		UserID:    userID,
+		WorkDir:   sessionWorkDir(config, sessionName),
+	}
+	addReaction(config, channelID, msg.EventTS, "eyes")
+	sendMessageToThread(config, channelID, msg.ThreadTS, fmt.Sprintf(":zap: Running `%s`", commandLine))
+	submitClaudeMessage(msg, config)
+}
+
 func printHelp() {
 	fmt.Printf(`claude-code-slack-anywhere v%s
 
 Control Claude Code remotely via Slack.
 
 COMMANDS:
-    setup <bot> <app>       Complete setup (tokens, hook, service)
-    doctor                  Check all dependencies and configuration
+    setup                   Browser-based OAuth setup (app, tokens, hook, service)
+    setup <bot> <app>       Manual setup with existing tokens
+    doctor [--fix]          Check all dependencies and configuration
+        --fix                 Repair what can be fixed (hook, service, ~/bin link, config)
+    manifest [options]      Print the Slack app manifest
+        --output <file>       Write the manifest to a file
+        --create              Create the app via apps.manifest.create
+        --config-token <tok>  App configuration token (xoxe...) for --create
+    service <action>        Manage the installed launchd/systemd service:
+                            start, stop, restart, status, logs [-f] [-n <lines>]
     listen [options]        Start the Slack bot listener manually
-        --config <path>       Path to config file (default: ~/.ccsa.json)
-        --projects-dir <path> Base directory for projects
-        --bot-token <token>   Slack bot token (xoxb-...)
-        --app-token <token>   Slack app token (xapp-...)
-        --user-ids <ids>      Authorized Slack user IDs (comma-separated)
+        --config <path>       Path to config file (default: ~/.ccsa.json, env CCSA_CONFIG)
+        --projects-dir <path> Base directory for projects (env CCSA_PROJECTS_DIR)
+        --bot-token <token>   Slack bot token (xoxb-..., env CCSA_BOT_TOKEN)
+        --app-token <token>   Slack app token (xapp-..., env CCSA_APP_TOKEN)
+        --user-ids <ids>      Authorized Slack user IDs (comma-separated, env CCSA_USER_IDS)
+    agent [options]         Run Claude for a listener on another machine
+        --primary <url>       Listener agent URL (ws://host:7411/agent)
+        --token <token>       Shared agent_token from the listener's config
+        --name <name>         Agent name used by !new --host (default: hostname)
+        --projects-dir <path> Base directory for session folders on this machine
+    share <path> [comment]  Upload a file to the current session's channel
+    send <session> <msg>    Send a prompt to a session's Claude from anywhere (stdin if no msg)
+        --channel <id>        Post the message to a channel instead (no Claude run)
+    top                     Live view of sessions: runs, queues, activity, usage; attach, cancel, tail
+    web [--addr host:port]  Local web dashboard: live output, usage graphs, send/cancel/reset
+    mcp                     MCP stdio server giving Claude slack_post, slack_ask_user and
+                            slack_upload_file for its session's channel (run by Claude)
+    gc [--yes]              Find orphaned state (sessions of archived channels or deleted
+                            folders, old uploads, leftover "Working..." messages) and clean it
+    keychain migrate        Move tokens from the config file to the macOS Keychain /
+                            Secret Service (libsecret), leaving references in the file
     install                 Install Claude hook manually
     hook                    Handle Claude hook (internal)
 
 SLACK COMMANDS (in any channel):
     !ping                   Check if bot is alive
-    !new <name>             Create new session with channel
+    !new <name>             Create new session with channel (--host <agent> for a remote one,
+                            --sandbox to run Claude in Docker)
     !kill                   Remove current session
-    !list                   List active sessions
+    !kill --purge           Remove it with its settings (and folder)
+    !rename <old> <new>     Rename a session and its channel (--move renames the folder too)
+    !list [--tag <tag>]     List active sessions (with a tag)
+    !list --idle <7d>       List sessions unused for a while
+    !tag add|rm <tag>       Tag the current session
     !reset                  Reset conversation context
+    !status                 Session health: run, queue, usage, workdir, branch
+    !timeline [days]        Session history: prompts, runs, commits, costs
+    !output [lines]         End of a PTY session's terminal
+    !keys <keys>            Keystrokes for a PTY session (down enter, esc...)
+    !esc                    Escape for a PTY session (!keys esc)
     !c <cmd>                Execute shell command
+    !queue                  Show queued messages with Cancel buttons
+    !urgent <prompt>        Queue a prompt ahead of the others
+    !sysprompt set <text>   Extra system prompt instructions for the channel
+    !aliases                List the command aliases from the config
+    !broadcast <s1,s2|all> <prompt>  Run a prompt in several sessions (admins)
 
 FLAGS:
     -h, --help              Show this help
@@ -1109,27 +2148,51 @@ func main() {
 		return
 
 	case "setup":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: claude-code-slack-anywhere setup <bot_token> <app_token>")
-			fmt.Println()
-			fmt.Println("Get tokens from your Slack App:")
-			fmt.Println("  1. Create app at https://api.slack.com/apps")
-			fmt.Println("  2. Enable Socket Mode (get App Token: xapp-...)")
-			fmt.Println("  3. Add Bot Token Scopes: channels:manage, channels:history,")
-			fmt.Println("     chat:write, users:read")
-			fmt.Println("  4. Install to workspace (get Bot Token: xoxb-...)")
-			os.Exit(1)
+		// setup <bot_token> <app_token> - manual token setup
+		if len(os.Args) >= 4 && !strings.HasPrefix(os.Args[2], "--") {
+			if err := setup(os.Args[2], os.Args[3]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
 		}
-		if err := setup(os.Args[2], os.Args[3]); err != nil {
+
+		// setup [--client-id <id>] [--client-secret <secret>] - OAuth flow
+		var clientID, clientSecret string
+		for i := 2; i < len(os.Args); i++ {
+			if os.Args[i] == "--client-id" && i+1 < len(os.Args) {
+				clientID = os.Args[i+1]
+				i++
+			} else if os.Args[i] == "--client-secret" && i+1 < len(os.Args) {
+				clientSecret = os.Args[i+1]
+				i++
+			} else if os.Args[i] == "-h" || os.Args[i] == "--help" {
+				fmt.Println("Usage:")
+				fmt.Println("  claude-code-slack-anywhere setup                      Browser-based OAuth setup")
+				fmt.Println("  claude-code-slack-anywhere setup <bot_token> <app_token>  Manual token setup")
+				fmt.Println()
+				fmt.Println("OAuth options:")
+				fmt.Println("  --client-id <id>          App client ID (skip app creation prompt)")
+				fmt.Println("  --client-secret <secret>  App client secret")
+				return
+			}
+		}
+		if err := setupOAuth(clientID, clientSecret); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
 	case "doctor":
-		doctor()
+		doctor(len(os.Args) > 2 && os.Args[2] == "--fix")
+
+	case "manifest":
+		if err := runManifestCommand(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
 	case "listen":
-		var opts listenOpts
+		var opts configOverrides
 		for i := 2; i < len(os.Args); i++ {
 			if os.Args[i] == "--projects-dir" && i+1 < len(os.Args) {
 				opts.projectsDir = os.Args[i+1]
@@ -1144,11 +2207,33 @@ func main() {
 				opts.appToken = os.Args[i+1]
 				i++
 			} else if os.Args[i] == "--user-ids" && i+1 < len(os.Args) {
-				opts.userIDs = strings.Split(os.Args[i+1], ",")
+				opts.userIDs = splitUserIDs(os.Args[i+1])
+				i++
+			}
+		}
+		if err := listen(resolveConfigOverrides(opts)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "agent":
+		var opts agentOpts
+		for i := 2; i < len(os.Args); i++ {
+			if os.Args[i] == "--primary" && i+1 < len(os.Args) {
+				opts.primary = os.Args[i+1]
+				i++
+			} else if os.Args[i] == "--name" && i+1 < len(os.Args) {
+				opts.name = os.Args[i+1]
+				i++
+			} else if os.Args[i] == "--token" && i+1 < len(os.Args) {
+				opts.token = os.Args[i+1]
+				i++
+			} else if os.Args[i] == "--projects-dir" && i+1 < len(os.Args) {
+				opts.projectsDir = os.Args[i+1]
 				i++
 			}
 		}
-		if err := listen(opts); err != nil {
+		if err := runAgent(opts); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -1183,6 +2268,59 @@ func main() {
 			os.Exit(1)
 		}
 
+	case "share":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: claude-code-slack-anywhere share <path> [comment]")
+			os.Exit(1)
+		}
+		comment := strings.Join(os.Args[3:], " ")
+		if err := shareFile(os.Args[2], comment); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "top":
+		if err := runTop(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "web":
+		if err := runWeb(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "mcp":
+		if err := runMCP(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "gc":
+		if err := runGC(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "service":
+		if err := runServiceCommand(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "keychain":
+		if err := runKeychain(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "send":
+		if err := runSendCommand(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 	case "install":
 		if err := installHook(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -1199,24 +2337,53 @@ func main() {
 
 		// Find session channel for current directory
 		cwd, _ := os.Getwd()
-		baseDir := getProjectsDir(config)
 		message := strings.Join(os.Args[1:], " ")
 
-		for name, channelID := range config.Sessions {
-			expectedPath := filepath.Join(baseDir, name)
-			if cwd == expectedPath || strings.HasSuffix(cwd, "/"+name) {
-				if _, err := sendMessage(config, channelID, message); err != nil {
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-					os.Exit(1)
-				}
-				return
+		if _, channelID := findSessionForCwd(config, cwd); channelID != "" {
+			if _, err := sendMessage(config, channelID, message); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
+			return
 		}
 
 		fmt.Println("Not in a session directory, notification not sent.")
 	}
 }
 
+// shareFile uploads a file to the session channel matching the current directory
+func shareFile(path, comment string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("not configured. Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+
+	cwd, _ := os.Getwd()
+	sessionName, channelID := findSessionForCwd(config, cwd)
+	if channelID == "" {
+		return fmt.Errorf("not in a session directory (cwd: %s)", cwd)
+	}
+
+	permalink, err := uploadFile(config, channelID, "", absPath, comment)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Shared %s to session %s %s\n", filepath.Base(absPath), sessionName, permalink)
+	return nil
+}
+
 // newRequest is a helper to create HTTP requests
 func newRequest(method, urlStr string, body interface{}) (*http.Request, error) {
 	return http.NewRequest(method, urlStr, nil)
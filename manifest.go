@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// botScopes are the OAuth bot scopes the listener needs
+var botScopes = []string{
+	"bookmarks:write",
+	"channels:history",
+	"channels:manage",
+	"channels:read",
+	"chat:write",
+	"commands",
+	"files:read",
+	"files:write",
+	"groups:history",
+	"groups:read",
+	"groups:write",
+	"im:history",
+	"pins:read",
+	"pins:write",
+	"reactions:read",
+	"reactions:write",
+	"users:read",
+}
+
+// scopePurposes explains what breaks without each bot scope
+var scopePurposes = map[string]string{
+	"bookmarks:write":  "bookmark the repo, folder and CI in session channels",
+	"channels:history": "receive messages in channels",
+	"channels:manage":  "create and archive session channels (!new, !kill)",
+	"channels:read":    "find channels by name",
+	"chat:write":       "post replies",
+	"commands":         "the /ccsa slash command",
+	"files:read":       "download uploaded files",
+	"files:write":      "upload snippets and shared files",
+	"groups:history":   "receive messages in private session channels",
+	"groups:read":      "find private channels by name",
+	"groups:write":     "create private session channels and invite users to them",
+	"im:history":       "receive DMs (setup user detection)",
+	"pins:read":        "check existing pins",
+	"pins:write":       "pin the GitHub repo link",
+	"reactions:read":   "read status reactions",
+	"reactions:write":  "add status reactions",
+	"users:read":       "resolve @mentions to names",
+}
+
+// missingBotScopes returns the required bot scopes absent from granted
+func missingBotScopes(granted []string) []string {
+	have := make(map[string]bool)
+	for _, scope := range granted {
+		have[strings.TrimSpace(scope)] = true
+	}
+	var missing []string
+	for _, scope := range botScopes {
+		if !have[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// slackAppManifest returns the Slack app manifest for the bot.
+// redirectURL is added to the OAuth redirect URLs when non-empty.
+func slackAppManifest(redirectURL string) map[string]interface{} {
+	oauth := map[string]interface{}{
+		"scopes": map[string]interface{}{
+			"bot": botScopes,
+		},
+	}
+	if redirectURL != "" {
+		oauth["redirect_urls"] = []string{redirectURL}
+	}
+
+	return map[string]interface{}{
+		"display_information": map[string]interface{}{
+			"name":        "claudeslack",
+			"description": "Control Claude Code remotely via Slack",
+		},
+		"features": map[string]interface{}{
+			"bot_user": map[string]interface{}{
+				"display_name":  "claudeslack",
+				"always_online": true,
+			},
+			"app_home": map[string]interface{}{
+				"messages_tab_enabled":           true,
+				"messages_tab_read_only_enabled": false,
+			},
+			"slash_commands": []map[string]interface{}{
+				{
+					"command":       slashCommandName,
+					"description":   "Run a claudeslack command (e.g. /ccsa sessions)",
+					"usage_hint":    "<command> [args]",
+					"should_escape": false,
+				},
+			},
+		},
+		"oauth_config": oauth,
+		"settings": map[string]interface{}{
+			"event_subscriptions": map[string]interface{}{
+				"bot_events": []string{
+					"message.channels", "message.groups", "message.im",
+					// Keep the channel name cache current (see channeldir.go)
+					"channel_created", "channel_rename", "channel_deleted", "channel_archive", "channel_unarchive",
+					"group_rename", "group_deleted", "group_archive", "group_unarchive",
+				},
+			},
+			"interactivity": map[string]interface{}{
+				"is_enabled": true,
+			},
+			"org_deploy_enabled":     false,
+			"socket_mode_enabled":    true,
+			"token_rotation_enabled": false,
+		},
+	}
+}
+
+// slashCommandName is the slash command registered by the manifest.
+// It maps to the ! commands: "/ccsa sessions" == "!sessions".
+const slashCommandName = "/ccsa"
+
+// slackAppManifestJSON renders the manifest as indented JSON
+func slackAppManifestJSON(redirectURL string) string {
+	data, _ := json.MarshalIndent(slackAppManifest(redirectURL), "", "  ")
+	return string(data)
+}
+
+// slackCreateAppURL returns the api.slack.com URL that pre-fills a new app from the manifest
+func slackCreateAppURL(redirectURL string) string {
+	data, _ := json.Marshal(slackAppManifest(redirectURL))
+	return "https://api.slack.com/apps?new_app=1&manifest_json=" + url.QueryEscape(string(data))
+}
+
+// botScopeString returns the comma-separated bot scopes for the authorize URL
+func botScopeString() string {
+	return strings.Join(botScopes, ",")
+}
+
+// createSlackApp creates the app from the manifest via apps.manifest.create
+// using an app configuration token (xoxe.xoxp-...)
+func createSlackApp(configToken string) (*ManifestCreateResult, error) {
+	manifest, err := json.Marshal(slackAppManifest(oauthRedirectURL))
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{"manifest": {string(manifest)}}
+
+	req, err := http.NewRequest("POST", "https://slack.com/api/apps.manifest.create", strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+configToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result ManifestCreateResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if !result.OK {
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("apps.manifest.create failed: %s (%s: %s)", result.Error, result.Errors[0].Pointer, result.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("apps.manifest.create failed: %s", result.Error)
+	}
+	return &result, nil
+}
+
+// ManifestCreateResult is the apps.manifest.create response
+type ManifestCreateResult struct {
+	OK          bool   `json:"ok"`
+	Error       string `json:"error"`
+	AppID       string `json:"app_id"`
+	Credentials struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	} `json:"credentials"`
+	OAuthAuthorizeURL string `json:"oauth_authorize_url"`
+	Errors            []struct {
+		Message string `json:"message"`
+		Pointer string `json:"pointer"`
+	} `json:"errors"`
+}
+
+// runManifestCommand implements "manifest [--create --config-token <xoxe...>] [--output <file>]"
+func runManifestCommand(args []string) error {
+	var create bool
+	var configToken, output string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--create":
+			create = true
+		case args[i] == "--config-token" && i+1 < len(args):
+			configToken = args[i+1]
+			i++
+		case (args[i] == "--output" || args[i] == "-o") && i+1 < len(args):
+			output = args[i+1]
+			i++
+		}
+	}
+
+	if !create {
+		manifest := slackAppManifestJSON(oauthRedirectURL)
+		if output != "" {
+			if err := os.WriteFile(output, []byte(manifest+"\n"), 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Manifest written to %s\n", output)
+			fmt.Println("Create the app at https://api.slack.com/apps > Create New App > From a manifest")
+			return nil
+		}
+		fmt.Println(manifest)
+		return nil
+	}
+
+	if configToken == "" {
+		return fmt.Errorf("--create needs --config-token (generate one at https://api.slack.com/apps > Your App Configuration Tokens)")
+	}
+	result, err := createSlackApp(configToken)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("App created: %s\n", result.AppID)
+	fmt.Printf("  Client ID:     %s\n", result.Credentials.ClientID)
+	fmt.Printf("  Client Secret: %s\n", result.Credentials.ClientSecret)
+	fmt.Println()
+	fmt.Println("Next: claude-code-slack-anywhere setup --client-id <id> --client-secret <secret>")
+	return nil
+}
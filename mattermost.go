@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// mattermostActionsPath is where Mattermost posts button clicks
+const mattermostActionsPath = "/mattermost/actions"
+
+// MattermostConfig connects the bot to a self-hosted Mattermost. Mattermost
+// has no Socket Mode: events come over its own WebSocket, and buttons call
+// back an HTTP endpoint the Mattermost server must reach.
+type MattermostConfig struct {
+	// URL is the server's address ("https://chat.example.com")
+	URL string `json:"url"`
+	// Token is a bot account's access token
+	Token string `json:"token"`
+	// UserIDs are the Mattermost users allowed to talk to Claude
+	UserIDs []string `json:"user_ids,omitempty"`
+	// ActionsListen serves button clicks (e.g. ":7413"); empty = no buttons
+	ActionsListen string `json:"actions_listen,omitempty"`
+	// ActionsURL is that endpoint as the Mattermost server sees it
+	// ("http://bot-host:7413/mattermost/actions")
+	ActionsURL string `json:"actions_url,omitempty"`
+}
+
+// isMattermostID reports whether a channel ID is a Mattermost one
+func isMattermostID(id string) bool {
+	if len(id) != 26 {
+		return false
+	}
+	for _, r := range id {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// slackToMattermost converts Slack mrkdwn to Markdown; Mattermost renders
+// Slack's emoji shortcodes itself
+func slackToMattermost(text string) string {
+	return convertMrkdwn(text, markdownProse)
+}
+
+// mattermostRequest performs one REST call (API v4)
+func mattermostRequest(config *Config, method, path, contentType string, body []byte, out interface{}) error {
+	mm := config.Mattermost
+	if mm == nil || mm.URL == "" || mm.Token == "" {
+		return errors.New("mattermost is not configured")
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(mm.URL, "/")+"/api/v4"+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+mm.Token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return &mattermostAPIError{Status: resp.StatusCode, Message: apiErr.Message}
+	}
+	if out != nil && len(data) > 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// mattermostJSON performs a REST call with a JSON body
+func mattermostJSON(config *Config, method, path string, payload, out interface{}) error {
+	var body []byte
+	contentType := ""
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	return mattermostRequest(config, method, path, contentType, body, out)
+}
+
+// mattermostAPIError is a non-2xx answer of the REST API
+type mattermostAPIError struct {
+	Status  int
+	Message string
+}
+
+func (e *mattermostAPIError) Error() string {
+	return fmt.Sprintf("mattermost error %d: %s", e.Status, e.Message)
+}
+
+// isMattermostNotFound reports whether err is a 404
+func isMattermostNotFound(err error) bool {
+	var apiErr *mattermostAPIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// mattermostPost is the part of a post the bridge reads
+type mattermostPost struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	UserID    string `json:"user_id"`
+	RootID    string `json:"root_id"`
+	Message   string `json:"message"`
+	Type      string `json:"type"`
+}
+
+var mattermostSelf struct {
+	sync.Mutex
+	id string
+}
+
+// mattermostBotID returns the bot account's user ID
+func mattermostBotID(config *Config) (string, error) {
+	mattermostSelf.Lock()
+	defer mattermostSelf.Unlock()
+	if mattermostSelf.id != "" {
+		return mattermostSelf.id, nil
+	}
+	var me struct {
+		ID string `json:"id"`
+	}
+	if err := mattermostJSON(config, "GET", "/users/me", nil, &me); err != nil {
+		return "", err
+	}
+	mattermostSelf.id = me.ID
+	return me.ID, nil
+}
+
+// mattermostChat is the Mattermost ChatBackend (REST API v4, events over its WebSocket)
+type mattermostChat struct{}
+
+func (mattermostChat) Name() string { return "mattermost" }
+
+func (mattermostChat) MaxMessageLen() int { return 4000 }
+
+// createMattermostPost posts a message (threadTS "" = in the channel)
+func createMattermostPost(config *Config, channelID, threadTS string, post map[string]interface{}) (string, error) {
+	post["channel_id"] = channelID
+	if threadTS != "" {
+		post["root_id"] = threadTS
+	}
+	var created mattermostPost
+	if err := mattermostJSON(config, "POST", "/posts", post, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (mattermostChat) PostMessage(config *Config, channelID, threadTS, text string) (string, error) {
+	return createMattermostPost(config, channelID, threadTS, map[string]interface{}{
+		"message": slackToMattermost(text),
+	})
+}
+
+func (mattermostChat) UpdateMessage(config *Config, channelID, ts, text string) error {
+	return mattermostJSON(config, "PUT", "/posts/"+ts+"/patch", map[string]interface{}{
+		"message": slackToMattermost(text),
+		"props":   map[string]interface{}{},
+	}, nil)
+}
+
+func (mattermostChat) DeleteMessage(config *Config, channelID, ts string) error {
+	err := mattermostJSON(config, "DELETE", "/posts/"+ts, nil, nil)
+	if isMattermostNotFound(err) {
+		return nil // already deleted
+	}
+	return err
+}
+
+func (mattermostChat) AddReaction(config *Config, channelID, ts, emoji string) error {
+	botID, err := mattermostBotID(config)
+	if err != nil {
+		return err
+	}
+	return mattermostJSON(config, "POST", "/reactions", map[string]string{
+		"user_id":    botID,
+		"post_id":    ts,
+		"emoji_name": emoji,
+	}, nil)
+}
+
+func (mattermostChat) RemoveReaction(config *Config, channelID, ts, emoji string) error {
+	botID, err := mattermostBotID(config)
+	if err != nil {
+		return err
+	}
+	err = mattermostJSON(config, "DELETE", "/users/"+botID+"/posts/"+ts+"/reactions/"+emoji, nil, nil)
+	if isMattermostNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// mattermostNoButtons logs once that buttons can't be shown
+var mattermostNoButtons sync.Once
+
+// mattermostProps holds buttons as an interactive message attachment; nil
+// without actions_url, since Mattermost could not deliver the clicks
+func mattermostProps(config *Config, buttons []Element, blockID string) map[string]interface{} {
+	if config.Mattermost == nil || config.Mattermost.ActionsURL == "" {
+		mattermostNoButtons.Do(func() {
+			logf("Mattermost: buttons dropped - set mattermost.actions_listen and actions_url to enable them")
+		})
+		return nil
+	}
+	var actions []map[string]interface{}
+	for i, btn := range buttons {
+		if btn.Type != "button" || btn.Text == nil {
+			continue // checkboxes have no Mattermost counterpart
+		}
+		style := btn.Style
+		if style == "" {
+			style = "default"
+		}
+		actions = append(actions, map[string]interface{}{
+			"id":    fmt.Sprintf("b%d", i),
+			"name":  unescapeMrkdwn(btn.Text.Text),
+			"style": style,
+			"integration": map[string]interface{}{
+				"url":     config.Mattermost.ActionsURL,
+				"context": map[string]string{"button": rememberButton(btn, blockID)},
+			},
+		})
+	}
+	return map[string]interface{}{"attachments": []map[string]interface{}{{"actions": actions}}}
+}
+
+func (mattermostChat) PostButtons(config *Config, channelID, threadTS, text string, buttons []Element, blockID string) (string, error) {
+	post := map[string]interface{}{"message": slackToMattermost(text)}
+	if props := mattermostProps(config, buttons, blockID); props != nil {
+		post["props"] = props
+	}
+	return createMattermostPost(config, channelID, threadTS, post)
+}
+
+func (mattermostChat) UpdateButtons(config *Config, channelID, ts, text string, buttons []Element, blockID string) error {
+	props := mattermostProps(config, buttons, blockID)
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	return mattermostJSON(config, "PUT", "/posts/"+ts+"/patch", map[string]interface{}{
+		"message": slackToMattermost(text),
+		"props":   props,
+	}, nil)
+}
+
+// uploadMattermost attaches data to a new post and returns the post's permalink
+func uploadMattermost(config *Config, channelID, threadTS, filename string, data []byte, comment string) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("channel_id", channelID)
+	part, err := w.CreateFormFile("files", filename)
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	w.Close()
+
+	var uploaded struct {
+		FileInfos []struct {
+			ID string `json:"id"`
+		} `json:"file_infos"`
+	}
+	if err := mattermostRequest(config, "POST", "/files", w.FormDataContentType(), body.Bytes(), &uploaded); err != nil {
+		return "", err
+	}
+	if len(uploaded.FileInfos) == 0 {
+		return "", errors.New("mattermost returned no file")
+	}
+	postID, err := createMattermostPost(config, channelID, threadTS, map[string]interface{}{
+		"message":  slackToMattermost(comment),
+		"file_ids": []string{uploaded.FileInfos[0].ID},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(config.Mattermost.URL, "/") + "/_redirect/pl/" + postID, nil
+}
+
+func (mattermostChat) UploadSnippet(config *Config, channelID, threadTS, filename, content, title string) (string, error) {
+	return uploadMattermost(config, channelID, threadTS, filename, []byte(content), "Full output:")
+}
+
+func (mattermostChat) UploadFile(config *Config, channelID, threadTS, filePath, comment string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return uploadMattermost(config, channelID, threadTS, filepath.Base(filePath), data, comment)
+}
+
+// ChannelName returns the channel's URL name, which follows Slack's naming
+func (mattermostChat) ChannelName(config *Config, channelID string) (string, error) {
+	var channel struct {
+		Name string `json:"name"`
+	}
+	if err := mattermostJSON(config, "GET", "/channels/"+channelID, nil, &channel); err != nil {
+		return "", err
+	}
+	return channel.Name, nil
+}
+
+// ArchiveChannel archives the channel (Mattermost's DELETE keeps its history)
+func (mattermostChat) ArchiveChannel(config *Config, channelID string) error {
+	return mattermostJSON(config, "DELETE", "/channels/"+channelID, nil, nil)
+}
+
+// Listen serves button clicks (actions_listen) and follows the WebSocket,
+// reconnecting with backoff until ctx is done
+func (mattermostChat) Listen(ctx context.Context, cfgMgr *ConfigManager) {
+	if addr := cfgMgr.Get().Mattermost.ActionsListen; addr != "" {
+		go serveMattermostActions(ctx, cfgMgr, addr)
+	}
+	attempt := 0
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := connectMattermost(ctx, cfgMgr)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > reconnectMaxDelay {
+			attempt = 0
+		}
+		delay := reconnectDelay(attempt, rand.Float64())
+		attempt++
+		logf("Mattermost WebSocket error: %v (reconnecting in %v, attempt %d)", err, delay.Round(100*time.Millisecond), attempt)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+}
+
+// mattermostWebSocketURL turns the server URL into its WebSocket endpoint
+func mattermostWebSocketURL(serverURL string) string {
+	u := strings.TrimSuffix(serverURL, "/") + "/api/v4/websocket"
+	if rest, ok := strings.CutPrefix(u, "https://"); ok {
+		return "wss://" + rest
+	}
+	return "ws://" + strings.TrimPrefix(u, "http://")
+}
+
+// connectMattermost follows one WebSocket connection, handing new posts to
+// handleSlackEvent as Slack message events
+func connectMattermost(ctx context.Context, cfgMgr *ConfigManager) error {
+	config := cfgMgr.Get()
+	mm := config.Mattermost
+	if mm == nil || mm.URL == "" || mm.Token == "" {
+		return errors.New("mattermost is not configured")
+	}
+	botID, err := mattermostBotID(config)
+	if err != nil {
+		return err
+	}
+
+	wsConfig, err := websocket.NewConfig(mattermostWebSocketURL(mm.URL), mm.URL)
+	if err != nil {
+		return err
+	}
+	wsConfig.Header.Set("Authorization", "Bearer "+mm.Token)
+	ws, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+	logf("Mattermost WebSocket connected")
+
+	for {
+		var frame struct {
+			Event string `json:"event"`
+			Data  struct {
+				Post string `json:"post"`
+			} `json:"data"`
+		}
+		if err := websocket.JSON.Receive(ws, &frame); err != nil {
+			return err
+		}
+		if frame.Event != "posted" {
+			continue
+		}
+		var post mattermostPost
+		if err := json.Unmarshal([]byte(frame.Data.Post), &post); err != nil || post.Type != "" || post.Message == "" {
+			continue // system messages
+		}
+		event := map[string]string{
+			"type":      "message",
+			"channel":   post.ChannelID,
+			"user":      post.UserID,
+			"text":      post.Message,
+			"ts":        post.ID,
+			"thread_ts": post.RootID,
+		}
+		if post.UserID == botID {
+			event["bot_id"] = botID
+		}
+		data, _ := json.Marshal(event)
+		workerPool.Submit(func() {
+			handleSlackEvent(ctx, cfgMgr, data)
+		})
+	}
+}
+
+// serveMattermostActions receives button clicks from the Mattermost server
+func serveMattermostActions(ctx context.Context, cfgMgr *ConfigManager, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(mattermostActionsPath, func(w http.ResponseWriter, r *http.Request) {
+		var click struct {
+			UserID    string `json:"user_id"`
+			ChannelID string `json:"channel_id"`
+			PostID    string `json:"post_id"`
+			Context   struct {
+				Button string `json:"button"`
+			} `json:"context"`
+		}
+		if r.Method != http.MethodPost || json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&click) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+
+		go func() {
+			config := cfgMgr.Get()
+			var post mattermostPost
+			if err := mattermostJSON(config, "GET", "/posts/"+click.PostID, nil, &post); err != nil {
+				logf("Mattermost action: %v", err)
+				return
+			}
+			btn, ok := recallButton(click.Context.Button)
+			if !ok {
+				mattermostChat{}.UpdateMessage(config, click.ChannelID, click.PostID, post.Message+"\n\n:hourglass: These buttons expired - run the command again")
+				return
+			}
+			action := chatButtonAction(click.ChannelID, click.UserID, click.PostID, post.Message, btn)
+			action.Message.ThreadTS = post.RootID
+			handleBlockAction(cfgMgr, action)
+		}()
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	logf("Mattermost actions listening on %s%s", addr, mattermostActionsPath)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logf("Mattermost actions server: %v", err)
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mcpProtocolVersion = "2024-11-05"
+	mcpAskDefault      = 10 * time.Minute // how long slack_ask_user waits by default
+	mcpAskMax          = time.Hour
+	mcpAskPoll         = time.Second
+	mcpAskMaxOptions   = 5
+)
+
+// mcpRequest is a JSON-RPC 2.0 request or notification (no ID)
+type mcpRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// mcpResponse is a JSON-RPC 2.0 response
+type mcpResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *mcpError       `json:"error,omitempty"`
+}
+
+type mcpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool describes a tool in tools/list
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// mcpTools are the tools Claude gets, all scoped to the session's channel
+var mcpTools = []mcpTool{
+	{
+		Name:        "slack_post",
+		Description: "Post a message to the Slack channel of this session (in the thread of the current task). Use it for progress worth a human's attention mid-task; the final answer is posted anyway. Slack mrkdwn: *bold*, _italic_, `code`.",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string", "description": "Message text"}},
+			"required":   []string{"text"},
+		},
+	},
+	{
+		Name:        "slack_ask_user",
+		Description: "Ask the user a question in Slack and wait for the answer (a tapped option or a reply in the thread). Use it when you are blocked on a decision only the user can make. Returns the answer, or a note that nobody answered in time.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"question":        map[string]interface{}{"type": "string", "description": "The question"},
+				"options":         map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Up to 5 suggested answers shown as buttons (the user can still reply freely)"},
+				"timeout_seconds": map[string]interface{}{"type": "integer", "description": "How long to wait (default 600, max 3600)"},
+			},
+			"required": []string{"question"},
+		},
+	},
+	{
+		Name:        "slack_upload_file",
+		Description: "Upload a file (screenshot, report, build artifact) to the Slack channel of this session.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path":    map[string]interface{}{"type": "string", "description": "File path, relative to the project folder or absolute"},
+				"comment": map[string]interface{}{"type": "string", "description": "Optional message posted with the file"},
+			},
+			"required": []string{"path"},
+		},
+	},
+}
+
+// mcpAsk is a question slack_ask_user is waiting on, shared with the listener
+// through ~/.ccsa/asks/<id>.json: the listener fills in the answer
+type mcpAsk struct {
+	ID         string    `json:"id"`
+	ChannelID  string    `json:"channel_id"`
+	ThreadTS   string    `json:"thread_ts,omitempty"` // run thread the question was posted in
+	TS         string    `json:"ts"`                  // the question message
+	Question   string    `json:"question"`
+	Options    []string  `json:"options,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Answer     string    `json:"answer,omitempty"`
+	AnsweredAt time.Time `json:"answered_at,omitempty"`
+}
+
+// getMCPAskDir returns the folder of pending slack_ask_user questions
+func getMCPAskDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "asks")
+}
+
+// saveMCPAsk writes a question (aside, then renamed in place)
+func saveMCPAsk(dir string, ask *mcpAsk) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ask)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "."+ask.ID+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, ask.ID+".json"))
+}
+
+func loadMCPAsk(dir, id string) (*mcpAsk, error) {
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var ask mcpAsk
+	if err := json.Unmarshal(data, &ask); err != nil {
+		return nil, err
+	}
+	return &ask, nil
+}
+
+// mcpAskMu serializes the listener's answers (a tap and a reply at once)
+var mcpAskMu sync.Mutex
+
+// answerMCPAsk records the answer of the first open question that match
+// accepts; false if there is none
+func answerMCPAsk(dir string, match func(ask *mcpAsk) bool, answer func(ask *mcpAsk) string) (*mcpAsk, bool) {
+	mcpAskMu.Lock()
+	defer mcpAskMu.Unlock()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		ask, err := loadMCPAsk(dir, strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil || ask.Answer != "" || !match(ask) {
+			continue
+		}
+		// Questions outlive their MCP server only if it crashed
+		if time.Since(ask.CreatedAt) > mcpAskMax+time.Minute {
+			os.Remove(file)
+			continue
+		}
+		ask.Answer, ask.AnsweredAt = answer(ask), time.Now()
+		if err := saveMCPAsk(dir, ask); err != nil {
+			logf("MCP ask: failed to save answer: %v", err)
+			return nil, false
+		}
+		return ask, true
+	}
+	return nil, false
+}
+
+// answerMCPAskFromThread answers an open question with a thread reply: replies
+// to the question itself, or in the run thread it was posted in
+func answerMCPAskFromThread(config *Config, channelID, threadTS, ts, text string) bool {
+	if threadTS == "" || text == "" || strings.HasPrefix(text, "!") {
+		return false
+	}
+	_, ok := answerMCPAsk(getMCPAskDir(), func(ask *mcpAsk) bool {
+		return ask.ChannelID == channelID && (threadTS == ask.TS || (ask.ThreadTS != "" && threadTS == ask.ThreadTS))
+	}, func(*mcpAsk) string { return text })
+	if ok {
+		logf("MCP ask answered by a reply in %s", channelID)
+		addReaction(config, channelID, ts, "white_check_mark")
+	}
+	return ok
+}
+
+// handleMCPAskAction answers a question with a tapped option (mcp_answer_<i>)
+func handleMCPAskAction(config *Config, action BlockActionPayload, act BlockAction) {
+	i, err := strconv.Atoi(strings.TrimPrefix(act.ActionID, "mcp_answer_"))
+	if err != nil {
+		return
+	}
+	ask, ok := answerMCPAsk(getMCPAskDir(), func(ask *mcpAsk) bool {
+		return ask.ID == act.Value && i < len(ask.Options)
+	}, func(ask *mcpAsk) string { return ask.Options[i] })
+	text := action.Message.Text + "\n\n:hourglass: _This question is no longer open_"
+	if ok {
+		text = fmt.Sprintf("%s\n\n:white_check_mark: *%s*", action.Message.Text, ask.Answer)
+	}
+	updateMessage(config, action.Channel.ID, action.Message.TS, text)
+}
+
+// mcpServer is the `mcp` subcommand: an MCP stdio server that Claude starts in
+// the session folder, so the folder tells which channel the tools post to
+type mcpServer struct {
+	config      *Config
+	sessionName string
+	channelID   string
+	cwd         string
+	askDir      string
+}
+
+// threadTS returns the thread of the session's current run ("" = channel)
+func (s *mcpServer) threadTS() string {
+	var latest *RunThread
+	for _, run := range threadRegistry.Unfinished() {
+		if run.ChannelID == s.channelID && (latest == nil || run.StartedAt.After(latest.StartedAt)) {
+			latest = run
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	return latest.ThreadTS
+}
+
+// serve answers requests line by line until in is closed
+func (s *mcpServer) serve(in io.Reader, out io.Writer) error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	enc := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var req mcpRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			mu.Lock()
+			enc.Encode(mcpResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &mcpError{Code: -32700, Message: "parse error"}})
+			mu.Unlock()
+			continue
+		}
+		// slack_ask_user blocks for minutes: answer requests concurrently
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := s.handle(req)
+			if resp == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			enc.Encode(resp)
+		}()
+	}
+	wg.Wait()
+	return scanner.Err()
+}
+
+// handle answers one request; nil for notifications
+func (s *mcpServer) handle(req mcpRequest) *mcpResponse {
+	if len(req.ID) == 0 {
+		return nil
+	}
+	resp := &mcpResponse{JSONRPC: "2.0", ID: req.ID}
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		if params.ProtocolVersion == "" {
+			params.ProtocolVersion = mcpProtocolVersion
+		}
+		resp.Result = map[string]interface{}{
+			"protocolVersion": params.ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "claude-code-slack-anywhere", "version": version},
+			"instructions":    fmt.Sprintf("Tools to reach the user in Slack channel of session %s while you work.", s.sessionName),
+		}
+	case "ping":
+		resp.Result = map[string]interface{}{}
+	case "tools/list":
+		// Outside a session folder there is no channel to talk to
+		tools := mcpTools
+		if s.channelID == "" {
+			tools = []mcpTool{}
+		}
+		resp.Result = map[string]interface{}{"tools": tools}
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &mcpError{Code: -32602, Message: "invalid params"}
+			break
+		}
+		text, err := s.callTool(params.Name, params.Arguments)
+		if err != nil {
+			text = "Error: " + err.Error()
+		}
+		resp.Result = map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": text}},
+			"isError": err != nil,
+		}
+	default:
+		resp.Error = &mcpError{Code: -32601, Message: "method not found: " + req.Method}
+	}
+	return resp
+}
+
+// callTool runs a tool and returns its text result
+func (s *mcpServer) callTool(name string, raw json.RawMessage) (string, error) {
+	var args struct {
+		Text           string   `json:"text"`
+		Question       string   `json:"question"`
+		Options        []string `json:"options"`
+		TimeoutSeconds int      `json:"timeout_seconds"`
+		Path           string   `json:"path"`
+		Comment        string   `json:"comment"`
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %v", err)
+		}
+	}
+
+	switch name {
+	case "slack_post":
+		if strings.TrimSpace(args.Text) == "" {
+			return "", fmt.Errorf("text is required")
+		}
+		if err := s.post(convertBold(args.Text)); err != nil {
+			return "", err
+		}
+		return "Posted to Slack", nil
+
+	case "slack_ask_user":
+		if strings.TrimSpace(args.Question) == "" {
+			return "", fmt.Errorf("question is required")
+		}
+		timeout := mcpAskDefault
+		if args.TimeoutSeconds > 0 {
+			timeout = min(time.Duration(args.TimeoutSeconds)*time.Second, mcpAskMax)
+		}
+		return s.ask(args.Question, args.Options, timeout)
+
+	case "slack_upload_file":
+		if args.Path == "" {
+			return "", fmt.Errorf("path is required")
+		}
+		path := args.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.cwd, path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if info.IsDir() {
+			return "", fmt.Errorf("%s is a directory", args.Path)
+		}
+		permalink, err := uploadFile(s.config, s.channelID, s.threadTS(), path, args.Comment)
+		if err != nil {
+			return "", err
+		}
+		return "Uploaded " + filepath.Base(path) + " " + permalink, nil
+	}
+	return "", fmt.Errorf("unknown tool %q", name)
+}
+
+// post sends text to the current run's thread, or the channel
+func (s *mcpServer) post(text string) error {
+	if ts := s.threadTS(); ts != "" {
+		return sendMessageToThread(s.config, s.channelID, ts, text)
+	}
+	_, err := sendMessage(s.config, s.channelID, text)
+	return err
+}
+
+// ask posts a question and waits for the listener to record its answer
+func (s *mcpServer) ask(question string, options []string, timeout time.Duration) (string, error) {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	ask := &mcpAsk{
+		ID:        hex.EncodeToString(buf),
+		ChannelID: s.channelID,
+		ThreadTS:  s.threadTS(),
+		Question:  question,
+		CreatedAt: time.Now(),
+	}
+	for _, opt := range options {
+		if opt = strings.TrimSpace(opt); opt != "" && len(ask.Options) < mcpAskMaxOptions {
+			ask.Options = append(ask.Options, opt)
+		}
+	}
+
+	text := fmt.Sprintf(":raising_hand: *Claude asks:* %s\n_Reply in this thread", convertBold(question))
+	if len(ask.Options) > 0 {
+		text += " or tap an answer"
+	}
+	text += "_"
+	var err error
+	if len(ask.Options) > 0 {
+		var buttons []Element
+		for i, opt := range ask.Options {
+			buttons = append(buttons, Element{
+				Type:     "button",
+				Text:     &TextObject{Type: "plain_text", Text: truncateRunes(opt, 70)},
+				ActionID: fmt.Sprintf("mcp_answer_%d", i),
+				Value:    ask.ID,
+			})
+		}
+		ask.TS, err = sendMessageWithButtonsGetTS(s.config, s.channelID, ask.ThreadTS, text, buttons, "mcp_"+ask.ID)
+	} else if ask.ThreadTS != "" {
+		ask.TS, err = sendMessageToThreadGetTS(s.config, s.channelID, ask.ThreadTS, text)
+	} else {
+		ask.TS, err = sendMessage(s.config, s.channelID, text)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := saveMCPAsk(s.askDir, ask); err != nil {
+		return "", err
+	}
+	defer os.Remove(filepath.Join(s.askDir, ask.ID+".json"))
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(mcpAskPoll)
+		if got, err := loadMCPAsk(s.askDir, ask.ID); err == nil && got.Answer != "" {
+			return "The user answered: " + got.Answer, nil
+		}
+	}
+	updateMessage(s.config, s.channelID, ask.TS, text+"\n\n:hourglass: _No answer in time - Claude continued without it_")
+	return fmt.Sprintf("No answer after %s. Continue with your best judgement, or stop and explain what you need.", timeout.Round(time.Second)), nil
+}
+
+// runMCP implements `mcp`: serves the Slack tools over stdio for the session
+// whose folder Claude started it in
+func runMCP() error {
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("not configured. Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+	}
+	cwd, _ := os.Getwd()
+	sessionName, channelID := findSessionForCwd(config, cwd)
+	if channelID == "" {
+		// Registered for every project: serve no tools rather than fail
+		fmt.Fprintf(os.Stderr, "mcp: not in a session directory (cwd: %s), no tools\n", cwd)
+	}
+
+	// stdout carries the protocol: send everything else (logf) to stderr
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	s := &mcpServer{config: config, sessionName: sessionName, channelID: channelID, cwd: cwd, askDir: getMCPAskDir()}
+	return s.serve(os.Stdin, out)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Push notification events
+const (
+	pushRunDone     = "run_done"     // a run finished or failed
+	pushWaiting     = "waiting"      // Claude asks for a permission or a question's answer
+	pushSessionDied = "session_died" // Claude's process for a channel crashed
+)
+
+const pushoverURL = "https://api.pushover.net/1/messages.json"
+
+// PushConfig sends phone notifications for the events that matter when Slack
+// notifications are muted
+type PushConfig struct {
+	// NtfyURL is an ntfy topic ("https://ntfy.sh/my-claude-topic")
+	NtfyURL string `json:"ntfy_url,omitempty"`
+	// NtfyToken is an access token for protected topics
+	NtfyToken string `json:"ntfy_token,omitempty"`
+	// PushoverToken and PushoverUser are the Pushover application and user keys
+	PushoverToken string `json:"pushover_token,omitempty"`
+	PushoverUser  string `json:"pushover_user,omitempty"`
+	// Events limits notifications to run_done, waiting and session_died (empty = all)
+	Events []string `json:"events,omitempty"`
+}
+
+// pushWanted reports whether event should be pushed
+func pushWanted(config *Config, event string) bool {
+	p := config.Push
+	if p == nil || (p.NtfyURL == "" && (p.PushoverToken == "" || p.PushoverUser == "")) {
+		return false
+	}
+	return len(p.Events) == 0 || slices.Contains(p.Events, event)
+}
+
+// notifyEvent mirrors a channel's event to macOS Notification Center and
+// sends it to ntfy and/or Pushover. It blocks: hook processes exit right after.
+func notifyEvent(config *Config, event, channelID, message string) {
+	mac, push := macNotifyWanted(config, event), pushWanted(config, event)
+	if !mac && !push {
+		return
+	}
+	title := getSessionByChannel(config, channelID)
+	if title == "" {
+		title = "Claude"
+	}
+	message = truncateRunes(message, 500)
+
+	if mac {
+		if err := notifyMac(title, message); err != nil {
+			logf("macOS notification failed: %v", err)
+		}
+	}
+	if !push {
+		return
+	}
+	// Waiting on you and crashes are urgent; finished runs are not
+	urgent := event != pushRunDone
+	p := config.Push
+	if p.NtfyURL != "" {
+		if err := sendNtfy(p, title, message, urgent); err != nil {
+			logf("ntfy notification failed: %v", err)
+		}
+	}
+	if p.PushoverToken != "" && p.PushoverUser != "" {
+		if err := sendPushover(p, title, message, urgent); err != nil {
+			logf("Pushover notification failed: %v", err)
+		}
+	}
+}
+
+// macNotifyWanted reports whether event goes to Notification Center
+// (mac_notifications, macOS only)
+func macNotifyWanted(config *Config, event string) bool {
+	return runtime.GOOS == "darwin" && slices.Contains(config.MacNotifications, event)
+}
+
+// notifyMac shows a Notification Center banner through osascript
+func notifyMac(title, message string) error {
+	script := fmt.Sprintf("display notification %s with title %s subtitle %s sound name \"default\"",
+		appleScriptString(message), appleScriptString("Claude"), appleScriptString(title))
+	if out, err := exec.Command("osascript", "-e", script).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func sendNtfy(p *PushConfig, title, message string, urgent bool) error {
+	req, err := http.NewRequest("POST", p.NtfyURL, strings.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", title)
+	req.Header.Set("Tags", "robot")
+	if urgent {
+		req.Header.Set("Priority", "high")
+	}
+	if p.NtfyToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.NtfyToken)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ntfy: %s", resp.Status)
+	}
+	return nil
+}
+
+func sendPushover(p *PushConfig, title, message string, urgent bool) error {
+	form := url.Values{
+		"token":   {p.PushoverToken},
+		"user":    {p.PushoverUser},
+		"title":   {title},
+		"message": {message},
+	}
+	if urgent {
+		form.Set("priority", "1")
+	}
+	resp, err := httpClient.Post(pushoverURL, "application/x-www-form-urlencoded", bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushover: %s", resp.Status)
+	}
+	return nil
+}
+
+// runPushMessage summarizes a finished run for a notification
+func runPushMessage(resp *ClaudeResponse, err error) string {
+	switch {
+	case err != nil:
+		return "Failed: " + err.Error()
+	case resp == nil:
+		return "Done"
+	case resp.IsError:
+		return "Failed: " + firstLine(resp.Result)
+	}
+	if line := firstLine(resp.Result); line != "" {
+		return "Done: " + line
+	}
+	return "Done"
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauthRedirectAddr is the local address of the temporary OAuth redirect server
+const oauthRedirectAddr = "localhost:3118"
+
+// oauthRedirectURL is the redirect URL registered in the app manifest
+const oauthRedirectURL = "http://" + oauthRedirectAddr + "/oauth/callback"
+
+// OAuthResult holds what oauth.v2.access returns that we care about
+type OAuthResult struct {
+	OK          bool   `json:"ok"`
+	Error       string `json:"error"`
+	AccessToken string `json:"access_token"`
+	BotUserID   string `json:"bot_user_id"`
+	Team        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"team"`
+	AuthedUser struct {
+		ID string `json:"id"`
+	} `json:"authed_user"`
+}
+
+// openBrowser opens a URL in the default browser (best effort)
+func openBrowser(target string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", target).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", target).Start()
+	default:
+		return exec.Command("xdg-open", target).Start()
+	}
+}
+
+// randomState returns a random OAuth state parameter
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// slackAuthorizeURL builds the OAuth v2 authorize URL
+func slackAuthorizeURL(clientID, state string) string {
+	params := url.Values{
+		"client_id":    {clientID},
+		"scope":        {botScopeString()},
+		"redirect_uri": {oauthRedirectURL},
+		"state":        {state},
+	}
+	return "https://slack.com/oauth/v2/authorize?" + params.Encode()
+}
+
+// runOAuthFlow opens the browser on Slack's authorize page, waits for the redirect
+// on a temporary localhost server and exchanges the code for a bot token
+func runOAuthFlow(clientID, clientSecret string, timeout time.Duration) (*OAuthResult, error) {
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", oauthRedirectAddr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start redirect server on %s: %w", oauthRedirectAddr, err)
+	}
+
+	resultCh := make(chan oauthRedirect, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/callback", oauthCallbackHandler(state, resultCh))
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	authURL := slackAuthorizeURL(clientID, state)
+	fmt.Println("Opening your browser to authorize the app...")
+	fmt.Printf("If it doesn't open, visit:\n  %s\n\n", authURL)
+	openBrowser(authURL)
+
+	var redirect oauthRedirect
+	select {
+	case redirect = <-resultCh:
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out waiting for authorization")
+	}
+	if redirect.err != nil {
+		return nil, redirect.err
+	}
+	return exchangeOAuthCode(clientID, clientSecret, redirect.code)
+}
+
+// oauthRedirect is the outcome of Slack's redirect: a code, or why there is none
+type oauthRedirect struct {
+	code string
+	err  error
+}
+
+// oauthCallbackHandler answers Slack's redirect. A request with the wrong state is
+// refused and the flow keeps waiting; the first valid one is sent to result (which
+// needs room for it) and later ones are only told the flow is over
+func oauthCallbackHandler(state string, result chan<- oauthRedirect) http.HandlerFunc {
+	var once sync.Once
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+		redirect := oauthRedirect{code: q.Get("code")}
+		if e := q.Get("error"); e != "" {
+			redirect.err = fmt.Errorf("authorization denied: %s", e)
+		} else if redirect.code == "" {
+			redirect.err = fmt.Errorf("redirect has no code")
+		}
+
+		first := false
+		once.Do(func() {
+			result <- redirect
+			first = true
+		})
+		switch {
+		case !first:
+			fmt.Fprint(w, "Authorization was already handled. You can close this tab.")
+		case redirect.err != nil:
+			fmt.Fprintf(w, "Authorization failed: %v. You can close this tab.", redirect.err)
+		default:
+			fmt.Fprint(w, "claudeslack is authorized! You can close this tab and return to the terminal.")
+		}
+	}
+}
+
+// exchangeOAuthCode calls oauth.v2.access to get the bot token
+func exchangeOAuthCode(clientID, clientSecret, code string) (*OAuthResult, error) {
+	params := url.Values{
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURL},
+	}
+	req, err := http.NewRequest("POST", "https://slack.com/api/oauth.v2.access", strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result OAuthResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("oauth.v2.access failed: %s", result.Error)
+	}
+	return &result, nil
+}
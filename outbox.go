@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// outboxMaxAge drops buffered messages that could not be delivered for this long
+const outboxMaxAge = 24 * time.Hour
+
+// outboxReplayInterval is how often the listener retries buffered messages
+const outboxReplayInterval = 30 * time.Second
+
+// errQueuedOffline is returned for posts that failed and were buffered for later
+var errQueuedOffline = errors.New("slack unreachable, message buffered for delivery")
+
+// OutboxEntry is a chat.postMessage call that failed because Slack was unreachable
+type OutboxEntry struct {
+	Method   string          `json:"method"`
+	Form     url.Values      `json:"form,omitempty"` // slackAPI calls
+	JSON     json.RawMessage `json:"json,omitempty"` // slackAPIJSON calls
+	QueuedAt time.Time       `json:"queued_at"`
+}
+
+// Outbox is the disk-backed buffer of undelivered messages (~/.ccsa/outbox.jsonl).
+// Hooks and the listener both append to it, so access is guarded by a file lock.
+type Outbox struct {
+	mu   sync.Mutex
+	path string
+}
+
+var outbox = &Outbox{path: getOutboxPath()}
+
+// getOutboxPath returns the path to the outbox file
+func getOutboxPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "outbox.jsonl")
+}
+
+// lock takes the in-process and cross-process lock on the outbox
+func (o *Outbox) lock() (func(), error) {
+	o.mu.Lock()
+	if err := os.MkdirAll(filepath.Dir(o.path), 0700); err != nil {
+		o.mu.Unlock()
+		return nil, err
+	}
+	f, err := os.OpenFile(o.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		o.mu.Unlock()
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		o.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+		o.mu.Unlock()
+	}, nil
+}
+
+// Pending reports whether messages are waiting for delivery
+func (o *Outbox) Pending() bool {
+	info, err := os.Stat(o.path)
+	return err == nil && info.Size() > 0
+}
+
+// Append buffers a failed call
+func (o *Outbox) Append(entry OutboxEntry) error {
+	unlock, err := o.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(o.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// readEntries returns the buffered entries in order (caller holds the lock)
+func (o *Outbox) readEntries() []OutboxEntry {
+	f, err := os.Open(o.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var entries []OutboxEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry OutboxEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// writeEntries replaces the outbox content (caller holds the lock)
+func (o *Outbox) writeEntries(entries []OutboxEntry) error {
+	if len(entries) == 0 {
+		return os.Remove(o.path)
+	}
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		data = append(append(data, line...), '\n')
+	}
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.path)
+}
+
+// Replay sends buffered entries in order and stops at the first one that still
+// can't reach Slack. Expired entries are dropped. Returns how many were sent.
+func (o *Outbox) Replay(send func(OutboxEntry) error) (int, error) {
+	unlock, err := o.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries := o.readEntries()
+	sent := 0
+	for i, entry := range entries {
+		if time.Since(entry.QueuedAt) > outboxMaxAge {
+			logf("Outbox: dropping %s queued at %s", entry.Method, entry.QueuedAt.Format(time.RFC3339))
+			continue
+		}
+		if err := send(entry); err != nil {
+			if werr := o.writeEntries(entries[i:]); werr != nil {
+				logf("Outbox: failed to save remaining messages: %v", werr)
+			}
+			return sent, err
+		}
+		sent++
+	}
+	if len(entries) > 0 {
+		o.writeEntries(nil)
+	}
+	return sent, nil
+}
+
+// bufferFailedPost queues a chat.postMessage that failed with a network error
+func bufferFailedPost(method string, form url.Values, jsonBody []byte, cause error) error {
+	if method != "chat.postMessage" {
+		return cause
+	}
+	entry := OutboxEntry{Method: method, Form: form, JSON: jsonBody, QueuedAt: time.Now()}
+	if err := outbox.Append(entry); err != nil {
+		logf("Outbox: failed to buffer message: %v", err)
+		return cause
+	}
+	logf("Slack unreachable (%v), buffered %s for delivery", cause, method)
+	return fmt.Errorf("%w: %v", errQueuedOffline, cause)
+}
+
+// replayOutbox delivers buffered messages. Only network failures keep a message
+// in the outbox; a post Slack itself rejects (e.g. archived channel) is dropped.
+func replayOutbox(config *Config) {
+	if !outbox.Pending() {
+		return
+	}
+	sent, err := outbox.Replay(func(entry OutboxEntry) error {
+		var result *SlackResponse
+		var err error
+		if entry.JSON != nil {
+			result, err = doSlackRequest(config, entry.Method, "application/json", entry.JSON)
+		} else {
+			result, err = doSlackRequest(config, entry.Method, "application/x-www-form-urlencoded", []byte(entry.Form.Encode()))
+		}
+		if err != nil {
+			return err
+		}
+		if !result.OK {
+			logf("Outbox: Slack rejected buffered %s: %s", entry.Method, result.Error)
+		}
+		time.Sleep(200 * time.Millisecond) // stay under chat.postMessage rate limits
+		return nil
+	})
+	if sent > 0 {
+		logf("Outbox: delivered %d buffered message(s)", sent)
+	}
+	if err != nil {
+		logf("Outbox: Slack still unreachable: %v", err)
+	}
+}
+
+// startOutboxReplayer periodically retries buffered messages until done is closed
+func startOutboxReplayer(cfgMgr *ConfigManager, done <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(outboxReplayInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				replayOutbox(cfgMgr.Get())
+			}
+		}
+	}()
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// defaultPersistentIdle stops a channel's idle Claude process after this long
+const defaultPersistentIdle = 15 * time.Minute
+
+// persistentHealthInterval is how often processes are checked for crashes and idleness
+const persistentHealthInterval = 30 * time.Second
+
+// persistentClaude is a long-lived `claude -p --input-format stream-json`
+// process serving one channel: each prompt is written to its stdin and the
+// turn ends with the "result" event, so later prompts skip the CLI's startup
+// and keep its warm state. A process that dies is replaced by a new one
+// resuming the same conversation.
+type persistentClaude struct {
+	channelID string
+	workDir   string
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	lines     chan string   // stdout, closed at exit
+	done      chan struct{} // closed once the process exited
+	exitErr   error
+	stderr    *tailBuffer
+
+	mu        sync.Mutex
+	sessionID string // conversation it holds ("" until its first turn)
+	busy      bool
+	stopped   bool // Close or Kill was called: an exit is expected
+	retired   bool // its settings changed: replaced at the next turn
+	lastUsed  time.Time
+}
+
+// persistentUserMessage is one prompt in the stream-json input format
+type persistentUserMessage struct {
+	Type    string `json:"type"`
+	Message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"message"`
+}
+
+// startPersistentClaude starts a process; args are the per-run arguments
+// (resume, system prompt...) without "-p <prompt>"
+func startPersistentClaude(channelID, workDir string, args, env []string, sessionID string) (*persistentClaude, error) {
+	args = append([]string{"-p", "--input-format", "stream-json"}, args...)
+	cmd := exec.Command(claudePath, args...)
+	cmd.Dir = workDir
+	cmd.Env = env
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	p := &persistentClaude{
+		channelID: channelID,
+		workDir:   workDir,
+		cmd:       cmd,
+		stdin:     stdin,
+		lines:     make(chan string, 256),
+		done:      make(chan struct{}),
+		stderr:    newTailBuffer(cliOutputTailSize),
+		sessionID: sessionID,
+		lastUsed:  time.Now(),
+	}
+	cmd.Stderr = p.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start claude: %w", err)
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			p.lines <- scanner.Text()
+		}
+		// Wait only after stdout is drained; done is closed before lines so
+		// a turn that sees the end can read the exit status
+		p.exitErr = cmd.Wait()
+		close(p.done)
+		close(p.lines)
+	}()
+	logf("Started persistent Claude for channel %s (pid %d)", channelID, cmd.Process.Pid)
+	return p, nil
+}
+
+// alive reports whether the process is still running
+func (p *persistentClaude) alive() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Send writes a prompt to the process
+func (p *persistentClaude) Send(prompt string) error {
+	var msg persistentUserMessage
+	msg.Type = "user"
+	msg.Message.Role = "user"
+	msg.Message.Content = prompt
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = p.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// Next returns the next stdout line; false once the process exited
+func (p *persistentClaude) Next() (string, bool) {
+	line, ok := <-p.lines
+	return line, ok
+}
+
+// turnReader is the process's stdout as a stream, for a turn's scanner:
+// reading stops at the result line, so the next turn's lines stay queued
+type turnReader struct {
+	p       *persistentClaude
+	pending []byte
+}
+
+func (r *turnReader) Read(b []byte) (int, error) {
+	for len(r.pending) == 0 {
+		line, ok := r.p.Next()
+		if !ok {
+			return 0, io.EOF
+		}
+		r.pending = append([]byte(line), '\n')
+	}
+	n := copy(b, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Wait returns the exit error if the process ended, nil while it runs
+func (p *persistentClaude) Wait() error {
+	if p.alive() {
+		return nil
+	}
+	return p.exitErr
+}
+
+// Kill stops the process right away (!c, timeouts)
+func (p *persistentClaude) Kill() {
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
+
+// Close ends the process gracefully: Claude exits at the end of its input
+func (p *persistentClaude) Close() {
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+	p.stdin.Close()
+	go func() {
+		select {
+		case <-p.done:
+		case <-time.After(10 * time.Second):
+			p.Kill()
+		}
+	}()
+}
+
+// PersistentPool holds the persistent Claude process of each channel
+type PersistentPool struct {
+	mu    sync.Mutex
+	procs map[string]*persistentClaude // channelID -> process
+}
+
+var persistentPool = &PersistentPool{procs: make(map[string]*persistentClaude)}
+
+// usePersistentClaude reports whether a run can go to a persistent process:
+// local, unsandboxed runs of the channel's own conversation. Short-lived
+// credentials are issued per process, so they need a fresh CLI each run.
+func usePersistentClaude(config *Config, channelID, host string, opts *ClaudeStreamingOptions) bool {
+	if config == nil || !config.PersistentClaude || host != "" || sessionSandboxed(config, channelID) || len(config.Credentials) > 0 {
+		return false
+	}
+	if opts != nil && opts.ForkFromChannel != "" {
+		return false
+	}
+	_, forked := forkStore.Get(channelID)
+	return !forked
+}
+
+// errPersistentBusy is returned by Acquire while the channel's process is
+// running a turn: runs outside the channel queue (!task, schedules...) go to
+// a one-off CLI instead
+var errPersistentBusy = errors.New("persistent claude is busy")
+
+// Acquire returns the channel's process for a turn, starting one when there
+// is none, it died, or it holds another conversation (after !reset). A busy
+// process is never replaced.
+func (pp *PersistentPool) Acquire(channelID, workDir string, args, env []string, sessionID string) (*persistentClaude, error) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if p := pp.procs[channelID]; p != nil {
+		p.mu.Lock()
+		busy := p.busy && p.alive()
+		reusable := p.alive() && !p.busy && !p.retired && p.workDir == workDir && p.sessionID == sessionID
+		p.mu.Unlock()
+		if busy {
+			return nil, errPersistentBusy
+		}
+		if reusable {
+			p.mu.Lock()
+			p.busy = true
+			p.mu.Unlock()
+			return p, nil
+		}
+		if p.alive() {
+			logf("Replacing persistent Claude for channel %s", channelID)
+			p.Close()
+		}
+		delete(pp.procs, channelID)
+	}
+	p, err := startPersistentClaude(channelID, workDir, args, env, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	p.busy = true
+	pp.procs[channelID] = p
+	return p, nil
+}
+
+// Release marks the end of a turn that ran in sessionID
+func (pp *PersistentPool) Release(p *persistentClaude, sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.busy = false
+	p.lastUsed = time.Now()
+	if sessionID != "" {
+		p.sessionID = sessionID
+	}
+}
+
+// Stop ends a channel's process (its conversation was reset or removed)
+func (pp *PersistentPool) Stop(channelID string) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if p := pp.procs[channelID]; p != nil {
+		p.Close()
+		delete(pp.procs, channelID)
+	}
+}
+
+// Retire has the channel's process replaced at its next turn, once the
+// current one (if any) is over, so changed arguments take effect
+func (pp *PersistentPool) Retire(channelID string) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if p := pp.procs[channelID]; p != nil {
+		p.mu.Lock()
+		p.retired = true
+		p.mu.Unlock()
+	}
+}
+
+// Restart starts a replacement for a crashed process with the same settings,
+// resuming the conversation it held. It is not started if the channel got
+// another process in the meantime.
+func (pp *PersistentPool) Restart(old *persistentClaude) (*persistentClaude, error) {
+	old.mu.Lock()
+	sessionID := old.sessionID
+	old.mu.Unlock()
+	// Per-run arguments: drop the startup flags and the conversation it started from
+	var args []string
+	for i := 4; i < len(old.cmd.Args); i++ {
+		switch old.cmd.Args[i] {
+		case "--resume":
+			i++
+		case "--fork-session":
+		default:
+			args = append(args, old.cmd.Args[i])
+		}
+	}
+	if sessionID != "" {
+		args = append(args, "--resume", sessionID)
+	}
+
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if pp.procs[old.channelID] != nil {
+		return nil, fmt.Errorf("channel already has a Claude process")
+	}
+	p, err := startPersistentClaude(old.channelID, old.workDir, args, old.cmd.Env, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	pp.procs[old.channelID] = p
+	return p, nil
+}
+
+// takeCrashed removes and returns the idle processes that exited on their own
+// (a process that dies during a turn is reported by the run)
+func (pp *PersistentPool) takeCrashed() []*persistentClaude {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	var crashed []*persistentClaude
+	for channelID, p := range pp.procs {
+		p.mu.Lock()
+		unexpected := !p.alive() && !p.stopped && !p.busy
+		p.mu.Unlock()
+		if unexpected {
+			delete(pp.procs, channelID)
+			crashed = append(crashed, p)
+		}
+	}
+	return crashed
+}
+
+// reap stops processes idle for longer than idle, and dead ones
+func (pp *PersistentPool) reap(idle time.Duration, now time.Time) int {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	stopped := 0
+	for channelID, p := range pp.procs {
+		p.mu.Lock()
+		expired := !p.alive() || (!p.busy && now.Sub(p.lastUsed) >= idle)
+		p.mu.Unlock()
+		if expired {
+			p.Close()
+			delete(pp.procs, channelID)
+			stopped++
+		}
+	}
+	return stopped
+}
+
+// StopAll ends every process (listener shutdown)
+func (pp *PersistentPool) StopAll() {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	for channelID, p := range pp.procs {
+		p.Close()
+		delete(pp.procs, channelID)
+	}
+}
+
+// Len returns the number of live processes (for !metrics)
+func (pp *PersistentPool) Len() int {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return len(pp.procs)
+}
+
+// persistentIdle returns how long idle processes are kept
+func persistentIdle(config *Config) time.Duration {
+	if config != nil && config.PersistentIdleMinutes > 0 {
+		return time.Duration(config.PersistentIdleMinutes) * time.Minute
+	}
+	return defaultPersistentIdle
+}
+
+// startPersistentReaper checks processes every 30 seconds: crashed ones are
+// reported in their channel and idle ones stopped; all of them are stopped
+// when the listener shuts down
+func startPersistentReaper(cfgMgr *ConfigManager, done <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(persistentHealthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				persistentPool.StopAll()
+				return
+			case now := <-ticker.C:
+				for _, p := range persistentPool.takeCrashed() {
+					reportPersistentCrash(cfgMgr.Get(), p, now)
+				}
+				if n := persistentPool.reap(persistentIdle(cfgMgr.Get()), now); n > 0 {
+					logf("Stopped %d idle persistent Claude process(es)", n)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,66 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// exeSuffix is the extension of executables
+const exeSuffix = ""
+
+// shellCommand runs a user-written command line with bash
+func shellCommand(ctx context.Context, script string) *exec.Cmd {
+	return exec.CommandContext(ctx, "bash", "-c", script)
+}
+
+// otherListenerPIDs returns the PIDs of other running listeners
+func otherListenerPIDs(myPid int) []int {
+	output, _ := exec.Command("pgrep", "-f", "claude-code-slack-anywhere listen").Output()
+	var pids []int
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if pid, err := strconv.Atoi(line); err == nil && pid != myPid {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// terminateProcess asks a process to exit (SIGTERM)
+func terminateProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// lockFile takes an exclusive lock on f, shared with other processes
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases lockFile's lock
+func unlockFile(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// linkExecutable makes target run the binary at src
+func linkExecutable(src, target string) error {
+	return os.Symlink(src, target)
+}
+
+// claudeLookup finds claude outside the usual install paths
+func claudeLookup() string {
+	p, _ := exec.LookPath("claude")
+	return p
+}
+
+// startPTY starts cmd on a new pseudo-terminal of the given size and
+// returns its controlling side
+func startPTY(cmd *exec.Cmd, rows, cols int) (*os.File, error) {
+	return pty.StartWithSize(cmd, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
+}
@@ -0,0 +1,79 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// exeSuffix is the extension of executables
+const exeSuffix = ".exe"
+
+// shellCommand runs a user-written command line with bash when installed
+// (Git for Windows, WSL), with cmd otherwise
+func shellCommand(ctx context.Context, script string) *exec.Cmd {
+	if _, err := exec.LookPath("bash"); err == nil {
+		return exec.CommandContext(ctx, "bash", "-c", script)
+	}
+	return exec.CommandContext(ctx, "cmd", "/C", script)
+}
+
+// otherListenerPIDs returns nothing: Windows has no pgrep -f, and the
+// Scheduled Task runs a single listener
+func otherListenerPIDs(myPid int) []int {
+	return nil
+}
+
+// terminateProcess ends a process (Windows has no SIGTERM)
+func terminateProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// lockFile takes an exclusive lock on f, shared with other processes
+func lockFile(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &windows.Overlapped{})
+}
+
+// unlockFile releases lockFile's lock
+func unlockFile(f *os.File) {
+	windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
+
+// linkExecutable makes target run the binary at src: symlinks need admin
+// rights or developer mode, hard links only the same volume
+func linkExecutable(src, target string) error {
+	if err := os.Symlink(src, target); err == nil {
+		return nil
+	}
+	return os.Link(src, target)
+}
+
+// claudeLookup finds claude with `where`, which knows claude.cmd (npm) and
+// claude.exe (native installer)
+func claudeLookup() string {
+	out, err := exec.Command("where", "claude").Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// startPTY is not available: pty_sessions need a Unix pseudo-terminal
+func startPTY(cmd *exec.Cmd, rows, cols int) (*os.File, error) {
+	return nil, fmt.Errorf("PTY sessions aren't supported on Windows")
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultPluginTimeout bounds an external plugin command
+const defaultPluginTimeout = 2 * time.Minute
+
+// CommandContext is what a command plugin knows about the message that ran it
+type CommandContext struct {
+	Config        *Config
+	ConfigManager *ConfigManager
+	ChannelID     string
+	ThreadTS      string // "" outside a thread
+	EventTS       string
+	UserID        string
+	SessionName   string // "" outside a session channel
+	WorkDir       string // the session folder ("" outside a session channel)
+	Args          string // text after the command name, trimmed
+	// Reply answers in the message's thread, or in the channel
+	Reply func(text string)
+}
+
+// CommandPlugin is a Slack "!" command living in its own file (or outside the
+// binary, see PluginCommand) rather than in handleSlackEvent
+type CommandPlugin interface {
+	// Name is the command without its "!"
+	Name() string
+	// Help is its !help line: "what it does", or "<args> - what it does"
+	Help() string
+	// Run handles the command; an error is posted as the answer
+	Run(cc *CommandContext) error
+}
+
+// commandPlugins are the built-in plugins in registration order
+var commandPlugins []CommandPlugin
+
+// registerCommand adds a built-in plugin; plugin files call it from init
+func registerCommand(p CommandPlugin) {
+	commandPlugins = append(commandPlugins, p)
+}
+
+// builtinPlugin returns the built-in plugin called name (without "!")
+func builtinPlugin(name string) CommandPlugin {
+	for _, p := range commandPlugins {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// findCommandPlugin returns the plugin handling "!name": built-in ones first,
+// then the plugin_commands of the config that don't shadow a built-in command
+func findCommandPlugin(config *Config, name string) CommandPlugin {
+	if p := builtinPlugin(name); p != nil {
+		return p
+	}
+	if isBuiltinCommand("!" + name) {
+		return nil
+	}
+	for key, pc := range config.PluginCommands {
+		if strings.TrimPrefix(key, "!") == name && pc.Command != "" {
+			return externalPlugin{name: name, PluginCommand: pc}
+		}
+	}
+	return nil
+}
+
+// pluginHelpLine renders a plugin's line in !help
+func pluginHelpLine(p CommandPlugin) string {
+	if args, desc, ok := strings.Cut(p.Help(), " - "); ok {
+		return fmt.Sprintf("• `!%s %s` - %s", p.Name(), args, desc)
+	}
+	return fmt.Sprintf("• `!%s` - %s", p.Name(), p.Help())
+}
+
+// commandPluginHelp renders the !help lines of the built-in plugins
+func commandPluginHelp() string {
+	var b strings.Builder
+	for _, p := range commandPlugins {
+		b.WriteString(pluginHelpLine(p) + "\n")
+	}
+	return b.String()
+}
+
+// externalPluginHelp renders the !help section of the config's plugin_commands
+func externalPluginHelp(config *Config) string {
+	if len(config.PluginCommands) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(config.PluginCommands))
+	commands := make(map[string]PluginCommand, len(config.PluginCommands))
+	for key, pc := range config.PluginCommands {
+		if name := strings.TrimPrefix(key, "!"); !isBuiltinCommand("!" + name) {
+			names = append(names, name)
+			commands[name] = pc
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	lines := []string{"\n\n:jigsaw: *Plugins*"}
+	for _, name := range names {
+		lines = append(lines, pluginHelpLine(externalPlugin{name: name, PluginCommand: commands[name]}))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runCommandPlugin runs text with its plugin, if a plugin handles it
+func runCommandPlugin(cc *CommandContext, text string) bool {
+	if !strings.HasPrefix(text, "!") {
+		return false
+	}
+	name, args := text[1:], ""
+	if i := strings.IndexAny(name, " \n"); i >= 0 {
+		name, args = name[:i], name[i+1:]
+	}
+	p := findCommandPlugin(cc.Config, name)
+	if p == nil {
+		return false
+	}
+	cc.Args = strings.TrimSpace(args)
+	if err := p.Run(cc); err != nil {
+		logf("Command !%s failed: %v", name, err)
+		cc.Reply(fmt.Sprintf(":x: `!%s`: %v", name, err))
+	}
+	return true
+}
+
+// PluginCommand is a Slack command run by an executable (plugin_commands)
+type PluginCommand struct {
+	// Command is the executable; the words typed after the command are its arguments
+	Command string `json:"command"`
+	// Help is its line in !help ("<env> - deploy to an environment")
+	Help string `json:"help,omitempty"`
+	// TimeoutSeconds stops the command (0 = 2 minutes)
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// AdminOnly limits the command to admin_user_ids
+	AdminOnly bool `json:"admin_only,omitempty"`
+}
+
+// externalPlugin runs a PluginCommand
+type externalPlugin struct {
+	name string
+	PluginCommand
+}
+
+func (p externalPlugin) Name() string { return p.name }
+
+func (p externalPlugin) Help() string {
+	if p.PluginCommand.Help == "" {
+		return "Run `" + p.Command + "`"
+	}
+	return p.PluginCommand.Help
+}
+
+// Run starts the executable in the session folder (home outside sessions)
+// with the message context in CCSA_* variables, and posts its output
+func (p externalPlugin) Run(cc *CommandContext) error {
+	if p.AdminOnly && !cc.Config.IsAdmin(cc.UserID) {
+		return fmt.Errorf("limited to the users in `admin_user_ids`")
+	}
+	timeout := defaultPluginTimeout
+	if p.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Command, strings.Fields(cc.Args)...)
+	cmd.Dir = cc.WorkDir
+	if info, err := os.Stat(cmd.Dir); cmd.Dir == "" || err != nil || !info.IsDir() {
+		cmd.Dir, _ = os.UserHomeDir()
+	}
+	cmd.Env = append(runEnv(cc.Config),
+		"CCSA_CHANNEL="+cc.ChannelID,
+		"CCSA_THREAD_TS="+cc.ThreadTS,
+		"CCSA_USER="+cc.UserID,
+		"CCSA_SESSION="+cc.SessionName,
+		"CCSA_WORKDIR="+cc.WorkDir,
+		"CCSA_ARGS="+cc.Args,
+	)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	output := strings.TrimSpace(sanitizeTerminalOutput(out.String()))
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		output = fmt.Sprintf(":stopwatch: Timeout (%s)\n\n%s", formatDuration(timeout), output)
+	case err != nil:
+		output = fmt.Sprintf(":warning: %s\n\nExit: %v", output, err)
+	case output == "":
+		output = "(no output)"
+	}
+	cc.Reply("```\n" + output + "\n```")
+	return nil
+}
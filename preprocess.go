@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	userMentionRe    = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|([^>]+))?>`)
+	channelMentionRe = regexp.MustCompile(`<#([CG][A-Z0-9]+)(?:\|([^>]*))?>`)
+	specialMentionRe = regexp.MustCompile(`<!(here|channel|everyone)(?:\|[^>]*)?>`)
+	slackLinkRe      = regexp.MustCompile(`<((?:https?|mailto|ftp):[^|>]+)(?:\|([^>]+))?>`)
+	emojiCodeRe      = regexp.MustCompile(`:[a-z0-9_+\-]*[a-z][a-z0-9_+\-]*:|:[+-]1:`)
+)
+
+// smartQuoteReplacer turns typographic punctuation (iOS/macOS autocorrect) back into ASCII
+var smartQuoteReplacer = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`,
+	"–", "-", "—", "--", "…", "...", " ", " ",
+)
+
+// slackEntityReplacer decodes the HTML entities Slack escapes in message text
+var slackEntityReplacer = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// commonEmoji maps frequent emoji codes to unicode; unknown codes are dropped
+var commonEmoji = map[string]string{
+	":+1:": "👍", ":thumbsup:": "👍", ":-1:": "👎", ":thumbsdown:": "👎",
+	":white_check_mark:": "✅", ":heavy_check_mark:": "✔", ":x:": "❌",
+	":warning:": "⚠️", ":fire:": "🔥", ":bug:": "🐛", ":rocket:": "🚀",
+	":tada:": "🎉", ":eyes:": "👀", ":pray:": "🙏", ":smile:": "😄",
+	":slightly_smiling_face:": "🙂", ":thinking_face:": "🤔", ":heart:": "❤️",
+}
+
+// userNameCache caches Slack user ID -> display name lookups
+var userNameCache sync.Map
+
+// cleanSlackMarkup removes Slack-specific markup that needs no API lookup:
+// links, channel/special mentions, HTML entities and smart quotes
+func cleanSlackMarkup(text string) string {
+	text = slackLinkRe.ReplaceAllStringFunc(text, func(m string) string {
+		parts := slackLinkRe.FindStringSubmatch(m)
+		link, label := parts[1], parts[2]
+		plain := strings.TrimPrefix(link, "mailto:")
+		if label == "" || label == plain || label == strings.TrimPrefix(strings.TrimPrefix(link, "https://"), "http://") {
+			return plain
+		}
+		return label + " (" + plain + ")"
+	})
+	text = channelMentionRe.ReplaceAllStringFunc(text, func(m string) string {
+		parts := channelMentionRe.FindStringSubmatch(m)
+		if parts[2] != "" {
+			return "#" + parts[2]
+		}
+		return "#" + parts[1]
+	})
+	text = specialMentionRe.ReplaceAllString(text, "@$1")
+	text = slackEntityReplacer.Replace(text)
+	return smartQuoteReplacer.Replace(text)
+}
+
+// preprocessSlackText prepares Slack message text before it is sent to Claude:
+// user mentions become names, known emoji codes outside code become unicode
+// (anything else between colons is kept), and remaining Slack markup is cleaned
+func preprocessSlackText(config *Config, text string) string {
+	text = userMentionRe.ReplaceAllStringFunc(text, func(m string) string {
+		parts := userMentionRe.FindStringSubmatch(m)
+		if parts[2] != "" {
+			return "@" + parts[2]
+		}
+		return "@" + lookupUserName(config, parts[1])
+	})
+	text = replaceOutsideCode(text, func(segment string) string {
+		return emojiCodeRe.ReplaceAllStringFunc(segment, func(code string) string {
+			if e, ok := commonEmoji[code]; ok {
+				return e
+			}
+			return code
+		})
+	})
+	return cleanSlackMarkup(text)
+}
+
+// replaceOutsideCode applies fn to text outside ``` fences and `inline code`
+func replaceOutsideCode(text string, fn func(string) string) string {
+	var b strings.Builder
+	fences := strings.Split(text, "```")
+	for i, fence := range fences {
+		if i > 0 {
+			b.WriteString("```")
+		}
+		if i%2 == 1 {
+			b.WriteString(fence) // inside a code block
+			continue
+		}
+		inline := strings.Split(fence, "`")
+		for j, part := range inline {
+			if j > 0 {
+				b.WriteString("`")
+			}
+			if j%2 == 1 {
+				b.WriteString(part)
+			} else {
+				b.WriteString(fn(part))
+			}
+		}
+	}
+	return b.String()
+}
+
+// lookupUserName resolves a Slack user ID to a display name (cached, falls back to the ID)
+func lookupUserName(config *Config, userID string) string {
+	if name, ok := userNameCache.Load(userID); ok {
+		return name.(string)
+	}
+	if config == nil {
+		return userID
+	}
+
+	params := url.Values{"user": {userID}}
+	req, err := http.NewRequest("GET", slackMethodURL(config, "users.info")+"?"+params.Encode(), nil)
+	if err != nil {
+		return userID
+	}
+	req.Header.Set("Authorization", "Bearer "+config.BotToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return userID
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK   bool `json:"ok"`
+		User struct {
+			Name    string `json:"name"`
+			Profile struct {
+				DisplayName string `json:"display_name"`
+				RealName    string `json:"real_name"`
+			} `json:"profile"`
+		} `json:"user"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || !result.OK {
+		return userID
+	}
+
+	name := result.User.Profile.DisplayName
+	if name == "" {
+		name = result.User.Profile.RealName
+	}
+	if name == "" {
+		name = result.User.Name
+	}
+	if name == "" {
+		name = userID
+	}
+	userNameCache.Store(userID, name)
+	return name
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// progressBlockID marks a run's live progress message
+const progressBlockID = "run_progress"
+
+// inProgressTodo returns the TodoWrite item being worked on ("" if none)
+func inProgressTodo(input json.RawMessage) string {
+	var data struct {
+		Todos []struct {
+			Content    string `json:"content"`
+			Status     string `json:"status"`
+			ActiveForm string `json:"activeForm"`
+		} `json:"todos"`
+	}
+	if json.Unmarshal(input, &data) != nil {
+		return ""
+	}
+	for _, t := range data.Todos {
+		if t.Status != "in_progress" {
+			continue
+		}
+		if t.ActiveForm != "" {
+			return t.ActiveForm
+		}
+		return t.Content
+	}
+	return ""
+}
+
+// formatProgress renders the live progress message: time since the last
+// output, turns so far, and the current tool and todo item when known
+func formatProgress(elapsed time.Duration, tool, todo string, turns int) string {
+	text := fmt.Sprintf(":hourglass_flowing_sand: Working... (%s)", formatDuration(elapsed))
+	if turns > 0 {
+		text += fmt.Sprintf(" · turn %d", turns)
+	}
+	if tool != "" {
+		text += "\n:gear: " + truncateRunes(tool, 150)
+	}
+	if todo != "" {
+		text += "\n:pushpin: " + truncateRunes(todo, 150)
+	}
+	return text
+}
+
+// progressButtons is the progress message's Stop button
+func progressButtons(channelID string) []Element {
+	return []Element{{
+		Type:     "button",
+		Text:     &TextObject{Type: "plain_text", Text: ":stop_sign: Stop"},
+		ActionID: "progress_cancel",
+		Value:    channelID,
+		Style:    "danger",
+	}}
+}
+
+// handleProgressCancelAction stops the run from its progress message, like
+// !cancel. The progress message goes away with the run, so the outcome is
+// posted in the thread.
+func handleProgressCancelAction(config *Config, action BlockActionPayload, act BlockAction) {
+	if !CancelClaudeProcess(act.Value) {
+		updateMessage(config, action.Channel.ID, action.Message.TS, ":information_source: This run already finished")
+		return
+	}
+	logf("Run in %s stopped by %s", act.Value, action.User.ID)
+	// Not under the progress message itself: it is deleted as the run ends
+	text := fmt.Sprintf(":stop_sign: Stopped by <@%s>", action.User.ID)
+	if action.Message.ThreadTS != "" {
+		sendMessageToThread(config, action.Channel.ID, action.Message.ThreadTS, text)
+	} else {
+		sendMessage(config, action.Channel.ID, text)
+	}
+}
+
+// setProgressToolLocked records the tool being run for the progress message
+// (caller must hold mutex)
+func (m *SlackThreadManager) setProgressToolLocked(toolName, summary string, input json.RawMessage) {
+	m.progressTool = strings.TrimSpace(toolName + " " + summary)
+	if toolName == "TodoWrite" {
+		m.progressTodo = inProgressTodo(input)
+	}
+}
+
+// CountTurn counts a new assistant message towards the run's turns
+func (m *SlackThreadManager) CountTurn(messageID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if messageID == "" || messageID == m.lastTurnID {
+		return
+	}
+	m.lastTurnID = messageID
+	m.turns++
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ptyOutputSize is how much recent terminal output a PTY session keeps for !output
+	ptyOutputSize = 256 * 1024
+	// Terminal size Claude draws for
+	ptyRows = 50
+	ptyCols = 160
+	// ptyEnterDelay lets Claude take in a pasted prompt before Enter submits it
+	ptyEnterDelay = 150 * time.Millisecond
+	// Lines shown by !output (default and most)
+	defaultPTYOutputLines = 40
+	maxPTYOutputLines     = 200
+)
+
+// ptyChannelEnv tells the Stop hook it runs in the listener's PTY session of a channel
+const ptyChannelEnv = "CCSA_PTY_CHANNEL"
+
+// Bracketed paste markers: a multi-line prompt is pasted, not submitted line by line
+const (
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+// ptyKeyNames are the named keys !keys can send
+var ptyKeyNames = map[string]string{
+	"enter":     "\r",
+	"esc":       "\x1b",
+	"escape":    "\x1b",
+	"tab":       "\t",
+	"shift-tab": "\x1b[Z",
+	"space":     " ",
+	"backspace": "\x7f",
+	"up":        "\x1b[A",
+	"down":      "\x1b[B",
+	"right":     "\x1b[C",
+	"left":      "\x1b[D",
+}
+
+// ptySession is an interactive Claude running on a pseudo-terminal owned by
+// the listener: prompts are typed into it, its Stop hook posts the answers,
+// and the end of its screen output is kept for !output
+type ptySession struct {
+	channelID string
+	workDir   string
+	cmd       *exec.Cmd
+	tty       *os.File
+	output    *tailBuffer
+	done      chan struct{} // closed once the process exited
+	writeMu   sync.Mutex    // keeps a prompt and its Enter together
+	retired   bool          // its arguments changed: replaced at the next prompt (PTYPool.mu)
+}
+
+// startPTYSession starts an interactive Claude in workDir
+func startPTYSession(channelID, workDir string, args, env []string) (*ptySession, error) {
+	cmd := exec.Command(claudePath, args...)
+	cmd.Dir = workDir
+	// The Stop hook reports the conversation ID of ptyChannelEnv's channel
+	cmd.Env = append(env, "TERM=xterm-256color", ptyChannelEnv+"="+channelID)
+	tty, err := startPTY(cmd, ptyRows, ptyCols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start claude: %w", err)
+	}
+	s := &ptySession{
+		channelID: channelID,
+		workDir:   workDir,
+		cmd:       cmd,
+		tty:       tty,
+		output:    newTailBuffer(ptyOutputSize),
+		done:      make(chan struct{}),
+	}
+	go func() {
+		// Reading fails once the process is gone and the terminal closed
+		io.Copy(s.output, tty)
+		cmd.Wait()
+		tty.Close()
+		close(s.done)
+	}()
+	logf("Started PTY Claude for channel %s (pid %d)", channelID, cmd.Process.Pid)
+	return s, nil
+}
+
+// alive reports whether the process is still running
+func (s *ptySession) alive() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Send types a prompt and submits it
+func (s *ptySession) Send(prompt string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := io.WriteString(s.tty, pasteStart+prompt+pasteEnd); err != nil {
+		return err
+	}
+	time.Sleep(ptyEnterDelay)
+	_, err := io.WriteString(s.tty, "\r")
+	return err
+}
+
+// Keys sends raw keystrokes (see parsePTYKeys)
+func (s *ptySession) Keys(keys string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := io.WriteString(s.tty, keys)
+	return err
+}
+
+// Output returns the last lines of the terminal as plain text
+func (s *ptySession) Output(lines int) string {
+	return lastLines(strings.TrimSpace(sanitizeTerminalOutput(s.output.String())), lines)
+}
+
+// Kill stops the process right away
+func (s *ptySession) Kill() {
+	if s.cmd.Process != nil {
+		s.cmd.Process.Kill()
+	}
+}
+
+// PTYPool holds the PTY session of each channel
+type PTYPool struct {
+	mu       sync.Mutex
+	sessions map[string]*ptySession // channelID -> session
+}
+
+var ptyPool = &PTYPool{sessions: make(map[string]*ptySession)}
+
+// Get returns the channel's running session, if any
+func (pp *PTYPool) Get(channelID string) *ptySession {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if s := pp.sessions[channelID]; s != nil && s.alive() {
+		return s
+	}
+	return nil
+}
+
+// Acquire returns the channel's session, starting one when there is none,
+// it exited, it runs in another folder or it was retired
+func (pp *PTYPool) Acquire(channelID, workDir string, args, env []string) (*ptySession, error) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if s := pp.sessions[channelID]; s != nil {
+		if s.alive() && s.workDir == workDir && !s.retired {
+			return s, nil
+		}
+		s.Kill()
+		delete(pp.sessions, channelID)
+	}
+	s, err := startPTYSession(channelID, workDir, args, env)
+	if err != nil {
+		return nil, err
+	}
+	pp.sessions[channelID] = s
+	return s, nil
+}
+
+// Retire has the channel's session replaced at its next prompt, so changed
+// arguments (!sysprompt) take effect
+func (pp *PTYPool) Retire(channelID string) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if s := pp.sessions[channelID]; s != nil {
+		s.retired = true
+	}
+}
+
+// Stop ends a channel's session (its conversation was reset or removed)
+func (pp *PTYPool) Stop(channelID string) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if s := pp.sessions[channelID]; s != nil {
+		s.Kill()
+		delete(pp.sessions, channelID)
+	}
+}
+
+// StopAll ends every session (listener shutdown)
+func (pp *PTYPool) StopAll() {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	for channelID, s := range pp.sessions {
+		s.Kill()
+		delete(pp.sessions, channelID)
+	}
+}
+
+// ptySessionArgs are the arguments of a channel's interactive Claude: it
+// resumes the channel's conversation when there is one
+func ptySessionArgs(config *Config, channelID string) []string {
+	args := []string{"--dangerously-skip-permissions", "--append-system-prompt", appendSystemPrompt(config, channelID)}
+	if sid, ok := getClaudeSessionID(channelID); ok {
+		args = append(args, "--resume", sid)
+	}
+	return args
+}
+
+// usePTYSession reports whether a channel's prompts go to its PTY session:
+// local, unsandboxed sessions listed in pty_sessions
+func usePTYSession(config *Config, channelID string) bool {
+	sessionName := getSessionByChannel(config, channelID)
+	return sessionName != "" && config.PTYSessions[sessionName] && !config.SandboxSessions[sessionName] && sessionHost(config, channelID) == ""
+}
+
+// sendToPTYSession types a prompt into the session's interactive Claude,
+// starting it if needed; the answer comes back through the Stop hook
+func sendToPTYSession(config *Config, m *QueuedMessage) {
+	for _, ts := range m.EventTimestamps() {
+		if ts != "" {
+			removeReaction(config, m.ChannelID, ts, "eyes")
+		}
+	}
+	if err := typeIntoPTYSession(config, m); err != nil {
+		if m.EventTS != "" {
+			addReaction(config, m.ChannelID, m.EventTS, "x")
+			sendMessageToThread(config, m.ChannelID, m.EventTS, fmt.Sprintf(":x: PTY session: %v", err))
+		} else {
+			sendMessage(config, m.ChannelID, fmt.Sprintf(":x: PTY session: %v", err))
+		}
+		if m.OnFinish != nil {
+			m.OnFinish(nil, fmt.Errorf("PTY session: %w", err))
+		}
+		return
+	}
+	if m.EventTS != "" {
+		addReaction(config, m.ChannelID, m.EventTS, "keyboard")
+	}
+	if m.OnFinish != nil {
+		m.OnFinish(&ClaudeResponse{Result: "Typed into the PTY session - the answer is posted in its channel"}, nil)
+	}
+}
+
+// typeIntoPTYSession starts the channel's PTY session if needed and sends m to it
+func typeIntoPTYSession(config *Config, m *QueuedMessage) error {
+	if err := ensureChannelBranch(config, m.ChannelID, m.WorkDir); err != nil {
+		return err
+	}
+	env, err := sessionRunEnv(config, getSessionByChannel(config, m.ChannelID), m.WorkDir)
+	if err != nil {
+		return err
+	}
+	s, err := ptyPool.Acquire(m.ChannelID, m.WorkDir, ptySessionArgs(config, m.ChannelID), env)
+	if err != nil {
+		return err
+	}
+	return s.Send(m.Text)
+}
+
+// parsePTYKeys turns "down down enter" into keystrokes: named keys, ctrl-<letter>,
+// and single characters typed as is (answers like "1" or "y")
+func parsePTYKeys(spec string) (string, error) {
+	var out strings.Builder
+	for _, name := range strings.Fields(spec) {
+		lower := strings.ToLower(name)
+		if seq, ok := ptyKeyNames[lower]; ok {
+			out.WriteString(seq)
+			continue
+		}
+		if letter, ok := strings.CutPrefix(lower, "ctrl-"); ok && len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z' {
+			out.WriteByte(letter[0] - 'a' + 1)
+			continue
+		}
+		if len([]rune(name)) == 1 {
+			out.WriteString(name)
+			continue
+		}
+		return "", fmt.Errorf("unknown key %q", name)
+	}
+	if out.Len() == 0 {
+		return "", fmt.Errorf("no keys given")
+	}
+	return out.String(), nil
+}
+
+// handleOutputCommand implements !output [lines]: the end of the PTY session's screen
+func handleOutputCommand(config *Config, channelID, arg string) string {
+	if !config.PTYSessions[getSessionByChannel(config, channelID)] {
+		return ":information_source: `!output` shows the terminal of a PTY session (`pty_sessions` in the config)"
+	}
+	lines := defaultPTYOutputLines
+	if arg = strings.TrimSpace(arg); arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return fmt.Sprintf("Usage: `!output [lines]` (default %d, max %d)", defaultPTYOutputLines, maxPTYOutputLines)
+		}
+		lines = min(n, maxPTYOutputLines)
+	}
+	s := ptyPool.Get(channelID)
+	if s == nil {
+		return ":zzz: Claude isn't running in this session - the next message starts it"
+	}
+	output := s.Output(lines)
+	if output == "" {
+		return ":desktop_computer: _(no output yet)_"
+	}
+	return "```\n" + output + "\n```"
+}
+
+// handleKeysCommand implements !keys <keys>: keystrokes for the PTY session,
+// e.g. to pick an option of a menu Claude shows
+func handleKeysCommand(config *Config, channelID, arg string) string {
+	if !config.PTYSessions[getSessionByChannel(config, channelID)] {
+		return ":information_source: `!keys` sends keystrokes to a PTY session (`pty_sessions` in the config)"
+	}
+	keys, err := parsePTYKeys(arg)
+	if err != nil {
+		return fmt.Sprintf(":x: %v\nUsage: `!keys <keys>` - e.g. `!keys down enter`, `!keys 1`, `!keys esc`, `!keys ctrl-c`", err)
+	}
+	s := ptyPool.Get(channelID)
+	if s == nil {
+		return ":zzz: Claude isn't running in this session - the next message starts it"
+	}
+	if err := s.Keys(keys); err != nil {
+		return fmt.Sprintf(":x: %v", err)
+	}
+	return ":keyboard: Sent"
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// purgeRequest is a `!kill --purge` waiting for confirmation
+type purgeRequest struct {
+	Name       string
+	WorkDir    string // "" when the folder can't be deleted from here
+	SharedWith string // session whose folder is this one or inside it
+}
+
+// pendingPurges holds `!kill --purge` requests waiting for a tap (channel:eventTS -> purgeRequest)
+var pendingPurges sync.Map
+
+// purgeableWorkDir returns the folder !kill --purge may delete for a session,
+// or "" when it lives on an agent, is missing, or is a projects root or home.
+// It is also "" when another session works in that folder or below it (a
+// `!fork --channel --shared` link, say): that session is returned as sharedWith
+func purgeableWorkDir(config *Config, channelID, name string) (dir, sharedWith string) {
+	if sessionHost(config, channelID) != "" {
+		return "", ""
+	}
+	dir = sessionWorkDir(config, name)
+	if !filepath.IsAbs(dir) {
+		return "", ""
+	}
+	if _, err := os.Lstat(dir); err != nil {
+		return "", ""
+	}
+	home, _ := os.UserHomeDir()
+	protected := append([]string{home, "/"}, getProjectsDirs(config)...)
+	for _, p := range protected {
+		if filepath.Clean(p) == filepath.Clean(dir) {
+			return "", ""
+		}
+	}
+
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", ""
+	}
+	for other, otherChannel := range config.Sessions {
+		if other == name || sessionHost(config, otherChannel) != "" {
+			continue
+		}
+		otherDir, err := filepath.EvalSymlinks(sessionWorkDir(config, other))
+		if err != nil {
+			continue
+		}
+		if otherDir == real || strings.HasPrefix(otherDir, real+string(filepath.Separator)) {
+			return "", other
+		}
+	}
+	return dir, ""
+}
+
+// removeWorkDir deletes a session folder. A linked git worktree (a `!fork
+// --channel` fork) is removed through git, so its repository forgets it too
+func removeWorkDir(dir string) error {
+	top, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return os.RemoveAll(dir)
+	}
+	gitDir, _ := gitOutput(dir, "rev-parse", "--absolute-git-dir")
+	common, _ := gitOutput(dir, "rev-parse", "--git-common-dir")
+	if common != "" && !filepath.IsAbs(common) {
+		common = filepath.Join(dir, common)
+	}
+	realDir, _ := filepath.EvalSymlinks(dir)
+	realTop, _ := filepath.EvalSymlinks(top)
+	realGitDir, _ := filepath.EvalSymlinks(gitDir)
+	realCommon, _ := filepath.EvalSymlinks(common)
+	if realTop != realDir || realGitDir == "" || realGitDir == realCommon {
+		return os.RemoveAll(dir)
+	}
+	_, err = gitOutput(realCommon, "worktree", "remove", "--force", realDir)
+	return err
+}
+
+// askPurgeConfirmation offers to purge a session, with or without its folder
+func askPurgeConfirmation(config *Config, channelID, threadTS, eventTS, name string) error {
+	id := channelID + ":" + eventTS
+	req := purgeRequest{Name: name}
+	req.WorkDir, req.SharedWith = purgeableWorkDir(config, channelID, name)
+	pendingPurges.Store(id, req)
+
+	text := fmt.Sprintf(":warning: Purge session `%s`? This forgets its Claude conversation and settings and archives this channel.", name)
+	var buttons []Element
+	if req.SharedWith != "" {
+		text += fmt.Sprintf("\nThe folder is kept: session `%s` works in it.", req.SharedWith)
+	}
+	if req.WorkDir != "" {
+		text += fmt.Sprintf("\nThe folder `%s` can be deleted too - this can't be undone.", req.WorkDir)
+		buttons = append(buttons, Element{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Purge and delete folder"},
+			ActionID: "purge_delete",
+			Value:    id,
+			Style:    "danger",
+		})
+	}
+	buttons = append(buttons,
+		Element{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Purge, keep folder"},
+			ActionID: "purge_keep",
+			Value:    id,
+		},
+		Element{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Cancel"},
+			ActionID: "purge_cancel",
+			Value:    id,
+		},
+	)
+	if err := sendMessageWithButtonsToThread(config, channelID, threadTS, text, buttons, "purge_"+eventTS); err != nil {
+		pendingPurges.Delete(id)
+		return err
+	}
+	return nil
+}
+
+// handlePurgeAction carries out or drops a confirmed `!kill --purge`
+func handlePurgeAction(cfgMgr *ConfigManager, config *Config, action BlockActionPayload, act BlockAction) {
+	pending, ok := pendingPurges.LoadAndDelete(act.Value)
+	if !ok {
+		updateMessage(config, action.Channel.ID, action.Message.TS, action.Message.Text+"\n\n:information_source: This purge is no longer pending (already handled, or the listener restarted)")
+		return
+	}
+	req := pending.(purgeRequest)
+	channelID := action.Channel.ID
+	if act.ActionID == "purge_cancel" {
+		updateMessage(config, channelID, action.Message.TS, fmt.Sprintf(":no_entry_sign: Purge cancelled by <@%s>", action.User.ID))
+		return
+	}
+	if cfgMgr.GetSessionByChannel(channelID) != req.Name {
+		updateMessage(config, channelID, action.Message.TS, fmt.Sprintf(":information_source: Session `%s` no longer belongs to this channel - nothing purged", req.Name))
+		return
+	}
+
+	deleteFolder := act.ActionID == "purge_delete"
+	if deleteFolder {
+		if _, shared := purgeableWorkDir(config, channelID, req.Name); shared != "" {
+			updateMessage(config, channelID, action.Message.TS, fmt.Sprintf(":information_source: Session `%s` now works in `%s` - nothing purged", shared, req.WorkDir))
+			return
+		}
+	}
+
+	report, err := purgeSession(cfgMgr, channelID, req, deleteFolder)
+	if err != nil {
+		updateMessage(config, channelID, action.Message.TS, fmt.Sprintf(":x: %s\n%v", report, err))
+		return
+	}
+	updateMessage(config, channelID, action.Message.TS, fmt.Sprintf(":wastebasket: %s (by <@%s>)", report, action.User.ID))
+	if err := archiveChannel(config, channelID); err != nil {
+		logf("Failed to archive channel: %v", err)
+		sendMessage(config, channelID, fmt.Sprintf(":x: Channel archive failed: %v", err))
+	}
+}
+
+// removeSession forgets a channel's session and conversation (as !kill does)
+// and returns the session name, "" when the channel had none
+func removeSession(cfgMgr *ConfigManager, channelID string) string {
+	name := cfgMgr.GetSessionByChannel(channelID)
+	resetClaudeSession(channelID)
+	watchManager.Stop(channelID, "all")
+	summaryStore.Remove(channelID)
+	if name != "" {
+		cfgMgr.DeleteSession(name)
+	}
+	return name
+}
+
+// purgeSession stops a session's work, forgets its conversation and settings,
+// optionally deletes its folder, and records it in the timeline
+func purgeSession(cfgMgr *ConfigManager, channelID string, req purgeRequest, deleteFolder bool) (string, error) {
+	CancelClaudeProcess(channelID)
+	resetClaudeSession(channelID)
+	watchManager.Stop(channelID, "all")
+	summaryStore.Remove(channelID)
+	if err := cfgMgr.PurgeSession(req.Name); err != nil {
+		return fmt.Sprintf("Session `%s` could not be removed", req.Name), err
+	}
+
+	report := fmt.Sprintf("Session `%s` purged", req.Name)
+	var folderErr error
+	if deleteFolder && req.WorkDir != "" {
+		if folderErr = removeWorkDir(req.WorkDir); folderErr == nil {
+			report += fmt.Sprintf(", folder `%s` deleted", req.WorkDir)
+		} else {
+			report += fmt.Sprintf(", folder `%s` not deleted", req.WorkDir)
+		}
+	}
+	logf("Purged session %s (%s)", req.Name, report)
+	timeline.Record(TimelineEvent{ChannelID: channelID, Kind: timelinePurge, Text: report})
+	return report, folderErr
+}
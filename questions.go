@@ -0,0 +1,522 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// questionSetRetention drops unanswered question sets after this long
+const questionSetRetention = 24 * time.Hour
+
+// questionTextCallbackID identifies the "Answer in text" modal
+const questionTextCallbackID = "question_text"
+
+// QuestionSet is one AskUserQuestion call posted to Slack. Answers are collected
+// per question and sent to Claude, in question order, once all are answered.
+type QuestionSet struct {
+	ID          string           `json:"id"`
+	SessionName string           `json:"session_name"`
+	ChannelID   string           `json:"channel_id"`
+	ThreadTS    string           `json:"thread_ts,omitempty"`
+	Questions   []PostedQuestion `json:"questions"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// PostedQuestion is a question message and its answer, once given
+type PostedQuestion struct {
+	Header      string   `json:"header"`
+	Question    string   `json:"question"`
+	Options     []string `json:"options"`
+	MultiSelect bool     `json:"multi_select,omitempty"`
+	MessageTS   string   `json:"message_ts"`
+	Answer      string   `json:"answer,omitempty"`
+	Answered    bool     `json:"answered"`
+	// Selected are the options toggled so far where checkboxes are buttons
+	Selected []int `json:"selected,omitempty"`
+}
+
+// Remaining returns how many questions still wait for an answer
+func (s *QuestionSet) Remaining() int {
+	n := 0
+	for _, q := range s.Questions {
+		if !q.Answered {
+			n++
+		}
+	}
+	return n
+}
+
+// QuestionStore persists open question sets (~/.ccsa/questions.json): the hook
+// process posts them, the listener collects the answers
+type QuestionStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+var questionStore = &QuestionStore{path: getQuestionStorePath()}
+
+// getQuestionStorePath returns the path to the open questions file
+func getQuestionStorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "questions.json")
+}
+
+func (s *QuestionStore) load() map[string]*QuestionSet {
+	sets := make(map[string]*QuestionSet)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return sets
+	}
+	json.Unmarshal(data, &sets)
+	return sets
+}
+
+func (s *QuestionStore) save(sets map[string]*QuestionSet) {
+	for id, set := range sets {
+		if time.Since(set.CreatedAt) > questionSetRetention {
+			delete(sets, id)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(sets, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, s.path)
+}
+
+// Add records a posted question set
+func (s *QuestionStore) Add(set *QuestionSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sets := s.load()
+	sets[set.ID] = set
+	s.save(sets)
+}
+
+// Answer records the chosen option for question qIdx of a set posted in channelID.
+// It returns the set, whether the answer was new (false if that question was
+// already answered) and whether the set is now complete, in which case it is
+// removed from the store.
+func (s *QuestionStore) Answer(setID, channelID string, qIdx, optIdx int) (*QuestionSet, bool, bool) {
+	return s.AnswerWith(setID, channelID, qIdx, func(q PostedQuestion) (string, bool) {
+		if optIdx < 0 || optIdx >= len(q.Options) {
+			return "", false
+		}
+		return q.Options[optIdx], true
+	})
+}
+
+// AnswerWith is Answer with the answer composed by pick from the stored question
+// (checked options, typed text); pick returns false to reject the answer
+func (s *QuestionStore) AnswerWith(setID, channelID string, qIdx int, pick func(q PostedQuestion) (string, bool)) (*QuestionSet, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sets := s.load()
+	set, ok := sets[setID]
+	if !ok || set.ChannelID != channelID || qIdx < 0 || qIdx >= len(set.Questions) {
+		return nil, false, false
+	}
+	q := &set.Questions[qIdx]
+	if q.Answered {
+		return set, false, false
+	}
+	answer, ok := pick(*q)
+	if !ok {
+		return set, false, false
+	}
+	q.Answer = answer
+	q.Answered = true
+
+	complete := set.Remaining() == 0
+	if complete {
+		delete(sets, setID)
+	}
+	s.save(sets)
+	return set, true, complete
+}
+
+// Toggle flips an option of a multi-select question whose checkboxes are
+// buttons; it returns the set, or nil if the question is no longer open
+func (s *QuestionStore) Toggle(setID, channelID string, qIdx, optIdx int) *QuestionSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sets := s.load()
+	set, ok := sets[setID]
+	if !ok || set.ChannelID != channelID || qIdx < 0 || qIdx >= len(set.Questions) {
+		return nil
+	}
+	q := &set.Questions[qIdx]
+	if q.Answered || !q.MultiSelect || optIdx < 0 || optIdx >= len(q.Options) {
+		return nil
+	}
+	if i := slices.Index(q.Selected, optIdx); i >= 0 {
+		q.Selected = slices.Delete(q.Selected, i, i+1)
+	} else {
+		q.Selected = append(q.Selected, optIdx)
+	}
+	s.save(sets)
+	return set
+}
+
+// postQuestionSet posts each question (in the run's thread when known) and records
+// the set so answers can be collected. Single-choice questions get one button per
+// option, multi-select ones checkboxes and a Submit button; every question can
+// also be answered in free text. Other chat backends have neither checkboxes
+// nor modals: options are toggle buttons there, and there is no free text.
+func postQuestionSet(config *Config, sessionName, channelID, sessionID string, questions []HookQuestion) {
+	set := &QuestionSet{
+		ID:          strconv.FormatInt(time.Now().UnixNano(), 36),
+		SessionName: sessionName,
+		ChannelID:   channelID,
+		CreatedAt:   time.Now(),
+	}
+	if run, ok := threadRegistry.Lookup(sessionID); ok {
+		set.ThreadTS = run.ThreadTS
+	}
+
+	for _, q := range questions {
+		if q.Question == "" {
+			continue
+		}
+		var options []string
+		for _, opt := range q.Options {
+			if opt.Label != "" {
+				options = append(options, opt.Label)
+			}
+		}
+		set.Questions = append(set.Questions, PostedQuestion{Header: q.Header, Question: q.Question, Options: options, MultiSelect: q.MultiSelect && len(options) > 0})
+	}
+
+	toggles := !onSlack(channelID)
+	for qIdx := range set.Questions {
+		q := &set.Questions[qIdx]
+		msg, buttons := questionMessage(set, qIdx, toggles)
+		ts, err := sendMessageWithButtonsGetTS(config, channelID, set.ThreadTS, msg, buttons, questionBlockID(set, qIdx))
+		if err != nil {
+			logf("Failed to post question %d: %v", qIdx+1, err)
+		}
+		q.MessageTS = ts
+	}
+
+	if len(set.Questions) > 0 {
+		questionStore.Add(set)
+		notifyEvent(config, pushWaiting, channelID, "Question: "+set.Questions[0].Question)
+	}
+}
+
+// questionBlockID is the block of a question's buttons
+func questionBlockID(set *QuestionSet, qIdx int) string {
+	return fmt.Sprintf("question_%s_%d", set.ID, qIdx)
+}
+
+// questionMessage renders a question and its buttons; with toggles, the options
+// of a multi-select question are buttons showing whether they're selected
+func questionMessage(set *QuestionSet, qIdx int, toggles bool) (string, []Element) {
+	q := &set.Questions[qIdx]
+	msg := fmt.Sprintf(":question: *%s*\n\n%s", q.Header, q.Question)
+	if len(set.Questions) > 1 {
+		msg = fmt.Sprintf(":question: *%s* (%d/%d)\n\n%s", q.Header, qIdx+1, len(set.Questions), q.Question)
+	}
+
+	var buttons []Element
+	submit := Element{
+		Type:     "button",
+		Text:     &TextObject{Type: "plain_text", Text: "Submit"},
+		ActionID: fmt.Sprintf("qsubmit_%d", qIdx),
+		Style:    "primary",
+		// Value format: setID:questionIndex
+		Value: fmt.Sprintf("%s:%d", set.ID, qIdx),
+	}
+	switch {
+	case q.MultiSelect && toggles:
+		msg += "\n_Tap all that apply, then Submit_"
+		for i, label := range q.Options {
+			if slices.Contains(q.Selected, i) {
+				label = ":white_check_mark: " + label
+			}
+			buttons = append(buttons, Element{
+				Type:     "button",
+				Text:     &TextObject{Type: "plain_text", Text: label},
+				ActionID: fmt.Sprintf("qtoggle_%d_%d", qIdx, i),
+				// Value format: setID:questionIndex:optionIndex
+				Value: fmt.Sprintf("%s:%d:%d", set.ID, qIdx, i),
+			})
+		}
+		buttons = append(buttons, submit)
+	case q.MultiSelect:
+		msg += "\n_Select all that apply, then Submit_"
+		checkboxes := Element{Type: "checkboxes", ActionID: fmt.Sprintf("qcheck_%d", qIdx)}
+		for i, label := range q.Options {
+			checkboxes.Options = append(checkboxes.Options, OptionObject{
+				Text:  &TextObject{Type: "plain_text", Text: label},
+				Value: strconv.Itoa(i),
+			})
+		}
+		buttons = append(buttons, checkboxes, submit)
+	default:
+		for i, label := range q.Options {
+			buttons = append(buttons, Element{
+				Type:     "button",
+				Text:     &TextObject{Type: "plain_text", Text: label},
+				ActionID: fmt.Sprintf("option_%d_%d", qIdx, i),
+				// Value format: setID:questionIndex:optionIndex
+				Value: fmt.Sprintf("%s:%d:%d", set.ID, qIdx, i),
+			})
+		}
+	}
+	if !toggles {
+		buttons = append(buttons, Element{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Answer in text"},
+			ActionID: fmt.Sprintf("qtext_%d", qIdx),
+			Value:    fmt.Sprintf("%s:%d", set.ID, qIdx),
+		})
+	}
+	return msg, buttons
+}
+
+// formatQuestionAnswers renders the answers as the prompt sent back to Claude
+func formatQuestionAnswers(set *QuestionSet) string {
+	var b strings.Builder
+	if len(set.Questions) == 1 {
+		b.WriteString("Answer to your question:\n")
+	} else {
+		b.WriteString("Answers to your questions:\n")
+	}
+	for i, q := range set.Questions {
+		fmt.Fprintf(&b, "%d. %s: %s\n   -> %s\n", i+1, q.Header, q.Question, q.Answer)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// parseQuestionRef splits a "setID:questionIndex[:optionIndex]" button value
+// (optIdx is -1 without an option)
+func parseQuestionRef(value string) (setID string, qIdx, optIdx int, ok bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", 0, 0, false
+	}
+	qIdx, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, 0, false
+	}
+	optIdx = -1
+	if len(parts) == 3 {
+		if optIdx, err = strconv.Atoi(parts[2]); err != nil {
+			return "", 0, 0, false
+		}
+	}
+	return parts[0], qIdx, optIdx, true
+}
+
+// handleQuestionAnswer records a tapped option
+func handleQuestionAnswer(config *Config, action BlockActionPayload, act BlockAction) {
+	var set *QuestionSet
+	var isNew, complete bool
+	setID, qIdx, optIdx, ok := parseQuestionRef(act.Value)
+	if ok && optIdx >= 0 {
+		set, isNew, complete = questionStore.Answer(setID, action.Channel.ID, qIdx, optIdx)
+	}
+	applyQuestionAnswer(config, action.User.ID, action.Channel.ID, action.Message.TS, action.Message.Text, set, qIdx, isNew, complete)
+}
+
+// handleQuestionSubmit answers a multi-select question with the checked options,
+// in the order they were offered
+func handleQuestionSubmit(config *Config, action BlockActionPayload, act BlockAction) {
+	setID, qIdx, _, ok := parseQuestionRef(act.Value)
+	if !ok {
+		return
+	}
+	var checked []int
+	if action.State != nil {
+		for _, opt := range action.State.Values[act.BlockID][fmt.Sprintf("qcheck_%d", qIdx)].SelectedOptions {
+			if i, err := strconv.Atoi(opt.Value); err == nil {
+				checked = append(checked, i)
+			}
+		}
+	}
+
+	empty := false
+	set, isNew, complete := questionStore.AnswerWith(setID, action.Channel.ID, qIdx, func(q PostedQuestion) (string, bool) {
+		// Toggle buttons (other chat backends) keep the selection in the store
+		selected := checked
+		if action.State == nil {
+			selected = slices.Clone(q.Selected)
+		}
+		if len(selected) == 0 {
+			empty = true
+			return "", false
+		}
+		sort.Ints(selected)
+		var labels []string
+		for _, i := range selected {
+			if i < 0 || i >= len(q.Options) {
+				return "", false
+			}
+			labels = append(labels, q.Options[i])
+		}
+		return strings.Join(labels, ", "), true
+	})
+	if empty {
+		hint := ":point_up: Select at least one option before submitting (or use *Answer in text*)"
+		if action.State == nil {
+			hint = ":point_up: Tap at least one option before submitting"
+		}
+		sendMessageToThread(config, action.Channel.ID, action.Message.TS, hint)
+		return
+	}
+	applyQuestionAnswer(config, action.User.ID, action.Channel.ID, action.Message.TS, action.Message.Text, set, qIdx, isNew, complete)
+}
+
+// handleQuestionToggle flips an option of a multi-select question shown as
+// toggle buttons, and redraws them
+func handleQuestionToggle(config *Config, action BlockActionPayload, act BlockAction) {
+	setID, qIdx, optIdx, ok := parseQuestionRef(act.Value)
+	if !ok || optIdx < 0 {
+		return
+	}
+	set := questionStore.Toggle(setID, action.Channel.ID, qIdx, optIdx)
+	if set == nil {
+		return
+	}
+	msg, buttons := questionMessage(set, qIdx, true)
+	if err := updateMessageWithButtons(config, action.Channel.ID, action.Message.TS, msg, buttons, questionBlockID(set, qIdx)); err != nil {
+		logf("Failed to update question %d: %v", qIdx+1, err)
+	}
+}
+
+// questionTextMeta is the private_metadata of the "Answer in text" modal
+type questionTextMeta struct {
+	SetID     string `json:"set_id"`
+	Question  int    `json:"question"`
+	ChannelID string `json:"channel_id"`
+}
+
+// openQuestionTextModal asks for a free-text answer to a question
+func openQuestionTextModal(config *Config, action BlockActionPayload, act BlockAction) {
+	setID, qIdx, _, ok := parseQuestionRef(act.Value)
+	if !ok || action.TriggerID == "" {
+		return
+	}
+	meta, _ := json.Marshal(questionTextMeta{SetID: setID, Question: qIdx, ChannelID: action.Channel.ID})
+	payload := map[string]interface{}{
+		"trigger_id": action.TriggerID,
+		"view": map[string]interface{}{
+			"type":             "modal",
+			"callback_id":      questionTextCallbackID,
+			"private_metadata": string(meta),
+			"title":            map[string]string{"type": "plain_text", "text": "Answer Claude"},
+			"submit":           map[string]string{"type": "plain_text", "text": "Send"},
+			"close":            map[string]string{"type": "plain_text", "text": "Cancel"},
+			"blocks": []map[string]interface{}{
+				{
+					"type": "section",
+					"text": map[string]string{"type": "mrkdwn", "text": action.Message.Text},
+				},
+				{
+					"type":     "input",
+					"block_id": "answer",
+					"label":    map[string]string{"type": "plain_text", "text": "Your answer"},
+					"element": map[string]interface{}{
+						"type":      "plain_text_input",
+						"action_id": "value",
+						"multiline": true,
+					},
+				},
+			},
+		},
+	}
+	result, err := slackAPIJSON(config, "views.open", payload)
+	if err == nil && !result.OK {
+		err = fmt.Errorf("views.open failed: %s", result.Error)
+	}
+	if err != nil {
+		logf("Failed to open answer modal: %v", err)
+		sendMessageToThread(config, action.Channel.ID, action.Message.TS, fmt.Sprintf(":x: Could not open the answer form: %v", err))
+	}
+}
+
+// handleQuestionTextSubmission records the answer typed in the modal
+func handleQuestionTextSubmission(config *Config, action BlockActionPayload) {
+	var meta questionTextMeta
+	if err := json.Unmarshal([]byte(action.View.PrivateMetadata), &meta); err != nil {
+		logf("Answer modal: invalid metadata: %v", err)
+		return
+	}
+	text := strings.TrimSpace(action.View.State.Values["answer"]["value"].Value)
+	set, isNew, complete := questionStore.AnswerWith(meta.SetID, meta.ChannelID, meta.Question, func(q PostedQuestion) (string, bool) {
+		return text, text != ""
+	})
+	if set == nil {
+		sendMessage(config, meta.ChannelID, ":information_source: That question is no longer open")
+		return
+	}
+	q := set.Questions[meta.Question]
+	applyQuestionAnswer(config, action.User.ID, meta.ChannelID, q.MessageTS, q.Question, set, meta.Question, isNew, complete)
+}
+
+// applyQuestionAnswer replaces the question's buttons with the answer, and sends
+// all answers to Claude once the last one is in
+func applyQuestionAnswer(config *Config, userID, channelID, messageTS, messageText string, set *QuestionSet, qIdx int, isNew, complete bool) {
+	if set == nil {
+		updateMessage(config, channelID, messageTS, messageText+"\n\n:information_source: This question is no longer open")
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	q := set.Questions[qIdx]
+	logf("Question %d/%d answered in %s: %s", qIdx+1, len(set.Questions), set.SessionName, q.Answer)
+	answered := fmt.Sprintf(":white_check_mark: *%s*\n\n%s\n:arrow_right: *%s*", q.Header, q.Question, q.Answer)
+	if remaining := set.Remaining(); remaining > 0 {
+		answered += fmt.Sprintf("\n_%d more question(s) to answer before this is sent_", remaining)
+	}
+	updateMessage(config, channelID, messageTS, answered)
+
+	if !complete {
+		return
+	}
+
+	notice := ":arrow_forward: All answers received - sending to Claude"
+	if len(set.Questions) == 1 {
+		notice = ":arrow_forward: Sending your answer to Claude"
+	}
+	var eventTS string
+	if set.ThreadTS != "" {
+		eventTS, _ = sendMessageToThreadGetTS(config, set.ChannelID, set.ThreadTS, notice)
+	} else {
+		eventTS, _ = sendMessage(config, set.ChannelID, notice)
+	}
+
+	// The session may have been renamed since the questions were posted
+	sessionName := set.SessionName
+	if name := getSessionByChannel(config, set.ChannelID); name != "" {
+		sessionName = name
+	}
+	msg := &QueuedMessage{
+		Text:      formatQuestionAnswers(set),
+		ChannelID: set.ChannelID,
+		ThreadTS:  set.ThreadTS,
+		EventTS:   eventTS,
+		UserID:    userID,
+		WorkDir:   sessionWorkDir(config, sessionName),
+	}
+	addReaction(config, msg.ChannelID, msg.EventTS, "eyes")
+	submitClaudeMessage(msg, config)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 )
 
@@ -14,14 +16,34 @@ type QueuedMessage struct {
 	UserID    string
 	WorkDir   string
 	FilePaths []string
+	// BatchedEventTS holds event timestamps of messages coalesced into this one
+	BatchedEventTS []string
+	// CacheKey is set for read-only prompts whose answer should be cached
+	CacheKey string
+	// NoCache skips the result cache lookup ("Re-run fresh")
+	NoCache bool
+	// Urgent messages wait ahead of the others (!urgent)
+	Urgent bool
+	// QueueID identifies the message in its channel's queue (!queue)
+	QueueID int
+	// OnFinish is called once the message ran, or was dropped or cancelled
+	// (resp is nil then)
+	OnFinish func(resp *ClaudeResponse, err error) `json:"-"`
+}
+
+// EventTimestamps returns the timestamps of all Slack messages behind this queued message
+func (m *QueuedMessage) EventTimestamps() []string {
+	return append([]string{m.EventTS}, m.BatchedEventTS...)
 }
 
 // ChannelQueue manages message queues per channel
 type ChannelQueue struct {
 	mu       sync.Mutex
-	busy     map[string]bool                // channel -> is processing
-	queues   map[string][]*QueuedMessage    // channel -> queued messages
+	busy     map[string]bool                 // channel -> is processing
+	queues   map[string][]*QueuedMessage     // channel -> queued messages
+	running  map[string]*QueuedMessage       // channel -> message being processed
 	handlers map[string]func(*QueuedMessage) // channel -> handler function
+	lastID   int
 }
 
 // NewChannelQueue creates a new queue manager
@@ -29,6 +51,7 @@ func NewChannelQueue() *ChannelQueue {
 	return &ChannelQueue{
 		busy:     make(map[string]bool),
 		queues:   make(map[string][]*QueuedMessage),
+		running:  make(map[string]*QueuedMessage),
 		handlers: make(map[string]func(*QueuedMessage)),
 	}
 }
@@ -41,23 +64,50 @@ func (cq *ChannelQueue) SetHandler(channelID string, handler func(*QueuedMessage
 }
 
 // Submit submits a message for processing
-// Returns: (isQueued bool, queuePosition int)
+// Returns: (isQueued bool, queuePosition int, duplicate bool)
 // isQueued=false means it will be processed immediately
 // isQueued=true means it was added to queue, position is 1-indexed
-func (cq *ChannelQueue) Submit(msg *QueuedMessage) (bool, int) {
+// duplicate=true means it repeats the message just before it and was dropped
+func (cq *ChannelQueue) Submit(msg *QueuedMessage) (bool, int, bool) {
 	cq.mu.Lock()
 	defer cq.mu.Unlock()
 
+	cq.lastID++
+	msg.QueueID = cq.lastID
+
 	if cq.busy[msg.ChannelID] {
-		// Channel is busy, queue the message
-		cq.queues[msg.ChannelID] = append(cq.queues[msg.ChannelID], msg)
-		position := len(cq.queues[msg.ChannelID])
-		return true, position
+		queue := cq.queues[msg.ChannelID]
+		previous := cq.running[msg.ChannelID]
+		if len(queue) > 0 {
+			previous = queue[len(queue)-1]
+		}
+		if isDuplicatePrompt(previous, msg) {
+			return true, 0, true
+		}
+
+		// Channel is busy, queue the message (urgent ones after earlier urgent ones)
+		position := len(queue)
+		if msg.Urgent {
+			position = 0
+			for position < len(queue) && queue[position].Urgent {
+				position++
+			}
+		}
+		cq.queues[msg.ChannelID] = slices.Insert(queue, position, msg)
+		return true, position + 1, false
 	}
 
 	// Channel is free, mark as busy and process
 	cq.busy[msg.ChannelID] = true
-	return false, 0
+	cq.running[msg.ChannelID] = msg
+	return false, 0, false
+}
+
+// isDuplicatePrompt reports whether msg repeats previous (same text in the
+// same thread, no files): a message sent twice runs once
+func isDuplicatePrompt(previous, msg *QueuedMessage) bool {
+	return previous != nil && len(msg.FilePaths) == 0 && len(previous.FilePaths) == 0 &&
+		previous.ThreadTS == msg.ThreadTS && strings.TrimSpace(previous.Text) == strings.TrimSpace(msg.Text)
 }
 
 // Done marks current processing as complete and processes next in queue
@@ -71,15 +121,57 @@ func (cq *ChannelQueue) Done(channelID string) *QueuedMessage {
 		// Get next message
 		next := queue[0]
 		cq.queues[channelID] = queue[1:]
+		cq.running[channelID] = next
 		// Keep busy=true since we're processing next
 		return next
 	}
 
 	// Queue empty, mark as free
 	cq.busy[channelID] = false
+	delete(cq.running, channelID)
+	return nil
+}
+
+// Snapshot returns the message being processed in a channel and the queued ones, in order
+func (cq *ChannelQueue) Snapshot(channelID string) (*QueuedMessage, []*QueuedMessage) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	return cq.running[channelID], slices.Clone(cq.queues[channelID])
+}
+
+// Cancel removes a queued message by QueueID, returning it (nil if it already ran)
+func (cq *ChannelQueue) Cancel(channelID string, queueID int) *QueuedMessage {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	queue := cq.queues[channelID]
+	for i, m := range queue {
+		if m.QueueID == queueID {
+			cq.queues[channelID] = slices.Delete(queue, i, i+1)
+			return m
+		}
+	}
 	return nil
 }
 
+// EditQueued replaces the text of the queued message sent as eventTS. found is
+// false when no queued message has that timestamp (it ran or was never
+// queued); updated is false when it was coalesced with other messages.
+func (cq *ChannelQueue) EditQueued(channelID, eventTS, text string) (found, updated bool) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	for _, m := range cq.queues[channelID] {
+		if !slices.Contains(m.EventTimestamps(), eventTS) {
+			continue
+		}
+		if len(m.BatchedEventTS) > 0 {
+			return true, false
+		}
+		m.Text = text
+		return true, true
+	}
+	return false, false
+}
+
 // QueueLength returns the current queue length for a channel
 func (cq *ChannelQueue) QueueLength(channelID string) int {
 	cq.mu.Lock()
@@ -108,3 +200,56 @@ func (cq *ChannelQueue) GetQueueStatus(channelID string) string {
 	}
 	return fmt.Sprintf("processing + %d queued", qLen)
 }
+
+// Drain removes and returns every queued (not yet running) message
+func (cq *ChannelQueue) Drain() []*QueuedMessage {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	var drained []*QueuedMessage
+	for channelID, queue := range cq.queues {
+		drained = append(drained, queue...)
+		delete(cq.queues, channelID)
+	}
+	return drained
+}
+
+// BusyChannels returns the channels currently processing a message
+func (cq *ChannelQueue) BusyChannels() []string {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	var channels []string
+	for channelID, busy := range cq.busy {
+		if busy {
+			channels = append(channels, channelID)
+		}
+	}
+	return channels
+}
+
+// ChannelCount returns the number of channels with queue state
+func (cq *ChannelQueue) ChannelCount() int {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	return len(cq.busy)
+}
+
+// PruneIdle removes state for channels that are idle with an empty queue
+// Returns the number of channels pruned
+func (cq *ChannelQueue) PruneIdle() int {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	pruned := 0
+	for channelID, busy := range cq.busy {
+		if !busy && len(cq.queues[channelID]) == 0 {
+			delete(cq.busy, channelID)
+			delete(cq.queues, channelID)
+			delete(cq.running, channelID)
+			delete(cq.handlers, channelID)
+			pruned++
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxQueueButtons is how many queued messages get a Cancel button (Slack's limit per block)
+const maxQueueButtons = 25
+
+// formatQueue renders a channel's running and queued messages, with a Cancel
+// button per queued one
+func formatQueue(channelID string, running *QueuedMessage, queued []*QueuedMessage) (string, []Element) {
+	if running == nil && len(queued) == 0 {
+		return ":white_check_mark: Nothing running or queued in this channel", nil
+	}
+	var lines []string
+	if running != nil {
+		lines = append(lines, ":arrow_forward: *Running:* "+timelinePromptText(running.Text))
+	}
+	if len(queued) == 0 {
+		lines = append(lines, "_Nothing queued_")
+	} else {
+		lines = append(lines, fmt.Sprintf(":hourglass: *Queued (%d):*", len(queued)))
+	}
+
+	var buttons []Element
+	for i, m := range queued {
+		line := fmt.Sprintf("%d. %s", i+1, timelinePromptText(m.Text))
+		if m.Urgent {
+			line += " :rotating_light:"
+		}
+		lines = append(lines, line)
+		if i < maxQueueButtons {
+			buttons = append(buttons, Element{
+				Type:     "button",
+				Text:     &TextObject{Type: "plain_text", Text: fmt.Sprintf("Cancel #%d", i+1)},
+				ActionID: fmt.Sprintf("queue_cancel_%d", m.QueueID),
+				Value:    channelID + ":" + strconv.Itoa(m.QueueID),
+			})
+		}
+	}
+	return strings.Join(lines, "\n"), buttons
+}
+
+// postQueue answers !queue
+func postQueue(config *Config, channelID, threadTS string) error {
+	running, queued := messageQueue.Snapshot(channelID)
+	text, buttons := formatQueue(channelID, running, queued)
+	if len(buttons) > 0 {
+		return sendMessageWithButtonsToThread(config, channelID, threadTS, text, buttons, "queue_list")
+	}
+	if threadTS != "" {
+		return sendMessageToThread(config, channelID, threadTS, text)
+	}
+	_, err := sendMessage(config, channelID, text)
+	return err
+}
+
+// handleQueueCancelAction drops a queued message and refreshes the !queue listing
+func handleQueueCancelAction(config *Config, action BlockActionPayload, act BlockAction) {
+	channelID, idStr, _ := strings.Cut(act.Value, ":")
+	queueID, _ := strconv.Atoi(idStr)
+
+	note := ":information_source: That message already started or was cancelled"
+	if m := messageQueue.Cancel(channelID, queueID); m != nil {
+		logf("Cancelled queued message in channel %s", channelID)
+		for _, ts := range m.EventTimestamps() {
+			removeReaction(config, channelID, ts, "hourglass_flowing_sand")
+			addReaction(config, channelID, ts, "no_entry_sign")
+		}
+		note = fmt.Sprintf(":no_entry_sign: Cancelled by <@%s>: %s", action.User.ID, timelinePromptText(m.Text))
+		if m.OnFinish != nil {
+			m.OnFinish(nil, fmt.Errorf("cancelled from the queue"))
+		}
+	}
+
+	running, queued := messageQueue.Snapshot(channelID)
+	text, buttons := formatQueue(channelID, running, queued)
+	updateMessageWithButtons(config, action.Channel.ID, action.Message.TS, text+"\n\n"+note, buttons, "queue_list")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quietDigestLines is how many held messages a digest shows inline
+const quietDigestLines = 30
+
+// parseQuietHours reads "23:00-08:00" as minutes since midnight
+func parseQuietHours(spec string) (start, end int, err error) {
+	from, to, ok := strings.Cut(strings.ReplaceAll(spec, " ", ""), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected HH:MM-HH:MM, e.g. 23:00-08:00")
+	}
+	if start, err = parseClock(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseClock(to); err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("quiet hours start and end at the same time")
+	}
+	return start, end, nil
+}
+
+// parseClock reads "HH:MM" as minutes since midnight
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// inQuietHours reports whether now falls in the config's quiet hours (local
+// time); the range may wrap past midnight
+func inQuietHours(config *Config, now time.Time) bool {
+	if config == nil || config.QuietHours == "" {
+		return false
+	}
+	start, end, err := parseQuietHours(config.QuietHours)
+	if err != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// QuietDigest holds the messages suppressed during quiet hours, per channel
+type QuietDigest struct {
+	mu    sync.Mutex
+	lines map[string][]string
+}
+
+var quietDigest = &QuietDigest{lines: make(map[string][]string)}
+
+// Add holds a message for the channel's digest
+func (d *QuietDigest) Add(channelID, text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lines[channelID] = append(d.lines[channelID], text)
+}
+
+// TakeAll returns and clears the held messages of every channel
+func (d *QuietDigest) TakeAll() map[string][]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	held := d.lines
+	d.lines = make(map[string][]string)
+	return held
+}
+
+// formatQuietDigest renders a channel's held messages; the full list is
+// returned separately when it doesn't fit inline
+func formatQuietDigest(lines []string) (string, string) {
+	text := fmt.Sprintf(":sunrise: *Quiet hours digest* - %d message(s) held overnight:\n", len(lines))
+	shown := lines
+	if len(shown) > quietDigestLines {
+		shown = shown[len(shown)-quietDigestLines:]
+		text += fmt.Sprintf("_(last %d - full list attached)_\n", quietDigestLines)
+	}
+	text += strings.Join(shown, "\n")
+	if len(shown) == len(lines) {
+		return text, ""
+	}
+	return text, strings.Join(lines, "\n")
+}
+
+// startQuietDigest posts each channel's digest once quiet hours are over
+func startQuietDigest(cfgMgr *ConfigManager, done <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				config := cfgMgr.Get()
+				if inQuietHours(config, now) {
+					continue
+				}
+				held := quietDigest.TakeAll()
+				channels := make([]string, 0, len(held))
+				for channelID := range held {
+					channels = append(channels, channelID)
+				}
+				sort.Strings(channels)
+				for _, channelID := range channels {
+					text, full := formatQuietDigest(held[channelID])
+					if _, err := sendMessage(config, channelID, text); err != nil {
+						logf("Quiet hours digest for %s failed: %v", channelID, err)
+						continue
+					}
+					if full != "" {
+						uploadSnippet(config, channelID, "", "quiet-hours.txt", full, "Quiet hours digest")
+					}
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// pendingRecoveries holds messages that hit a login or trust screen, for the
+// recovery buttons (channel:eventTS -> *QueuedMessage)
+var pendingRecoveries sync.Map
+
+// hasCLIRecovery reports whether a screen gets a dedicated recovery message
+func hasCLIRecovery(kind string) bool {
+	return kind == cliScreenLogin || kind == cliScreenTrust
+}
+
+// claudeStatePath returns the CLI's state file (~/.claude.json) for a channel's
+// runs: sandboxed sessions keep their own in the container's home
+func claudeStatePath(config *Config, channelID string) string {
+	if sessionSandboxed(config, channelID) {
+		return filepath.Join(getSandboxStateDir(getSessionByChannel(config, channelID)), ".claude.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude.json")
+}
+
+// trustClaudeFolder records that the folder trust dialog was accepted for dir,
+// the same way the CLI does, keeping everything else in the state file
+func trustClaudeFolder(statePath, dir string) error {
+	state := make(map[string]interface{})
+	if data, err := os.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("parse %s: %w", statePath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	projects, _ := state["projects"].(map[string]interface{})
+	if projects == nil {
+		projects = make(map[string]interface{})
+		state["projects"] = projects
+	}
+	project, _ := projects[dir].(map[string]interface{})
+	if project == nil {
+		project = make(map[string]interface{})
+		projects[dir] = project
+	}
+	project["hasTrustDialogAccepted"] = true
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, statePath)
+}
+
+// postCLIRecovery explains how to get past a login or trust screen and offers
+// to retry the message once that's done
+func postCLIRecovery(config *Config, msg *QueuedMessage, kind string) {
+	remote := sessionHost(config, msg.ChannelID)
+	where := "the machine running the bot"
+	if remote != "" {
+		where = fmt.Sprintf("agent `%s`", remote)
+	} else if sessionSandboxed(config, msg.ChannelID) {
+		where = "the sandbox (set `ANTHROPIC_API_KEY` or `CLAUDE_CODE_OAUTH_TOKEN` for the listener)"
+	}
+
+	retryID := msg.ChannelID + ":" + msg.EventTS
+	var text string
+	buttons := []Element{{
+		Type:     "button",
+		Text:     &TextObject{Type: "plain_text", Text: "Retry"},
+		ActionID: "recover_retry",
+		Value:    retryID,
+	}}
+	switch kind {
+	case cliScreenLogin:
+		text = fmt.Sprintf(":closed_lock_with_key: *Claude isn't logged in* on %s, so your message wasn't run.\n"+
+			"1. Run `claude` there and type `/login` (or run `claude setup-token` and set `CLAUDE_CODE_OAUTH_TOKEN` for the listener)\n"+
+			"2. Tap *Retry*", where)
+	case cliScreenTrust:
+		text = fmt.Sprintf(":shield: *Claude is waiting for the folder trust prompt* for `%s`, so your message wasn't run.\n"+
+			"Tap *Trust folder & retry* to accept it (recorded in Claude's `.claude.json`, like answering the prompt), "+
+			"or run `claude` in that folder once on %s and accept.", msg.WorkDir, where)
+		if remote == "" {
+			buttons = append([]Element{{
+				Type:     "button",
+				Text:     &TextObject{Type: "plain_text", Text: "Trust folder & retry"},
+				ActionID: "recover_trust",
+				Value:    retryID,
+				Style:    "primary",
+			}}, buttons...)
+		}
+	default:
+		return
+	}
+
+	pendingRecoveries.Store(retryID, msg)
+	if err := sendMessageWithButtonsToThread(config, msg.ChannelID, msg.ThreadTS, text, buttons, "recover_"+msg.EventTS); err != nil {
+		logf("Failed to post recovery steps: %v", err)
+		pendingRecoveries.Delete(retryID)
+	}
+}
+
+// handleRecoveryAction runs a message again after a login or trust screen,
+// accepting the trust prompt first for recover_trust
+func handleRecoveryAction(config *Config, action BlockActionPayload, act BlockAction) {
+	pending, ok := pendingRecoveries.LoadAndDelete(act.Value)
+	if !ok {
+		updateMessage(config, action.Channel.ID, action.Message.TS, ":information_source: Already retried")
+		return
+	}
+	msg := pending.(*QueuedMessage)
+
+	if act.ActionID == "recover_trust" {
+		if err := trustClaudeFolder(claudeStatePath(config, msg.ChannelID), msg.WorkDir); err != nil {
+			logf("Failed to trust %s: %v", msg.WorkDir, err)
+			updateMessage(config, action.Channel.ID, action.Message.TS, fmt.Sprintf(":x: Could not record folder trust: %v", err))
+			return
+		}
+		logf("Trusted folder %s", msg.WorkDir)
+	}
+
+	updateMessage(config, action.Channel.ID, action.Message.TS, ":arrows_counterclockwise: Retrying your message")
+	for _, ts := range msg.EventTimestamps() {
+		removeReaction(config, msg.ChannelID, ts, "white_check_mark")
+		addReaction(config, msg.ChannelID, ts, "eyes")
+	}
+	dispatchClaudeMessage(msg, config)
+}
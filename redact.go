@@ -0,0 +1,85 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redactedText replaces a secret in messages posted to Slack
+const redactedText = "[redacted]"
+
+// builtinSecretPatterns match common credentials; for patterns with a group
+// only the group (the value after "password=" ...) is masked
+var builtinSecretPatterns = []string{
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,                                     // AWS access key ID
+	`(?i)aws_secret_access_key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`, // AWS secret key
+	`\bxox[abposr]-[0-9A-Za-z-]{10,}`,                                   // Slack tokens
+	`\bxapp-[0-9A-Za-z-]{10,}`,                                          // Slack app tokens
+	`\bgh[pousr]_[A-Za-z0-9]{36,}`,                                      // GitHub tokens
+	`\bgithub_pat_[A-Za-z0-9_]{22,}`,                                    // GitHub fine-grained tokens
+	`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`,                            // Anthropic / OpenAI keys
+	`\bAIza[0-9A-Za-z_-]{35}`,                                           // Google API keys
+	`\b[rs]k_live_[0-9A-Za-z]{16,}`,                                     // Stripe live keys
+	`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`,   // JWTs
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{20,})`,
+	`(?i)\b(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)["']?\s*[:=]\s*["']?([^\s"'` + "`" + `,;]{8,})`,
+}
+
+// compiledRedactions caches the compiled patterns for the current config
+var compiledRedactions struct {
+	sync.Mutex
+	source string
+	res    []*regexp.Regexp
+}
+
+// redactionRegexps compiles the built-in and configured patterns; invalid
+// ones are logged and skipped
+func redactionRegexps(config *Config) []*regexp.Regexp {
+	if config != nil && config.RedactSecrets != nil && !*config.RedactSecrets {
+		return nil
+	}
+	patterns := builtinSecretPatterns
+	if config != nil && len(config.RedactPatterns) > 0 {
+		patterns = append(append([]string(nil), patterns...), config.RedactPatterns...)
+	}
+	source := strings.Join(patterns, "\n")
+
+	compiledRedactions.Lock()
+	defer compiledRedactions.Unlock()
+	if compiledRedactions.res != nil && compiledRedactions.source == source {
+		return compiledRedactions.res
+	}
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			logf("Ignoring invalid redact pattern %q: %v", p, err)
+			continue
+		}
+		res = append(res, re)
+	}
+	compiledRedactions.source, compiledRedactions.res = source, res
+	return res
+}
+
+// redactSecrets masks credentials in text about to be posted to Slack, where
+// session channels may be visible to the whole workspace
+func redactSecrets(config *Config, text string) string {
+	for _, re := range redactionRegexps(config) {
+		if re.NumSubexp() == 0 {
+			text = re.ReplaceAllLiteralString(text, redactedText)
+			continue
+		}
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			// Mask the value only, keeping "password=" for context
+			loc := re.FindStringSubmatchIndex(match)
+			if len(loc) < 4 || loc[2] < 0 {
+				return redactedText
+			}
+			return match[:loc[2]] + redactedText + match[loc[3]:]
+		})
+	}
+	return text
+}
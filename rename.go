@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const renameUsage = "Usage: `!rename <old> <new> [--move]` - rename a session and its channel (`--move` renames the project folder too)"
+
+// parseRenameArgs splits "!rename" arguments into the old and new session names
+func parseRenameArgs(arg string) (oldName, newName string, move bool, ok bool) {
+	var names []string
+	for _, field := range strings.Fields(arg) {
+		if field == "--move" {
+			move = true
+		} else {
+			names = append(names, field)
+		}
+	}
+	if len(names) != 2 || names[0] == names[1] {
+		return "", "", false, false
+	}
+	return names[0], names[1], move, true
+}
+
+// renameSession renames a session: the Slack channel, the session's config
+// entries, its sandbox state and, with move, its project folder and Claude's
+// transcripts (so the conversation resumes from the new folder). Sessions run
+// in the folder named after them, so without move the new folder must exist.
+func renameSession(cfgMgr *ConfigManager, config *Config, oldName, newName string, move bool) (string, error) {
+	channelID, ok := cfgMgr.GetSession(oldName)
+	if !ok {
+		return "", fmt.Errorf("session `%s` not found (see `!sessions`)", oldName)
+	}
+	if _, taken := cfgMgr.GetSession(newName); taken {
+		return "", fmt.Errorf("session `%s` already exists", newName)
+	}
+	if _, running := activeProcesses.Load(channelID); running || (messageQueue != nil && messageQueue.IsBusy(channelID)) {
+		return "", fmt.Errorf("<#%s> is busy - wait for the task to finish or `!cancel` it", channelID)
+	}
+
+	host := config.SessionHosts[oldName]
+	oldDir := sessionWorkDir(config, oldName)
+	newDir := filepath.Join(filepath.Dir(oldDir), newName) // same base directory
+	if host != "" && move {
+		return "", fmt.Errorf("`--move` isn't supported for sessions on agent `%s` - rename the folder there first", host)
+	}
+	if host == "" {
+		_, newErr := os.Stat(newDir)
+		if move {
+			if newErr == nil {
+				return "", fmt.Errorf("`%s` already exists", newDir)
+			}
+			if _, err := os.Stat(oldDir); err != nil {
+				return "", fmt.Errorf("nothing to move: %v", err)
+			}
+		} else if newErr != nil {
+			return "", fmt.Errorf("sessions run in the folder named after them and `%s` doesn't exist - add `--move` to rename `%s` too", newDir, oldDir)
+		}
+	}
+
+	if move {
+		if err := os.Rename(oldDir, newDir); err != nil {
+			return "", fmt.Errorf("move folder: %v", err)
+		}
+	}
+	if toSlackChannelName(oldName) != toSlackChannelName(newName) {
+		if err := renameChannel(config, channelID, newName); err != nil {
+			if move {
+				os.Rename(newDir, oldDir)
+			}
+			return "", err
+		}
+	}
+	if err := cfgMgr.RenameSession(oldName, newName); err != nil {
+		return "", err
+	}
+
+	var notes []string
+	if move {
+		oldTranscripts, newTranscripts := claudeTranscriptDir(oldDir), claudeTranscriptDir(newDir)
+		if _, err := os.Stat(oldTranscripts); err == nil {
+			if err := os.Rename(oldTranscripts, newTranscripts); err != nil {
+				logf("Failed to move transcripts for %s: %v", newName, err)
+				notes = append(notes, ":warning: Claude's transcripts weren't moved, the next message may offer to start fresh")
+			}
+		}
+		notes = append(notes, fmt.Sprintf(":file_folder: Moved `%s` to `%s`", oldDir, newDir))
+	}
+	if config.SandboxSessions[oldName] {
+		if err := os.Rename(getSandboxStateDir(oldName), getSandboxStateDir(newName)); err != nil && !os.IsNotExist(err) {
+			logf("Failed to move sandbox state for %s: %v", newName, err)
+		}
+	}
+
+	logf("Session renamed: %s -> %s (move: %v)", oldName, newName, move)
+	msg := fmt.Sprintf(":label: Session `%s` renamed to `%s` (<#%s>)", oldName, newName, channelID)
+	for _, note := range notes {
+		msg += "\n" + note
+	}
+	return msg, nil
+}
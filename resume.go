@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pendingStaleMessages holds the message that triggered a stale-session prompt (channelID -> *QueuedMessage)
+var pendingStaleMessages sync.Map
+
+var nonAlnumRe = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// claudeProjectsDir returns where Claude stores session transcripts (~/.claude/projects)
+func claudeProjectsDir() string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "projects")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude", "projects")
+}
+
+// claudeTranscriptDir returns the transcript folder Claude uses for a working directory
+// (every non-alphanumeric character of the resolved absolute path becomes "-")
+func claudeTranscriptDir(workDir string) string {
+	if abs, err := filepath.Abs(workDir); err == nil {
+		workDir = abs
+	}
+	// Claude runs in the real folder (its cwd), e.g. for !fork --shared links
+	if real, err := filepath.EvalSymlinks(workDir); err == nil {
+		workDir = real
+	}
+	return filepath.Join(claudeProjectsDir(), nonAlnumRe.ReplaceAllString(workDir, "-"))
+}
+
+// isSessionStale reports whether the stored session ID can no longer be resumed
+// because its transcript is gone. Unknown layouts are never reported stale.
+func isSessionStale(workDir, sessionID string) bool {
+	if _, err := os.Stat(claudeProjectsDir()); err != nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(claudeTranscriptDir(workDir), sessionID+".jsonl"))
+	return os.IsNotExist(err)
+}
+
+// latestTranscriptSession returns the most recent resumable session for a working directory
+func latestTranscriptSession(workDir string) (string, time.Time, bool) {
+	entries, err := os.ReadDir(claudeTranscriptDir(workDir))
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	var latest string
+	var latestMod time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latestMod) {
+			latest = strings.TrimSuffix(entry.Name(), ".jsonl")
+			latestMod = info.ModTime()
+		}
+	}
+	return latest, latestMod, latest != ""
+}
+
+// offerStaleSessionResume checks the channel's session before running msg. If the
+// stored session can't be resumed, it parks msg and posts resume options instead.
+// Returns true when msg was parked.
+func offerStaleSessionResume(msg *QueuedMessage, config *Config) bool {
+	// Transcripts of agent and sandboxed sessions live elsewhere
+	if sessionHost(config, msg.ChannelID) != "" || sessionSandboxed(config, msg.ChannelID) {
+		return false
+	}
+	sid, ok := getClaudeSessionID(msg.ChannelID)
+	if !ok || !isSessionStale(msg.WorkDir, sid) {
+		return false
+	}
+	logf("Stale session %s for channel %s", sid, msg.ChannelID)
+
+	if previous, loaded := pendingStaleMessages.Swap(msg.ChannelID, msg); loaded {
+		// Only the latest message is kept; clear the older one's status
+		old := previous.(*QueuedMessage)
+		for _, ts := range old.EventTimestamps() {
+			removeReaction(config, old.ChannelID, ts, "eyes")
+		}
+	}
+
+	var buttons []Element
+	text := fmt.Sprintf(":warning: The previous Claude session (`%s`) can no longer be resumed - its transcript is gone.", shortID(sid))
+	if latest, mod, found := latestTranscriptSession(msg.WorkDir); found {
+		text += fmt.Sprintf("\nMost recent local session: `%s` (%s)", shortID(latest), mod.Format("Mon Jan 2 15:04"))
+		buttons = append(buttons, Element{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Resume latest session"},
+			ActionID: "stale_resume",
+			Value:    msg.ChannelID + ":" + latest,
+			Style:    "primary",
+		})
+	}
+	text += "\nHow should I run your message?"
+	buttons = append(buttons,
+		Element{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Start fresh"},
+			ActionID: "stale_fresh",
+			Value:    msg.ChannelID,
+		},
+		Element{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Cancel"},
+			ActionID: "stale_cancel",
+			Value:    msg.ChannelID,
+			Style:    "danger",
+		},
+	)
+
+	if err := sendMessageWithButtons(config, msg.ChannelID, text, buttons, "stale_"+msg.ChannelID); err != nil {
+		// Can't ask: behave as before and let Claude start over
+		logf("Failed to post resume options: %v", err)
+		pendingStaleMessages.Delete(msg.ChannelID)
+		resetClaudeSession(msg.ChannelID)
+		return false
+	}
+	return true
+}
+
+// handleStaleSessionAction applies the chosen resume option and runs the parked message
+func handleStaleSessionAction(config *Config, action BlockActionPayload, act BlockAction) {
+	channelID, sessionID, _ := strings.Cut(act.Value, ":")
+	pending, ok := pendingStaleMessages.LoadAndDelete(channelID)
+	if !ok {
+		updateMessage(config, action.Channel.ID, action.Message.TS, ":information_source: Already handled")
+		return
+	}
+	msg := pending.(*QueuedMessage)
+
+	switch act.ActionID {
+	case "stale_resume":
+		claudeSessionIDs.Store(channelID, sessionID)
+		saveSessionsToDisk()
+		updateMessage(config, action.Channel.ID, action.Message.TS, fmt.Sprintf(":arrow_forward: Resuming session `%s`", shortID(sessionID)))
+	case "stale_fresh":
+		resetClaudeSession(channelID)
+		updateMessage(config, action.Channel.ID, action.Message.TS, ":sparkles: Starting a fresh session")
+	default:
+		for _, ts := range msg.EventTimestamps() {
+			removeReaction(config, msg.ChannelID, ts, "eyes")
+		}
+		updateMessage(config, action.Channel.ID, action.Message.TS, ":no_entry_sign: Message dropped")
+		return
+	}
+
+	dispatchClaudeMessage(msg, config)
+}
+
+// shortID abbreviates a session ID for display
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reviewDiffMax caps the diff fed to a review run; larger diffs are cut
+const reviewDiffMax = 120000
+
+// reviewSystemPrompt turns the one-shot run into a reviewer that answers in JSON
+const reviewSystemPrompt = `You are reviewing a GitHub pull request. You may read files in the working directory for context but never modify anything.
+Focus on bugs, security problems, races, error handling, missing tests and confusing code. Skip praise and style nits a formatter would fix.
+Answer with a single JSON object and nothing else:
+{"summary": "<2-5 sentence overall assessment>", "comments": [{"path": "<file path as in the diff>", "line": <line number in the new version of the file, on a line present in the diff>, "severity": "blocker|major|minor|nit", "body": "<the problem and a concrete fix>"}]}
+Use an empty comments array if there is nothing worth raising.`
+
+var prURLRe = regexp.MustCompile(`^https?://github\.com/([\w.-]+)/([\w.-]+)/pull/(\d+)`)
+
+// pullRequestRef identifies a pull request on GitHub
+type pullRequestRef struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (pr pullRequestRef) String() string {
+	return fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repo, pr.Number)
+}
+
+func (pr pullRequestRef) URL() string {
+	return fmt.Sprintf("https://github.com/%s/%s/pull/%d", pr.Owner, pr.Repo, pr.Number)
+}
+
+// reviewComment is one finding on a line of the diff
+type reviewComment struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Body     string `json:"body"`
+}
+
+// prReview is the structured answer of a review run
+type prReview struct {
+	Summary  string          `json:"summary"`
+	Comments []reviewComment `json:"comments"`
+}
+
+// parsePullRequestURL reads owner, repo and number from a PR URL (Slack <...> markup allowed)
+func parsePullRequestURL(s string) (pullRequestRef, bool) {
+	m := prURLRe.FindStringSubmatch(cleanSlackMarkup(strings.TrimSpace(s)))
+	if m == nil {
+		return pullRequestRef{}, false
+	}
+	n, _ := strconv.Atoi(m[3])
+	return pullRequestRef{Owner: m[1], Repo: m[2], Number: n}, true
+}
+
+// parseReviewArgs splits "<pr-url> [--submit]"
+func parseReviewArgs(args string) (pullRequestRef, bool, bool) {
+	var pr pullRequestRef
+	found, submit := false, false
+	for _, f := range strings.Fields(args) {
+		if f == "--submit" {
+			submit = true
+			continue
+		}
+		if ref, ok := parsePullRequestURL(f); ok && !found {
+			pr, found = ref, true
+			continue
+		}
+		return pullRequestRef{}, false, false
+	}
+	return pr, submit, found
+}
+
+// parseReviewOutput extracts the review JSON from Claude's answer, which may
+// be wrapped in prose or a code fence
+func parseReviewOutput(out string) (*prReview, error) {
+	start, end := strings.Index(out, "{"), strings.LastIndex(out, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in the answer")
+	}
+	var review prReview
+	if err := json.Unmarshal([]byte(out[start:end+1]), &review); err != nil {
+		return nil, fmt.Errorf("invalid review JSON: %w", err)
+	}
+	if review.Summary == "" && len(review.Comments) == 0 {
+		return nil, fmt.Errorf("empty review")
+	}
+	return &review, nil
+}
+
+// githubAPI calls the GitHub REST API with github_token, or through `gh api`
+// (and its login) when no token is configured
+func githubAPI(config *Config, method, path, accept string, body []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if config.GitHubToken != "" {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, "https://api.github.com"+path, reader)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+config.GitHubToken)
+		req.Header.Set("Accept", accept)
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("GitHub API %s: %s", resp.Status, strings.TrimSpace(string(data)))
+		}
+		return data, nil
+	}
+
+	if _, err := exec.LookPath("gh"); err != nil {
+		return nil, fmt.Errorf("set `github_token` in the config or install the gh CLI")
+	}
+	args := []string{"api", "-X", method, "-H", "Accept: " + accept, path}
+	if body != nil {
+		args = append(args, "--input", "-")
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Env = runEnv(config)
+	if body != nil {
+		cmd.Stdin = bytes.NewReader(body)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gh api: %v - %s", err, strings.TrimSpace(stderr.String()+" "+stdout.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// fetchPullRequestDiff returns the unified diff of a pull request
+func fetchPullRequestDiff(config *Config, pr pullRequestRef) (string, error) {
+	data, err := githubAPI(config, "GET", fmt.Sprintf("/repos/%s/%s/pulls/%d", pr.Owner, pr.Repo, pr.Number), "application/vnd.github.diff", nil)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// runReview asks a one-shot, read-only Claude run to review the diff
+func runReview(config *Config, pr pullRequestRef, diff, workDir string) (*prReview, error) {
+	if claudePath == "" {
+		return nil, fmt.Errorf("claude binary not found")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	truncated := ""
+	if len(diff) > reviewDiffMax {
+		diff = diff[:reviewDiffMax]
+		truncated = "\n(The diff was cut here because it is too large: review what is shown.)"
+	}
+	prompt := fmt.Sprintf("Review pull request %s (%s).\n\n```diff\n%s\n```%s", pr, pr.URL(), diff, truncated)
+
+	// The diff goes through stdin: it can exceed the size of one argument.
+	// Only read-only tools are allowed, anything else is denied in -p mode.
+	cmd := exec.CommandContext(ctx, claudePath,
+		"-p",
+		"--output-format", "json",
+		"--append-system-prompt", reviewSystemPrompt,
+		"--allowedTools", "Read,Grep,Glob",
+	)
+	cmd.Dir = workDir
+	cmd.Env = runEnv(config)
+	cmd.Stdin = strings.NewReader(prompt)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("claude error: %w - %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp ClaudeResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("JSON parse error: %w", err)
+	}
+	if resp.IsError {
+		return nil, fmt.Errorf("claude error: %s", resp.Result)
+	}
+	return parseReviewOutput(resp.Result)
+}
+
+// reviewSeverityRank orders findings, blockers first
+func reviewSeverityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case "blocker":
+		return 0
+	case "major":
+		return 1
+	case "minor":
+		return 2
+	}
+	return 3
+}
+
+// reviewSeverityEmoji marks each finding in Slack
+func reviewSeverityEmoji(severity string) string {
+	switch strings.ToLower(severity) {
+	case "blocker":
+		return ":red_circle:"
+	case "major":
+		return ":large_orange_circle:"
+	case "minor":
+		return ":large_yellow_circle:"
+	}
+	return ":white_circle:"
+}
+
+// formatReview renders a review for Slack, most severe findings first
+func formatReview(pr pullRequestRef, review *prReview) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ":mag: *Review of <%s|%s>*\n%s", pr.URL(), pr, review.Summary)
+	if len(review.Comments) == 0 {
+		b.WriteString("\n\n:white_check_mark: No comments")
+		return b.String()
+	}
+	comments := append([]reviewComment(nil), review.Comments...)
+	sort.SliceStable(comments, func(i, j int) bool {
+		return reviewSeverityRank(comments[i].Severity) < reviewSeverityRank(comments[j].Severity)
+	})
+	fmt.Fprintf(&b, "\n\n*%d comment(s):*", len(comments))
+	for _, c := range comments {
+		loc := c.Path
+		if c.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", c.Path, c.Line)
+		}
+		fmt.Fprintf(&b, "\n%s `%s` %s", reviewSeverityEmoji(c.Severity), loc, c.Body)
+	}
+	return b.String()
+}
+
+// submitReview posts the review on GitHub as a comment-only review. Comments
+// on lines GitHub rejects (outside the diff) are folded into the review body.
+func submitReview(config *Config, pr pullRequestRef, review *prReview) (string, error) {
+	type ghComment struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Side string `json:"side"`
+		Body string `json:"body"`
+	}
+	body := review.Summary + "\n\n_Reviewed by Claude from Slack._"
+	var inline []ghComment
+	var folded []string
+	for _, c := range review.Comments {
+		text := fmt.Sprintf("**%s**: %s", c.Severity, c.Body)
+		if c.Path == "" || c.Line <= 0 {
+			folded = append(folded, fmt.Sprintf("- `%s` %s", c.Path, text))
+			continue
+		}
+		inline = append(inline, ghComment{Path: c.Path, Line: c.Line, Side: "RIGHT", Body: text})
+	}
+
+	post := func(body string, comments []ghComment) ([]byte, error) {
+		payload, _ := json.Marshal(map[string]interface{}{"event": "COMMENT", "body": body, "comments": comments})
+		return githubAPI(config, "POST", fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", pr.Owner, pr.Repo, pr.Number), "application/vnd.github+json", payload)
+	}
+	withFolded := func(extra []string) string {
+		if len(extra) == 0 {
+			return body
+		}
+		return body + "\n\n" + strings.Join(extra, "\n")
+	}
+
+	data, err := post(withFolded(folded), inline)
+	if err != nil && len(inline) > 0 {
+		logf("Review of %s: inline comments rejected, retrying in the body: %v", pr, err)
+		for _, c := range inline {
+			folded = append(folded, fmt.Sprintf("- `%s:%d` %s", c.Path, c.Line, c.Body))
+		}
+		data, err = post(withFolded(folded), []ghComment{})
+	}
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	json.Unmarshal(data, &created)
+	return created.HTMLURL, nil
+}
+
+// handleReviewCommand handles "!review <pr-url> [--submit]": the review runs in
+// the background and is posted in the thread of the command
+func handleReviewCommand(config *Config, channelID, eventTS, args string) string {
+	pr, submit, ok := parseReviewArgs(args)
+	if !ok {
+		return "Usage: `!review <github-pr-url> [--submit]` - review a pull request (`--submit` also posts the review on GitHub)"
+	}
+	if claudePath == "" {
+		return ":x: claude binary not found"
+	}
+
+	// Run inside the session's folder when it's the PR's repo, for context
+	workDir := getProjectsDir(config)
+	if sessionName := getSessionByChannel(config, channelID); sessionName != "" && sessionHost(config, channelID) == "" {
+		workDir = sessionWorkDir(config, sessionName)
+	}
+
+	addReaction(config, channelID, eventTS, "mag")
+	workerPool.Submit(func() {
+		fail := func(format string, a ...interface{}) {
+			msg := fmt.Sprintf(format, a...)
+			logf("Review of %s: %s", pr, msg)
+			removeReaction(config, channelID, eventTS, "mag")
+			addReaction(config, channelID, eventTS, "x")
+			sendMessageToThread(config, channelID, eventTS, ":x: "+msg)
+		}
+
+		diff, err := fetchPullRequestDiff(config, pr)
+		if err != nil {
+			fail("Could not fetch the diff of %s: %v", pr, err)
+			return
+		}
+		if strings.TrimSpace(diff) == "" {
+			fail("%s has no changes", pr)
+			return
+		}
+		review, err := runReview(config, pr, diff, workDir)
+		if err != nil {
+			fail("Review of %s failed: %v", pr, err)
+			return
+		}
+		sendMessageToThread(config, channelID, eventTS, formatReview(pr, review))
+
+		if submit {
+			url, err := submitReview(config, pr, review)
+			if err != nil {
+				fail("Could not submit the review to GitHub: %v", err)
+				return
+			}
+			sendMessageToThread(config, channelID, eventTS, fmt.Sprintf(":outbox_tray: Review submitted on GitHub: %s", url))
+		}
+		removeReaction(config, channelID, eventTS, "mag")
+		addReaction(config, channelID, eventTS, "white_check_mark")
+		logf("Reviewed %s (submitted: %v)", pr, submit)
+	})
+	return ""
+}
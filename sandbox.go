@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultSandboxImage is used when sandbox_image is not set (see README for its Dockerfile)
+const defaultSandboxImage = "ccsa-sandbox"
+
+// sandboxHome is the container's HOME, backed by a per-session state directory
+const sandboxHome = "/claude-home"
+
+// sandboxDockerExitCode is docker run's exit status when the container couldn't start
+const sandboxDockerExitCode = 125
+
+// sandboxPassthroughEnv are host variables handed to the container when set (Claude auth)
+var sandboxPassthroughEnv = []string{"ANTHROPIC_API_KEY", "CLAUDE_CODE_OAUTH_TOKEN"}
+
+// sessionSandboxed reports whether a channel's session runs in Docker
+func sessionSandboxed(config *Config, channelID string) bool {
+	if config == nil || len(config.SandboxSessions) == 0 {
+		return false
+	}
+	return config.SandboxSessions[getSessionByChannel(config, channelID)]
+}
+
+// getSandboxStateDir returns the session's container HOME on the host
+// (~/.ccsa/sandbox/<session>): Claude's login, settings and transcripts live there
+func getSandboxStateDir(sessionName string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "sandbox", nonAlnumRe.ReplaceAllString(sessionName, "-"))
+}
+
+// sandboxRun is a Claude process running in a container. Killing the docker
+// client doesn't stop the container, so Kill goes through `docker kill`.
+type sandboxRun struct {
+	cmd       *exec.Cmd
+	container string
+}
+
+// Kill stops the container and the docker client
+func (r *sandboxRun) Kill() error {
+	exec.Command("docker", "kill", r.container).Run()
+	if r.cmd.Process != nil {
+		return r.cmd.Process.Kill()
+	}
+	return nil
+}
+
+// sandboxArgs builds the `docker run` arguments: only the project directory (at
+// the same path, so paths in messages match) and the session's state directory
+// are mounted, and files are written as the host user
+func sandboxArgs(config *Config, container, workDir, stateDir string, env []string, claudeArgs []string) []string {
+	image := config.SandboxImage
+	if image == "" {
+		image = defaultSandboxImage
+	}
+	args := []string{
+		"run", "--rm", "-i",
+		"--name", container,
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"-v", workDir + ":" + workDir,
+		"-w", workDir,
+		"-v", stateDir + ":" + sandboxHome,
+		"-e", "HOME=" + sandboxHome,
+	}
+	// "-e NAME" copies the value from the docker client's environment
+	for _, kv := range env {
+		if name, _, ok := strings.Cut(kv, "="); ok {
+			args = append(args, "-e", name)
+		}
+	}
+	for _, name := range sandboxPassthroughEnv {
+		if os.Getenv(name) != "" {
+			args = append(args, "-e", name)
+		}
+	}
+	args = append(args, image, "claude")
+	return append(args, claudeArgs...)
+}
+
+// sandboxCommand prepares a Claude run inside the session's container
+func sandboxCommand(ctx context.Context, config *Config, sessionName, workDir string, claudeArgs []string) (*sandboxRun, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, fmt.Errorf("session `%s` is sandboxed but docker is not installed", sessionName)
+	}
+	stateDir := getSandboxStateDir(sessionName)
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return nil, fmt.Errorf("sandbox state dir: %w", err)
+	}
+
+	// Credentials and the session's variables are copied into the container
+	passed := append(credentialEnv(config), sessionEnvVars(config, sessionName, os.Environ())...)
+	container := fmt.Sprintf("ccsa-%s-%d", nonAlnumRe.ReplaceAllString(strings.ToLower(sessionName), "-"), time.Now().Unix())
+	cmd := exec.CommandContext(ctx, "docker", sandboxArgs(config, container, workDir, stateDir, passed, claudeArgs)...)
+	cmd.Env = append(runEnv(config), passed...)
+
+	run := &sandboxRun{cmd: cmd, container: container}
+	// Timeouts go through docker kill too
+	cmd.Cancel = run.Kill
+	return run, nil
+}
@@ -27,18 +27,18 @@ type Scheduler struct {
 	tasks  map[string]*ScheduledTask // ID -> task
 	nextID int
 	stopCh chan struct{}
-	config *Config
+	cfgMgr *ConfigManager
 }
 
 // Global scheduler instance
 var scheduler *Scheduler
 
 // NewScheduler creates a new scheduler
-func NewScheduler(config *Config) *Scheduler {
+func NewScheduler(cfgMgr *ConfigManager) *Scheduler {
 	s := &Scheduler{
 		tasks:  make(map[string]*ScheduledTask),
 		stopCh: make(chan struct{}),
-		config: config,
+		cfgMgr: cfgMgr,
 	}
 	go s.run()
 	return s
@@ -55,6 +55,7 @@ func (s *Scheduler) run() {
 			return
 		case <-ticker.C:
 			s.checkAndRunTasks()
+			s.runDueCronJobs()
 		}
 	}
 }
@@ -82,18 +83,19 @@ func (s *Scheduler) checkAndRunTasks() {
 // executeTask runs a scheduled task
 func (s *Scheduler) executeTask(task *ScheduledTask) {
 	logf("Running scheduled task %s: %s", task.ID, task.Command)
+	config := s.cfgMgr.Get()
 
 	// Notify that task is starting
-	sendMessageToThread(s.config, task.ChannelID, task.ThreadTS,
+	sendMessageToThread(config, task.ChannelID, task.ThreadTS,
 		fmt.Sprintf(":alarm_clock: *Scheduled task running:* `%s`", task.Command))
 
 	// Build prompt with slack prefix
 	prompt := slackUserPrefix + task.Command
 
 	// Run Claude
-	resp, err := callClaudeStreaming(prompt, task.ChannelID, task.ThreadTS, task.WorkDir, s.config)
+	resp, err := callClaudeStreaming(prompt, task.ChannelID, task.ThreadTS, task.WorkDir, config)
 	if err != nil {
-		sendMessageToThread(s.config, task.ChannelID, task.ThreadTS,
+		sendMessageToThread(config, task.ChannelID, task.ThreadTS,
 			fmt.Sprintf(":x: Scheduled task failed: %v", err))
 		return
 	}
@@ -102,6 +104,17 @@ func (s *Scheduler) executeTask(task *ScheduledTask) {
 		task.ID, resp.Usage.InputTokens, resp.Usage.OutputTokens)
 }
 
+// runDueCronJobs submits the recurring prompts (!schedule) that are due
+func (s *Scheduler) runDueCronJobs() {
+	config := s.cfgMgr.Get()
+	if config == nil {
+		return
+	}
+	for _, job := range cronStore.Due(time.Now()) {
+		runCronJob(config, job)
+	}
+}
+
 // Schedule adds a new scheduled task
 // Returns task ID and formatted run time
 func (s *Scheduler) Schedule(channelID, threadTS, workDir, timeSpec, command string) (string, time.Time, error) {
@@ -241,7 +254,7 @@ func parseTimeOfDay(spec string, now time.Time) (time.Time, error) {
 		}
 	}
 
-	return time.Time{}, fmt.Errorf("invalid time format: %s (use e.g., 9am, 14:30, 5m, 1h)")
+	return time.Time{}, fmt.Errorf("invalid time format: %s (use e.g., 9am, 14:30, 5m, 1h)", spec)
 }
 
 // Stop stops the scheduler
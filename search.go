@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	searchMaxHits    = 15
+	searchSnippetLen = 160 // runes around the first match
+)
+
+// slackEscaper escapes text shown verbatim in mrkdwn
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// searchHit is one match of !search
+type searchHit struct {
+	SessionName string
+	ChannelID   string
+	At          time.Time
+	Kind        string // "session", "prompt", "answer" or "commit"
+	Snippet     string
+	Link        string // optional URL (commit on GitHub)
+}
+
+// searchTerms splits a query into lowercase terms that must all match
+func searchTerms(query string) []string {
+	return strings.Fields(strings.ToLower(strings.Trim(query, `"“”`)))
+}
+
+// matchesAll reports whether text contains every term (case-insensitive)
+func matchesAll(text string, terms []string) bool {
+	lower := strings.ToLower(text)
+	for _, term := range terms {
+		if !strings.Contains(lower, term) {
+			return false
+		}
+	}
+	return len(terms) > 0
+}
+
+// searchSnippet returns the single-line text around the first term
+func searchSnippet(text string, terms []string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	at := 0
+	if i := strings.Index(string(lower), terms[0]); i >= 0 {
+		at = len([]rune(string(lower)[:i]))
+	}
+	start := max(0, at-searchSnippetLen/3)
+	end := min(len(runes), start+searchSnippetLen)
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(runes) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// searchTranscripts matches prompts and answers in every conversation Claude
+// kept for workDir (older ones from before !reset included)
+func searchTranscripts(sessionName, channelID, workDir string, terms []string) []searchHit {
+	dir := claudeTranscriptDir(workDir)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	var hits []searchHit
+	for _, file := range files {
+		exchanges, err := readTranscriptExchanges(file)
+		if err != nil {
+			continue
+		}
+		for _, ex := range exchanges {
+			// One hit per exchange, the prompt first
+			switch {
+			case matchesAll(ex.Prompt, terms):
+				hits = append(hits, searchHit{SessionName: sessionName, ChannelID: channelID, At: ex.At, Kind: "prompt", Snippet: searchSnippet(ex.Prompt, terms)})
+			case matchesAll(ex.Reply, terms):
+				hits = append(hits, searchHit{SessionName: sessionName, ChannelID: channelID, At: ex.At, Kind: "answer", Snippet: searchSnippet(ex.Reply, terms)})
+			}
+		}
+	}
+	return hits
+}
+
+// searchSessions searches session metadata (name, branch, GitHub repo), commits
+// from the timeline and the transcripts of every local session, newest first
+func searchSessions(config *Config, query string) []searchHit {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	since := time.Now().Add(-timelineRetention)
+
+	var hits []searchHit
+	for name, channelID := range config.Sessions {
+		workDir := sessionWorkDir(config, name)
+		githubURL := ""
+		if config.SessionHosts[name] == "" {
+			githubURL = getGitHubURL(workDir)
+		}
+
+		meta := strings.Join([]string{name, config.ChannelBranches[channelID], githubURL}, " ")
+		if matchesAll(meta, terms) {
+			hits = append(hits, searchHit{SessionName: name, ChannelID: channelID, Kind: "session", Snippet: strings.TrimSpace(meta)})
+		}
+
+		for _, ev := range timeline.Events(channelID, since) {
+			if ev.Kind != timelineCommit || ev.ChannelID != channelID || !matchesAll(ev.Text, terms) {
+				continue
+			}
+			hit := searchHit{SessionName: name, ChannelID: channelID, At: ev.At, Kind: "commit", Snippet: ev.Text}
+			if hash, _, _ := strings.Cut(ev.Text, " "); githubURL != "" && hash != "" {
+				hit.Link = githubURL + "/commit/" + hash
+			}
+			hits = append(hits, hit)
+		}
+
+		// Transcripts of agent and sandbox sessions aren't stored here
+		if config.SessionHosts[name] != "" || config.SandboxSessions[name] {
+			continue
+		}
+		if _, err := os.Stat(workDir); err != nil {
+			continue
+		}
+		hits = append(hits, searchTranscripts(name, channelID, workDir, terms)...)
+	}
+
+	// Session matches first, then newest first
+	sort.SliceStable(hits, func(i, j int) bool {
+		if (hits[i].Kind == "session") != (hits[j].Kind == "session") {
+			return hits[i].Kind == "session"
+		}
+		return hits[i].At.After(hits[j].At)
+	})
+	return hits
+}
+
+// formatSearchResults renders !search hits, capped at searchMaxHits
+func formatSearchResults(query string, hits []searchHit) string {
+	if len(hits) == 0 {
+		return fmt.Sprintf(":mag: No results for `%s`", query)
+	}
+	header := fmt.Sprintf(":mag: *%d result(s) for* `%s`", len(hits), query)
+	if len(hits) > searchMaxHits {
+		header += fmt.Sprintf(" (newest %d shown)", searchMaxHits)
+		hits = hits[:searchMaxHits]
+	}
+	lines := []string{header}
+	for _, h := range hits {
+		when := ""
+		if !h.At.IsZero() {
+			when = " " + h.At.Local().Format("Jan 2 15:04")
+		}
+		snippet := slackEscaper.Replace(h.Snippet)
+		if h.Link != "" {
+			snippet = fmt.Sprintf("<%s|%s>", h.Link, snippet)
+		}
+		lines = append(lines, fmt.Sprintf("• <#%s> `%s`%s _%s_: %s", h.ChannelID, h.SessionName, when, h.Kind, snippet))
+	}
+	return strings.Join(lines, "\n")
+}
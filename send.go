@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const sendUsage = `Usage:
+    claude-code-slack-anywhere send <session> <message>       Send a prompt to a session's Claude
+    claude-code-slack-anywhere send --channel <id> <message>  Post a message to a channel
+The message is read from stdin when omitted.`
+
+// InboxMessage is a prompt handed to the listener by the send command. The CLI
+// posts it to the channel, then drops it in ~/.ccsa/inbox for the listener to run.
+type InboxMessage struct {
+	Action    string    `json:"action,omitempty"` // "" runs Text, else inboxCancel or inboxReset
+	Session   string    `json:"session"`
+	ChannelID string    `json:"channel_id"`
+	Text      string    `json:"text"`
+	TS        string    `json:"ts"` // the message posted in the channel
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Inbox actions besides prompts (from `top` and the web dashboard)
+const (
+	inboxCancel = "cancel" // stop the channel's run
+	inboxReset  = "reset"  // start a fresh conversation
+	// inboxSession records the conversation of a PTY session (Text is its
+	// ID), sent by the Stop hook
+	inboxSession = "session"
+)
+
+// getInboxDir returns the folder the send command drops prompts in
+func getInboxDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "inbox")
+}
+
+// writeInboxMessage adds a prompt to the inbox (written aside, then renamed in
+// place so the listener never reads half a file)
+func writeInboxMessage(dir string, m InboxMessage) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%d.json", m.CreatedAt.UnixNano(), os.Getpid())
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+// inboxMu serializes inbox scans so each prompt is taken once
+var inboxMu sync.Mutex
+
+// takeInboxMessages reads and removes the inbox's prompts, oldest first
+func takeInboxMessages(dir string) []InboxMessage {
+	inboxMu.Lock()
+	defer inboxMu.Unlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names) // names start with the creation time
+
+	var msgs []InboxMessage
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			continue
+		}
+		var m InboxMessage
+		if err := json.Unmarshal(data, &m); err != nil || m.ChannelID == "" || (m.Text == "" && m.Action == "") {
+			logf("Inbox: dropping invalid %s", name)
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+// runInboxMessages submits the inbox's prompts like messages typed in their
+// channel, and applies cancels and resets
+func runInboxMessages(cfgMgr *ConfigManager) {
+	config := cfgMgr.Get()
+	if config == nil {
+		return
+	}
+	for _, m := range takeInboxMessages(getInboxDir()) {
+		sessionName := getSessionByChannel(config, m.ChannelID)
+		if sessionName == "" {
+			logf("Inbox: no session for channel %s anymore, dropping prompt", m.ChannelID)
+			continue
+		}
+		switch m.Action {
+		case inboxCancel:
+			if CancelClaudeProcess(m.ChannelID) {
+				logf("Inbox: cancelled the run of %s", sessionName)
+				sendMessage(config, m.ChannelID, ":stop_sign: Task cancelled from the terminal")
+			}
+			continue
+		case inboxSession:
+			// Late reports from a session stopped since (!reset) are ignored
+			if ptyPool.Get(m.ChannelID) == nil {
+				continue
+			}
+			if sid, ok := getClaudeSessionID(m.ChannelID); !ok || sid != m.Text {
+				claudeSessionIDs.Store(m.ChannelID, m.Text)
+				saveSessionsToDisk()
+				forkStore.Clear(m.ChannelID)
+			}
+			continue
+		case inboxReset:
+			resetClaudeSession(m.ChannelID)
+			timeline.Record(TimelineEvent{ChannelID: m.ChannelID, Kind: timelineReset})
+			logf("Inbox: reset the conversation of %s", sessionName)
+			sendMessage(config, m.ChannelID, ":arrows_counterclockwise: Conversation reset from the terminal. Next message starts a fresh context.")
+			continue
+		}
+		logf("Inbox: prompt for %s from the send command", sessionName)
+		runExternalPrompt(config, sessionName, m.ChannelID, m.Text, m.TS)
+	}
+}
+
+// runExternalPrompt runs a prompt that didn't come from Slack (send, the REST
+// API) like a message typed in the channel; ts is its copy posted there
+func runExternalPrompt(config *Config, sessionName, channelID, text, ts string) {
+	msg := &QueuedMessage{
+		Text:      text,
+		ChannelID: channelID,
+		EventTS:   ts,
+		WorkDir:   sessionWorkDir(config, sessionName),
+	}
+	addReaction(config, msg.ChannelID, msg.EventTS, "eyes")
+	submitClaudeMessage(msg, config)
+}
+
+// startInboxWatcher runs prompts left by the send command: those waiting from
+// before the listener started, then new ones as they arrive
+func startInboxWatcher(cfgMgr *ConfigManager, stop <-chan struct{}) error {
+	dir := getInboxDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	go runInboxMessages(cfgMgr)
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Files arrive by rename, under their final name
+				if strings.HasSuffix(event.Name, ".json") && event.Has(fsnotify.Create) {
+					runInboxMessages(cfgMgr)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logf("Inbox watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// parseSendArgs splits the send command's arguments
+func parseSendArgs(args []string) (session, channelID, message string, ok bool) {
+	if len(args) >= 2 && args[0] == "--channel" {
+		return "", args[1], strings.Join(args[2:], " "), true
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", "", "", false
+	}
+	return args[0], "", strings.Join(args[1:], " "), true
+}
+
+// runSendCommand implements `send`: a prompt for a session's Claude, or a plain
+// message to a channel
+func runSendCommand(args []string) error {
+	session, channelID, message, ok := parseSendArgs(args)
+	if !ok {
+		return fmt.Errorf("%s", sendUsage)
+	}
+	if strings.TrimSpace(message) == "" {
+		if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			message = string(data)
+		}
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return fmt.Errorf("empty message\n%s", sendUsage)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("not configured. Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+	}
+
+	if session == "" {
+		if _, err := sendMessage(config, channelID, message); err != nil {
+			return err
+		}
+		fmt.Printf("Posted to %s\n", channelID)
+		return nil
+	}
+
+	if err := sendPromptToSession(config, session, message, "the CLI"); err != nil {
+		return err
+	}
+	fmt.Printf("Sent to session %s (the listener runs it, or queues it behind the current task)\n", session)
+	return nil
+}
+
+// sendPromptToSession posts a prompt to the session's channel and hands it to
+// the listener through the inbox; from says where it was sent from
+func sendPromptToSession(config *Config, session, message, from string) error {
+	channelID, ok := config.Sessions[session]
+	if !ok {
+		var names []string
+		for name := range config.Sessions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown session %q (sessions: %s)", session, strings.Join(names, ", "))
+	}
+	// The listener ignores bot messages: the post is for the record, the
+	// prompt goes through the inbox
+	ts, err := sendMessage(config, channelID, fmt.Sprintf(":incoming_envelope: *Sent from %s:*\n%s", from, message))
+	if err != nil {
+		return err
+	}
+	return writeInboxMessage(getInboxDir(), InboxMessage{Session: session, ChannelID: channelID, Text: message, TS: ts, CreatedAt: time.Now()})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const serviceUsage = "Usage: claude-code-slack-anywhere service start|stop|restart|status|logs [-f] [-n <lines>]"
+
+// scheduledTaskName is the listener's Windows Scheduled Task
+const scheduledTaskName = "ccsa"
+
+// servicePlatform returns how the listener runs as a service: "launchd"
+// (macOS), "schtasks" (Windows) or "systemd"
+func servicePlatform() string {
+	if runtime.GOOS == "windows" {
+		return "schtasks"
+	}
+	if _, err := os.Stat("/Library"); err == nil {
+		return "launchd"
+	}
+	return "systemd"
+}
+
+// scheduledTaskScript is the script the Windows Scheduled Task runs
+func scheduledTaskScript(home string) string {
+	return filepath.Join(home, ".ccsa", "ccsa-listen.cmd")
+}
+
+// serviceCommands returns the command lines of a service action; plistPath
+// is the launchd plist (launchd only)
+func serviceCommands(platform, action, plistPath string, uid int) ([][]string, error) {
+	switch platform {
+	case "launchd":
+		switch action {
+		case "start":
+			return [][]string{{"launchctl", "load", "-w", plistPath}}, nil
+		case "stop":
+			return [][]string{{"launchctl", "unload", plistPath}}, nil
+		case "restart":
+			return [][]string{{"launchctl", "kickstart", "-k", fmt.Sprintf("gui/%d/com.ccsa", uid)}}, nil
+		case "status":
+			return [][]string{{"launchctl", "list", "com.ccsa"}}, nil
+		}
+	case "schtasks":
+		switch action {
+		case "start":
+			return [][]string{{"schtasks", "/Run", "/TN", scheduledTaskName}}, nil
+		case "stop":
+			return [][]string{{"schtasks", "/End", "/TN", scheduledTaskName}}, nil
+		case "restart":
+			return [][]string{{"schtasks", "/End", "/TN", scheduledTaskName}, {"schtasks", "/Run", "/TN", scheduledTaskName}}, nil
+		case "status":
+			return [][]string{{"schtasks", "/Query", "/TN", scheduledTaskName, "/V", "/FO", "LIST"}}, nil
+		}
+	default:
+		switch action {
+		case "start", "stop", "restart":
+			return [][]string{{"systemctl", "--user", action, "ccsa"}}, nil
+		case "status":
+			return [][]string{{"systemctl", "--user", "status", "ccsa", "--no-pager"}}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown service action %q\n%s", action, serviceUsage)
+}
+
+// logsCommand returns the command showing the listener's log: ~/.ccsa.log
+// (launchd, Windows, or when it exists), otherwise the systemd journal
+func logsCommand(platform, logPath string, lines int, follow bool) []string {
+	n := strconv.Itoa(lines)
+	if platform == "schtasks" {
+		script := fmt.Sprintf("Get-Content -LiteralPath '%s' -Tail %s", strings.ReplaceAll(logPath, "'", "''"), n)
+		if follow {
+			script += " -Wait"
+		}
+		return []string{"powershell", "-NoProfile", "-Command", script}
+	}
+	if _, err := os.Stat(logPath); platform == "launchd" || err == nil {
+		cmd := []string{"tail", "-n", n}
+		if follow {
+			cmd = append(cmd, "-f")
+		}
+		return append(cmd, logPath)
+	}
+	cmd := []string{"journalctl", "--user", "-u", "ccsa", "-n", n, "--no-pager"}
+	if follow {
+		cmd = append(cmd, "-f")
+	}
+	return cmd
+}
+
+// runServiceCommand implements "service start|stop|restart|status|logs"
+func runServiceCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", serviceUsage)
+	}
+	home, _ := os.UserHomeDir()
+	platform := servicePlatform()
+
+	action := args[0]
+	var commands [][]string
+	if action == "logs" {
+		lines, follow := 50, false
+		for i := 1; i < len(args); i++ {
+			switch {
+			case args[i] == "-f" || args[i] == "--follow":
+				follow = true
+			case args[i] == "-n" && i+1 < len(args):
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n <= 0 {
+					return fmt.Errorf("invalid line count %q", args[i+1])
+				}
+				lines = n
+				i++
+			default:
+				return fmt.Errorf("%s", serviceUsage)
+			}
+		}
+		commands = [][]string{logsCommand(platform, filepath.Join(home, ".ccsa.log"), lines, follow)}
+	} else {
+		if serviceFilePath(home) == "" {
+			return fmt.Errorf("the service is not installed - run: claude-code-slack-anywhere doctor --fix")
+		}
+		plistPath := filepath.Join(home, "Library", "LaunchAgents", "com.ccsa.plist")
+		var err error
+		if commands, err = serviceCommands(platform, action, plistPath, os.Getuid()); err != nil {
+			return err
+		}
+	}
+
+	for i, argv := range commands {
+		cmd := exec.Command(argv[0], argv[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err := cmd.Run()
+		if err == nil {
+			continue
+		}
+		_, exited := err.(*exec.ExitError)
+		switch {
+		case exited && action == "status":
+			// A stopped service makes status fail: its output says it all
+			if platform == "launchd" {
+				fmt.Println("not running")
+			}
+			return nil
+		case exited && action == "restart" && i < len(commands)-1:
+			continue // ending a task that wasn't running
+		}
+		return fmt.Errorf("%s: %w", strings.Join(argv, " "), err)
+	}
+	switch action {
+	case "start", "stop", "restart":
+		fmt.Printf("Service %s: done\n", action)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// serviceEnvVars are passed from the installing shell to the service when set
+var serviceEnvVars = []string{"NVM_DIR", "LANG"}
+
+// commonBinDirs are added to the service PATH when present: Homebrew on Apple
+// silicon and Intel, user installs, and the system defaults launchd/systemd use
+var commonBinDirs = []string{
+	"/opt/homebrew/bin",
+	"/usr/local/bin",
+	"/usr/bin",
+	"/bin",
+	"/usr/sbin",
+	"/sbin",
+}
+
+// servicePATH builds the PATH for the service: claude's own directory first (nvm
+// installs live in a per-version dir), then the installing shell's PATH, then
+// common install locations. Missing and duplicate entries are dropped.
+func servicePATH(home, claudeBin, shellPATH string) string {
+	var dirs []string
+	if claudeBin != "" {
+		dirs = append(dirs, filepath.Dir(claudeBin))
+	}
+	dirs = append(dirs, filepath.SplitList(shellPATH)...)
+	dirs = append(dirs, filepath.Join(home, ".local", "bin"), filepath.Join(home, "bin"))
+	dirs = append(dirs, commonBinDirs...)
+
+	seen := make(map[string]bool)
+	var kept []string
+	for _, dir := range dirs {
+		if dir == "" || !filepath.IsAbs(dir) || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			kept = append(kept, dir)
+		}
+	}
+	return strings.Join(kept, string(os.PathListSeparator))
+}
+
+// serviceEnvironment is the environment rendered into the service file
+func serviceEnvironment(home string) map[string]string {
+	env := map[string]string{
+		"HOME": home,
+		"PATH": servicePATH(home, claudePath, os.Getenv("PATH")),
+	}
+	for _, name := range serviceEnvVars {
+		if v := os.Getenv(name); v != "" {
+			env[name] = v
+		}
+	}
+	return env
+}
+
+// sortedEnvKeys keeps service files stable between installs
+func sortedEnvKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// launchdEnvironment renders env as a plist EnvironmentVariables entry
+func launchdEnvironment(env map[string]string) string {
+	var b strings.Builder
+	b.WriteString("    <key>EnvironmentVariables</key>\n    <dict>\n")
+	for _, k := range sortedEnvKeys(env) {
+		fmt.Fprintf(&b, "        <key>%s</key>\n        <string>%s</string>\n", html.EscapeString(k), html.EscapeString(env[k]))
+	}
+	b.WriteString("    </dict>\n")
+	return b.String()
+}
+
+// systemdEnvironment renders env as Environment= lines (quoted, % and \ escaped)
+func systemdEnvironment(env map[string]string) string {
+	var b strings.Builder
+	for _, k := range sortedEnvKeys(env) {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(env[k])
+		fmt.Fprintf(&b, "Environment=\"%s=%s\"\n", k, v)
+	}
+	return b.String()
+}
+
+// windowsEnvironment renders env as cmd `set` lines
+func windowsEnvironment(env map[string]string) string {
+	var b strings.Builder
+	for _, k := range sortedEnvKeys(env) {
+		fmt.Fprintf(&b, "set \"%s=%s\"\r\n", k, env[k])
+	}
+	return b.String()
+}
+
+var (
+	windowsEnvRe   = regexp.MustCompile(`(?m)^set "([^=]+)=(.*)"\r?$`)
+	plistEnvRe     = regexp.MustCompile(`(?s)<key>EnvironmentVariables</key>\s*<dict>(.*?)</dict>`)
+	plistEnvPairRe = regexp.MustCompile(`(?s)<key>(.*?)</key>\s*<string>(.*?)</string>`)
+	systemdEnvRe   = regexp.MustCompile(`(?m)^Environment="([^=]+)=((?:[^"\\]|\\.)*)"$`)
+)
+
+// parseServiceEnvironment reads the environment back from a plist or systemd unit
+func parseServiceEnvironment(content string) map[string]string {
+	env := make(map[string]string)
+	if m := plistEnvRe.FindStringSubmatch(content); m != nil {
+		for _, pair := range plistEnvPairRe.FindAllStringSubmatch(m[1], -1) {
+			env[html.UnescapeString(pair[1])] = html.UnescapeString(pair[2])
+		}
+		return env
+	}
+	if m := windowsEnvRe.FindAllStringSubmatch(content, -1); m != nil {
+		for _, pair := range m {
+			env[pair[1]] = pair[2]
+		}
+		return env
+	}
+	for _, m := range systemdEnvRe.FindAllStringSubmatch(content, -1) {
+		env[m[1]] = strings.NewReplacer(`\\`, `\`, `\"`, `"`, "%%", "%").Replace(m[2])
+	}
+	return env
+}
+
+// serviceFilePath returns the installed launchd plist, systemd unit or
+// Scheduled Task script ("" if none)
+func serviceFilePath(home string) string {
+	path := filepath.Join(home, ".config", "systemd", "user", "ccsa.service")
+	switch servicePlatform() {
+	case "launchd":
+		path = filepath.Join(home, "Library", "LaunchAgents", "com.ccsa.plist")
+	case "schtasks":
+		path = scheduledTaskScript(home)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// lookPathIn finds an executable in a PATH value, like exec.LookPath
+func lookPathIn(name, pathList string) string {
+	if runtime.GOOS == "windows" {
+		// No executable bit: go by extension, as cmd does
+		for _, dir := range filepath.SplitList(pathList) {
+			for _, ext := range []string{".exe", ".cmd", ".bat"} {
+				if info, err := os.Stat(filepath.Join(dir, name+ext)); err == nil && !info.IsDir() {
+					return filepath.Join(dir, name+ext)
+				}
+			}
+		}
+		return ""
+	}
+	for _, dir := range filepath.SplitList(pathList) {
+		p := filepath.Join(dir, name)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return p
+		}
+	}
+	return ""
+}
+
+// checkServiceClaude runs `claude --version` with exactly the environment the
+// service file gives the listener, and returns the version it printed
+func checkServiceClaude(servicePath string) (string, error) {
+	data, err := os.ReadFile(servicePath)
+	if err != nil {
+		return "", err
+	}
+	env := parseServiceEnvironment(string(data))
+	if env["PATH"] == "" {
+		return "", fmt.Errorf("service sets no PATH (reinstall with doctor --fix)")
+	}
+	bin := lookPathIn("claude", env["PATH"])
+	if bin == "" {
+		return "", fmt.Errorf("claude not found in the service PATH")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, "--version")
+	for _, k := range sortedEnvKeys(env) {
+		cmd.Env = append(cmd.Env, k+"="+env[k])
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		// e.g. "env: node: No such file or directory" when node isn't on PATH
+		return "", fmt.Errorf("%s --version failed: %s", bin, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
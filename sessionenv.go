@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SessionEnvironment prepares the toolchain of a session's Claude runs
+type SessionEnvironment struct {
+	// Env is set for every run; $VAR and ${VAR} expand from the listener's environment
+	Env map[string]string `json:"env,omitempty"`
+	// Setup are shell commands run in the session folder before each run
+	// (`source ~/.nvm/nvm.sh && nvm use`, `eval "$(direnv export bash)"`);
+	// the environment they leave is Claude's
+	Setup []string `json:"setup,omitempty"`
+}
+
+// sessionSetupTimeout bounds a session's setup commands
+const sessionSetupTimeout = time.Minute
+
+// setupEnvMarker separates the setup commands' own output from the environment they leave
+const setupEnvMarker = "\x00__ccsa_env__\x00"
+
+// setupShellVars are shell bookkeeping, not environment worth passing on
+var setupShellVars = map[string]bool{"_": true, "SHLVL": true, "PWD": true, "OLDPWD": true}
+
+// sessionEnvVars returns a session's static variables, expanded against base
+func sessionEnvVars(config *Config, sessionName string, base []string) []string {
+	se, ok := config.SessionEnv[sessionName]
+	if !ok || len(se.Env) == 0 {
+		return nil
+	}
+	lookup := make(map[string]string, len(base))
+	for _, kv := range base {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			lookup[k] = v
+		}
+	}
+	names := make([]string, 0, len(se.Env))
+	for name := range se.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vars := make([]string, 0, len(names))
+	for _, name := range names {
+		vars = append(vars, name+"="+os.Expand(se.Env[name], func(k string) string { return lookup[k] }))
+	}
+	return vars
+}
+
+// sessionRunEnv returns the environment of a local Claude run for a session:
+// the listener's (with credentials), the session's variables, then whatever
+// its setup commands changed
+func sessionRunEnv(config *Config, sessionName, workDir string) ([]string, error) {
+	env := runEnv(config)
+	if config == nil || sessionName == "" {
+		return env, nil
+	}
+	env = append(env, sessionEnvVars(config, sessionName, env)...)
+	setup := config.SessionEnv[sessionName].Setup
+	if len(setup) == 0 {
+		return env, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionSetupTimeout)
+	defer cancel()
+	script := "set -e\n" + strings.Join(setup, "\n") + "\nprintf '\\0__ccsa_env__\\0'\nenv -0\n"
+	cmd := exec.CommandContext(ctx, "bash", "-c", script)
+	cmd.Dir = workDir
+	cmd.Env = env
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("setup of session `%s` failed: %v\n%s", sessionName, err, lastLines(strings.TrimSpace(sanitizeTerminalOutput(stderr.String())), 10))
+	}
+	_, vars, ok := strings.Cut(stdout.String(), setupEnvMarker)
+	if !ok {
+		return nil, fmt.Errorf("setup of session `%s` exited early", sessionName)
+	}
+	env = env[:0:0]
+	for _, kv := range strings.Split(vars, "\x00") {
+		if name, _, ok := strings.Cut(kv, "="); ok && name != "" && !setupShellVars[name] {
+			env = append(env, kv)
+		}
+	}
+	return env, nil
+}
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// configSetting is a config key that can be read and changed from Slack with !config
+type configSetting struct {
+	key  string
+	help string
+	get  func(*Config) string
+	set  func(*Config, string) error
+}
+
+// configSettings is the safe subset of the config editable from Slack: no
+// tokens, users or commands that would run on the host
+var configSettings = []configSetting{
+	{
+		key:  "projects_dir",
+		help: "Base directory for projects",
+		get:  func(c *Config) string { return c.ProjectsDir },
+		set: func(c *Config, v string) error {
+			check := &Config{ProjectsDir: v}
+			if info, err := os.Stat(getProjectsDir(check)); err != nil || !info.IsDir() {
+				return fmt.Errorf("`%s` is not a directory", v)
+			}
+			c.ProjectsDir = v
+			return nil
+		},
+	},
+	{
+		key:  "channel_visibility",
+		help: "Visibility of new session channels (public or private)",
+		get: func(c *Config) string {
+			if c.ChannelVisibility == "" {
+				return "public"
+			}
+			return c.ChannelVisibility
+		},
+		set: func(c *Config, v string) error {
+			if v != "public" && v != "private" {
+				return fmt.Errorf("expected `public` or `private`")
+			}
+			c.ChannelVisibility = v
+			return nil
+		},
+	},
+	{
+		key:  "batch_window_ms",
+		help: "Merge messages sent within this window (0 = default 2000, negative disables)",
+		get:  func(c *Config) string { return strconv.Itoa(c.BatchWindowMs) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("expected a number of milliseconds")
+			}
+			c.BatchWindowMs = n
+			return nil
+		},
+	},
+	{
+		key:  "result_cache_minutes",
+		help: "Answer repeated read-only questions from cache this long (0 = disabled)",
+		get:  func(c *Config) string { return strconv.Itoa(c.ResultCacheMinutes) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("expected a number of minutes (0 disables)")
+			}
+			c.ResultCacheMinutes = n
+			return nil
+		},
+	},
+	{
+		key:  "summary_every_runs",
+		help: "Regenerate the pinned session summary after this many runs (0 = only `!summarize`)",
+		get:  func(c *Config) string { return strconv.Itoa(c.SummaryEveryRuns) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("expected a number of runs (0 disables)")
+			}
+			c.SummaryEveryRuns = n
+			return nil
+		},
+	},
+	{
+		key:  "verbose_default",
+		help: "Show all tool calls in channels without `!verbose`/`!quiet` (on/off)",
+		get: func(c *Config) string {
+			if c.VerboseDefault == nil || *c.VerboseDefault {
+				return "on"
+			}
+			return "off"
+		},
+		set: func(c *Config, v string) error {
+			on, err := parseOnOff(v)
+			if err != nil {
+				return err
+			}
+			c.VerboseDefault = &on
+			return nil
+		},
+	},
+	{
+		key:  "persistent_claude",
+		help: "Keep one Claude process per channel instead of starting the CLI each run (on/off)",
+		get: func(c *Config) string {
+			if c.PersistentClaude {
+				return "on"
+			}
+			return "off"
+		},
+		set: func(c *Config, v string) error {
+			on, err := parseOnOff(v)
+			if err != nil {
+				return err
+			}
+			c.PersistentClaude = on
+			return nil
+		},
+	},
+	{
+		key:  "persistent_idle_minutes",
+		help: "Stop a channel's idle Claude process after this long (0 = default 15)",
+		get:  func(c *Config) string { return strconv.Itoa(c.PersistentIdleMinutes) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("expected a number of minutes")
+			}
+			c.PersistentIdleMinutes = n
+			return nil
+		},
+	},
+	{
+		key:  "sandbox_image",
+		help: "Docker image for `--sandbox` sessions (\"default\" = " + defaultSandboxImage + ")",
+		get: func(c *Config) string {
+			if c.SandboxImage == "" {
+				return defaultSandboxImage
+			}
+			return c.SandboxImage
+		},
+		set: func(c *Config, v string) error {
+			if v == "default" {
+				v = ""
+			}
+			c.SandboxImage = v
+			return nil
+		},
+	},
+	{
+		key:  "mention_after_minutes",
+		help: "Mention the prompt's author when a run takes this long or fails (0 = never)",
+		get:  func(c *Config) string { return strconv.Itoa(c.MentionAfterMinutes) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("expected a number of minutes (0 disables)")
+			}
+			c.MentionAfterMinutes = n
+			return nil
+		},
+	},
+	{
+		key:  "quiet_hours",
+		help: "Hold tool chatter in this local time range and post a digest after (\"23:00-08:00\", off = disabled)",
+		get: func(c *Config) string {
+			if c.QuietHours == "" {
+				return "off"
+			}
+			return c.QuietHours
+		},
+		set: func(c *Config, v string) error {
+			if v == "off" {
+				c.QuietHours = ""
+				return nil
+			}
+			if _, _, err := parseQuietHours(v); err != nil {
+				return err
+			}
+			c.QuietHours = v
+			return nil
+		},
+	},
+}
+
+// parseOnOff reads a boolean setting
+func parseOnOff(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "on", "true", "yes", "1":
+		return true, nil
+	case "off", "false", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off")
+}
+
+// findConfigSetting returns the editable setting for key
+func findConfigSetting(key string) (configSetting, bool) {
+	for _, s := range configSettings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return configSetting{}, false
+}
+
+// handleConfigCommand handles "!config [get <key> | set <key> <value>]"
+func handleConfigCommand(cfgMgr *ConfigManager, config *Config, userID, args string) string {
+	if !config.IsAdmin(userID) {
+		return ":no_entry: `!config` is limited to the users in `admin_user_ids`"
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		lines := []string{"*Settings* (`!config set <key> <value>`)"}
+		for _, s := range configSettings {
+			lines = append(lines, fmt.Sprintf("• `%s` = `%s` - %s", s.key, s.get(config), s.help))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	usage := "Usage: `!config [get <key> | set <key> <value>]`"
+	if len(fields) < 2 {
+		return usage
+	}
+	setting, ok := findConfigSetting(fields[1])
+	if !ok {
+		var keys []string
+		for _, s := range configSettings {
+			keys = append(keys, "`"+s.key+"`")
+		}
+		return fmt.Sprintf(":x: `%s` can't be changed from Slack. Settings: %s", fields[1], strings.Join(keys, ", "))
+	}
+
+	switch fields[0] {
+	case "get":
+		return fmt.Sprintf("`%s` = `%s`", setting.key, setting.get(config))
+	case "set":
+		if len(fields) < 3 {
+			return usage
+		}
+		// Values may contain spaces (e.g. folder names)
+		value := strings.Join(fields[2:], " ")
+		updated, err := cfgMgr.Update(func(c *Config) error { return setting.set(c, value) })
+		if err != nil {
+			return fmt.Sprintf(":x: Could not set `%s`: %v", setting.key, err)
+		}
+		logf("Config %s set to %q by %s", setting.key, value, userID)
+		msg := fmt.Sprintf(":gear: `%s` = `%s`", setting.key, setting.get(updated))
+		if setting.key == "projects_dir" && updated.ProjectsDir != value {
+			msg += "\n:warning: The listener's `--projects-dir` flag overrides this until it restarts without it"
+		}
+		return msg
+	}
+	return usage
+}
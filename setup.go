@@ -9,6 +9,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
 )
 
 // Setup and installation functions
@@ -16,8 +19,11 @@ import (
 func installService() error {
 	home, _ := os.UserHomeDir()
 
-	if _, err := os.Stat("/Library"); err == nil {
+	switch servicePlatform() {
+	case "launchd":
 		return installLaunchdService(home)
+	case "schtasks":
+		return installScheduledTask(home)
 	}
 	return installSystemdService(home)
 }
@@ -46,13 +52,15 @@ func installLaunchdService(home string) error {
     <true/>
     <key>KeepAlive</key>
     <true/>
+    <key>ExitTimeOut</key>
+    <integer>90</integer>
     <key>StandardOutPath</key>
     <string>%s</string>
     <key>StandardErrorPath</key>
     <string>%s</string>
-</dict>
+%s</dict>
 </plist>
-`, binPath, logPath, logPath)
+`, binPath, logPath, logPath, launchdEnvironment(serviceEnvironment(home)))
 
 	if err := os.WriteFile(plistPath, []byte(plist), 0644); err != nil {
 		return fmt.Errorf("failed to write plist: %w", err)
@@ -80,12 +88,13 @@ After=network.target
 
 [Service]
 ExecStart=%s listen
-Restart=always
+%sRestart=always
 RestartSec=10
+TimeoutStopSec=90
 
 [Install]
 WantedBy=default.target
-`, binPath)
+`, binPath, systemdEnvironment(serviceEnvironment(home)))
 
 	if err := os.WriteFile(servicePath, []byte(service), 0644); err != nil {
 		return fmt.Errorf("failed to write service file: %w", err)
@@ -101,6 +110,32 @@ WantedBy=default.target
 	return nil
 }
 
+// installScheduledTask starts the listener at logon on Windows, through a
+// script that sets its environment and appends its output to ~/.ccsa.log
+func installScheduledTask(home string) error {
+	script := scheduledTaskScript(home)
+	if err := os.MkdirAll(filepath.Dir(script), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(script), err)
+	}
+	content := fmt.Sprintf("@echo off\r\n%s\"%s\" listen >> \"%s\" 2>&1\r\n",
+		windowsEnvironment(serviceEnvironment(home)), binPath, filepath.Join(home, ".ccsa.log"))
+	if err := os.WriteFile(script, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write task script: %w", err)
+	}
+
+	out, err := exec.Command("schtasks", "/Create", "/TN", scheduledTaskName, "/TR", `"`+script+`"`, "/SC", "ONLOGON", "/RL", "LIMITED", "/F").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled task: %s", strings.TrimSpace(string(out)))
+	}
+	exec.Command("schtasks", "/End", "/TN", scheduledTaskName).Run()
+	if out, err := exec.Command("schtasks", "/Run", "/TN", scheduledTaskName).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to start scheduled task: %s", strings.TrimSpace(string(out)))
+	}
+
+	fmt.Println("Service installed and started (Scheduled Task)")
+	return nil
+}
+
 func setup(botToken, appToken string) error {
 	fmt.Println("claudeslack Setup")
 	fmt.Println("========================")
@@ -115,39 +150,27 @@ func setup(botToken, appToken string) error {
 	// Step 1: Verify tokens and get bot info
 	fmt.Println("Step 1/4: Verifying Slack tokens...")
 
-	// Test bot token
-	req, _ := http.NewRequest("GET", "https://slack.com/api/auth.test", nil)
-	req.Header.Set("Authorization", "Bearer "+botToken)
-	resp, err := httpClient.Do(req)
+	botUser, err := verifyBotToken(botToken)
 	if err != nil {
-		return fmt.Errorf("failed to connect to Slack: %w", err)
+		return fmt.Errorf("invalid bot token: %w", err)
 	}
-	defer resp.Body.Close()
+	fmt.Printf("Bot verified: @%s\n\n", botUser)
 
-	var authResult struct {
-		OK     bool   `json:"ok"`
-		Error  string `json:"error"`
-		UserID string `json:"user_id"`
-		User   string `json:"user"`
-		BotID  string `json:"bot_id"`
-	}
-	json.NewDecoder(resp.Body).Decode(&authResult)
-
-	if !authResult.OK {
-		return fmt.Errorf("invalid bot token: %s", authResult.Error)
-	}
-	fmt.Printf("Bot verified: @%s\n\n", authResult.User)
-
-	// Step 2: Get user ID
+	// Step 2: Get user ID from the first DM to the bot (temporary Socket Mode connection)
 	fmt.Println("Step 2/4: Send a DM to your bot in Slack...")
-	fmt.Println("   Waiting for your message...")
+	fmt.Printf("   Waiting for your message (up to %s)...\n", setupDMTimeout)
 
-	// We need to use Socket Mode to receive events
-	// For setup, we'll use a simpler approach: ask user to input their user ID
-	fmt.Print("\nEnter your Slack User ID (find it in your profile > ... > Copy member ID): ")
-	reader := bufio.NewReader(os.Stdin)
-	userID, _ := reader.ReadString('\n')
-	userID = strings.TrimSpace(userID)
+	userID, dmChannel, err := waitForDirectMessage(appToken, setupDMTimeout)
+	if err == nil {
+		fmt.Printf("Message received from %s\n", userID)
+		sendMessage(config, dmChannel, ":wave: Got it! You're now authorized to control Claude from Slack.")
+	} else {
+		fmt.Printf("   Could not detect your user ID: %v\n", err)
+		fmt.Print("\nEnter your Slack User ID (find it in your profile > ... > Copy member ID): ")
+		reader := bufio.NewReader(os.Stdin)
+		userID, _ = reader.ReadString('\n')
+		userID = strings.TrimSpace(userID)
+	}
 
 	if userID == "" {
 		return fmt.Errorf("user ID is required")
@@ -159,6 +182,137 @@ func setup(botToken, appToken string) error {
 	}
 	fmt.Printf("User ID saved: %s\n\n", userID)
 
+	finishSetup()
+	return nil
+}
+
+// setupDMTimeout is how long setup waits for the first DM before asking for the user ID
+const setupDMTimeout = 2 * time.Minute
+
+// waitForDirectMessage opens a temporary Socket Mode connection and returns the
+// user and DM channel of the first person who messages the bot directly
+func waitForDirectMessage(appToken string, timeout time.Duration) (string, string, error) {
+	wsURL, err := openSocketModeURL(nil, appToken)
+	if err != nil {
+		return "", "", err
+	}
+	ws, err := websocket.Dial(wsURL, "", "https://slack.com")
+	if err != nil {
+		return "", "", fmt.Errorf("websocket dial failed: %w", err)
+	}
+	defer ws.Close()
+
+	deadline := time.Now().Add(timeout)
+	ws.SetReadDeadline(deadline)
+
+	for {
+		var envelope SocketModeEnvelope
+		if err := websocket.JSON.Receive(ws, &envelope); err != nil {
+			if time.Now().After(deadline) {
+				return "", "", fmt.Errorf("timed out waiting for a DM")
+			}
+			return "", "", fmt.Errorf("websocket receive failed: %w", err)
+		}
+		if envelope.EnvelopeID != "" {
+			websocket.JSON.Send(ws, map[string]string{"envelope_id": envelope.EnvelopeID})
+		}
+		if envelope.Type == "disconnect" {
+			return "", "", fmt.Errorf("disconnected by server")
+		}
+		if envelope.Type != "events_api" {
+			continue
+		}
+
+		var callback EventCallback
+		json.Unmarshal(envelope.Payload, &callback)
+		var event struct {
+			Type        string `json:"type"`
+			Subtype     string `json:"subtype"`
+			User        string `json:"user"`
+			BotID       string `json:"bot_id"`
+			Channel     string `json:"channel"`
+			ChannelType string `json:"channel_type"`
+		}
+		json.Unmarshal(callback.Event, &event)
+		if event.Type == "message" && event.ChannelType == "im" && event.Subtype == "" && event.BotID == "" && event.User != "" {
+			return event.User, event.Channel, nil
+		}
+	}
+}
+
+// setupOAuth runs the browser-based setup: create the app from the manifest,
+// authorize it via OAuth (bot token + user ID), then ask only for the app token
+func setupOAuth(clientID, clientSecret string) error {
+	fmt.Println("claudeslack Setup (OAuth)")
+	fmt.Println("========================")
+	fmt.Println()
+
+	reader := bufio.NewReader(os.Stdin)
+	prompt := func(label string) string {
+		fmt.Print(label)
+		value, _ := reader.ReadString('\n')
+		return strings.TrimSpace(value)
+	}
+
+	// Step 1: Create the app from the manifest
+	fmt.Println("Step 1/4: Create the Slack app from the manifest...")
+	if clientID == "" || clientSecret == "" {
+		createURL := slackCreateAppURL(oauthRedirectURL)
+		fmt.Println("   Opening api.slack.com with the app manifest pre-filled.")
+		fmt.Printf("   If it doesn't open, visit:\n   %s\n\n", createURL)
+		openBrowser(createURL)
+		fmt.Println("   After creating the app, copy the credentials from Basic Information > App Credentials.")
+		if clientID == "" {
+			clientID = prompt("   Client ID: ")
+		}
+		if clientSecret == "" {
+			clientSecret = prompt("   Client Secret: ")
+		}
+	}
+	if clientID == "" || clientSecret == "" {
+		return fmt.Errorf("client ID and client secret are required")
+	}
+	fmt.Println()
+
+	// Step 2: OAuth authorization (bot token + authorizing user)
+	fmt.Println("Step 2/4: Authorizing in your browser...")
+	result, err := runOAuthFlow(clientID, clientSecret, 5*time.Minute)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Installed to workspace %s (user: %s)\n\n", result.Team.Name, result.AuthedUser.ID)
+
+	fmt.Println("   Socket Mode needs an App-Level Token: Basic Information > App-Level Tokens")
+	fmt.Println("   > Generate Token with scope connections:write")
+	appToken := prompt("   App Token (xapp-...): ")
+	if !strings.HasPrefix(appToken, "xapp-") {
+		return fmt.Errorf("invalid app token: must start with xapp-")
+	}
+
+	config := &Config{
+		BotToken: result.AccessToken,
+		AppToken: appToken,
+		UserIDs:  []string{result.AuthedUser.ID},
+		Sessions: make(map[string]string),
+	}
+	if existing, err := loadConfig(); err == nil {
+		// Keep sessions and settings from a previous setup
+		existing.BotToken = config.BotToken
+		existing.AppToken = config.AppToken
+		existing.UserIDs = config.UserIDs
+		config = existing
+	}
+	if err := saveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Config saved: %s\n\n", getConfigPath())
+
+	finishSetup()
+	return nil
+}
+
+// finishSetup installs the hook and background service and prints usage
+func finishSetup() {
 	// Step 3: Install Claude hook
 	fmt.Println("Step 3/4: Installing Claude hook...")
 	if err := installHook(); err != nil {
@@ -190,17 +344,28 @@ func setup(botToken, appToken string) error {
 	fmt.Println("  !list         List sessions")
 	fmt.Println()
 	fmt.Println("Or just message in a project channel to interact with Claude.")
-
-	return nil
 }
 
-// Doctor - check all dependencies
-func doctor() {
+// Doctor - check all dependencies. With fix, repairs what it can and reports each action.
+func doctor(fix bool) {
 	fmt.Println("claude-code-slack-anywhere doctor")
 	fmt.Println("===================================")
 	fmt.Println()
 
 	allGood := true
+	var fixed []string
+	reader := bufio.NewReader(os.Stdin)
+
+	// report records a successful repair, or prints why it failed
+	report := func(action string, err error) bool {
+		if err != nil {
+			fmt.Printf("   Fix failed (%s): %v\n", action, err)
+			return false
+		}
+		fmt.Printf("   Fixed: %s\n", action)
+		fixed = append(fixed, action)
+		return true
+	}
 
 	fmt.Print("claude............ ")
 	if claudePath != "" {
@@ -213,42 +378,82 @@ func doctor() {
 
 	fmt.Print("binary in ~/bin... ")
 	home, _ := os.UserHomeDir()
-	expectedBinPath := filepath.Join(home, "bin", "claude-code-slack-anywhere")
+	expectedBinPath := filepath.Join(home, "bin", "claude-code-slack-anywhere"+exeSuffix)
 	if _, err := os.Stat(expectedBinPath); err == nil {
 		fmt.Printf("%s\n", expectedBinPath)
 	} else {
 		fmt.Println("not found")
-		fmt.Println("   Run: make install")
-		allGood = false
+		if !fix || !report("linked "+expectedBinPath+" -> "+binPath, linkBinary(expectedBinPath)) {
+			fmt.Println("   Run: make install")
+			allGood = false
+		}
 	}
 
 	fmt.Print("config............ ")
 	config, err := loadConfig()
 	if err != nil {
-		fmt.Println("not found")
-		fmt.Println("   Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
-		allGood = false
+		if os.IsNotExist(err) {
+			fmt.Println("not found")
+		} else {
+			fmt.Printf("unreadable (%v)\n", err)
+		}
+		config = nil
+		if fix {
+			config, err = repairConfig(reader)
+			if !report("regenerated "+getConfigPath(), err) {
+				config = nil
+			}
+		}
+		if config == nil {
+			fmt.Println("   Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+			allGood = false
+		}
 	} else {
 		fmt.Printf("%s\n", getConfigPath())
+	}
 
+	if config != nil {
 		fmt.Print("  bot_token....... ")
-		if config.BotToken != "" {
-			fmt.Println("configured")
-		} else {
+		if config.BotToken == "" {
 			fmt.Println("missing")
 			allGood = false
+		} else if botUser, err := verifyBotToken(config.BotToken); err != nil {
+			fmt.Printf("invalid (%v)\n", err)
+			allGood = false
+		} else {
+			fmt.Printf("valid (@%s)\n", botUser)
+
+			fmt.Print("  bot scopes...... ")
+			if granted, err := fetchGrantedScopes(config.BotToken); err != nil {
+				fmt.Printf("unknown (%v)\n", err)
+			} else if missing := missingBotScopes(granted); len(missing) > 0 {
+				fmt.Printf("missing %d\n", len(missing))
+				for _, scope := range missing {
+					fmt.Printf("   - %s (needed to %s)\n", scope, scopePurposes[scope])
+				}
+				fmt.Println("   Add them in OAuth & Permissions > Bot Token Scopes, then reinstall the app")
+				fmt.Println("   (or compare with: claude-code-slack-anywhere manifest)")
+				allGood = false
+			} else {
+				fmt.Printf("ok (%d granted)\n", len(granted))
+			}
 		}
 
 		fmt.Print("  app_token....... ")
-		if config.AppToken != "" {
-			fmt.Println("configured")
-		} else {
+		if config.AppToken == "" {
 			fmt.Println("missing")
 			allGood = false
+		} else if _, err := openSocketModeURL(config, config.AppToken); err != nil {
+			fmt.Printf("invalid (%v)\n", err)
+			allGood = false
+		} else {
+			fmt.Println("valid")
 		}
 
 		fmt.Print("  user_id......... ")
-		if config.UserID != "" {
+		if len(config.UserIDs) > 0 {
+			fmt.Printf("%s\n", strings.Join(config.UserIDs, ", "))
+		} else if config.UserID != "" {
 			fmt.Printf("%s\n", config.UserID)
 		} else {
 			fmt.Println("missing")
@@ -258,21 +463,17 @@ func doctor() {
 
 	fmt.Print("claude hook....... ")
 	settingsPath := filepath.Join(home, ".claude", "settings.json")
+	hookInstalled := false
 	if data, err := os.ReadFile(settingsPath); err == nil {
 		var settings map[string]interface{}
 		if json.Unmarshal(data, &settings) == nil {
 			if hooks, ok := settings["hooks"].(map[string]interface{}); ok {
-				if _, hasStop := hooks["Stop"]; hasStop {
-					fmt.Println("installed")
-				} else {
-					fmt.Println("not installed")
-					fmt.Println("   Run: claude-code-slack-anywhere install")
-					allGood = false
-				}
+				_, hookInstalled = hooks["Stop"]
+			}
+			if hookInstalled {
+				fmt.Println("installed")
 			} else {
 				fmt.Println("not installed")
-				fmt.Println("   Run: claude-code-slack-anywhere install")
-				allGood = false
 			}
 		} else {
 			fmt.Println("settings.json parse error")
@@ -280,9 +481,32 @@ func doctor() {
 	} else {
 		fmt.Println("~/.claude/settings.json not found")
 	}
+	if !hookInstalled {
+		if !fix || !report("installed Claude hook", reinstallHook(settingsPath)) {
+			fmt.Println("   Run: claude-code-slack-anywhere install")
+			allGood = false
+		}
+	}
 
 	fmt.Print("service........... ")
-	if _, err := os.Stat("/Library"); err == nil {
+	if servicePlatform() == "schtasks" {
+		out, err := exec.Command("schtasks", "/Query", "/TN", scheduledTaskName, "/FO", "CSV", "/NH").Output()
+		switch {
+		case err != nil:
+			fmt.Println("not installed")
+			if !fix || !report("installed scheduled task", installService()) {
+				fmt.Println("   Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+				allGood = false
+			}
+		case strings.Contains(string(out), "Running"):
+			fmt.Println("running (Scheduled Task)")
+		default:
+			fmt.Println("installed but not running")
+			if !fix || !report("restarted scheduled task", installService()) {
+				fmt.Println("   Run: claude-code-slack-anywhere service start")
+			}
+		}
+	} else if _, err := os.Stat("/Library"); err == nil {
 		plistPath := filepath.Join(home, "Library", "LaunchAgents", "com.ccsa.plist")
 		if _, err := os.Stat(plistPath); err == nil {
 			cmd := exec.Command("launchctl", "list", "com.ccsa")
@@ -290,12 +514,16 @@ func doctor() {
 				fmt.Println("running (launchd)")
 			} else {
 				fmt.Println("installed but not running")
-				fmt.Println("   Run: launchctl load ~/Library/LaunchAgents/com.ccsa.plist")
+				if !fix || !report("reloaded launchd service", installService()) {
+					fmt.Println("   Run: launchctl load ~/Library/LaunchAgents/com.ccsa.plist")
+				}
 			}
 		} else {
 			fmt.Println("not installed")
-			fmt.Println("   Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
-			allGood = false
+			if !fix || !report("installed launchd service", installService()) {
+				fmt.Println("   Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+				allGood = false
+			}
 		}
 	} else {
 		cmd := exec.Command("systemctl", "--user", "is-active", "ccsa")
@@ -305,19 +533,149 @@ func doctor() {
 			servicePath := filepath.Join(home, ".config", "systemd", "user", "ccsa.service")
 			if _, err := os.Stat(servicePath); err == nil {
 				fmt.Println("installed but not running")
-				fmt.Println("   Run: systemctl --user start ccsa")
+				if !fix || !report("restarted systemd service", installService()) {
+					fmt.Println("   Run: systemctl --user start ccsa")
+				}
 			} else {
 				fmt.Println("not installed")
-				fmt.Println("   Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+				if !fix || !report("installed systemd service", installService()) {
+					fmt.Println("   Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+					allGood = false
+				}
+			}
+		}
+	}
+
+	// The service doesn't get the login shell's PATH: run claude the way it will
+	if path := serviceFilePath(home); path != "" {
+		fmt.Print("service env....... ")
+		if version, err := checkServiceClaude(path); err == nil {
+			fmt.Printf("ok (claude %s)\n", version)
+		} else {
+			fmt.Println(err)
+			if !fix || !report("reinstalled service with current PATH", installService()) {
+				fmt.Println("   Run: claude-code-slack-anywhere doctor --fix (from a shell where claude works)")
 				allGood = false
 			}
 		}
 	}
 
 	fmt.Println()
+	if len(fixed) > 0 {
+		fmt.Printf("Applied %d fix(es):\n", len(fixed))
+		for _, action := range fixed {
+			fmt.Printf("  - %s\n", action)
+		}
+		fmt.Println()
+	}
 	if allGood {
 		fmt.Println("All checks passed!")
+	} else if fix {
+		fmt.Println("Some issues need manual attention (see above).")
 	} else {
-		fmt.Println("Some issues found. Fix them and run 'claude-code-slack-anywhere doctor' again.")
+		fmt.Println("Some issues found. Fix them, or run 'claude-code-slack-anywhere doctor --fix'.")
+	}
+}
+
+// verifyBotToken calls auth.test and returns the bot's user name
+func verifyBotToken(botToken string) (string, error) {
+	user, _, err := authTest(botToken)
+	return user, err
+}
+
+// fetchGrantedScopes returns the scopes granted to the bot token
+// (Slack reports them in the X-OAuth-Scopes header of every Web API response)
+func fetchGrantedScopes(botToken string) ([]string, error) {
+	_, scopes, err := authTest(botToken)
+	if err != nil {
+		return nil, err
+	}
+	if scopes == "" {
+		return nil, fmt.Errorf("Slack did not report the token's scopes")
+	}
+	return strings.Split(scopes, ","), nil
+}
+
+// authTest calls auth.test and returns the bot's user name and granted scopes
+func authTest(botToken string) (string, string, error) {
+	req, _ := http.NewRequest("GET", "https://slack.com/api/auth.test", nil)
+	req.Header.Set("Authorization", "Bearer "+botToken)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to connect to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var authResult struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		User  string `json:"user"`
+	}
+	json.NewDecoder(resp.Body).Decode(&authResult)
+	if !authResult.OK {
+		return "", "", fmt.Errorf("%s", authResult.Error)
+	}
+	return authResult.User, resp.Header.Get("X-OAuth-Scopes"), nil
+}
+
+// linkBinary symlinks the running binary to target (the path the hook calls)
+func linkBinary(target string) error {
+	if binPath == "" {
+		return fmt.Errorf("cannot determine current executable")
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	os.Remove(target) // dangling symlink
+	return linkExecutable(binPath, target)
+}
+
+// reinstallHook installs the Stop hook, creating ~/.claude/settings.json if needed
+func reinstallHook(settingsPath string) error {
+	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(settingsPath, []byte("{}\n"), 0600); err != nil {
+			return err
+		}
+	}
+	return installHook()
+}
+
+// repairConfig backs up an unreadable config and prompts for the values to write a new one
+func repairConfig(reader *bufio.Reader) (*Config, error) {
+	configPath := getConfigPath()
+	if _, err := os.Stat(configPath); err == nil {
+		backup := configPath + ".bak"
+		if err := os.Rename(configPath, backup); err != nil {
+			return nil, err
+		}
+		fmt.Printf("   Backed up broken config to %s\n", backup)
+	}
+
+	prompt := func(label string) string {
+		fmt.Print(label)
+		value, _ := reader.ReadString('\n')
+		return strings.TrimSpace(value)
+	}
+	botToken := prompt("   Bot token (xoxb-...): ")
+	appToken := prompt("   App token (xapp-...): ")
+	userID := prompt("   Your Slack user ID: ")
+	projectsDir := prompt("   Projects directory: ")
+	if !strings.HasPrefix(botToken, "xoxb-") || !strings.HasPrefix(appToken, "xapp-") || userID == "" {
+		return nil, fmt.Errorf("bot token (xoxb-), app token (xapp-) and user ID are required")
+	}
+
+	config := &Config{
+		BotToken:    botToken,
+		AppToken:    appToken,
+		UserIDs:     []string{userID},
+		ProjectsDir: projectsDir,
+		Sessions:    make(map[string]string),
+	}
+	if err := saveConfig(config); err != nil {
+		return nil, err
 	}
+	return config, nil
 }
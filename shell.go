@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// defaultShellConfirm are destructive commands `!c` runs only after a
+// confirmation tap, when shell_confirm is not set
+var defaultShellConfirm = []string{
+	`\brm\s`,
+	`\bgit\s+push\b.*\s(--force\b|--force-with-lease\b|-f\b)`,
+	`\bgit\s+reset\s+--hard\b`,
+	`\bgit\s+clean\s+-\w*f`,
+	`\bdd\s`,
+	`\bmkfs\b`,
+	`\b(shutdown|reboot|halt|poweroff)\b`,
+}
+
+// shellVerdict is what `!c` does with a command
+type shellVerdict int
+
+const (
+	shellRun     shellVerdict = iota // run right away
+	shellConfirm                     // ask for a confirmation tap first
+	shellRefused                     // don't run
+)
+
+// shellSeparators split a command line into the commands it chains
+var shellSeparators = regexp.MustCompile(`&&|\|\||[;|&\n]`)
+
+// pendingShellCommands holds `!c` commands waiting for confirmation (channel:eventTS -> command)
+var pendingShellCommands sync.Map
+
+// compileShellPatterns compiles `!c` patterns; invalid ones are logged and skipped
+func compileShellPatterns(setting string, patterns []string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			logf("Ignoring invalid %s pattern %q: %v", setting, p, err)
+			continue
+		}
+		res = append(res, re)
+	}
+	return res
+}
+
+// matchingPattern returns the first pattern matching s, or ""
+func matchingPattern(res []*regexp.Regexp, s string) string {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return re.String()
+		}
+	}
+	return ""
+}
+
+// checkShellCommand applies shell_disabled, shell_deny, shell_allow and
+// shell_confirm to a `!c` command. With an allowlist every chained command
+// must match it, and command substitution and redirections are refused.
+func checkShellCommand(config *Config, cmdStr string) (shellVerdict, string) {
+	if config == nil {
+		return shellRun, ""
+	}
+	if config.ShellDisabled {
+		return shellRefused, "`!c` is disabled on this listener (`shell_disabled`)"
+	}
+	if p := matchingPattern(compileShellPatterns("shell_deny", config.ShellDeny), cmdStr); p != "" {
+		return shellRefused, fmt.Sprintf("Command matches the denylist (`%s`)", p)
+	}
+	if len(config.ShellAllow) > 0 {
+		if strings.Contains(cmdStr, "`") || strings.Contains(cmdStr, "$(") {
+			return shellRefused, "Command substitution isn't allowed with a `shell_allow` list"
+		}
+		if strings.ContainsAny(cmdStr, "<>\n") {
+			return shellRefused, "Redirections and multi-line commands aren't allowed with a `shell_allow` list"
+		}
+		allow := compileShellPatterns("shell_allow", config.ShellAllow)
+		for _, part := range shellSeparators.Split(cmdStr, -1) {
+			part = strings.TrimSpace(part)
+			if part != "" && matchingPattern(allow, part) == "" {
+				return shellRefused, fmt.Sprintf("`%s` is not in the allowlist (`shell_allow`)", part)
+			}
+		}
+	}
+	confirm := defaultShellConfirm
+	if config.ShellConfirm != nil {
+		confirm = *config.ShellConfirm
+	}
+	if p := matchingPattern(compileShellPatterns("shell_confirm", confirm), cmdStr); p != "" {
+		return shellConfirm, fmt.Sprintf("matches `%s`", p)
+	}
+	return shellRun, ""
+}
+
+// runShellCommand runs a `!c` command and formats its output for Slack
+func runShellCommand(cmdStr string) string {
+	output, err := executeCommand(cmdStr)
+	if err != nil {
+		output = fmt.Sprintf(":warning: %s\n\nExit: %v", output, err)
+	}
+	return "```\n" + output + "\n```"
+}
+
+// askShellConfirmation parks a destructive `!c` command behind Run/Cancel buttons
+func askShellConfirmation(config *Config, channelID, threadTS, eventTS, cmdStr, reason string) error {
+	id := channelID + ":" + eventTS
+	pendingShellCommands.Store(id, cmdStr)
+	text := fmt.Sprintf(":warning: This command looks destructive (%s):\n```\n%s\n```\nRun it?", reason, cmdStr)
+	buttons := []Element{
+		{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Run"},
+			ActionID: "shell_run",
+			Value:    id,
+			Style:    "danger",
+		},
+		{
+			Type:     "button",
+			Text:     &TextObject{Type: "plain_text", Text: "Cancel"},
+			ActionID: "shell_cancel",
+			Value:    id,
+		},
+	}
+	if err := sendMessageWithButtonsToThread(config, channelID, threadTS, text, buttons, "shell_"+eventTS); err != nil {
+		pendingShellCommands.Delete(id)
+		return err
+	}
+	return nil
+}
+
+// handleShellConfirmAction runs or drops a command waiting for confirmation
+func handleShellConfirmAction(config *Config, action BlockActionPayload, act BlockAction) {
+	pending, ok := pendingShellCommands.LoadAndDelete(act.Value)
+	if !ok {
+		updateMessage(config, action.Channel.ID, action.Message.TS, action.Message.Text+"\n\n:information_source: This command is no longer pending (already handled, or the listener restarted)")
+		return
+	}
+	cmdStr := pending.(string)
+	if act.ActionID == "shell_cancel" {
+		updateMessage(config, action.Channel.ID, action.Message.TS, fmt.Sprintf(":no_entry_sign: Cancelled `!c` by <@%s>", action.User.ID))
+		return
+	}
+
+	// The config may have changed while the command waited
+	if verdict, reason := checkShellCommand(config, cmdStr); verdict == shellRefused {
+		updateMessage(config, action.Channel.ID, action.Message.TS, ":no_entry: "+reason)
+		return
+	}
+	updateMessage(config, action.Channel.ID, action.Message.TS, fmt.Sprintf(":white_check_mark: Confirmed by <@%s>:\n```\n%s\n```", action.User.ID, cmdStr))
+	logf("Running confirmed !c in %s: %s", action.Channel.ID, cmdStr)
+	output := runShellCommand(cmdStr)
+	if threadTS := action.Message.ThreadTS; threadTS != "" {
+		sendMessageToThread(config, action.Channel.ID, threadTS, output)
+	} else {
+		sendMessage(config, action.Channel.ID, output)
+	}
+}
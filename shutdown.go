@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// shutdownTimeout bounds how long shutdown waits for active runs to finish
+const shutdownTimeout = 60 * time.Second
+
+// getQueueFilePath returns where pending messages are saved across restarts (~/.ccsa/queue.json)
+func getQueueFilePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "queue.json")
+}
+
+// gracefulShutdown runs after the Socket Mode loop stopped accepting events:
+// pending messages are saved, busy channels are told, and active runs get
+// shutdownTimeout to finish before being killed
+func gracefulShutdown(config *Config) {
+	// Messages that haven't started yet: batched (prefix not applied yet) then queued
+	var pending []*QueuedMessage
+	for _, m := range messageBatcher.Flush() {
+		if !strings.HasPrefix(m.Text, "/") {
+			m.Text = slackUserPrefix + m.Text
+		}
+		pending = append(pending, m)
+	}
+	pending = append(pending, messageQueue.Drain()...)
+	if err := saveQueueToDisk(pending); err != nil {
+		logf("Failed to save %d pending message(s): %v", len(pending), err)
+	} else if len(pending) > 0 {
+		logf("Saved %d pending message(s) to %s", len(pending), getQueueFilePath())
+	}
+
+	pendingByChannel := make(map[string]int)
+	for _, m := range pending {
+		pendingByChannel[m.ChannelID]++
+	}
+	notified := make(map[string]bool)
+	for _, channelID := range messageQueue.BusyChannels() {
+		notice := fmt.Sprintf(":zzz: Bot restarting - the current task has up to %s to finish", formatDuration(shutdownTimeout))
+		if n := pendingByChannel[channelID]; n > 0 {
+			notice += fmt.Sprintf(", %d queued message(s) will run after the restart", n)
+		}
+		sendMessage(config, channelID, notice)
+		notified[channelID] = true
+	}
+	for channelID, n := range pendingByChannel {
+		if !notified[channelID] {
+			sendMessage(config, channelID, fmt.Sprintf(":zzz: Bot restarting - %d pending message(s) will run after the restart", n))
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		workerPool.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logf("Graceful shutdown complete")
+	case <-time.After(shutdownTimeout):
+		logf("Shutdown timeout, killing active runs")
+		activeProcesses.Range(func(key, _ interface{}) bool {
+			CancelClaudeProcess(key.(string))
+			return true
+		})
+	}
+}
+
+// saveQueueToDisk persists pending messages (removes the file when there are none)
+func saveQueueToDisk(messages []*QueuedMessage) error {
+	path := getQueueFilePath()
+	if len(messages) == 0 {
+		os.Remove(path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// loadQueueFromDisk reads and removes the messages saved by the previous shutdown
+func loadQueueFromDisk() []*QueuedMessage {
+	path := getQueueFilePath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	os.Remove(path)
+
+	var messages []*QueuedMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		logf("Ignoring unreadable queue file: %v", err)
+		return nil
+	}
+	return messages
+}
+
+// restorePendingMessages re-dispatches messages saved by the previous shutdown
+func restorePendingMessages(config *Config) {
+	messages := loadQueueFromDisk()
+	if len(messages) == 0 {
+		return
+	}
+	logf("Restoring %d pending message(s) from previous run", len(messages))
+
+	perChannel := make(map[string]int)
+	for _, m := range messages {
+		perChannel[m.ChannelID]++
+	}
+	for channelID, n := range perChannel {
+		sendMessage(config, channelID, fmt.Sprintf(":arrow_forward: Back online - running %d message(s) left pending by the restart", n))
+	}
+	for _, m := range messages {
+		dispatchClaudeMessage(m, config)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// buttonValueTTL is how long a posted button keeps working
+const buttonValueTTL = 24 * time.Hour
+
+var (
+	errButtonForged  = errors.New("invalid button signature")
+	errButtonExpired = errors.New("button expired")
+)
+
+var buttonKey struct {
+	once sync.Once
+	key  []byte
+}
+
+// getButtonKeyPath returns the path to the button signing key. It is shared by
+// the listener and hook processes, which post buttons the listener verifies.
+func getButtonKeyPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "button.key")
+}
+
+// loadButtonKey reads the signing key, creating it on first use
+func loadButtonKey() []byte {
+	buttonKey.once.Do(func() {
+		path := getButtonKeyPath()
+		if data, err := os.ReadFile(path); err == nil && len(data) >= 32 {
+			buttonKey.key = data
+			return
+		}
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			logf("Button signing key: %v", err)
+		}
+		os.MkdirAll(filepath.Dir(path), 0700)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			// Another process created it first
+			if data, err := os.ReadFile(path); err == nil && len(data) >= 32 {
+				buttonKey.key = data
+				return
+			}
+		} else {
+			f.Write(key)
+			f.Close()
+		}
+		buttonKey.key = key
+	})
+	return buttonKey.key
+}
+
+// buttonMAC authenticates a value and its expiry
+func buttonMAC(key []byte, value, expiry string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value + "|" + expiry))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// signButtonValue appends an expiry and an HMAC: "value|expiry|mac"
+func signButtonValue(key []byte, value string, now time.Time) string {
+	expiry := strconv.FormatInt(now.Add(buttonValueTTL).Unix(), 36)
+	return value + "|" + expiry + "|" + buttonMAC(key, value, expiry)
+}
+
+// verifyButtonValue checks a signed value and returns the original one
+func verifyButtonValue(key []byte, signed string, now time.Time) (string, error) {
+	rest, mac, ok := cutLast(signed, "|")
+	if !ok {
+		return "", errButtonForged
+	}
+	value, expiry, ok := cutLast(rest, "|")
+	if !ok || !hmac.Equal([]byte(mac), []byte(buttonMAC(key, value, expiry))) {
+		return "", errButtonForged
+	}
+	exp, err := strconv.ParseInt(expiry, 36, 64)
+	if err != nil {
+		return "", errButtonForged
+	}
+	if now.After(time.Unix(exp, 0)) {
+		return "", errButtonExpired
+	}
+	return value, nil
+}
+
+// cutLast splits s around the last sep
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// signButtons signs the value of every button before it is posted
+func signButtons(buttons []Element) []Element {
+	key := loadButtonKey()
+	signed := make([]Element, len(buttons))
+	for i, b := range buttons {
+		if b.Value != "" {
+			b.Value = signButtonValue(key, b.Value, time.Now())
+		}
+		signed[i] = b
+	}
+	return signed
+}
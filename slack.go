@@ -3,8 +3,10 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
@@ -28,12 +30,21 @@ var httpClient = &http.Client{
 // Slack API types
 
 type SlackResponse struct {
-	OK      bool            `json:"ok"`
-	Error   string          `json:"error,omitempty"`
-	Channel json.RawMessage `json:"channel,omitempty"`
-	TS      string          `json:"ts,omitempty"`
-	URL     string          `json:"url,omitempty"` // For Socket Mode connection
-	File    *SlackFileInfo  `json:"file,omitempty"`
+	OK       bool            `json:"ok"`
+	Error    string          `json:"error,omitempty"`
+	Channel  json.RawMessage `json:"channel,omitempty"`
+	TS       string          `json:"ts,omitempty"`
+	URL      string          `json:"url,omitempty"` // For Socket Mode connection
+	File     *SlackFileInfo  `json:"file,omitempty"`
+	Needed   string          `json:"needed,omitempty"`   // missing_scope: the scope the call needs
+	Provided string          `json:"provided,omitempty"` // missing_scope: the scopes the token has
+}
+
+// logMissingScope names the exact scope a failed call needs
+func logMissingScope(method string, result *SlackResponse) {
+	if result.Error == "missing_scope" {
+		logf("Slack %s failed: missing scope %q - add it under OAuth & Permissions and reinstall the app", method, result.Needed)
+	}
 }
 
 type SlackFileInfo struct {
@@ -68,6 +79,7 @@ type SlackFile struct {
 	Name               string `json:"name"`
 	Mimetype           string `json:"mimetype"`
 	Filetype           string `json:"filetype"`
+	Subtype            string `json:"subtype,omitempty"` // "slack_audio" for voice clips
 	URLPrivateDownload string `json:"url_private_download"`
 	URLPrivate         string `json:"url_private"`
 }
@@ -79,6 +91,10 @@ type SocketModeEnvelope struct {
 	Payload      json.RawMessage `json:"payload"`
 	RetryAttempt int             `json:"retry_attempt,omitempty"`
 	RetryReason  string          `json:"retry_reason,omitempty"`
+	Reason       string          `json:"reason,omitempty"` // disconnect: warning, refresh_requested, link_disabled
+	DebugInfo    struct {
+		ApproximateConnectionTime int `json:"approximate_connection_time"` // hello: seconds until Slack drops the link
+	} `json:"debug_info"`
 }
 
 // Event callback payload
@@ -99,6 +115,37 @@ type BlockActionPayload struct {
 	Message     SlackMessage  `json:"message"`
 	Actions     []BlockAction `json:"actions"`
 	ResponseURL string        `json:"response_url"`
+	TriggerID   string        `json:"trigger_id"`
+	View        *ViewPayload  `json:"view,omitempty"`
+	// State holds the message's inputs (checkboxes) when a button is clicked
+	State *struct {
+		Values map[string]map[string]struct {
+			SelectedOptions []struct {
+				Value string `json:"value"`
+			} `json:"selected_options"`
+		} `json:"values"`
+	} `json:"state,omitempty"`
+}
+
+// Modal view payload (view_submission)
+type ViewPayload struct {
+	ID              string `json:"id"`
+	CallbackID      string `json:"callback_id"`
+	PrivateMetadata string `json:"private_metadata"`
+	State           struct {
+		Values map[string]map[string]struct {
+			Value string `json:"value"`
+		} `json:"values"`
+	} `json:"state"`
+}
+
+// Slash command payload (Socket Mode "slash_commands" envelope)
+type SlashCommandPayload struct {
+	Command   string `json:"command"`
+	Text      string `json:"text"`
+	UserID    string `json:"user_id"`
+	ChannelID string `json:"channel_id"`
+	TriggerID string `json:"trigger_id"`
 }
 
 type BlockAction struct {
@@ -122,61 +169,95 @@ type TextObject struct {
 }
 
 type Element struct {
-	Type     string      `json:"type"`
-	Text     *TextObject `json:"text,omitempty"`
-	ActionID string      `json:"action_id,omitempty"`
-	Value    string      `json:"value,omitempty"`
-	Style    string      `json:"style,omitempty"`
+	Type     string         `json:"type"`
+	Text     *TextObject    `json:"text,omitempty"`
+	ActionID string         `json:"action_id,omitempty"`
+	Value    string         `json:"value,omitempty"`
+	Style    string         `json:"style,omitempty"`
+	Options  []OptionObject `json:"options,omitempty"` // checkboxes
+}
+
+// OptionObject is one choice of a checkboxes element
+type OptionObject struct {
+	Text        *TextObject `json:"text"`
+	Value       string      `json:"value"`
+	Description *TextObject `json:"description,omitempty"`
 }
 
 // Slack API helpers
 
 func slackAPI(config *Config, method string, params url.Values) (*SlackResponse, error) {
-	apiURL := fmt.Sprintf("https://slack.com/api/%s", method)
-
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(params.Encode()))
+	body := []byte(params.Encode())
+	if flushOutboxFirst(config, method) {
+		return nil, bufferFailedPost(method, params, nil, errors.New("earlier messages still undelivered"))
+	}
+	result, err := doSlackRequest(config, method, "application/x-www-form-urlencoded", body)
 	if err != nil {
-		return nil, err
+		return nil, bufferFailedPost(method, params, nil, err)
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Authorization", "Bearer "+config.BotToken)
+	return result, nil
+}
 
-	resp, err := httpClient.Do(req)
+func slackAPIJSON(config *Config, method string, payload interface{}) (*SlackResponse, error) {
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	if flushOutboxFirst(config, method) {
+		return nil, bufferFailedPost(method, nil, jsonData, errors.New("earlier messages still undelivered"))
+	}
+	result, err := doSlackRequest(config, method, "application/json", jsonData)
+	if err != nil {
+		return nil, bufferFailedPost(method, nil, jsonData, err)
+	}
+	return result, nil
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	var result SlackResponse
-	json.Unmarshal(body, &result)
-	return &result, nil
+// flushOutboxFirst delivers buffered messages before a new post so they stay in
+// order. Returns true when Slack is still unreachable and the post must be buffered too.
+func flushOutboxFirst(config *Config, method string) bool {
+	if method != "chat.postMessage" || !outbox.Pending() {
+		return false
+	}
+	replayOutbox(config)
+	return outbox.Pending()
 }
 
-func slackAPIJSON(config *Config, method string, payload interface{}) (*SlackResponse, error) {
-	apiURL := fmt.Sprintf("https://slack.com/api/%s", method)
+// defaultSlackAPIURL is the Web API base unless slack_api_url says otherwise
+const defaultSlackAPIURL = "https://slack.com/api"
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
+// slackMethodURL returns the URL of a Web API method
+func slackMethodURL(config *Config, method string) string {
+	base := defaultSlackAPIURL
+	if config != nil && config.SlackAPIURL != "" {
+		base = strings.TrimSuffix(config.SlackAPIURL, "/")
 	}
+	return base + "/" + method
+}
+
+// doSlackRequest performs one Web API call; err is only set when Slack could not be reached
+func doSlackRequest(config *Config, method, contentType string, body []byte) (*SlackResponse, error) {
+	apiURL := slackMethodURL(config, method)
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewReader(jsonData))
+	req, err := http.NewRequest("POST", apiURL, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Authorization", "Bearer "+config.BotToken)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
+		apiErrors.Record(method, classifyAPIError("", 0, err), time.Now())
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	respBody, _ := io.ReadAll(resp.Body)
 	var result SlackResponse
-	json.Unmarshal(body, &result)
+	json.Unmarshal(respBody, &result)
+	logMissingScope(method, &result)
+	apiErrors.Record(method, classifyAPIError(result.Error, resp.StatusCode, nil), time.Now())
 	return &result, nil
 }
 
@@ -240,6 +321,91 @@ func downloadSlackFileToDir(config *Config, file SlackFile, targetDir string) (s
 	return localPath, nil
 }
 
+// SavedAttachment describes a Slack file that was downloaded into a session workdir
+type SavedAttachment struct {
+	Name      string // Original Slack filename
+	LocalPath string // Absolute path on disk
+	RelPath   string // Path relative to the session workdir
+}
+
+// saveAttachments downloads all supported files of a message into the session workdir.
+// Text/code files go to workDir/uploads/ (visible to Claude as project files),
+// images go to workDir/.slack-uploads/. Returns saved files and per-file errors.
+func saveAttachments(config *Config, files []SlackFile, workDir string) ([]SavedAttachment, []string) {
+	var saved []SavedAttachment
+	var failures []string
+
+	for _, file := range files {
+		logf("File attached: name=%s mimetype=%s filetype=%s", file.Name, file.Mimetype, file.Filetype)
+
+		var targetDir string
+		switch {
+		case isAudioFile(file):
+			continue // Voice clips are transcribed separately
+		case isImageFile(file):
+			targetDir = filepath.Join(workDir, ".slack-uploads")
+		case isTextFile(file):
+			targetDir = filepath.Join(workDir, "uploads")
+		default:
+			logf("Skipping unsupported file type: %s (mimetype=%s, filetype=%s)", file.Name, file.Mimetype, file.Filetype)
+			continue
+		}
+
+		if err := os.MkdirAll(targetDir, 0755); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", file.Name, err))
+			continue
+		}
+
+		logf("Downloading file: %s (%s)", file.Name, file.Mimetype)
+		localPath, err := downloadSlackFileToDir(config, file, targetDir)
+		if err != nil {
+			logf("Failed to download file %s: %v", file.Name, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", file.Name, err))
+			continue
+		}
+
+		relPath, err := filepath.Rel(workDir, localPath)
+		if err != nil {
+			relPath = localPath
+		}
+		saved = append(saved, SavedAttachment{Name: file.Name, LocalPath: localPath, RelPath: relPath})
+		logf("Saved file to: %s", localPath)
+	}
+
+	return saved, failures
+}
+
+// attachmentPrompt appends saved file paths to a prompt so Claude knows where to read them
+func attachmentPrompt(text string, saved []SavedAttachment) string {
+	if len(saved) == 0 {
+		return text
+	}
+	var lines []string
+	for _, a := range saved {
+		lines = append(lines, "- "+a.LocalPath)
+	}
+	fileList := strings.Join(lines, "\n")
+	if text == "" {
+		return fmt.Sprintf("Please analyze these files:\n%s", fileList)
+	}
+	return fmt.Sprintf("%s\n\nAttached file(s):\n%s", text, fileList)
+}
+
+// attachmentConfirmation formats the Slack confirmation listing saved locations
+func attachmentConfirmation(saved []SavedAttachment, failures []string) string {
+	var lines []string
+	if len(saved) > 0 {
+		lines = append(lines, fmt.Sprintf(":paperclip: Saved %d file(s):", len(saved)))
+		for _, a := range saved {
+			lines = append(lines, fmt.Sprintf("• `%s`", a.RelPath))
+		}
+	}
+	for _, f := range failures {
+		lines = append(lines, fmt.Sprintf(":warning: Failed to download %s", f))
+	}
+	return strings.Join(lines, "\n")
+}
+
 // isImageFile checks if a Slack file is an image
 func isImageFile(file SlackFile) bool {
 	return strings.HasPrefix(file.Mimetype, "image/")
@@ -288,25 +454,28 @@ func isTextFile(file SlackFile) bool {
 }
 
 func sendMessage(config *Config, channelID string, text string) (string, error) {
-	const maxLen = 3000
+	return postMessageParts(config, channelID, "", text)
+}
 
-	messages := splitMessage(text, maxLen)
+// sendMessageToThread sends a message as a reply to an existing message (thread)
+func sendMessageToThread(config *Config, channelID string, threadTS string, text string) error {
+	_, err := postMessageParts(config, channelID, threadTS, text)
+	return err
+}
+
+// postMessageParts posts text split at the backend's message size and
+// returns the timestamp of the last part
+func postMessageParts(config *Config, channelID, threadTS, text string) (string, error) {
+	chat := chatFor(channelID)
+	messages := splitMessage(redactSecrets(config, text), chat.MaxMessageLen())
 	var lastTS string
 
 	for _, msg := range messages {
-		params := url.Values{
-			"channel": {channelID},
-			"text":    {msg},
-		}
-
-		result, err := slackAPI(config, "chat.postMessage", params)
+		ts, err := chat.PostMessage(config, channelID, threadTS, msg)
 		if err != nil {
 			return "", err
 		}
-		if !result.OK {
-			return "", fmt.Errorf("slack error: %s", result.Error)
-		}
-		lastTS = result.TS
+		lastTS = ts
 
 		if len(messages) > 1 {
 			time.Sleep(100 * time.Millisecond)
@@ -315,40 +484,74 @@ func sendMessage(config *Config, channelID string, text string) (string, error)
 	return lastTS, nil
 }
 
-// sendMessageToThread sends a message as a reply to an existing message (thread)
-func sendMessageToThread(config *Config, channelID string, threadTS string, text string) error {
-	const maxLen = 3000
+// sendMessageToThreadGetTS sends a message to a thread and returns its timestamp
+func sendMessageToThreadGetTS(config *Config, channelID string, threadTS string, text string) (string, error) {
+	return chatFor(channelID).PostMessage(config, channelID, threadTS, redactSecrets(config, text))
+}
 
-	messages := splitMessage(text, maxLen)
+func addReaction(config *Config, channelID string, timestamp string, emoji string) error {
+	return chatFor(channelID).AddReaction(config, channelID, timestamp, emoji)
+}
 
-	for _, msg := range messages {
-		params := url.Values{
-			"channel":   {channelID},
-			"text":      {msg},
-			"thread_ts": {threadTS},
-		}
+func removeReaction(config *Config, channelID string, timestamp string, emoji string) error {
+	return chatFor(channelID).RemoveReaction(config, channelID, timestamp, emoji)
+}
 
-		result, err := slackAPI(config, "chat.postMessage", params)
-		if err != nil {
-			return err
-		}
-		if !result.OK {
-			return fmt.Errorf("slack error: %s", result.Error)
-		}
+func sendMessageWithButtons(config *Config, channelID string, text string, buttons []Element, blockID string) error {
+	return sendMessageWithButtonsToThread(config, channelID, "", text, buttons, blockID)
+}
 
-		if len(messages) > 1 {
-			time.Sleep(100 * time.Millisecond)
-		}
+// sendMessageWithButtonsToThread posts buttons in a thread (threadTS "" = channel)
+func sendMessageWithButtonsToThread(config *Config, channelID, threadTS string, text string, buttons []Element, blockID string) error {
+	_, err := sendMessageWithButtonsGetTS(config, channelID, threadTS, text, buttons, blockID)
+	return err
+}
+
+// sendMessageWithButtonsGetTS posts buttons and returns the message timestamp
+func sendMessageWithButtonsGetTS(config *Config, channelID, threadTS string, text string, buttons []Element, blockID string) (string, error) {
+	return chatFor(channelID).PostButtons(config, channelID, threadTS, redactSecrets(config, text), signButtons(buttons), blockID)
+}
+
+func updateMessage(config *Config, channelID string, ts string, text string) error {
+	return chatFor(channelID).UpdateMessage(config, channelID, ts, redactSecrets(config, text))
+}
+
+// updateMessageWithButtons replaces a message's text and buttons (no buttons: text only)
+func updateMessageWithButtons(config *Config, channelID, ts, text string, buttons []Element, blockID string) error {
+	if len(buttons) == 0 {
+		return updateMessage(config, channelID, ts, text)
 	}
-	return nil
+	return chatFor(channelID).UpdateButtons(config, channelID, ts, redactSecrets(config, text), signButtons(buttons), blockID)
 }
 
-// sendMessageToThreadGetTS sends a message to a thread and returns its timestamp
-func sendMessageToThreadGetTS(config *Config, channelID string, threadTS string, text string) (string, error) {
+func deleteMessage(config *Config, channelID string, ts string) error {
+	return chatFor(channelID).DeleteMessage(config, channelID, ts)
+}
+
+// uploadSnippet uploads content as a Slack snippet and returns the file URL
+func uploadSnippet(config *Config, channelID, threadTS, filename, content, title string) (string, error) {
+	return chatFor(channelID).UploadSnippet(config, channelID, threadTS, filename, redactSecrets(config, content), title)
+}
+
+// uploadFile uploads a local file (any type) to a channel and returns the file URL
+func uploadFile(config *Config, channelID, threadTS, filePath, comment string) (string, error) {
+	return chatFor(channelID).UploadFile(config, channelID, threadTS, filePath, comment)
+}
+
+// slackChat is the Slack ChatBackend (Web API, events over Socket Mode)
+type slackChat struct{}
+
+func (slackChat) Name() string { return "slack" }
+
+func (slackChat) MaxMessageLen() int { return 3000 }
+
+func (slackChat) PostMessage(config *Config, channelID, threadTS, text string) (string, error) {
 	payload := map[string]interface{}{
-		"channel":   channelID,
-		"text":      text,
-		"thread_ts": threadTS,
+		"channel": channelID,
+		"text":    text,
+	}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
 	}
 
 	result, err := slackAPIJSON(config, "chat.postMessage", payload)
@@ -361,7 +564,7 @@ func sendMessageToThreadGetTS(config *Config, channelID string, threadTS string,
 	return result.TS, nil
 }
 
-func addReaction(config *Config, channelID string, timestamp string, emoji string) error {
+func (slackChat) AddReaction(config *Config, channelID, timestamp, emoji string) error {
 	params := url.Values{
 		"channel":   {channelID},
 		"timestamp": {timestamp},
@@ -379,7 +582,7 @@ func addReaction(config *Config, channelID string, timestamp string, emoji strin
 	return nil
 }
 
-func removeReaction(config *Config, channelID string, timestamp string, emoji string) error {
+func (slackChat) RemoveReaction(config *Config, channelID, timestamp, emoji string) error {
 	params := url.Values{
 		"channel":   {channelID},
 		"timestamp": {timestamp},
@@ -395,24 +598,50 @@ func removeReaction(config *Config, channelID string, timestamp string, emoji st
 	return nil
 }
 
-func sendMessageWithButtons(config *Config, channelID string, text string, buttons []Element, blockID string) error {
+// buttonBlocks lays out text above an actions block of buttons
+func buttonBlocks(text string, buttons []Element, blockID string) []Block {
+	return []Block{
+		{
+			Type: "section",
+			Text: &TextObject{Type: "mrkdwn", Text: text},
+		},
+		{
+			Type:     "actions",
+			BlockID:  blockID,
+			Elements: buttons,
+		},
+	}
+}
+
+func (slackChat) PostButtons(config *Config, channelID, threadTS, text string, buttons []Element, blockID string) (string, error) {
 	payload := map[string]interface{}{
 		"channel": channelID,
 		"text":    text,
-		"blocks": []Block{
-			{
-				Type: "section",
-				Text: &TextObject{Type: "mrkdwn", Text: text},
-			},
-			{
-				Type:     "actions",
-				BlockID:  blockID,
-				Elements: buttons,
-			},
-		},
+		"blocks":  buttonBlocks(text, buttons, blockID),
+	}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
 	}
 
 	result, err := slackAPIJSON(config, "chat.postMessage", payload)
+	if err != nil {
+		return "", err
+	}
+	if !result.OK {
+		return "", fmt.Errorf("slack error: %s", result.Error)
+	}
+	return result.TS, nil
+}
+
+func (slackChat) UpdateMessage(config *Config, channelID, ts, text string) error {
+	payload := map[string]interface{}{
+		"channel": channelID,
+		"ts":      ts,
+		"text":    text,
+		"blocks":  []Block{}, // Remove buttons
+	}
+
+	result, err := slackAPIJSON(config, "chat.update", payload)
 	if err != nil {
 		return err
 	}
@@ -422,12 +651,12 @@ func sendMessageWithButtons(config *Config, channelID string, text string, butto
 	return nil
 }
 
-func updateMessage(config *Config, channelID string, ts string, text string) error {
+func (slackChat) UpdateButtons(config *Config, channelID, ts, text string, buttons []Element, blockID string) error {
 	payload := map[string]interface{}{
 		"channel": channelID,
 		"ts":      ts,
 		"text":    text,
-		"blocks":  []Block{}, // Remove buttons
+		"blocks":  buttonBlocks(text, buttons, blockID),
 	}
 
 	result, err := slackAPIJSON(config, "chat.update", payload)
@@ -440,7 +669,7 @@ func updateMessage(config *Config, channelID string, ts string, text string) err
 	return nil
 }
 
-func deleteMessage(config *Config, channelID string, ts string) error {
+func (slackChat) DeleteMessage(config *Config, channelID, ts string) error {
 	payload := map[string]interface{}{
 		"channel": channelID,
 		"ts":      ts,
@@ -459,8 +688,7 @@ func deleteMessage(config *Config, channelID string, ts string) error {
 	return nil
 }
 
-// uploadSnippet uploads content as a Slack snippet and returns the file URL
-func uploadSnippet(config *Config, channelID, threadTS, filename, content, title string) (string, error) {
+func (slackChat) UploadSnippet(config *Config, channelID, threadTS, filename, content, title string) (string, error) {
 	// Use files.upload API (v1)
 	params := url.Values{
 		"channels":        {channelID},
@@ -482,6 +710,69 @@ func uploadSnippet(config *Config, channelID, threadTS, filename, content, title
 	return result.File.Permalink, nil
 }
 
+func (slackChat) UploadFile(config *Config, channelID, threadTS, filePath, comment string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fields := map[string]string{
+		"channels":        channelID,
+		"filename":        filepath.Base(filePath),
+		"title":           filepath.Base(filePath),
+		"initial_comment": comment,
+	}
+	if threadTS != "" {
+		fields["thread_ts"] = threadTS
+	}
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		if err := writer.WriteField(k, v); err != nil {
+			return "", err
+		}
+	}
+	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", slackMethodURL(config, "files.upload"), &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+config.BotToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result SlackResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if !result.OK {
+		return "", fmt.Errorf("failed to upload file: %s", result.Error)
+	}
+	if result.File == nil {
+		return "", nil
+	}
+	return result.File.Permalink, nil
+}
+
 func splitMessage(text string, maxLen int) []string {
 	if len(text) <= maxLen {
 		return []string{text}
@@ -511,16 +802,14 @@ func splitMessage(text string, maxLen int) []string {
 }
 
 func createChannel(config *Config, name string) (string, error) {
-	// Slack channel names: lowercase, no spaces, max 80 chars
-	channelName := strings.ToLower(name)
-	channelName = strings.ReplaceAll(channelName, " ", "-")
-	if len(channelName) > 80 {
-		channelName = channelName[:80]
-	}
+	channelName := toSlackChannelName(name)
 
 	params := url.Values{
 		"name": {channelName},
 	}
+	if config.ChannelVisibility == "private" {
+		params.Set("is_private", "true")
+	}
 
 	result, err := slackAPI(config, "conversations.create", params)
 	if err != nil {
@@ -529,7 +818,11 @@ func createChannel(config *Config, name string) (string, error) {
 	if !result.OK {
 		// Channel might already exist
 		if result.Error == "name_taken" {
-			// Try to find existing channel
+			// It exists: a cache loaded before it was created must not hide it
+			if id, ok := channelDirectory.Lookup(channelName, time.Now()); ok {
+				return id, nil
+			}
+			channelDirectory.Invalidate()
 			return findChannelByName(config, channelName)
 		}
 		return "", fmt.Errorf("failed to create channel: %s", result.Error)
@@ -539,54 +832,43 @@ func createChannel(config *Config, name string) (string, error) {
 	if err := json.Unmarshal(result.Channel, &channel); err != nil {
 		return "", fmt.Errorf("failed to parse channel: %w", err)
 	}
+	channelDirectory.Add(channel.Name, channel.ID)
 
+	if users := config.ChannelInvitees(); len(users) > 0 {
+		if err := inviteToChannel(config, channel.ID, users); err != nil {
+			logf("Failed to invite users to #%s: %v", channelName, err)
+		}
+	}
 	return channel.ID, nil
 }
 
-func findChannelByName(config *Config, name string) (string, error) {
-	params := url.Values{
-		"types": {"public_channel,private_channel"},
-		"limit": {"1000"},
-	}
-
-	req, err := http.NewRequest("GET", "https://slack.com/api/conversations.list?"+params.Encode(), nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Authorization", "Bearer "+config.BotToken)
-
-	resp, err := httpClient.Do(req)
+// inviteToChannel adds users to a channel (conversations.invite), skipping
+// those already in it
+func inviteToChannel(config *Config, channelID string, users []string) error {
+	result, err := slackAPI(config, "conversations.invite", url.Values{
+		"channel": {channelID},
+		"users":   {strings.Join(users, ",")},
+		"force":   {"true"},
+	})
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		OK       bool           `json:"ok"`
-		Channels []SlackChannel `json:"channels"`
-		Error    string         `json:"error"`
-	}
-	json.NewDecoder(resp.Body).Decode(&result)
-
-	if !result.OK {
-		return "", fmt.Errorf("failed to list channels: %s", result.Error)
+		return err
 	}
-
-	for _, ch := range result.Channels {
-		if ch.Name == name {
-			return ch.ID, nil
-		}
+	if !result.OK && result.Error != "already_in_channel" {
+		return fmt.Errorf("failed to invite: %s", result.Error)
 	}
-
-	return "", fmt.Errorf("channel not found: %s", name)
+	return nil
 }
 
 func getChannelName(config *Config, channelID string) (string, error) {
+	return chatFor(channelID).ChannelName(config, channelID)
+}
+
+func (slackChat) ChannelName(config *Config, channelID string) (string, error) {
 	params := url.Values{
 		"channel": {channelID},
 	}
 
-	req, err := http.NewRequest("GET", "https://slack.com/api/conversations.info?"+params.Encode(), nil)
+	req, err := http.NewRequest("GET", slackMethodURL(config, "conversations.info")+"?"+params.Encode(), nil)
 	if err != nil {
 		return "", err
 	}
@@ -615,8 +897,12 @@ func getChannelName(config *Config, channelID string) (string, error) {
 	return result.Channel.Name, nil
 }
 
-// archiveChannel archives a Slack channel
+// archiveChannel archives a session channel
 func archiveChannel(config *Config, channelID string) error {
+	return chatFor(channelID).ArchiveChannel(config, channelID)
+}
+
+func (slackChat) ArchiveChannel(config *Config, channelID string) error {
 	params := url.Values{
 		"channel": {channelID},
 	}
@@ -631,8 +917,31 @@ func archiveChannel(config *Config, channelID string) error {
 	return nil
 }
 
-// pinMessage pins a message in a channel
+// renameChannel renames a Slack channel (name is sanitized like createChannel's)
+func renameChannel(config *Config, channelID, name string) error {
+	if !onSlack(channelID) {
+		return nil
+	}
+	params := url.Values{
+		"channel": {channelID},
+		"name":    {toSlackChannelName(name)},
+	}
+
+	result, err := slackAPI(config, "conversations.rename", params)
+	if err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("failed to rename channel: %s", result.Error)
+	}
+	return nil
+}
+
+// pinMessage pins a message in a channel (Slack only, a no-op elsewhere)
 func pinMessage(config *Config, channelID string, messageTS string) error {
+	if !onSlack(channelID) {
+		return nil
+	}
 	params := url.Values{
 		"channel":   {channelID},
 		"timestamp": {messageTS},
@@ -651,6 +960,92 @@ func pinMessage(config *Config, channelID string, messageTS string) error {
 	return nil
 }
 
+// setChannelTopic sets a channel's topic (conversations.setTopic)
+func setChannelTopic(config *Config, channelID, topic string) error {
+	if !onSlack(channelID) {
+		return nil
+	}
+	result, err := slackAPI(config, "conversations.setTopic", url.Values{
+		"channel": {channelID},
+		"topic":   {truncateRunes(topic, 250)},
+	})
+	if err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("failed to set topic: %s", result.Error)
+	}
+	return nil
+}
+
+// addBookmark adds a link to a channel's bookmarks bar (bookmarks.add)
+func addBookmark(config *Config, channelID, title, link, emoji string) error {
+	if !onSlack(channelID) {
+		return nil
+	}
+	result, err := slackAPI(config, "bookmarks.add", url.Values{
+		"channel_id": {channelID},
+		"title":      {title},
+		"type":       {"link"},
+		"link":       {link},
+		"emoji":      {emoji},
+	})
+	if err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("failed to add bookmark %q: %s", title, result.Error)
+	}
+	return nil
+}
+
+// sessionChannelTopic describes where a session runs
+func sessionChannelTopic(workDir, host string, sandbox bool) string {
+	switch {
+	case host != "":
+		return fmt.Sprintf(":satellite_antenna: %s on agent %s", workDir, host)
+	case sandbox:
+		return fmt.Sprintf(":whale: %s (Docker sandbox)", workDir)
+	}
+	return ":file_folder: " + workDir
+}
+
+// sessionChannelBookmarks returns the bookmarks of a session channel (title,
+// link, emoji): its GitHub repo and CI, and its folder
+func sessionChannelBookmarks(workDir, githubURL string) [][3]string {
+	var bookmarks [][3]string
+	if githubURL != "" {
+		bookmarks = append(bookmarks,
+			[3]string{"GitHub", githubURL, ":octocat:"},
+			[3]string{"CI", strings.TrimSuffix(githubURL, "/") + "/actions", ":white_check_mark:"},
+		)
+	}
+	if workDir != "" {
+		bookmarks = append(bookmarks, [3]string{filepath.Base(workDir), "file://" + workDir, ":file_folder:"})
+	}
+	return bookmarks
+}
+
+// provisionSessionChannel sets up a new session channel: topic with the
+// project path and host, bookmarks for the repo, CI and folder. Failures are
+// logged - the session works without them.
+func provisionSessionChannel(config *Config, channelID, workDir, host string, sandbox bool) {
+	if err := setChannelTopic(config, channelID, sessionChannelTopic(workDir, host, sandbox)); err != nil {
+		logf("Channel %s: %v", channelID, err)
+	}
+	githubURL := ""
+	if host == "" {
+		githubURL = getGitHubURL(workDir)
+	} else {
+		workDir = "" // the folder is on the agent's machine
+	}
+	for _, b := range sessionChannelBookmarks(workDir, githubURL) {
+		if err := addBookmark(config, channelID, b[0], b[1], b[2]); err != nil {
+			logf("Channel %s: %v", channelID, err)
+		}
+	}
+}
+
 // getGitHubURL extracts the GitHub URL from a git repository
 func getGitHubURL(projectDir string) string {
 	gitConfigPath := filepath.Join(projectDir, ".git", "config")
@@ -752,7 +1147,10 @@ func savePinnedChannelsToDisk() {
 
 // hasGitHubPinned checks if the channel already has a GitHub link pinned
 func hasGitHubPinned(config *Config, channelID string) bool {
-	apiURL := "https://slack.com/api/pins.list"
+	if !onSlack(channelID) {
+		return false
+	}
+	apiURL := slackMethodURL(config, "pins.list")
 	params := url.Values{
 		"channel": {channelID},
 	}
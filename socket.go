@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// reconnectBaseDelay and reconnectMaxDelay bound the exponential backoff
+	reconnectBaseDelay = 1 * time.Second
+	reconnectMaxDelay  = 2 * time.Minute
+	// socketPingInterval is how often we ping Slack to prove the link is alive
+	socketPingInterval = 30 * time.Second
+	// socketIdleTimeout closes a link that received nothing (not even a pong) for this long
+	socketIdleTimeout = 3 * socketPingInterval
+	// connectionRefreshMargin reconnects this long before Slack expires the link
+	connectionRefreshMargin = 60 * time.Second
+	// outageNotifyAfter is how long the link must be down before busy channels are told
+	outageNotifyAfter = 2 * time.Minute
+)
+
+// errSocketRefresh means the link is being replaced on purpose (Slack asked for a
+// refresh, or it is about to expire): reconnect right away, without backoff
+var errSocketRefresh = errors.New("socket mode connection refresh")
+
+// reconnectDelay returns the backoff before reconnect attempt n (0-based):
+// exponential, capped, with jitter so restarts don't reconnect in lockstep
+func reconnectDelay(attempt int, jitter float64) time.Duration {
+	d := reconnectMaxDelay
+	if attempt < 16 {
+		if exp := reconnectBaseDelay << attempt; exp < reconnectMaxDelay {
+			d = exp
+		}
+	}
+	// Between half and the full delay
+	return d/2 + time.Duration(jitter*float64(d/2))
+}
+
+// activityConn records when bytes last arrived, including the ping/pong
+// control frames the websocket package answers without surfacing them
+type activityConn struct {
+	net.Conn
+	lastRead atomic.Int64
+}
+
+func (c *activityConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.lastRead.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+// idleFor returns how long nothing was received
+func (c *activityConn) idleFor() time.Duration {
+	return time.Since(time.Unix(0, c.lastRead.Load()))
+}
+
+// dialSocketMode opens the WebSocket over an activityConn
+func dialSocketMode(ctx context.Context, wsURL string) (*websocket.Conn, *activityConn, error) {
+	wsConfig, err := websocket.NewConfig(wsURL, "https://slack.com")
+	if err != nil {
+		return nil, nil, err
+	}
+	host := wsConfig.Location.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "443")
+	}
+
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 15 * time.Second}}
+	raw, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn := &activityConn{Conn: raw}
+	conn.lastRead.Store(time.Now().UnixNano())
+
+	raw.SetDeadline(time.Now().Add(15 * time.Second))
+	ws, err := websocket.NewClient(wsConfig, conn)
+	if err != nil {
+		raw.Close()
+		return nil, nil, err
+	}
+	raw.SetDeadline(time.Time{})
+	return ws, conn, nil
+}
+
+// keepSocketAlive pings Slack and closes the link once it goes quiet, so a
+// half-open connection turns into a reconnect instead of a silent hang
+func keepSocketAlive(ws *websocket.Conn, conn *activityConn, wsMutex *sync.Mutex, done <-chan struct{}) {
+	ticker := time.NewTicker(socketPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		if idle := conn.idleFor(); idle > socketIdleTimeout {
+			logf("Socket Mode: nothing received for %s, reconnecting", formatDuration(idle))
+			ws.Close()
+			return
+		}
+
+		wsMutex.Lock()
+		ws.PayloadType = websocket.PingFrame
+		_, err := ws.Write([]byte("ccsa"))
+		ws.PayloadType = websocket.TextFrame
+		wsMutex.Unlock()
+		if err != nil {
+			logf("Socket Mode: ping failed: %v", err)
+			ws.Close()
+			return
+		}
+	}
+}
+
+// socketOutage tracks a Socket Mode outage and tells busy channels about long ones
+type socketOutage struct {
+	mu      sync.Mutex
+	since   time.Time
+	notices map[string]string // channel -> notice ts
+}
+
+var connectionOutage = &socketOutage{}
+
+// Down records a failed connection attempt; after outageNotifyAfter, channels
+// with a run in flight are told (the Web API often still works)
+func (o *socketOutage) Down(config *Config, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.since.IsZero() {
+		o.since = time.Now()
+	}
+	if o.notices != nil || time.Since(o.since) < outageNotifyAfter {
+		return
+	}
+	o.notices = make(map[string]string)
+	logf("Socket Mode down for %s: %v", formatDuration(time.Since(o.since)), err)
+	for _, channelID := range messageQueue.BusyChannels() {
+		ts, err := sendMessage(config, channelID, ":electric_plug: Lost the Slack connection - reconnecting. New messages won't be seen until it's back.")
+		if err == nil {
+			o.notices[channelID] = ts
+		}
+	}
+}
+
+// Active reports whether the link has been down since the last hello
+func (o *socketOutage) Active() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return !o.since.IsZero()
+}
+
+// Up ends the outage and updates any notices that were posted
+func (o *socketOutage) Up(config *Config) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.since.IsZero() {
+		return
+	}
+	downtime := time.Since(o.since)
+	if downtime >= outageNotifyAfter {
+		logf("Socket Mode reconnected after %s", formatDuration(downtime))
+	}
+	for channelID, ts := range o.notices {
+		updateMessage(config, channelID, ts, fmt.Sprintf(":electric_plug: Reconnected after %s. Resend anything you sent meanwhile that didn't get a :eyes:.", formatDuration(downtime)))
+	}
+	o.since = time.Time{}
+	o.notices = nil
+}
+
+// runSocketMode keeps a Socket Mode connection up until ctx is cancelled
+func runSocketMode(ctx context.Context, cfgMgr *ConfigManager) {
+	attempt := 0
+	for ctx.Err() == nil {
+		err := connectSocketMode(ctx, cfgMgr)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errSocketRefresh) {
+			logf("Socket Mode: refreshing connection")
+			attempt = 0
+			continue
+		}
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+
+		// A link that came up (hello) since the last failure resets the backoff
+		if !connectionOutage.Active() {
+			attempt = 0
+		}
+		connectionOutage.Down(cfgMgr.Get(), err)
+
+		delay := reconnectDelay(attempt, rand.Float64())
+		attempt++
+		logf("Socket Mode error: %v (reconnecting in %v, attempt %d)", err, delay.Round(100*time.Millisecond), attempt)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (slackChat) Listen(ctx context.Context, cfgMgr *ConfigManager) {
+	runSocketMode(ctx, cfgMgr)
+}
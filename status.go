@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChannelStatus is what !status reports about a session channel
+type ChannelStatus struct {
+	SessionName  string
+	WorkDir      string
+	Host         string // agent running the session ("" = this machine)
+	Sandboxed    bool
+	Branch       string
+	Running      bool
+	QueueLength  int
+	SessionID    string
+	ForkPending  bool // first run will fork another session
+	Verbose      bool
+	LastActivity time.Time
+	Runs         int
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64
+	Credentials  []CredentialState
+	APICalls     int // Slack API calls in the error window
+	APIFailures  int
+	APIDegraded  bool
+}
+
+// collectChannelStatus gathers a channel's state from the config, the run
+// registries and its timeline
+func collectChannelStatus(config *Config, channelID string) ChannelStatus {
+	st := ChannelStatus{
+		SessionName: getSessionByChannel(config, channelID),
+		Host:        sessionHost(config, channelID),
+		Sandboxed:   sessionSandboxed(config, channelID),
+		Verbose:     IsVerbose(channelID),
+	}
+	if st.SessionName != "" {
+		st.WorkDir = sessionWorkDir(config, st.SessionName)
+	}
+	_, st.Running = activeProcesses.Load(channelID)
+	if messageQueue != nil {
+		st.QueueLength = messageQueue.QueueLength(channelID)
+	}
+	if sid, ok := getClaudeSessionID(channelID); ok {
+		st.SessionID = sid
+	} else if sid, ok := forkStore.Get(channelID); ok {
+		st.SessionID, st.ForkPending = sid, true
+	}
+
+	st.Branch = config.ChannelBranches[channelID]
+	if st.Branch == "" && st.Host == "" && st.WorkDir != "" {
+		st.Branch, _ = gitOutput(st.WorkDir, "rev-parse", "--abbrev-ref", "HEAD")
+	}
+
+	for _, ev := range timeline.Events(channelID, time.Now().Add(-timelineRetention)) {
+		if ev.ChannelID == "" {
+			continue
+		}
+		st.LastActivity = ev.At
+		if ev.Kind == timelineRun {
+			st.Runs++
+			st.InputTokens += ev.InputTokens
+			st.OutputTokens += ev.OutputTokens
+			st.CostUSD += ev.CostUSD
+		}
+	}
+	st.Credentials = credentialStates(config)
+	now := time.Now()
+	st.APICalls, st.APIFailures, _ = apiErrors.Snapshot(now)
+	st.APIDegraded = apiErrors.Degraded(now)
+	return st
+}
+
+// formatStatusFields renders the status as label/value pairs, in display order
+func formatStatusFields(st ChannelStatus, now time.Time) [][2]string {
+	run := "Idle"
+	if st.Running {
+		run = ":hourglass_flowing_sand: Running"
+	}
+	queue := "Empty"
+	if st.QueueLength > 0 {
+		queue = fmt.Sprintf("%d waiting", st.QueueLength)
+	}
+
+	where := "`" + st.WorkDir + "`"
+	switch {
+	case st.Host != "":
+		where += fmt.Sprintf(" on agent `%s`", st.Host)
+	case st.Sandboxed:
+		where += " (Docker sandbox)"
+	}
+	branch := "-"
+	if st.Branch != "" {
+		branch = "`" + st.Branch + "`"
+	}
+
+	session := "None (next message starts one)"
+	if st.ForkPending {
+		session = fmt.Sprintf("Forks `%s` on the next message", shortID(st.SessionID))
+	} else if st.SessionID != "" {
+		session = "`" + st.SessionID + "`"
+	}
+
+	activity := "None recorded"
+	if !st.LastActivity.IsZero() {
+		activity = fmt.Sprintf("%s ago (%s)", formatDuration(now.Sub(st.LastActivity)), st.LastActivity.Local().Format("Jan 2 15:04"))
+	}
+	usage := fmt.Sprintf("%d run(s), %s in / %s out", st.Runs, formatTokenCount(st.InputTokens), formatTokenCount(st.OutputTokens))
+	if st.CostUSD > 0 {
+		usage += fmt.Sprintf(", $%.2f", st.CostUSD)
+	}
+	mode := "Verbose"
+	if !st.Verbose {
+		mode = "Quiet"
+	}
+
+	api := fmt.Sprintf("%d/%d call(s) failed (last %d min)", st.APIFailures, st.APICalls, int(apiErrorWindow.Minutes()))
+	if st.APIDegraded {
+		api += " - :warning: degraded"
+	}
+
+	fields := [][2]string{
+		{"Run", run},
+		{"Queue", queue},
+		{"Claude session", session},
+		{"Last activity", activity},
+		{"Usage (30 days)", usage},
+		{"Output", mode},
+		{"Workdir", where},
+		{"Branch", branch},
+		{"Slack API", api},
+	}
+	if len(st.Credentials) > 0 {
+		fields = append(fields, [2]string{"Credentials", formatCredentialExpiry(st.Credentials, now)})
+	}
+	return fields
+}
+
+// formatCredentialExpiry summarizes credential expiry on one line for !status
+func formatCredentialExpiry(states []CredentialState, now time.Time) string {
+	var parts []string
+	for _, c := range states {
+		switch {
+		case !c.Issued:
+			parts = append(parts, fmt.Sprintf("`%s` not issued", c.Name))
+		case c.Err != nil:
+			parts = append(parts, fmt.Sprintf("`%s` :x: failed", c.Name))
+		case now.After(c.ExpiresAt):
+			parts = append(parts, fmt.Sprintf("`%s` expired", c.Name))
+		default:
+			parts = append(parts, fmt.Sprintf("`%s` expires in %s", c.Name, formatDuration(c.ExpiresAt.Sub(now))))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatTokenCount shortens a token count (12345 -> 12.3k)
+func formatTokenCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// postChannelStatus posts !status as a Block Kit message (plain text on
+// other chat backends)
+func postChannelStatus(config *Config, channelID, threadTS string) error {
+	st := collectChannelStatus(config, channelID)
+	if st.SessionName == "" {
+		msg := ":x: Not in a session channel"
+		if threadTS != "" {
+			return sendMessageToThread(config, channelID, threadTS, msg)
+		}
+		_, err := sendMessage(config, channelID, msg)
+		return err
+	}
+
+	fields := formatStatusFields(st, time.Now())
+	var lines []string
+	var blockFields []map[string]string
+	for _, f := range fields {
+		lines = append(lines, fmt.Sprintf("*%s:* %s", f[0], f[1]))
+		blockFields = append(blockFields, map[string]string{"type": "mrkdwn", "text": fmt.Sprintf("*%s*\n%s", f[0], f[1])})
+	}
+	title := fmt.Sprintf("Status of %s", st.SessionName)
+
+	// Other chat backends have no Block Kit: the same fields as plain text
+	if !onSlack(channelID) {
+		text := "*" + title + "*\n" + strings.Join(lines, "\n")
+		if threadTS != "" {
+			return sendMessageToThread(config, channelID, threadTS, text)
+		}
+		_, err := sendMessage(config, channelID, text)
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"channel": channelID,
+		"text":    title + "\n" + strings.Join(lines, "\n"),
+		"blocks": []map[string]interface{}{
+			{
+				"type": "header",
+				"text": map[string]string{"type": "plain_text", "text": title},
+			},
+			{
+				"type":   "section",
+				"fields": blockFields,
+			},
+		},
+	}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
+	}
+
+	result, err := slackAPIJSON(config, "chat.postMessage", payload)
+	if err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("slack error: %s", result.Error)
+	}
+	return nil
+}
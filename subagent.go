@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	subagentShownTools     = 5               // latest tool calls listed under a subagent
+	subagentUpdateInterval = 2 * time.Second // between updates of its message
+	subagentResultPreview  = 600             // runes of its final report shown
+)
+
+// isSubagentTool reports whether a tool call spawns a subagent
+func isSubagentTool(name string) bool {
+	return name == "Task" || name == "Agent"
+}
+
+// subagentView is the single Slack message following one subagent: its tool
+// activity collapsed to the latest calls, then its final report
+type subagentView struct {
+	ts          string
+	description string
+	agentType   string
+	started     time.Time
+	tools       []string // latest tool calls, formatted
+	toolCount   int
+	lastText    string // latest thing it said
+	lastUpdate  time.Time
+}
+
+// renderSubagent renders a subagent's message; result is set once it finished
+func renderSubagent(v *subagentView, finished bool, result string, isError bool, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ":robot_face: *subagent: %s*", v.description)
+	if v.agentType != "" {
+		fmt.Fprintf(&b, " _(%s)_", v.agentType)
+	}
+	switch {
+	case !finished:
+		fmt.Fprintf(&b, " - running %s", formatDuration(now.Sub(v.started)))
+	case isError:
+		fmt.Fprintf(&b, " - :x: failed after %s", formatDuration(now.Sub(v.started)))
+	default:
+		fmt.Fprintf(&b, " - :white_check_mark: done in %s", formatDuration(now.Sub(v.started)))
+	}
+	if v.toolCount > 0 {
+		fmt.Fprintf(&b, ", %d tool call(s)", v.toolCount)
+	}
+
+	if !finished {
+		if hidden := v.toolCount - len(v.tools); hidden > 0 {
+			fmt.Fprintf(&b, "\n>  _... %d earlier_", hidden)
+		}
+		for _, t := range v.tools {
+			b.WriteString("\n>  " + t)
+		}
+		if v.lastText != "" {
+			b.WriteString("\n>  _" + truncateRunes(strings.Join(strings.Fields(v.lastText), " "), 200) + "_")
+		}
+		return b.String()
+	}
+	if result = strings.TrimSpace(result); result != "" {
+		b.WriteString("\n> " + strings.ReplaceAll(convertBold(truncateRunes(result, subagentResultPreview)), "\n", "\n> "))
+	}
+	return b.String()
+}
+
+// StartSubagent posts the message following a subagent spawned by toolID
+func (m *SlackThreadManager) StartSubagent(toolID string, input json.RawMessage) {
+	var in struct {
+		Description  string `json:"description"`
+		SubagentType string `json:"subagent_type"`
+	}
+	json.Unmarshal(input, &in)
+	if in.Description == "" {
+		in.Description = "task"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordActivityLocked()
+	setLiveActivity(m.channelID, "Subagent "+in.Description)
+
+	// Keep the thread in order: what came before goes first
+	m.flushToolBatchLocked()
+	if m.currentAssistantContent.Len() > 0 {
+		m.flushAssistantText(true)
+		m.currentAssistantTS = ""
+		m.currentAssistantContent.Reset()
+	}
+
+	now := time.Now()
+	v := &subagentView{description: in.Description, agentType: in.SubagentType, started: now, lastUpdate: now}
+	v.ts = m.postGetTS(renderSubagent(v, false, "", false, now))
+	if m.subagents == nil {
+		m.subagents = make(map[string]*subagentView)
+	}
+	m.subagents[toolID] = v
+}
+
+// SubagentEvent folds an event of the subagent spawned by parentID into its
+// message instead of the main thread
+func (m *SlackThreadManager) SubagentEvent(parentID string, event *StreamEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := m.subagents[parentID]
+	if v == nil || event.Message == nil {
+		return
+	}
+	m.recordActivityLocked()
+	for _, content := range event.Message.Content {
+		switch content.Type {
+		case "text":
+			if strings.TrimSpace(content.Text) != "" {
+				v.lastText = content.Text
+			}
+		case "tool_use":
+			v.toolCount++
+			summary, _, _ := strings.Cut(formatToolInput(content.Name, content.Input), "\n") // file of a diff
+			line := strings.TrimSpace(getToolEmoji(content.Name) + " " + summary)
+			v.tools = append(v.tools, truncateRunes(strings.Join(strings.Fields(line), " "), 150))
+			if len(v.tools) > subagentShownTools {
+				v.tools = v.tools[len(v.tools)-subagentShownTools:]
+			}
+			setLiveActivity(m.channelID, fmt.Sprintf("Subagent %s: %s", v.description, content.Name))
+		}
+	}
+
+	interval := subagentUpdateInterval
+	if apiDegraded() {
+		interval = degradedStreamInterval
+	}
+	if now := time.Now(); v.ts != "" && now.Sub(v.lastUpdate) >= interval {
+		v.lastUpdate = now
+		updateMessage(m.config, m.channelID, v.ts, renderSubagent(v, false, "", false, now))
+	}
+}
+
+// FinishSubagent shows a subagent's report once its tool call returned; false
+// if toolUseID isn't a subagent
+func (m *SlackThreadManager) FinishSubagent(toolUseID string, result json.RawMessage, isError bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := m.subagents[toolUseID]
+	if v == nil {
+		return false
+	}
+	delete(m.subagents, toolUseID)
+	m.recordActivityLocked()
+	text := renderSubagent(v, true, toolResultText(result), isError, time.Now())
+	if v.ts != "" {
+		updateMessage(m.config, m.channelID, v.ts, text)
+	} else {
+		m.post(text)
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// summaryModel keeps summaries cheap: they only condense the conversation
+const summaryModel = "haiku"
+
+// summaryPrompt asks for the pinned summary, in Slack mrkdwn
+const summaryPrompt = `Summarize this session so far for a pinned Slack message that helps someone pick the work back up.
+Use exactly these sections, as Slack mrkdwn (*bold* headings, • bullets, no tables, no ** or # markdown):
+*Goal* - one or two sentences
+*Decisions* - choices made and why
+*Files touched* - paths changed, with a few words each
+*Open TODOs* - what is left or was deferred
+At most 6 bullets per section; write "none" for an empty one. Output only the summary. Do not use tools or change anything.`
+
+// channelSummary is the pinned summary message of a channel
+type channelSummary struct {
+	TS        string    `json:"ts,omitempty"`         // pinned message, "" until first posted
+	Runs      int       `json:"runs"`                 // successful runs since the last summary
+	UpdatedAt time.Time `json:"updated_at,omitempty"` // last regeneration
+}
+
+// SummaryStore tracks each channel's pinned summary in ~/.ccsa/summaries.json
+type SummaryStore struct {
+	mu       sync.Mutex
+	path     string
+	channels map[string]*channelSummary
+	running  map[string]bool // summaries being generated
+}
+
+var summaryStore = &SummaryStore{path: getSummaryStorePath()}
+
+// getSummaryStorePath returns the path to the summaries file
+func getSummaryStorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "summaries.json")
+}
+
+func (s *SummaryStore) loadLocked() {
+	if s.channels != nil {
+		return
+	}
+	s.channels = make(map[string]*channelSummary)
+	s.running = make(map[string]bool)
+	if data, err := os.ReadFile(s.path); err == nil {
+		json.Unmarshal(data, &s.channels)
+	}
+}
+
+func (s *SummaryStore) saveLocked() {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return
+	}
+	data, err := json.Marshal(s.channels)
+	if err != nil {
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, s.path)
+}
+
+func (s *SummaryStore) entryLocked(channelID string) *channelSummary {
+	s.loadLocked()
+	entry := s.channels[channelID]
+	if entry == nil {
+		entry = &channelSummary{}
+		s.channels[channelID] = entry
+	}
+	return entry
+}
+
+// CountRun records a successful run and reports whether the summary is due
+// (every runs, 0 = never)
+func (s *SummaryStore) CountRun(channelID string, every int) bool {
+	if every <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entryLocked(channelID)
+	entry.Runs++
+	s.saveLocked()
+	return entry.Runs >= every && !s.running[channelID]
+}
+
+// Begin marks a summary as being generated; false if one already is
+func (s *SummaryStore) Begin(channelID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	if s.running[channelID] {
+		return false
+	}
+	s.running[channelID] = true
+	return true
+}
+
+// End clears the running mark
+func (s *SummaryStore) End(channelID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, channelID)
+}
+
+// Get returns the channel's pinned message and the runs since it was updated
+func (s *SummaryStore) Get(channelID string) (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	if entry := s.channels[channelID]; entry != nil {
+		return entry.TS, entry.Runs
+	}
+	return "", 0
+}
+
+// Updated records a regenerated summary posted as ts
+func (s *SummaryStore) Updated(channelID, ts string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entryLocked(channelID)
+	entry.TS, entry.Runs, entry.UpdatedAt = ts, 0, at
+	s.saveLocked()
+}
+
+// Remove forgets a channel (its session was killed)
+func (s *SummaryStore) Remove(channelID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	if _, ok := s.channels[channelID]; ok {
+		delete(s.channels, channelID)
+		s.saveLocked()
+	}
+}
+
+// generateSummary asks a cheap model to summarize the channel's conversation.
+// It works on a fork of the session, so the conversation itself is untouched.
+func generateSummary(config *Config, sessionID, workDir string) (string, error) {
+	if claudePath == "" {
+		return "", fmt.Errorf("claude binary not found")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, claudePath,
+		"-p", summaryPrompt,
+		"--output-format", "json",
+		"--resume", sessionID,
+		"--fork-session",
+		"--model", summaryModel,
+	)
+	cmd.Dir = workDir
+	cmd.Env = runEnv(config)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("claude error: %w - %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp ClaudeResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return "", fmt.Errorf("JSON parse error: %w", err)
+	}
+	if resp.IsError || strings.TrimSpace(resp.Result) == "" {
+		return "", fmt.Errorf("no summary: %s", resp.Result)
+	}
+	return convertBold(strings.TrimSpace(resp.Result)), nil
+}
+
+// formatSummaryMessage is the text of the pinned summary
+func formatSummaryMessage(sessionName, summary string, at time.Time) string {
+	return fmt.Sprintf(":bookmark_tabs: *Session summary - `%s`* (updated %s, `!summarize` to refresh)\n\n%s",
+		sessionName, at.Local().Format("Jan 2 15:04"), summary)
+}
+
+// summarizeChannel regenerates the channel's summary and updates its pinned
+// message, posting and pinning a new one the first time (or if it was deleted)
+func summarizeChannel(config *Config, channelID string) error {
+	sessionName := getSessionByChannel(config, channelID)
+	if sessionName == "" {
+		return fmt.Errorf("not in a session channel")
+	}
+	if host := sessionHost(config, channelID); host != "" {
+		return fmt.Errorf("this session runs on agent `%s`: its conversation isn't stored here", host)
+	}
+	if config.SandboxSessions[sessionName] {
+		return fmt.Errorf("sandboxed sessions keep their conversation inside the container")
+	}
+	sessionID, ok := getClaudeSessionID(channelID)
+	if !ok {
+		return fmt.Errorf("no conversation to summarize yet")
+	}
+	if !summaryStore.Begin(channelID) {
+		return fmt.Errorf("a summary is already being generated")
+	}
+	defer summaryStore.End(channelID)
+
+	summary, err := generateSummary(config, sessionID, sessionWorkDir(config, sessionName))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	text := formatSummaryMessage(sessionName, summary, now)
+
+	if ts, _ := summaryStore.Get(channelID); ts != "" {
+		if err := updateMessage(config, channelID, ts, text); err == nil {
+			summaryStore.Updated(channelID, ts, now)
+			logf("Updated summary of %s", sessionName)
+			return nil
+		}
+		logf("Summary message of %s is gone, posting a new one", sessionName)
+	}
+	ts, err := sendMessage(config, channelID, text)
+	if err != nil {
+		return err
+	}
+	if err := pinMessage(config, channelID, ts); err != nil {
+		logf("Failed to pin summary: %v", err)
+	}
+	summaryStore.Updated(channelID, ts, now)
+	logf("Posted summary of %s", sessionName)
+	return nil
+}
+
+// maybeAutoSummarize regenerates the summary every summary_every_runs successful runs
+func maybeAutoSummarize(config *Config, channelID string) {
+	if config == nil || !summaryStore.CountRun(channelID, config.SummaryEveryRuns) {
+		return
+	}
+	workerPool.Submit(func() {
+		if err := summarizeChannel(config, channelID); err != nil {
+			logf("Auto-summary of %s failed: %v", channelID, err)
+		}
+	})
+}
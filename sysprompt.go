@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const syspromptUsage = "Usage: `!sysprompt [show | set <instructions> | clear]` - extra system prompt instructions for this channel (replacing `system_prompt` from the config)"
+
+// maxSystemPromptLen keeps channel instructions to a sensible size for a command-line argument
+const maxSystemPromptLen = 8000
+
+// channelSystemPrompt returns the instructions added for a channel and where they come from
+func channelSystemPrompt(config *Config, channelID string) (string, string) {
+	if config == nil {
+		return "", ""
+	}
+	if prompt := config.ChannelSystemPrompts[channelID]; prompt != "" {
+		return prompt, "this channel"
+	}
+	if config.SystemPrompt != "" {
+		return config.SystemPrompt, "`system_prompt` in the config"
+	}
+	return "", ""
+}
+
+// appendSystemPrompt is the --append-system-prompt of a channel's runs: the
+// built-in remote-work rules, then the channel's (or the config's) instructions
+func appendSystemPrompt(config *Config, channelID string) string {
+	extra, _ := channelSystemPrompt(config, channelID)
+	if extra == "" {
+		return SlackSystemPromptAppend
+	}
+	return SlackSystemPromptAppend + "\nPROJECT INSTRUCTIONS:\n" + extra + "\n"
+}
+
+// handleSyspromptCommand answers !sysprompt in a session channel
+func handleSyspromptCommand(cfgMgr *ConfigManager, config *Config, channelID, arg string) string {
+	arg = strings.TrimSpace(arg)
+	sub, rest := arg, ""
+	if i := strings.IndexAny(arg, " \n"); i >= 0 {
+		sub, rest = arg[:i], arg[i+1:]
+	}
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case sub == "" || sub == "show":
+		prompt, from := channelSystemPrompt(config, channelID)
+		if prompt == "" {
+			return ":page_facing_up: No extra system prompt here - only the built-in remote-work rules. `!sysprompt set <instructions>` to add some."
+		}
+		return fmt.Sprintf(":page_facing_up: System prompt instructions (from %s):\n```\n%s\n```", from, prompt)
+	case sub == "set" && rest != "":
+		if len(rest) > maxSystemPromptLen {
+			return fmt.Sprintf(":x: Instructions are limited to %d characters", maxSystemPromptLen)
+		}
+		if err := cfgMgr.SetChannelSystemPrompt(channelID, rest); err != nil {
+			return fmt.Sprintf(":x: Could not save: %v", err)
+		}
+		persistentPool.Retire(channelID)
+		ptyPool.Retire(channelID)
+		return ":page_facing_up: System prompt instructions saved - they apply from the next message"
+	case sub == "clear" && rest == "":
+		if err := cfgMgr.SetChannelSystemPrompt(channelID, ""); err != nil {
+			return fmt.Sprintf(":x: Could not save: %v", err)
+		}
+		persistentPool.Retire(channelID)
+		ptyPool.Retire(channelID)
+		if config.SystemPrompt != "" {
+			return ":page_facing_up: Channel instructions cleared - `system_prompt` from the config applies again"
+		}
+		return ":page_facing_up: Channel instructions cleared"
+	}
+	return syspromptUsage
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const tagUsage = "Usage: `!tag [add <tag>... | rm [<tag>...]]` - tag this session (`rm` alone removes every tag); `!list --tag <tag>` lists the sessions with a tag"
+
+// tagRe is what a tag may look like once normalized
+var tagRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// normalizeTag lowercases a tag and drops a leading #
+func normalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	return tag, tagRe.MatchString(tag)
+}
+
+// applyTagCommand returns a session's tags after "add <tags>" or "rm [<tags>]"
+func applyTagCommand(current []string, args []string) ([]string, error) {
+	if len(args) == 0 || (args[0] != "add" && args[0] != "rm") || (args[0] == "add" && len(args) == 1) {
+		return nil, fmt.Errorf("%s", tagUsage)
+	}
+	if args[0] == "rm" && len(args) == 1 {
+		return nil, nil
+	}
+	tags := slices.Clone(current)
+	for _, arg := range args[1:] {
+		tag, ok := normalizeTag(arg)
+		if !ok {
+			return nil, fmt.Errorf("`%s` is not a valid tag (letters, digits, `-`, `_` and `.`)", arg)
+		}
+		i := slices.Index(tags, tag)
+		switch {
+		case args[0] == "add" && i < 0:
+			tags = append(tags, tag)
+		case args[0] == "rm" && i >= 0:
+			tags = slices.Delete(tags, i, i+1)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// formatTags renders tags for Slack
+func formatTags(tags []string) string {
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = "`#" + tag + "`"
+	}
+	return strings.Join(parts, " ")
+}
+
+// listFilter is what "!list" options ask for
+type listFilter struct {
+	tag  string
+	idle time.Duration
+}
+
+// parseListArgs reads "!list [--tag <tag>] [--idle <duration>]"
+func parseListArgs(arg string) (listFilter, bool) {
+	var filter listFilter
+	fields := strings.Fields(arg)
+	for i := 0; i < len(fields); i += 2 {
+		if i+1 >= len(fields) {
+			return listFilter{}, false
+		}
+		var ok bool
+		switch fields[i] {
+		case "--tag":
+			filter.tag, ok = normalizeTag(fields[i+1])
+		case "--idle":
+			filter.idle, ok = parseIdleDuration(fields[i+1])
+		}
+		if !ok {
+			return listFilter{}, false
+		}
+	}
+	return filter, true
+}
+
+// filterSessionsByTag keeps the sessions tagged tag
+func filterSessionsByTag(sessions map[string]string, tags map[string][]string, tag string) map[string]string {
+	tagged := make(map[string]string)
+	for name, channelID := range sessions {
+		if slices.Contains(tags[name], tag) {
+			tagged[name] = channelID
+		}
+	}
+	return tagged
+}
+
+// formatSessionList lists sessions by name with their tags, only those
+// tagged filter when it is set
+func formatSessionList(sessions map[string]string, tags map[string][]string, filter string) string {
+	names := make([]string, 0, len(sessions))
+	for name := range sessions {
+		if filter == "" || slices.Contains(tags[name], filter) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		if filter != "" {
+			return fmt.Sprintf("No sessions tagged `#%s`", filter)
+		}
+		return "No active sessions"
+	}
+	sort.Strings(names)
+
+	header := "*Active Sessions:*"
+	if filter != "" {
+		header = fmt.Sprintf("*Sessions tagged `#%s`:*", filter)
+	}
+	lines := []string{header}
+	for _, name := range names {
+		line := fmt.Sprintf("• `%s` → <#%s>", name, sessions[name])
+		if len(tags[name]) > 0 {
+			line += "  " + formatTags(tags[name])
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	telegramAPIBase = "https://api.telegram.org/bot"
+	// telegramPollTimeout is how long a getUpdates call waits for updates
+	telegramPollTimeout = 50 * time.Second
+	// telegramChannelPrefix marks Telegram chats among channel IDs (Telegram
+	// chat IDs are integers, negative for groups)
+	telegramChannelPrefix = "tg"
+)
+
+// telegramPollClient outlives a long-polling getUpdates call
+var telegramPollClient = &http.Client{Timeout: telegramPollTimeout + 20*time.Second}
+
+// TelegramConfig connects the bot to Telegram, alone or next to Slack
+type TelegramConfig struct {
+	// BotToken is the token @BotFather gave the bot
+	BotToken string `json:"bot_token"`
+	// UserIDs are the Telegram users allowed to talk to Claude
+	UserIDs []int64 `json:"user_ids,omitempty"`
+}
+
+// isTelegramID reports whether a channel ID is a Telegram chat ("tg<chat id>")
+func isTelegramID(id string) bool {
+	rest, ok := strings.CutPrefix(id, telegramChannelPrefix)
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseInt(rest, 10, 64)
+	return err == nil
+}
+
+// telegramChannel returns the channel ID of a Telegram chat
+func telegramChannel(chatID int64) string {
+	return telegramChannelPrefix + strconv.FormatInt(chatID, 10)
+}
+
+// telegramChatID returns the Telegram chat of a channel ID
+func telegramChatID(channelID string) int64 {
+	id, _ := strconv.ParseInt(strings.TrimPrefix(channelID, telegramChannelPrefix), 10, 64)
+	return id
+}
+
+// telegramMessageID parses a message ID ("ts")
+func telegramMessageID(ts string) int64 {
+	id, _ := strconv.ParseInt(ts, 10, 64)
+	return id
+}
+
+// slackToTelegram converts Slack mrkdwn to Telegram HTML
+func slackToTelegram(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range mrkdwnCodeRe.FindAllStringIndex(text, -1) {
+		b.WriteString(telegramProse(text[last:loc[0]]))
+		code := unescapeMrkdwn(text[loc[0]:loc[1]])
+		if inner, ok := strings.CutPrefix(code, "```"); ok {
+			inner = strings.Trim(strings.TrimSuffix(inner, "```"), "\n")
+			b.WriteString("<pre>" + html.EscapeString(inner) + "</pre>")
+		} else {
+			b.WriteString("<code>" + html.EscapeString(strings.Trim(code, "`")) + "</code>")
+		}
+		last = loc[1]
+	}
+	b.WriteString(telegramProse(text[last:]))
+	return b.String()
+}
+
+// telegramProse converts mrkdwn outside code spans
+func telegramProse(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range mrkdwnLinkRe.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(telegramFormat(s[last:m[0]]))
+		link := unescapeMrkdwn(s[m[2]:m[3]])
+		label := link
+		if m[4] >= 0 {
+			label = unescapeMrkdwn(s[m[4]:m[5]])
+		}
+		b.WriteString(`<a href="` + html.EscapeString(link) + `">` + html.EscapeString(label) + "</a>")
+		last = m[1]
+	}
+	b.WriteString(telegramFormat(s[last:]))
+	return b.String()
+}
+
+// telegramFormat escapes plain text and converts bold, strike and emoji
+func telegramFormat(s string) string {
+	s = html.EscapeString(unescapeMrkdwn(replaceShortcodes(s)))
+	s = mrkdwnBoldRe.ReplaceAllString(s, "<b>$1</b>")
+	return mrkdwnStrikeRe.ReplaceAllString(s, "<s>$1</s>")
+}
+
+// telegramAPIError is a failed Bot API call
+type telegramAPIError struct {
+	Code        int
+	Description string
+}
+
+func (e *telegramAPIError) Error() string {
+	return fmt.Sprintf("telegram error %d: %s", e.Code, e.Description)
+}
+
+// telegramResponse is the envelope of every Bot API answer
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// telegramRequest performs one Bot API call, waiting out rate limits
+func telegramRequest(config *Config, client *http.Client, method, contentType string, body []byte, out interface{}) error {
+	if config.Telegram == nil || config.Telegram.BotToken == "" {
+		return errors.New("telegram is not configured")
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("POST", telegramAPIBase+config.Telegram.BotToken+"/"+method, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			// The URL holds the token: keep it out of logs
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return fmt.Errorf("telegram %s: %w", method, err)
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+
+		var result telegramResponse
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("telegram %s: %s", method, resp.Status)
+		}
+		if result.ErrorCode == http.StatusTooManyRequests && attempt < 3 {
+			time.Sleep(time.Duration(max(result.Parameters.RetryAfter, 1)) * time.Second)
+			continue
+		}
+		if !result.OK {
+			return &telegramAPIError{Code: result.ErrorCode, Description: result.Description}
+		}
+		if out != nil {
+			return json.Unmarshal(result.Result, out)
+		}
+		return nil
+	}
+}
+
+// telegramJSON performs a Bot API call with a JSON body
+func telegramJSON(config *Config, method string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return telegramRequest(config, httpClient, method, "application/json", body, out)
+}
+
+// telegramErrorContains reports whether err is a Bot API error mentioning text
+func telegramErrorContains(err error, text string) bool {
+	var apiErr *telegramAPIError
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.Description, text)
+}
+
+// sendTelegramHTML sends or edits a message as HTML, falling back to plain
+// text when Telegram can't parse the conversion
+func sendTelegramHTML(config *Config, method, text string, payload map[string]interface{}, out interface{}) error {
+	payload["text"] = truncateRunes(slackToTelegram(text), 4096)
+	payload["parse_mode"] = "HTML"
+	err := telegramJSON(config, method, payload, out)
+	if telegramErrorContains(err, "can't parse entities") {
+		delete(payload, "parse_mode")
+		payload["text"] = truncateRunes(unescapeMrkdwn(replaceShortcodes(text)), 4096)
+		err = telegramJSON(config, method, payload, out)
+	}
+	return err
+}
+
+// telegramMessage is the part of a Telegram message the bridge reads
+type telegramMessage struct {
+	MessageID int64 `json:"message_id"`
+	From      *struct {
+		ID    int64 `json:"id"`
+		IsBot bool  `json:"is_bot"`
+	} `json:"from"`
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text           string           `json:"text"`
+	Caption        string           `json:"caption"`
+	ReplyToMessage *telegramMessage `json:"reply_to_message"`
+}
+
+// telegramReplyTo is the reply_parameters of a message posted in a thread
+func telegramReplyTo(threadTS string) map[string]interface{} {
+	return map[string]interface{}{"message_id": telegramMessageID(threadTS), "allow_sending_without_reply": true}
+}
+
+// telegramKeyboard lays buttons out in rows of two
+func telegramKeyboard(buttons []Element, blockID string) map[string]interface{} {
+	var rows [][]map[string]string
+	var row []map[string]string
+	for _, btn := range buttons {
+		if btn.Type != "button" || btn.Text == nil {
+			continue // checkboxes have no Telegram counterpart
+		}
+		row = append(row, map[string]string{
+			"text":          unescapeMrkdwn(replaceShortcodes(btn.Text.Text)),
+			"callback_data": rememberButton(btn, blockID),
+		})
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return map[string]interface{}{"inline_keyboard": rows}
+}
+
+// telegramReactions are the emoji bots may react with (one per message)
+var telegramReactions = map[string]string{
+	"eyes":             "👀",
+	"white_check_mark": "👌",
+	"x":                "👎",
+	"zap":              "⚡",
+	"+1":               "👍",
+	"pencil2":          "✍",
+}
+
+// telegramReacted remembers each message's reaction: a bot has only one, so
+// removing a reaction must not clear the one that replaced it
+var telegramReacted sync.Map
+
+// telegramChat is the Telegram ChatBackend (Bot API, long-polled updates).
+// Threads are reply chains (see threadRoot).
+type telegramChat struct{}
+
+func (telegramChat) Name() string { return "telegram" }
+
+func (telegramChat) MaxMessageLen() int { return 3500 }
+
+// postTelegram sends a message and records it in its reply chain
+func postTelegram(config *Config, channelID, threadTS, text string, keyboard map[string]interface{}) (string, error) {
+	payload := map[string]interface{}{
+		"chat_id":                  telegramChatID(channelID),
+		"disable_web_page_preview": true,
+	}
+	if threadTS != "" {
+		payload["reply_parameters"] = telegramReplyTo(threadTS)
+	}
+	if keyboard != nil {
+		payload["reply_markup"] = keyboard
+	}
+	var msg telegramMessage
+	if err := sendTelegramHTML(config, "sendMessage", text, payload, &msg); err != nil {
+		return "", err
+	}
+	ts := strconv.FormatInt(msg.MessageID, 10)
+	if threadTS != "" {
+		recordReply(channelID, ts, threadTS)
+	}
+	return ts, nil
+}
+
+func (telegramChat) PostMessage(config *Config, channelID, threadTS, text string) (string, error) {
+	return postTelegram(config, channelID, threadTS, text, nil)
+}
+
+// editTelegram replaces a message's text and keyboard (nil = none)
+func editTelegram(config *Config, channelID, ts, text string, keyboard map[string]interface{}) error {
+	payload := map[string]interface{}{
+		"chat_id":                  telegramChatID(channelID),
+		"message_id":               telegramMessageID(ts),
+		"disable_web_page_preview": true,
+	}
+	if keyboard != nil {
+		payload["reply_markup"] = keyboard
+	}
+	err := sendTelegramHTML(config, "editMessageText", text, payload, nil)
+	if telegramErrorContains(err, "message is not modified") {
+		return nil
+	}
+	return err
+}
+
+func (telegramChat) UpdateMessage(config *Config, channelID, ts, text string) error {
+	return editTelegram(config, channelID, ts, text, nil)
+}
+
+func (telegramChat) DeleteMessage(config *Config, channelID, ts string) error {
+	err := telegramJSON(config, "deleteMessage", map[string]interface{}{
+		"chat_id":    telegramChatID(channelID),
+		"message_id": telegramMessageID(ts),
+	}, nil)
+	if telegramErrorContains(err, "message to delete not found") {
+		return nil // already deleted
+	}
+	return err
+}
+
+// setTelegramReaction replaces a message's reaction ("" = none)
+func setTelegramReaction(config *Config, channelID, ts, emoji string) error {
+	reaction := []map[string]string{}
+	if emoji != "" {
+		reaction = append(reaction, map[string]string{"type": "emoji", "emoji": emoji})
+	}
+	err := telegramJSON(config, "setMessageReaction", map[string]interface{}{
+		"chat_id":    telegramChatID(channelID),
+		"message_id": telegramMessageID(ts),
+		"reaction":   reaction,
+	}, nil)
+	if err == nil {
+		telegramReacted.Store(channelID+":"+ts, emoji)
+	}
+	return err
+}
+
+func (telegramChat) AddReaction(config *Config, channelID, ts, emoji string) error {
+	unicode, ok := telegramReactions[emoji]
+	if !ok {
+		return nil
+	}
+	return setTelegramReaction(config, channelID, ts, unicode)
+}
+
+func (telegramChat) RemoveReaction(config *Config, channelID, ts, emoji string) error {
+	current, _ := telegramReacted.Load(channelID + ":" + ts)
+	if unicode, ok := telegramReactions[emoji]; !ok || current != unicode {
+		return nil
+	}
+	return setTelegramReaction(config, channelID, ts, "")
+}
+
+func (telegramChat) PostButtons(config *Config, channelID, threadTS, text string, buttons []Element, blockID string) (string, error) {
+	return postTelegram(config, channelID, threadTS, text, telegramKeyboard(buttons, blockID))
+}
+
+func (telegramChat) UpdateButtons(config *Config, channelID, ts, text string, buttons []Element, blockID string) error {
+	return editTelegram(config, channelID, ts, text, telegramKeyboard(buttons, blockID))
+}
+
+// uploadTelegram sends data as a document; Telegram files have no public URL,
+// so the returned URL is empty
+func uploadTelegram(config *Config, channelID, threadTS, filename string, data []byte, caption string) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("chat_id", strconv.FormatInt(telegramChatID(channelID), 10))
+	if caption != "" {
+		w.WriteField("caption", truncateRunes(unescapeMrkdwn(replaceShortcodes(caption)), 1024))
+	}
+	if threadTS != "" {
+		replyTo, _ := json.Marshal(telegramReplyTo(threadTS))
+		w.WriteField("reply_parameters", string(replyTo))
+	}
+	part, err := w.CreateFormFile("document", filename)
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	w.Close()
+
+	var msg telegramMessage
+	if err := telegramRequest(config, httpClient, "sendDocument", w.FormDataContentType(), body.Bytes(), &msg); err != nil {
+		return "", err
+	}
+	if threadTS != "" {
+		recordReply(channelID, strconv.FormatInt(msg.MessageID, 10), threadTS)
+	}
+	return "", nil
+}
+
+func (telegramChat) UploadSnippet(config *Config, channelID, threadTS, filename, content, title string) (string, error) {
+	return uploadTelegram(config, channelID, threadTS, filename, []byte(content), title)
+}
+
+func (telegramChat) UploadFile(config *Config, channelID, threadTS, filePath, comment string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return uploadTelegram(config, channelID, threadTS, filepath.Base(filePath), data, comment)
+}
+
+// ChannelName returns a group's title (a private chat's username)
+func (telegramChat) ChannelName(config *Config, channelID string) (string, error) {
+	var chat struct {
+		Title    string `json:"title"`
+		Username string `json:"username"`
+	}
+	if err := telegramJSON(config, "getChat", map[string]int64{"chat_id": telegramChatID(channelID)}, &chat); err != nil {
+		return "", err
+	}
+	if chat.Title != "" {
+		return chat.Title, nil
+	}
+	return chat.Username, nil
+}
+
+func (telegramChat) ArchiveChannel(config *Config, channelID string) error {
+	return errors.New("archiving chats is not supported on Telegram")
+}
+
+// telegramUpdate is one getUpdates entry
+type telegramUpdate struct {
+	UpdateID      int64            `json:"update_id"`
+	Message       *telegramMessage `json:"message"`
+	CallbackQuery *struct {
+		ID   string `json:"id"`
+		From struct {
+			ID int64 `json:"id"`
+		} `json:"from"`
+		Message *telegramMessage `json:"message"`
+		Data    string           `json:"data"`
+	} `json:"callback_query"`
+}
+
+// Listen long-polls getUpdates until ctx is done, backing off on errors
+func (telegramChat) Listen(ctx context.Context, cfgMgr *ConfigManager) {
+	var offset int64
+	attempt := 0
+	logf("Telegram: polling for updates")
+	for ctx.Err() == nil {
+		body, _ := json.Marshal(map[string]interface{}{
+			"offset":          offset,
+			"timeout":         int(telegramPollTimeout.Seconds()),
+			"allowed_updates": []string{"message", "callback_query"},
+		})
+		var updates []telegramUpdate
+		err := telegramRequest(cfgMgr.Get(), telegramPollClient, "getUpdates", "application/json", body, &updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			delay := reconnectDelay(attempt, rand.Float64())
+			attempt++
+			logf("Telegram getUpdates error: %v (retrying in %v, attempt %d)", err, delay.Round(100*time.Millisecond), attempt)
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			continue
+		}
+		attempt = 0
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			switch {
+			case u.Message != nil:
+				if event, ok := telegramMessageEvent(u.Message); ok {
+					workerPool.Submit(func() {
+						handleSlackEvent(ctx, cfgMgr, event)
+					})
+				}
+			case u.CallbackQuery != nil && u.CallbackQuery.Message != nil:
+				cq := u.CallbackQuery
+				go handleTelegramCallback(cfgMgr, cq.ID, cq.From.ID, cq.Message, cq.Data)
+			}
+		}
+	}
+}
+
+// telegramMessageEvent converts a Telegram message into a Slack message event
+func telegramMessageEvent(msg *telegramMessage) (json.RawMessage, bool) {
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	if text == "" || msg.From == nil {
+		return nil, false
+	}
+	channelID := telegramChannel(msg.Chat.ID)
+	ts := strconv.FormatInt(msg.MessageID, 10)
+	event := map[string]string{
+		"type":    "message",
+		"channel": channelID,
+		"user":    strconv.FormatInt(msg.From.ID, 10),
+		"text":    text,
+		"ts":      ts,
+	}
+	if msg.From.IsBot {
+		event["bot_id"] = event["user"]
+	}
+	if msg.ReplyToMessage != nil {
+		replyTo := strconv.FormatInt(msg.ReplyToMessage.MessageID, 10)
+		event["thread_ts"] = threadRoot(channelID, replyTo)
+		recordReply(channelID, ts, replyTo)
+	}
+	out, _ := json.Marshal(event)
+	return out, true
+}
+
+// handleTelegramCallback acknowledges an inline keyboard tap and runs it as a Slack block action
+func handleTelegramCallback(cfgMgr *ConfigManager, queryID string, userID int64, msg *telegramMessage, data string) {
+	config := cfgMgr.Get()
+	if err := telegramJSON(config, "answerCallbackQuery", map[string]string{"callback_query_id": queryID}, nil); err != nil {
+		logf("Telegram callback ack failed: %v", err)
+	}
+
+	channelID := telegramChannel(msg.Chat.ID)
+	ts := strconv.FormatInt(msg.MessageID, 10)
+	btn, ok := recallButton(data)
+	if !ok {
+		telegramChat{}.UpdateMessage(config, channelID, ts, msg.Text+"\n\n:hourglass: These buttons expired - run the command again")
+		return
+	}
+	handleBlockAction(cfgMgr, chatButtonAction(channelID, strconv.FormatInt(userID, 10), ts, msg.Text, btn))
+}
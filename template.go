@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SessionTemplate scaffolds the folder of a new session (!new --template):
+// a local directory copied, or a git repository cloned without its history,
+// then Prompt is given to Claude as its first instruction
+type SessionTemplate struct {
+	Dir    string `json:"dir,omitempty"`
+	Clone  string `json:"clone,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// templateNames lists the configured templates
+func templateNames(config *Config) []string {
+	var names []string
+	for name := range config.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupTemplate returns a template by name, or an error listing the others
+func lookupTemplate(config *Config, name string) (SessionTemplate, error) {
+	tmpl, ok := config.Templates[name]
+	if !ok {
+		if len(config.Templates) == 0 {
+			return tmpl, fmt.Errorf("no templates configured (see `templates` in the config)")
+		}
+		return tmpl, fmt.Errorf("no template `%s` - available: `%s`", name, strings.Join(templateNames(config), "`, `"))
+	}
+	if (tmpl.Dir == "") == (tmpl.Clone == "") {
+		return tmpl, fmt.Errorf("template `%s` needs exactly one of `dir` or `clone`", name)
+	}
+	return tmpl, nil
+}
+
+// scaffoldFromTemplate fills a new session folder from a template; the result
+// starts as a fresh git repository
+func scaffoldFromTemplate(config *Config, tmpl SessionTemplate, workDir string) error {
+	if tmpl.Clone != "" {
+		if err := cloneRepo(config, tmpl.Clone, workDir); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(workDir, ".git")); err != nil {
+			return err
+		}
+	} else {
+		src := expandHome(tmpl.Dir)
+		if err := copyTemplateDir(src, workDir); err != nil {
+			return err
+		}
+	}
+	_, err := gitOutput(workDir, "init", "--quiet")
+	return err
+}
+
+// copyTemplateDir copies a directory tree, keeping file modes and symlinks
+// and skipping .git
+func copyTemplateDir(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("`%s` is not a directory", src)
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFileMode(path, target, info.Mode().Perm())
+		}
+		return nil // sockets, devices...
+	})
+}
+
+// copyFileMode copies a file, creating dst with mode
+func copyFileMode(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ansiEscapeRe matches CSI sequences (colors, cursor moves), OSC sequences
+// (titles, hyperlinks) and the remaining two-byte escapes
+var ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// isBoxDrawing reports box-drawing and block characters used for borders
+func isBoxDrawing(r rune) bool {
+	return r >= 0x2500 && r <= 0x259F
+}
+
+// isSpinnerGlyph reports characters CLIs animate as progress spinners
+func isSpinnerGlyph(r rune) bool {
+	return (r >= 0x2800 && r <= 0x28FF) || strings.ContainsRune("◐◓◑◒◴◷◶◵✶✸✹✺✻✽·", r)
+}
+
+// sanitizeTerminalOutput turns captured terminal output into plain text for Slack:
+// escape sequences are removed, carriage-return and backspace redraws are applied
+// (only the final state of a progress line is kept), border-only lines are dropped
+// and consecutive spinner frames collapse into one line
+func sanitizeTerminalOutput(s string) string {
+	s = ansiEscapeRe.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	var lines []string
+	var lastKey string
+	for _, line := range strings.Split(s, "\n") {
+		line = applyRedraws(line)
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && strings.IndexFunc(trimmed, func(r rune) bool { return !isBoxDrawing(r) && !unicode.IsSpace(r) }) < 0 {
+			continue // ─────, ╭──╮ and friends
+		}
+		// Strip side borders ("│ text │"), keeping the text's indentation
+		if r, _ := utf8.DecodeRuneInString(line); isBoxDrawing(r) {
+			line = strings.TrimPrefix(strings.TrimLeftFunc(line, isBoxDrawing), " ")
+		}
+		line = strings.TrimRightFunc(line, func(r rune) bool { return isBoxDrawing(r) || unicode.IsSpace(r) })
+
+		// "⠋ Installing" / "⠙ Installing": keep only the last frame
+		key := ""
+		if r, size := utf8.DecodeRuneInString(trimmed); isSpinnerGlyph(r) && strings.HasPrefix(trimmed[size:], " ") {
+			key = strings.TrimSpace(trimmed[size:])
+		}
+		if key != "" && key == lastKey && len(lines) > 0 {
+			lines[len(lines)-1] = line
+			continue
+		}
+		lastKey = key
+		lines = append(lines, line)
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// applyRedraws resolves \r (return to line start) and \b (backspace) the way a
+// terminal would, and drops other control characters
+func applyRedraws(line string) string {
+	var buf []rune
+	col := 0
+	for _, r := range line {
+		switch {
+		case r == '\r':
+			col = 0
+		case r == '\b':
+			if col > 0 {
+				col--
+			}
+		case r == '\t' || !unicode.IsControl(r):
+			if col < len(buf) {
+				buf[col] = r
+			} else {
+				buf = append(buf, r)
+			}
+			col++
+		}
+	}
+	return string(buf)
+}
+
+// toolResultText extracts the text of a tool_result's content, which is either a
+// JSON string or a list of text blocks; anything else is shown as raw JSON
+func toolResultText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &blocks); err == nil && len(blocks) > 0 {
+		var texts []string
+		for _, b := range blocks {
+			if b.Type == "text" {
+				texts = append(texts, b.Text)
+			}
+		}
+		if len(texts) > 0 {
+			return strings.Join(texts, "\n")
+		}
+	}
+	return string(raw)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// finishedRunRetention is how long finished runs stay in the registry
+const finishedRunRetention = 7 * 24 * time.Hour
+
+// RunThread records where a Claude run posts in Slack. Runs are keyed by the
+// Claude session ID, which hooks also receive, so every component (stream,
+// hooks, a restarted listener) can find the right thread without relying on
+// in-memory state or message timestamps.
+type RunThread struct {
+	RunID       string     `json:"run_id"`
+	ChannelID   string     `json:"channel_id"`
+	ThreadTS    string     `json:"thread_ts,omitempty"`    // empty: run posts in the channel
+	HeartbeatTS string     `json:"heartbeat_ts,omitempty"` // "Working..." message currently shown
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// ThreadRegistry is the persisted run ID -> thread map (~/.ccsa/threads.json).
+// The file is re-read on every access since hooks run in separate processes.
+type ThreadRegistry struct {
+	mu   sync.Mutex
+	path string
+}
+
+var threadRegistry = &ThreadRegistry{path: getThreadRegistryPath()}
+
+// getThreadRegistryPath returns the path to the thread registry file
+func getThreadRegistryPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "threads.json")
+}
+
+func (r *ThreadRegistry) load() map[string]*RunThread {
+	runs := make(map[string]*RunThread)
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return runs
+	}
+	json.Unmarshal(data, &runs)
+	return runs
+}
+
+func (r *ThreadRegistry) save(runs map[string]*RunThread) {
+	// Drop old finished runs so the file stays small
+	for id, run := range runs {
+		if run.FinishedAt != nil && time.Since(*run.FinishedAt) > finishedRunRetention {
+			delete(runs, id)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, r.path)
+}
+
+// update applies fn to the run (creating it if needed) and persists the registry
+func (r *ThreadRegistry) update(runID string, fn func(run *RunThread)) {
+	if runID == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runs := r.load()
+	run, ok := runs[runID]
+	if !ok {
+		run = &RunThread{RunID: runID, StartedAt: time.Now()}
+		runs[runID] = run
+	}
+	fn(run)
+	r.save(runs)
+}
+
+// Register records the thread a run posts into (marks it running again if resumed)
+func (r *ThreadRegistry) Register(runID, channelID, threadTS string) {
+	r.update(runID, func(run *RunThread) {
+		run.ChannelID = channelID
+		run.ThreadTS = threadTS
+		run.FinishedAt = nil
+	})
+}
+
+// SetHeartbeat records (or clears, with "") the run's heartbeat message
+func (r *ThreadRegistry) SetHeartbeat(runID, ts string) {
+	r.update(runID, func(run *RunThread) {
+		run.HeartbeatTS = ts
+	})
+}
+
+// Finish marks the run as done
+func (r *ThreadRegistry) Finish(runID string) {
+	r.update(runID, func(run *RunThread) {
+		now := time.Now()
+		run.FinishedAt = &now
+		run.HeartbeatTS = ""
+	})
+}
+
+// Lookup returns the thread for a run
+func (r *ThreadRegistry) Lookup(runID string) (*RunThread, bool) {
+	if runID == "" {
+		return nil, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.load()[runID]
+	return run, ok
+}
+
+// Unfinished returns runs that never reported completion (e.g. the listener died mid-run)
+func (r *ThreadRegistry) Unfinished() []*RunThread {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var runs []*RunThread
+	for _, run := range r.load() {
+		if run.FinishedAt == nil {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// Stale returns runs still showing a heartbeat message that finished or
+// started before cutoff
+func (r *ThreadRegistry) Stale(cutoff time.Time) []*RunThread {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var runs []*RunThread
+	for _, run := range r.load() {
+		if run.HeartbeatTS != "" && (run.FinishedAt != nil || run.StartedAt.Before(cutoff)) {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// repairInterruptedRuns closes out runs left open by a previous listener:
+// stale heartbeats are replaced and the thread is told the run was interrupted
+func repairInterruptedRuns(config *Config) {
+	for _, run := range threadRegistry.Unfinished() {
+		if _, running := activeProcesses.Load(run.ChannelID); running {
+			continue
+		}
+		logf("Repairing interrupted run %s in channel %s", run.RunID, run.ChannelID)
+		notice := ":warning: Interrupted - the listener restarted while this task was running. Send a message to continue."
+		if run.HeartbeatTS != "" {
+			updateMessage(config, run.ChannelID, run.HeartbeatTS, notice)
+		} else {
+			postToRunThread(config, run, notice)
+		}
+		threadRegistry.Finish(run.RunID)
+		timeline.Record(TimelineEvent{ChannelID: run.ChannelID, Kind: timelineInterrupted})
+	}
+}
+
+// postToRunThread posts into the run's thread, or the channel for channel-level runs
+func postToRunThread(config *Config, run *RunThread, text string) error {
+	if run.ThreadTS != "" {
+		return sendMessageToThread(config, run.ChannelID, run.ThreadTS, text)
+	}
+	_, err := sendMessage(config, run.ChannelID, text)
+	return err
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// timelineRetention is how long timeline events are kept
+	timelineRetention = 30 * 24 * time.Hour
+	// timelineCompactSize rewrites the file without expired events past this size
+	timelineCompactSize = 2 << 20
+	// timelineMaxLines caps how many events !timeline shows
+	timelineMaxLines = 40
+)
+
+// Timeline event kinds
+const (
+	timelinePrompt      = "prompt"
+	timelineRun         = "run"
+	timelineCommit      = "commit"
+	timelineReset       = "reset"
+	timelineRestart     = "restart"
+	timelineInterrupted = "interrupted"
+	timelinePurge       = "purge"
+)
+
+// TimelineEvent is one entry of a session's history. Listener-wide events
+// (restarts) have no channel and show up in every session's timeline.
+type TimelineEvent struct {
+	At           time.Time `json:"at"`
+	ChannelID    string    `json:"channel_id,omitempty"`
+	Kind         string    `json:"kind"`
+	Text         string    `json:"text,omitempty"`
+	DurationMs   int       `json:"duration_ms,omitempty"`
+	Turns        int       `json:"turns,omitempty"`
+	CostUSD      float64   `json:"cost_usd,omitempty"`
+	InputTokens  int       `json:"input_tokens,omitempty"`
+	OutputTokens int       `json:"output_tokens,omitempty"`
+	Failed       bool      `json:"failed,omitempty"`
+}
+
+// Timeline is the append-only event log behind !timeline (~/.ccsa/timeline.jsonl)
+type Timeline struct {
+	mu   sync.Mutex
+	path string
+}
+
+var timeline = &Timeline{path: getTimelinePath()}
+
+// getTimelinePath returns the path to the timeline log
+func getTimelinePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "timeline.jsonl")
+}
+
+// Record appends an event
+func (t *Timeline) Record(ev TimelineEvent) {
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		logf("Timeline: %v", err)
+		return
+	}
+	f.Write(append(data, '\n'))
+	info, _ := f.Stat()
+	f.Close()
+
+	if info != nil && info.Size() > timelineCompactSize {
+		t.compactLocked(ev.At)
+	}
+}
+
+// readLocked returns all events in file order
+func (t *Timeline) readLocked() []TimelineEvent {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var events []TimelineEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var ev TimelineEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) == nil {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// compactLocked drops events older than timelineRetention
+func (t *Timeline) compactLocked(now time.Time) {
+	var buf strings.Builder
+	for _, ev := range t.readLocked() {
+		if now.Sub(ev.At) > timelineRetention {
+			continue
+		}
+		data, _ := json.Marshal(ev)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(buf.String()), 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, t.path)
+}
+
+// LastActivity returns when each channel last had an event
+func (t *Timeline) LastActivity() map[string]time.Time {
+	t.mu.Lock()
+	all := t.readLocked()
+	t.mu.Unlock()
+
+	last := make(map[string]time.Time)
+	for _, ev := range all {
+		if ev.ChannelID != "" && ev.At.After(last[ev.ChannelID]) {
+			last[ev.ChannelID] = ev.At
+		}
+	}
+	return last
+}
+
+// Events returns a channel's events (plus listener-wide ones) since a time, oldest first
+func (t *Timeline) Events(channelID string, since time.Time) []TimelineEvent {
+	t.mu.Lock()
+	all := t.readLocked()
+	t.mu.Unlock()
+
+	var events []TimelineEvent
+	for _, ev := range all {
+		if (ev.ChannelID == channelID || ev.ChannelID == "") && !ev.At.Before(since) {
+			events = append(events, ev)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return events
+}
+
+// timelinePromptText shortens a prompt to its first line
+func timelinePromptText(prompt string) string {
+	prompt = strings.TrimSpace(strings.TrimPrefix(prompt, slackUserPrefix))
+	if line, _, ok := strings.Cut(prompt, "\n"); ok {
+		prompt = line + " ..."
+	}
+	if r := []rune(prompt); len(r) > 100 {
+		prompt = string(r[:100]) + "..."
+	}
+	return prompt
+}
+
+// recordRunTimeline records a finished run and the commits it made since headBefore
+func recordRunTimeline(msg *QueuedMessage, resp *ClaudeResponse, runErr error, headBefore string) {
+	ev := TimelineEvent{ChannelID: msg.ChannelID, Kind: timelineRun}
+	if runErr != nil {
+		ev.Failed = true
+		ev.Text = runErr.Error()
+	} else {
+		ev.DurationMs, ev.Turns, ev.CostUSD, ev.Failed = resp.DurationMs, resp.NumTurns, resp.CostUSD, resp.IsError
+		ev.InputTokens, ev.OutputTokens = resp.Usage.InputTokens, resp.Usage.OutputTokens
+	}
+	timeline.Record(ev)
+
+	if headBefore == "" {
+		return
+	}
+	log, err := gitOutput(msg.WorkDir, "log", "--reverse", "--format=%h %s", headBefore+"..HEAD")
+	if err != nil || log == "" {
+		return
+	}
+	for _, line := range strings.Split(log, "\n") {
+		timeline.Record(TimelineEvent{ChannelID: msg.ChannelID, Kind: timelineCommit, Text: line})
+	}
+}
+
+// formatTimeline renders events grouped by day, newest last
+func formatTimeline(sessionName string, events []TimelineEvent, days int) string {
+	if len(events) == 0 {
+		return fmt.Sprintf(":spiral_calendar_pad: Nothing recorded for `%s` in the last %d day(s)", sessionName, days)
+	}
+
+	runs, commits := 0, 0
+	var cost float64
+	for _, ev := range events {
+		switch ev.Kind {
+		case timelineRun:
+			runs++
+			cost += ev.CostUSD
+		case timelineCommit:
+			commits++
+		}
+	}
+
+	var lines []string
+	header := fmt.Sprintf(":spiral_calendar_pad: *Timeline for `%s`* - last %d day(s): %d run(s), %d commit(s)", sessionName, days, runs, commits)
+	if cost > 0 {
+		header += fmt.Sprintf(", $%.2f", cost)
+	}
+	lines = append(lines, header)
+	if len(events) > timelineMaxLines {
+		lines = append(lines, fmt.Sprintf("_%d earlier event(s) not shown_", len(events)-timelineMaxLines))
+		events = events[len(events)-timelineMaxLines:]
+	}
+
+	day := ""
+	for _, ev := range events {
+		local := ev.At.Local()
+		if d := local.Format("Mon Jan 2"); d != day {
+			day = d
+			lines = append(lines, "*"+d+"*")
+		}
+		lines = append(lines, fmt.Sprintf("`%s` %s", local.Format("15:04"), formatTimelineEvent(ev)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTimelineEvent renders one event without its time
+func formatTimelineEvent(ev TimelineEvent) string {
+	switch ev.Kind {
+	case timelinePrompt:
+		return ":speech_balloon: " + ev.Text
+	case timelineRun:
+		if ev.Failed {
+			if ev.Text != "" {
+				return ":x: Run failed: " + ev.Text
+			}
+			return ":x: Run ended with an error"
+		}
+		var parts []string
+		if ev.DurationMs > 0 {
+			parts = append(parts, formatDuration(time.Duration(ev.DurationMs)*time.Millisecond))
+		}
+		if ev.Turns > 0 {
+			parts = append(parts, fmt.Sprintf("%d turn(s)", ev.Turns))
+		}
+		if ev.CostUSD > 0 {
+			parts = append(parts, fmt.Sprintf("$%.2f", ev.CostUSD))
+		}
+		if len(parts) == 0 {
+			return ":white_check_mark: Run finished"
+		}
+		return ":white_check_mark: Run finished (" + strings.Join(parts, ", ") + ")"
+	case timelineCommit:
+		return ":package: Commit " + ev.Text
+	case timelineReset:
+		return ":broom: Conversation reset"
+	case timelineRestart:
+		return ":arrows_counterclockwise: Listener restarted"
+	case timelineInterrupted:
+		return ":warning: Run interrupted by a listener restart"
+	case timelinePurge:
+		return ":wastebasket: " + ev.Text
+	}
+	return ev.Kind + " " + ev.Text
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// liveStateInterval is how often the listener writes ~/.ccsa/live.json for `top`
+const liveStateInterval = 2 * time.Second
+
+// liveRun is what a channel's run is doing right now
+type liveRun struct {
+	Started  time.Time
+	Activity string
+}
+
+// liveRuns holds the current activity of running channels (channelID -> liveRun)
+var liveRuns sync.Map
+
+// startLiveRun marks the start of a channel's run
+func startLiveRun(channelID string) {
+	liveRuns.Store(channelID, liveRun{Started: time.Now(), Activity: "Starting"})
+}
+
+// setLiveActivity records what the channel's run is doing (last tool call, writing...)
+func setLiveActivity(channelID, activity string) {
+	run := liveRun{Started: time.Now()}
+	if prev, ok := liveRuns.Load(channelID); ok {
+		run = prev.(liveRun)
+	}
+	activity = strings.Join(strings.Fields(strings.NewReplacer("`", "", "*", "").Replace(activity)), " ")
+	run.Activity = activity
+	liveRuns.Store(channelID, run)
+}
+
+// LiveChannel is a channel's in-memory state as published for `top`
+type LiveChannel struct {
+	Running  bool      `json:"running"`
+	Since    time.Time `json:"since,omitempty"`
+	Queue    int       `json:"queue"`
+	Activity string    `json:"activity,omitempty"`
+}
+
+// LiveState is the listener's in-memory state, published in ~/.ccsa/live.json
+// since runs and queues aren't visible to other processes otherwise
+type LiveState struct {
+	PID       int                    `json:"pid"`
+	UpdatedAt time.Time              `json:"updated_at"`
+	Channels  map[string]LiveChannel `json:"channels"`
+}
+
+// getLiveStatePath returns the path to the published listener state
+func getLiveStatePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "live.json")
+}
+
+// snapshotLiveState collects the running and queued state of every session channel
+func snapshotLiveState(config *Config) LiveState {
+	state := LiveState{PID: os.Getpid(), UpdatedAt: time.Now(), Channels: make(map[string]LiveChannel)}
+	for _, channelID := range config.Sessions {
+		var ch LiveChannel
+		_, ch.Running = activeProcesses.Load(channelID)
+		if messageQueue != nil {
+			ch.Queue = messageQueue.QueueLength(channelID)
+		}
+		if run, ok := liveRuns.Load(channelID); ok && ch.Running {
+			ch.Since, ch.Activity = run.(liveRun).Started, run.(liveRun).Activity
+		}
+		state.Channels[channelID] = ch
+	}
+	return state
+}
+
+// startLiveStateWriter publishes the listener's state until done, then removes it
+func startLiveStateWriter(cfgMgr *ConfigManager, done <-chan struct{}) {
+	path := getLiveStatePath()
+	write := func() {
+		config := cfgMgr.Get()
+		if config == nil {
+			return
+		}
+		data, err := json.Marshal(snapshotLiveState(config))
+		if err != nil || os.MkdirAll(filepath.Dir(path), 0700) != nil {
+			return
+		}
+		tmp := path + ".tmp"
+		if os.WriteFile(tmp, data, 0600) == nil {
+			os.Rename(tmp, path)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(liveStateInterval)
+		defer ticker.Stop()
+		write()
+		for {
+			select {
+			case <-done:
+				os.Remove(path)
+				return
+			case <-ticker.C:
+				write()
+			}
+		}
+	}()
+}
+
+// loadLiveState reads the listener's published state; ok is false when the
+// listener isn't running (no file, or not refreshed lately)
+func loadLiveState(now time.Time) (LiveState, bool) {
+	var state LiveState
+	data, err := os.ReadFile(getLiveStatePath())
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return LiveState{}, false
+	}
+	return state, now.Sub(state.UpdatedAt) < 3*liveStateInterval
+}
+
+// topRow is one session line of `top`
+type topRow struct {
+	Name         string
+	ChannelID    string
+	Where        string // "", "agent <name>" or "sandbox"
+	Live         LiveChannel
+	LastActivity time.Time
+	InputTokens  int
+	OutputTokens int
+}
+
+// collectTopRows builds the session list from the config, the published live
+// state and the timeline (usage over its 30 days)
+func collectTopRows(config *Config, live LiveState, events map[string][]TimelineEvent) []topRow {
+	var rows []topRow
+	for name, channelID := range config.Sessions {
+		row := topRow{Name: name, ChannelID: channelID, Live: live.Channels[channelID]}
+		if host := config.SessionHosts[name]; host != "" {
+			row.Where = "agent " + host
+		} else if config.SandboxSessions[name] {
+			row.Where = "sandbox"
+		}
+		for _, ev := range events[channelID] {
+			if ev.ChannelID == "" {
+				continue
+			}
+			row.LastActivity = ev.At
+			row.InputTokens += ev.InputTokens
+			row.OutputTokens += ev.OutputTokens
+		}
+		rows = append(rows, row)
+	}
+	// Busy sessions first, then most recently active
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Live.Running != rows[j].Live.Running {
+			return rows[i].Live.Running
+		}
+		if !rows[i].LastActivity.Equal(rows[j].LastActivity) {
+			return rows[i].LastActivity.After(rows[j].LastActivity)
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+// fitWidth pads or cuts s to exactly n columns
+func fitWidth(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		if n <= 1 {
+			return string(r[:n])
+		}
+		return string(r[:n-1]) + "…"
+	}
+	return s + strings.Repeat(" ", n-len(r))
+}
+
+// renderTop draws the session table
+func renderTop(rows []topRow, selected int, live LiveState, listenerUp bool, status string, width int, now time.Time) string {
+	var b strings.Builder
+	header := "claude-code-slack-anywhere top - "
+	if listenerUp {
+		header += fmt.Sprintf("listener running (pid %d)", live.PID)
+	} else {
+		header += "listener NOT running"
+	}
+	b.WriteString(fitWidth(header, width-9) + now.Format(" 15:04:05") + "\n\n")
+
+	activityWidth := max(width-68, 10)
+	b.WriteString("  " + fitWidth("SESSION", 22) + fitWidth("STATE", 14) + fitWidth("QUEUE", 6) + fitWidth("ACTIVITY", activityWidth) + fitWidth("LAST", 10) + "TOKENS (30d)\n")
+	if len(rows) == 0 {
+		b.WriteString("  No sessions. Create one from Slack with !new <name>\n")
+	}
+	for i, row := range rows {
+		cursor := "  "
+		if i == selected {
+			cursor = "> "
+		}
+		state := "idle"
+		if row.Live.Running {
+			state = "running"
+			if !row.Live.Since.IsZero() {
+				state += " " + formatDuration(now.Sub(row.Live.Since))
+			}
+		}
+		if !listenerUp {
+			state = "-"
+		}
+		name := row.Name
+		if row.Where != "" {
+			name += " (" + row.Where + ")"
+		}
+		last := "-"
+		if !row.LastActivity.IsZero() {
+			last = formatDuration(now.Sub(row.LastActivity).Truncate(time.Minute)) + " ago"
+		}
+		tokens := fmt.Sprintf("%s in / %s out", formatTokenCount(row.InputTokens), formatTokenCount(row.OutputTokens))
+		b.WriteString(cursor + fitWidth(name, 22) + fitWidth(state, 14) + fitWidth(strconv.Itoa(row.Live.Queue), 6) +
+			fitWidth(row.Live.Activity, activityWidth) + fitWidth(last, 10) + tokens + "\n")
+	}
+	b.WriteString("\n[j/k] select  [a] attach  [c] cancel run  [t] tail  [q] quit\n")
+	if status != "" {
+		b.WriteString(status + "\n")
+	}
+	return b.String()
+}
+
+// renderTail shows the end of a session's transcript, the last height lines
+func renderTail(name string, exchanges []transcriptExchange, width, height int) string {
+	var lines []string
+	for _, ex := range exchanges {
+		lines = append(lines, "", fmt.Sprintf("── You, %s", ex.At.Local().Format("Jan 2 15:04")))
+		lines = append(lines, strings.Split(ex.Prompt, "\n")...)
+		who := "── Claude"
+		if ex.Tools > 0 {
+			who += fmt.Sprintf(" (%d tool calls)", ex.Tools)
+		}
+		lines = append(lines, who)
+		lines = append(lines, strings.Split(ex.Reply, "\n")...)
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(fitWidth(line, width), " ")
+	}
+	if keep := height - 3; len(lines) > keep {
+		lines = lines[len(lines)-keep:]
+	}
+	return fmt.Sprintf("tail %s - [any key] back\n%s\n", name, strings.Join(lines, "\n"))
+}
+
+// stty runs stty on the terminal and returns its output
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// terminalSize returns the terminal's columns and rows (80x24 if unknown)
+func terminalSize() (int, int) {
+	out, err := stty("size")
+	if err == nil {
+		if f := strings.Fields(out); len(f) == 2 {
+			rows, _ := strconv.Atoi(f[0])
+			cols, _ := strconv.Atoi(f[1])
+			if rows > 0 && cols > 0 {
+				return cols, rows
+			}
+		}
+	}
+	return 80, 24
+}
+
+// runTop is the `top` command: a live view of the sessions the listener manages
+func runTop() error {
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("not configured. Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("top needs an interactive terminal")
+	}
+	rawMode := func() { stty("-icanon", "-echo", "min", "1"); fmt.Print("\x1b[?25l") }
+	restore := func() { stty(saved); fmt.Print("\x1b[?25h\x1b[H\x1b[2J") }
+	rawMode()
+	defer restore()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	// Keys are read one request at a time, so nothing reads the terminal
+	// while an attached Claude owns it
+	keys := make(chan byte)
+	wantKey := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 1)
+		for range wantKey {
+			if n, err := os.Stdin.Read(buf); err != nil || n == 0 {
+				close(keys)
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+	wantKey <- struct{}{}
+
+	var (
+		selected   int
+		status     string
+		tailing    string // channel being tailed
+		events     map[string][]TimelineEvent
+		eventsAt   time.Time
+		rows       []topRow
+		ticker     = time.NewTicker(time.Second)
+		escPending int
+	)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		if c, err := loadConfig(); err == nil {
+			config = c
+		}
+		loadSessionsFromDisk() // Claude session IDs the listener stored
+		// The timeline file is bigger: re-read it every 10s
+		if now.Sub(eventsAt) > 10*time.Second {
+			events = make(map[string][]TimelineEvent)
+			for _, channelID := range config.Sessions {
+				events[channelID] = timeline.Events(channelID, now.Add(-timelineRetention))
+			}
+			eventsAt = now
+		}
+		live, up := loadLiveState(now)
+		rows = collectTopRows(config, live, events)
+		selected = min(max(selected, 0), max(len(rows)-1, 0))
+		width, height := terminalSize()
+
+		screen := renderTop(rows, selected, live, up, status, width, now)
+		if tailing != "" {
+			name := getSessionByChannel(config, tailing)
+			screen = fmt.Sprintf("tail %s - no transcript\n", name)
+			if sid, ok := getClaudeSessionID(tailing); ok && name != "" {
+				path := filepath.Join(claudeTranscriptDir(sessionWorkDir(config, name)), sid+".jsonl")
+				if exchanges, err := readTranscriptExchanges(path); err == nil {
+					screen = renderTail(name, exchanges, width, height)
+				}
+			}
+		}
+		fmt.Print("\x1b[H\x1b[2J" + strings.ReplaceAll(screen, "\n", "\r\n"))
+
+		select {
+		case <-sigs:
+			return nil
+		case <-ticker.C:
+			continue
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			// Arrow keys arrive as ESC [ A / ESC [ B
+			switch {
+			case key == 0x1b:
+				escPending = 1
+			case escPending == 1 && key == '[':
+				escPending = 2
+			default:
+				if escPending == 2 {
+					key = map[byte]byte{'A': 'k', 'B': 'j'}[key]
+				}
+				escPending = 0
+				if tailing != "" {
+					tailing, key = "", 0 // any key leaves the tail
+				}
+			}
+			if escPending > 0 || key == 0 {
+				wantKey <- struct{}{}
+				continue
+			}
+			status = ""
+
+			var row *topRow
+			if selected < len(rows) {
+				row = &rows[selected]
+			}
+			if key == 'a' && row != nil {
+				status = attachSession(config, row, restore, rawMode)
+			}
+			wantKey <- struct{}{}
+
+			switch key {
+			case 'q':
+				return nil
+			case 'j':
+				selected++
+			case 'k':
+				selected--
+			case 't':
+				if row != nil {
+					tailing = row.ChannelID
+				}
+			case 'c':
+				if row == nil {
+					continue
+				}
+				if !row.Live.Running {
+					status = fmt.Sprintf("%s has no run to cancel", row.Name)
+					continue
+				}
+				if err := writeInboxMessage(getInboxDir(), InboxMessage{Action: inboxCancel, Session: row.Name, ChannelID: row.ChannelID, CreatedAt: now}); err != nil {
+					status = "Cancel failed: " + err.Error()
+				} else {
+					status = "Cancel sent for " + row.Name
+				}
+			}
+		}
+	}
+}
+
+// attachSession opens the session's conversation in an interactive Claude in
+// this terminal, and comes back to `top` when it exits
+func attachSession(config *Config, row *topRow, restore, rawMode func()) string {
+	switch {
+	case row.Where != "":
+		return fmt.Sprintf("%s runs on %s: attach from there", row.Name, row.Where)
+	case row.Live.Running:
+		return fmt.Sprintf("%s is running: cancel or wait before attaching", row.Name)
+	case claudePath == "":
+		return "claude binary not found"
+	}
+	args := []string{}
+	if sid, ok := getClaudeSessionID(row.ChannelID); ok {
+		args = append(args, "--resume", sid)
+	}
+	cmd := exec.Command(claudePath, args...)
+	cmd.Dir = sessionWorkDir(config, row.Name)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	restore()
+	err := cmd.Run()
+	rawMode()
+	if err != nil {
+		return fmt.Sprintf("claude exited: %v", err)
+	}
+	return "Detached from " + row.Name + " (Slack messages continue this conversation)"
+}
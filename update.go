@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	updateReleaseURL    = "https://api.github.com/repos/sderosiaux/claudeslack/releases/latest"
+	updateCheckInterval = 24 * time.Hour
+	// updateNotesLimit keeps the release notes of the notice short
+	updateNotesLimit = 1500
+)
+
+// githubRelease is the part of a GitHub release the update check reads
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+// getUpdateStatePath returns the file remembering the last version announced
+func getUpdateStatePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ccsa", "update.json")
+}
+
+// parseVersion reads "v2.1.0" as its numbers (missing or non-numeric parts are 0)
+func parseVersion(v string) [3]int {
+	var out [3]int
+	v, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(v), "v"), "-")
+	for i, part := range strings.SplitN(v, ".", 3) {
+		out[i], _ = strconv.Atoi(part)
+	}
+	return out
+}
+
+// newerVersion reports whether latest is a later release than current
+func newerVersion(latest, current string) bool {
+	l, c := parseVersion(latest), parseVersion(current)
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// formatUpdateNotice announces a release with its notes
+func formatUpdateNotice(rel githubRelease, current string) string {
+	tag := "v" + strings.TrimPrefix(rel.TagName, "v")
+	text := fmt.Sprintf(":package: *%s available* (running v%s) - update with `git pull && make install`, then restart the listener", tag, current)
+	if notes := strings.TrimSpace(rel.Body); notes != "" {
+		text += "\n\n" + markdownToSlack(truncateRunes(notes, updateNotesLimit))
+	}
+	if rel.HTMLURL != "" {
+		text += fmt.Sprintf("\n<%s|Release notes>", rel.HTMLURL)
+	}
+	return text
+}
+
+// updateChannel returns where update notices go: update_channel, or the
+// first authorized user's DM with the bot
+func updateChannel(config *Config) string {
+	if config.UpdateChannel != "" {
+		return config.UpdateChannel
+	}
+	if len(config.UserIDs) > 0 {
+		return config.UserIDs[0]
+	}
+	return config.UserID
+}
+
+// fetchLatestRelease asks GitHub for the latest release
+func fetchLatestRelease() (githubRelease, error) {
+	var rel githubRelease
+	resp, err := httpClient.Get(updateReleaseURL)
+	if err != nil {
+		return rel, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return rel, fmt.Errorf("github: %s", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&rel)
+	return rel, err
+}
+
+// checkForUpdate posts a notice for a newer release, once per release
+func checkForUpdate(config *Config) {
+	rel, err := fetchLatestRelease()
+	if err != nil {
+		logf("Update check failed: %v", err)
+		return
+	}
+	if !newerVersion(rel.TagName, version) {
+		return
+	}
+	var state struct {
+		Notified string `json:"notified"`
+	}
+	path := getUpdateStatePath()
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	if state.Notified == rel.TagName {
+		return
+	}
+	target := updateChannel(config)
+	if target == "" {
+		return
+	}
+	if _, err := sendMessage(config, target, formatUpdateNotice(rel, version)); err != nil {
+		logf("Failed to post update notice: %v", err)
+		return
+	}
+	logf("Announced %s (running v%s)", rel.TagName, version)
+	state.Notified = rel.TagName
+	if data, err := json.Marshal(state); err == nil && os.MkdirAll(filepath.Dir(path), 0700) == nil {
+		os.WriteFile(path, data, 0600)
+	}
+}
+
+// startUpdateCheck looks for a new release at startup, then daily
+func startUpdateCheck(cfgMgr *ConfigManager, done <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(updateCheckInterval)
+		defer ticker.Stop()
+		for {
+			if config := cfgMgr.Get(); config != nil && (config.CheckUpdates == nil || *config.CheckUpdates) {
+				checkForUpdate(config)
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// isAudioFile checks if a Slack file is an audio clip (voice message or audio upload)
+func isAudioFile(file SlackFile) bool {
+	if file.Subtype == "slack_audio" {
+		return true
+	}
+	return strings.HasPrefix(file.Mimetype, "audio/")
+}
+
+// transcriptionConfigured reports whether a local command or API is set up
+func transcriptionConfigured(config *Config) bool {
+	return config.TranscribeCommand != "" || config.TranscribeAPIURL != ""
+}
+
+// transcribeAudio converts an audio file to text using the configured backend
+// (local command first, then the OpenAI-compatible API)
+func transcribeAudio(config *Config, audioPath string) (string, error) {
+	if config.TranscribeCommand != "" {
+		return transcribeWithCommand(config.TranscribeCommand, audioPath)
+	}
+	if config.TranscribeAPIURL != "" {
+		return transcribeWithAPI(config.TranscribeAPIURL, config.TranscribeAPIKey, audioPath)
+	}
+	return "", fmt.Errorf("no transcription backend configured (set transcribe_command or transcribe_api_url)")
+}
+
+// transcribeWithCommand runs a local command (e.g. whisper.cpp); {file} is replaced
+// by the quoted audio path and stdout is used as the transcript
+func transcribeWithCommand(command, audioPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cmdStr := command
+	if strings.Contains(cmdStr, "{file}") {
+		cmdStr = strings.ReplaceAll(cmdStr, "{file}", shellQuote(audioPath))
+	} else {
+		cmdStr += " " + shellQuote(audioPath)
+	}
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", cmdStr)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("transcription command failed: %w - %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	transcript := strings.TrimSpace(stdout.String())
+	if transcript == "" {
+		return "", fmt.Errorf("transcription command produced no output")
+	}
+	return transcript, nil
+}
+
+// transcribeWithAPI posts the audio to an OpenAI-compatible /audio/transcriptions endpoint
+func transcribeWithAPI(apiURL, apiKey, audioPath string) (string, error) {
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("model", "whisper-1")
+	part, err := writer.CreateFormFile("file", filepath.Base(audioPath))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", apiURL, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Text  string `json:"text"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse transcription response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if result.Error != nil {
+		return "", fmt.Errorf("transcription API error: %s", result.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("transcription API returned HTTP %d", resp.StatusCode)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// transcribeAudioAttachments downloads and transcribes every audio file.
+// Returns the joined transcripts and per-file failures.
+func transcribeAudioAttachments(config *Config, files []SlackFile, workDir string) (string, []string) {
+	var transcripts []string
+	var failures []string
+
+	uploadsDir := filepath.Join(workDir, ".slack-uploads")
+	for _, file := range files {
+		if !isAudioFile(file) {
+			continue
+		}
+		if err := os.MkdirAll(uploadsDir, 0755); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", file.Name, err))
+			continue
+		}
+		localPath, err := downloadSlackFileToDir(config, file, uploadsDir)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", file.Name, err))
+			continue
+		}
+		transcript, err := transcribeAudio(config, localPath)
+		if err != nil {
+			logf("Transcription failed for %s: %v", file.Name, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", file.Name, err))
+			continue
+		}
+		logf("Transcribed %s (%d chars)", file.Name, len(transcript))
+		transcripts = append(transcripts, transcript)
+	}
+
+	return strings.Join(transcripts, "\n\n"), failures
+}
+
+// shellQuote single-quotes a string for bash
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long a watch waits for changes to settle before prompting
+const watchDebounce = 5 * time.Second
+
+// watchMaxFiles caps the changed files listed in a watch prompt
+const watchMaxFiles = 20
+
+// watchSkipDirs are never watched (build output, dependencies, VCS data)
+var watchSkipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true, "target": true, "__pycache__": true,
+}
+
+// globToRegexp converts a glob with ** (any directories), *, ? and {a,b} into
+// a regexp matching slash-separated paths relative to the workdir
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	inBraces := false
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '{' && !inBraces:
+			inBraces = true
+			b.WriteString("(?:")
+		case c == '}' && inBraces:
+			inBraces = false
+			b.WriteString(")")
+		case c == ',' && inBraces:
+			b.WriteString("|")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if inBraces {
+		return nil, fmt.Errorf("unclosed { in %q", glob)
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// FileWatch turns file changes matching a glob into prompts, posted in its own thread
+type FileWatch struct {
+	ID        string
+	ChannelID string
+	Pattern   string
+	Prompt    string
+	ThreadTS  string
+	WorkDir   string
+	UserID    string
+	Started   time.Time
+
+	match   *regexp.Regexp
+	watcher *fsnotify.Watcher
+	mu      sync.Mutex
+	changed map[string]bool
+	timer   *time.Timer
+}
+
+// WatchManager holds the active file watches. Watches last until !watch stop
+// or a listener restart.
+type WatchManager struct {
+	mu      sync.Mutex
+	watches map[string]*FileWatch // ID -> watch
+	nextID  int
+}
+
+var watchManager = &WatchManager{watches: make(map[string]*FileWatch)}
+
+// Start watches workDir for changes matching pattern
+func (wm *WatchManager) Start(config *Config, channelID, userID, workDir, pattern, prompt string) (*FileWatch, error) {
+	match, err := globToRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := addWatchDirs(watcher, workDir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	wm.mu.Lock()
+	wm.nextID++
+	w := &FileWatch{
+		ID:        fmt.Sprintf("watch-%d", wm.nextID),
+		ChannelID: channelID,
+		Pattern:   pattern,
+		Prompt:    prompt,
+		WorkDir:   workDir,
+		UserID:    userID,
+		Started:   time.Now(),
+		match:     match,
+		watcher:   watcher,
+		changed:   make(map[string]bool),
+	}
+	wm.watches[w.ID] = w
+	wm.mu.Unlock()
+
+	threadTS, err := sendMessage(config, channelID, fmt.Sprintf(":eyes: *Watching* `%s` (`%s`) - changes run: %s\nResults are posted in this thread. Stop with `!watch stop %s`", pattern, w.ID, prompt, w.ID))
+	if err != nil {
+		wm.Stop(channelID, w.ID)
+		return nil, err
+	}
+	w.ThreadTS = threadTS
+	go w.loop()
+	logf("Watch %s started in %s: %s", w.ID, workDir, pattern)
+	return w, nil
+}
+
+// Stop ends a channel's watch ("all" stops every watch of the channel)
+func (wm *WatchManager) Stop(channelID, id string) int {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	stopped := 0
+	for wid, w := range wm.watches {
+		if w.ChannelID != channelID || (id != "all" && wid != id) {
+			continue
+		}
+		w.watcher.Close()
+		w.mu.Lock()
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		w.mu.Unlock()
+		delete(wm.watches, wid)
+		stopped++
+	}
+	return stopped
+}
+
+// List returns a channel's watches, oldest first
+func (wm *WatchManager) List(channelID string) []*FileWatch {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	var list []*FileWatch
+	for _, w := range wm.watches {
+		if w.ChannelID == channelID {
+			list = append(list, w)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Started.Before(list[j].Started) })
+	return list
+}
+
+// addWatchDirs adds root and its subdirectories (fsnotify isn't recursive)
+func addWatchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && (watchSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// loop collects matching changes until the watcher is closed
+func (w *FileWatch) loop() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					addWatchDirs(w.watcher, event.Name)
+					continue
+				}
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			rel, err := filepath.Rel(w.WorkDir, event.Name)
+			if err != nil || !w.match.MatchString(filepath.ToSlash(rel)) {
+				continue
+			}
+			// Changes made while Claude runs are its own edits: reacting to
+			// them would loop
+			if _, running := activeProcesses.Load(w.ChannelID); running {
+				continue
+			}
+			w.mu.Lock()
+			w.changed[filepath.ToSlash(rel)] = true
+			if w.timer != nil {
+				w.timer.Stop()
+			}
+			w.timer = time.AfterFunc(watchDebounce, w.fire)
+			w.mu.Unlock()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logf("Watch %s error: %v", w.ID, err)
+		}
+	}
+}
+
+// fire submits the watch prompt with the files changed since the last run
+func (w *FileWatch) fire() {
+	w.mu.Lock()
+	files := make([]string, 0, len(w.changed))
+	for f := range w.changed {
+		files = append(files, f)
+	}
+	w.changed = make(map[string]bool)
+	w.mu.Unlock()
+	if len(files) == 0 || configMgr == nil {
+		return
+	}
+	config := configMgr.Get()
+	if config == nil {
+		return
+	}
+
+	prompt, shown := watchPrompt(w.Prompt, files)
+	eventTS, err := sendMessageToThreadGetTS(config, w.ChannelID, w.ThreadTS, fmt.Sprintf(":arrows_counterclockwise: %s changed, running the watch prompt", shown))
+	if err != nil {
+		logf("Watch %s: failed to post: %v", w.ID, err)
+		return
+	}
+	msg := &QueuedMessage{
+		Text:      prompt,
+		ChannelID: w.ChannelID,
+		ThreadTS:  w.ThreadTS,
+		EventTS:   eventTS,
+		UserID:    w.UserID,
+		WorkDir:   w.WorkDir,
+	}
+	addReaction(config, msg.ChannelID, msg.EventTS, "eyes")
+	submitClaudeMessage(msg, config)
+}
+
+// watchPrompt appends the changed files to the prompt; shown summarizes them for Slack
+func watchPrompt(prompt string, files []string) (string, string) {
+	sort.Strings(files)
+	shown := fmt.Sprintf("`%s`", files[0])
+	if len(files) > 1 {
+		shown = fmt.Sprintf("%d files", len(files))
+	}
+	more := 0
+	if len(files) > watchMaxFiles {
+		more = len(files) - watchMaxFiles
+		files = files[:watchMaxFiles]
+	}
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nFiles changed since the last run:")
+	for _, f := range files {
+		b.WriteString("\n- " + f)
+	}
+	if more > 0 {
+		fmt.Fprintf(&b, "\n- ... and %d more", more)
+	}
+	return b.String(), shown
+}
+
+// parseWatchArgs splits `<glob> "<prompt>"` (quotes optional)
+func parseWatchArgs(arg string) (pattern, prompt string, ok bool) {
+	pattern, prompt, _ = strings.Cut(strings.TrimSpace(arg), " ")
+	prompt = strings.TrimSpace(prompt)
+	prompt = strings.TrimSpace(strings.Trim(prompt, `"“”`))
+	return pattern, prompt, pattern != "" && prompt != ""
+}
+
+// handleWatchCommand handles `!watch <glob> "<prompt>"`, `!watch list` and `!watch stop <id|all>`
+func handleWatchCommand(config *Config, channelID, userID, args string) string {
+	usage := "Usage: `!watch <glob> \"<prompt>\"` (e.g. `!watch src/**/*.go \"run go vet and summarize new issues\"`), `!watch list`, `!watch stop <id|all>`"
+	sessionName := getSessionByChannel(config, channelID)
+	if sessionName == "" {
+		return ":x: Not in a session channel. Use `!watch` in a session channel."
+	}
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return usage
+	}
+
+	switch fields[0] {
+	case "list":
+		watches := watchManager.List(channelID)
+		if len(watches) == 0 {
+			return ":eyes: No active watches in this channel"
+		}
+		lines := []string{":eyes: *Active watches:*"}
+		for _, w := range watches {
+			lines = append(lines, fmt.Sprintf("• `%s` `%s` since %s: %s", w.ID, w.Pattern, w.Started.Local().Format("Jan 2 15:04"), w.Prompt))
+		}
+		return strings.Join(lines, "\n")
+	case "stop":
+		if len(fields) != 2 {
+			return usage
+		}
+		if n := watchManager.Stop(channelID, fields[1]); n > 0 {
+			return fmt.Sprintf(":stop_sign: Stopped %d watch(es)", n)
+		}
+		return fmt.Sprintf(":shrug: No watch `%s` in this channel", fields[1])
+	}
+
+	if host := sessionHost(config, channelID); host != "" {
+		return fmt.Sprintf(":x: This session runs on agent `%s`: its files can't be watched from here", host)
+	}
+	pattern, prompt, ok := parseWatchArgs(args)
+	if !ok {
+		return usage
+	}
+	workDir := sessionWorkDir(config, sessionName)
+	if _, err := watchManager.Start(config, channelID, userID, workDir, pattern, prompt); err != nil {
+		return fmt.Sprintf(":x: Could not start watch: %v", err)
+	}
+	return ""
+}
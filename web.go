@@ -0,0 +1,380 @@
+package main
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	webDefaultAddr  = "127.0.0.1:7420"
+	webUsageDays    = 14
+	webStreamPoll   = time.Second
+	webStreamMax    = 20 // exchanges sent by the live stream
+	webTokenHeader  = "X-CCSA-Token"
+	webTokenMetaTag = `<meta name="ccsa-token" content="%s">`
+)
+
+//go:embed webui
+var webAssets embed.FS
+
+// webSession is a session as listed by the dashboard
+type webSession struct {
+	Name         string      `json:"name"`
+	ChannelID    string      `json:"channel_id"`
+	Where        string      `json:"where,omitempty"`
+	Live         LiveChannel `json:"live"`
+	LastActivity time.Time   `json:"last_activity,omitempty"`
+	InputTokens  int         `json:"input_tokens"`
+	OutputTokens int         `json:"output_tokens"`
+}
+
+// webUsageDay is one bar of the usage graph
+type webUsageDay struct {
+	Day          string  `json:"day"` // YYYY-MM-DD, local time
+	Runs         int     `json:"runs"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// webUsage sums the runs of the last days days per day, oldest first (days
+// without runs included, so the graph has no gaps)
+func webUsage(events []TimelineEvent, days int, now time.Time) []webUsageDay {
+	usage := make([]webUsageDay, days)
+	index := make(map[string]int, days)
+	for i := range usage {
+		day := now.AddDate(0, 0, i-days+1).Format("2006-01-02")
+		usage[i].Day = day
+		index[day] = i
+	}
+	for _, ev := range events {
+		if ev.Kind != timelineRun || ev.ChannelID == "" {
+			continue
+		}
+		i, ok := index[ev.At.Local().Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		usage[i].Runs++
+		usage[i].InputTokens += ev.InputTokens
+		usage[i].OutputTokens += ev.OutputTokens
+		usage[i].CostUSD += ev.CostUSD
+	}
+	return usage
+}
+
+// webHostAllowed reports whether a request's Host is the local machine, so a
+// page on another site can't reach the dashboard through DNS rebinding
+func webHostAllowed(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// webServer serves the dashboard. Actions go through the listener's inbox,
+// like `send` and `top`, so the dashboard works beside a running listener.
+type webServer struct {
+	token string // required on actions, handed to the page only
+	index []byte
+}
+
+func newWebServer() (*webServer, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	page, err := fs.ReadFile(webAssets, "webui/index.html")
+	if err != nil {
+		return nil, err
+	}
+	s := &webServer{token: hex.EncodeToString(buf)}
+	s.index = []byte(strings.Replace(string(page), "<!--ccsa-token-->", fmt.Sprintf(webTokenMetaTag, html.EscapeString(s.token)), 1))
+	return s, nil
+}
+
+func (s *webServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/api/sessions", s.handleSessions)
+	mux.HandleFunc("/api/usage", s.handleUsage)
+	mux.HandleFunc("/api/stream", s.handleStream)
+	mux.HandleFunc("/api/send", s.action(s.handleSend))
+	mux.HandleFunc("/api/cancel", s.action(s.handleInboxAction(inboxCancel)))
+	mux.HandleFunc("/api/reset", s.action(s.handleInboxAction(inboxReset)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !webHostAllowed(r.Host) {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// action guards a state-changing endpoint: POST only, with the page's token
+func (s *webServer) action(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get(webTokenHeader) != s.token {
+			http.Error(w, "invalid token", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
+
+// webConfig loads the current config (the listener may change it while we run)
+func webConfig(w http.ResponseWriter) *Config {
+	config, err := loadConfig()
+	if err != nil {
+		http.Error(w, "not configured", http.StatusServiceUnavailable)
+		return nil
+	}
+	return config
+}
+
+func (s *webServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(s.index)
+}
+
+func (s *webServer) handleSessions(w http.ResponseWriter, r *http.Request) {
+	config := webConfig(w)
+	if config == nil {
+		return
+	}
+	now := time.Now()
+	events := make(map[string][]TimelineEvent)
+	for _, channelID := range config.Sessions {
+		events[channelID] = timeline.Events(channelID, now.Add(-timelineRetention))
+	}
+	live, up := loadLiveState(now)
+	sessions := []webSession{}
+	for _, row := range collectTopRows(config, live, events) {
+		sessions = append(sessions, webSession{
+			Name: row.Name, ChannelID: row.ChannelID, Where: row.Where, Live: row.Live,
+			LastActivity: row.LastActivity, InputTokens: row.InputTokens, OutputTokens: row.OutputTokens,
+		})
+	}
+	writeJSON(w, map[string]interface{}{"listener": up, "sessions": sessions})
+}
+
+// handleUsage returns the per-day usage of a channel, or of every session
+// without ?channel=
+func (s *webServer) handleUsage(w http.ResponseWriter, r *http.Request) {
+	config := webConfig(w)
+	if config == nil {
+		return
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -webUsageDays)
+	var events []TimelineEvent
+	if channelID := r.URL.Query().Get("channel"); channelID != "" {
+		events = timeline.Events(channelID, since)
+	} else {
+		for _, channelID := range config.Sessions {
+			events = append(events, timeline.Events(channelID, since)...)
+		}
+	}
+	writeJSON(w, webUsage(events, webUsageDays, now))
+}
+
+// webTranscriptPath returns the transcript of a channel's conversation
+func webTranscriptPath(config *Config, channelID string) (string, bool) {
+	name := getSessionByChannel(config, channelID)
+	if name == "" || config.SessionHosts[name] != "" || config.SandboxSessions[name] {
+		return "", false
+	}
+	loadSessionsFromDisk() // Claude session IDs the listener stored
+	sid, ok := getClaudeSessionID(channelID)
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(claudeTranscriptDir(sessionWorkDir(config, name)), sid+".jsonl"), true
+}
+
+// webStreamEvent is one update of the live stream
+type webStreamEvent struct {
+	Live      LiveChannel          `json:"live"`
+	Listener  bool                 `json:"listener"`
+	Exchanges []transcriptExchange `json:"exchanges"`
+	Note      string               `json:"note,omitempty"`
+}
+
+// handleStream sends a channel's live state and its last exchanges as
+// server-sent events whenever either changes
+func (s *webServer) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	channelID := r.URL.Query().Get("channel")
+	if channelID == "" {
+		http.Error(w, "missing channel", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+
+	ticker := time.NewTicker(webStreamPoll)
+	defer ticker.Stop()
+	var (
+		last    string
+		fileKey string
+		shown   []transcriptExchange
+	)
+	for {
+		if config, err := loadConfig(); err == nil {
+			ev := webStreamEvent{Exchanges: shown}
+			live, up := loadLiveState(time.Now())
+			ev.Live, ev.Listener = live.Channels[channelID], up
+
+			if path, ok := webTranscriptPath(config, channelID); !ok {
+				ev.Note, ev.Exchanges, fileKey = "No transcript for this session here", nil, ""
+			} else if info, err := os.Stat(path); err != nil {
+				ev.Note, ev.Exchanges, fileKey = "Transcript not found", nil, ""
+			} else if key := fmt.Sprintf("%s %d %d", path, info.Size(), info.ModTime().UnixNano()); key != fileKey {
+				// Only re-read the transcript when it changed
+				if exchanges, err := readTranscriptExchanges(path); err == nil {
+					if len(exchanges) > webStreamMax {
+						exchanges = exchanges[len(exchanges)-webStreamMax:]
+					}
+					ev.Exchanges, fileKey = exchanges, key
+				}
+			}
+			shown = ev.Exchanges
+
+			data, _ := json.Marshal(ev)
+			if string(data) != last {
+				last = string(data)
+				fmt.Fprintf(w, "data: %s\n\n", data)
+				flusher.Flush()
+			}
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// webActionRequest is the body of the dashboard's actions
+type webActionRequest struct {
+	ChannelID string `json:"channel_id"`
+	Text      string `json:"text,omitempty"`
+}
+
+func readWebAction(w http.ResponseWriter, r *http.Request) (*Config, string, webActionRequest, bool) {
+	var req webActionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return nil, "", req, false
+	}
+	config := webConfig(w)
+	if config == nil {
+		return nil, "", req, false
+	}
+	name := getSessionByChannel(config, req.ChannelID)
+	if name == "" {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return nil, "", req, false
+	}
+	return config, name, req, true
+}
+
+func (s *webServer) handleSend(w http.ResponseWriter, r *http.Request) {
+	config, name, req, ok := readWebAction(w, r)
+	if !ok {
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		http.Error(w, "empty message", http.StatusBadRequest)
+		return
+	}
+	if err := sendPromptToSession(config, name, text, "the dashboard"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, map[string]string{"status": "sent"})
+}
+
+// handleInboxAction hands a cancel or reset to the listener
+func (s *webServer) handleInboxAction(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, name, req, ok := readWebAction(w, r)
+		if !ok {
+			return
+		}
+		m := InboxMessage{Action: action, Session: name, ChannelID: req.ChannelID, CreatedAt: time.Now()}
+		if err := writeInboxMessage(getInboxDir(), m); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]string{"status": action + " requested"})
+	}
+}
+
+// runWeb implements `web [--addr host:port]`
+func runWeb(args []string) error {
+	addr := webDefaultAddr
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--addr" && i+1 < len(args):
+			addr = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--addr="):
+			addr = strings.TrimPrefix(args[i], "--addr=")
+		default:
+			return fmt.Errorf("usage: claude-code-slack-anywhere web [--addr host:port]")
+		}
+	}
+	if _, err := loadConfig(); err != nil {
+		return fmt.Errorf("not configured. Run: claude-code-slack-anywhere setup <bot_token> <app_token>")
+	}
+	s, err := newWebServer()
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if host, _, _ := net.SplitHostPort(addr); !webHostAllowed(host) {
+		fmt.Println("Warning: the dashboard is only reachable as localhost (it rejects other Host names)")
+	}
+	fmt.Printf("Dashboard on http://%s (Ctrl+C to stop)\n", ln.Addr())
+	return http.Serve(ln, s.handler())
+}